@@ -39,7 +39,8 @@ type processor struct {
 	outf string
 	errf string
 
-	format string
+	format   string
+	compress string
 
 	out ion.Writer
 	err *ErrorReport
@@ -76,6 +77,13 @@ func newProcessor(args []string) (*processor, error) {
 			}
 			ret.format = args[i]
 
+		case "-c", "--compress":
+			i++
+			if i >= len(args) {
+				return nil, errors.New("no compression specified")
+			}
+			ret.compress = args[i]
+
 		case "-e", "--error-report":
 			i++
 			if i >= len(args) {
@@ -112,22 +120,39 @@ func (p *processor) run() (deferredErr error) {
 		}
 	}()
 
+	var newWriter func(out io.Writer) ion.Writer
 	switch p.format {
 	case "", "pretty":
-		p.out = ion.NewTextWriterOpts(outf, ion.TextWriterPretty)
+		newWriter = func(out io.Writer) ion.Writer { return ion.NewTextWriterOpts(out, ion.TextWriterPretty) }
 	case "text":
-		p.out = ion.NewTextWriter(outf)
+		newWriter = func(out io.Writer) ion.Writer { return ion.NewTextWriter(out) }
 	case "binary":
-		p.out = ion.NewBinaryWriter(outf)
+		newWriter = func(out io.Writer) ion.Writer { return ion.NewBinaryWriter(out) }
+	case "binary11":
+		newWriter = func(out io.Writer) ion.Writer { return ion.NewBinaryWriter11(out) }
+	case "json":
+		newWriter = func(out io.Writer) ion.Writer { return ion.NewJSONWriter(out) }
+	case "pretty-json":
+		newWriter = func(out io.Writer) ion.Writer { return ion.NewJSONWriterOpts(out, ion.JSONWriterPretty) }
 	case "events":
-		p.out = NewEventWriter(outf)
+		newWriter = NewEventWriter
 	case "none":
-		p.out = NewNopWriter()
+		newWriter = func(out io.Writer) ion.Writer { return NewNopWriter() }
 	default:
 		err = errors.New("unrecognized output format \"" + p.format + "\"")
 		return err
 	}
 
+	switch p.compress {
+	case "", "none":
+		p.out = newWriter(outf)
+	case "gzip":
+		p.out = ion.NewGzipWriter(outf, newWriter)
+	default:
+		err = errors.New("unrecognized compression \"" + p.compress + "\"")
+		return err
+	}
+
 	errf, err := OpenError(p.errf)
 	if err != nil {
 		return err
@@ -208,7 +233,7 @@ func (p *processor) processFile(in string) (err error) {
 func (p *processor) processReader(in io.Reader) {
 	// We intentionally ignore the returned error; it's been written
 	// to p.err, and only gets returned to short-circuit further execution.
-	p.process(ion.NewReader(in))
+	p.process(ion.NewReaderOpts(in, nil, ion.ReaderDetectGzip))
 }
 
 func (p *processor) process(in ion.Reader) error {
@@ -0,0 +1,534 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+)
+
+// Opcodes used by the Ion 1.1 binary encoding. Opcodes not listed here are
+// grouped into ranges (e.g., 0x60-0x68 for ints) and handled by the encoders
+// and decoders directly.
+const (
+	op11Int             = 0x60
+	op11Float           = 0x6A
+	op11True            = 0x6E
+	op11False           = 0x6F
+	op11Decimal         = 0x70
+	op11Timestamp       = 0x80
+	op11String          = 0x90
+	op11SymbolText      = 0xA0
+	op11List            = 0xB0
+	op11Sexp            = 0xC0
+	op11Struct          = 0xD0
+	op11IVM             = 0xE0
+	op11SymbolAddr1     = 0xE1
+	op11SymbolAddr2     = 0xE2
+	op11SymbolAddr3     = 0xE3
+	op11Annotations1    = 0xE4
+	op11Annotations2    = 0xE5
+	op11AnnotationsLen  = 0xE6
+	op11AnnotationSym1  = 0xE7
+	op11AnnotationSym2  = 0xE8
+	op11AnnotationsSym  = 0xE9
+	op11Null            = 0xEA
+	op11TypedNull       = 0xEB
+	op11NOP             = 0xEC
+	op11NOPLen          = 0xED
+	op11SystemSymbol    = 0xEE
+	op11SystemMacro     = 0xEF
+	op11DelimitedEnd    = 0xF0
+	op11DelimitedList   = 0xF1
+	op11DelimitedSexp   = 0xF2
+	op11DelimitedStruct = 0xF3
+	op11MacroFlex       = 0xF4
+	op11MacroFlexLen    = 0xF5
+	op11IntLen          = 0xF6
+	op11DecimalLen      = 0xF7
+	op11TimestampLen    = 0xF8
+	op11StringLen       = 0xF9
+	op11SymbolTextLen   = 0xFA
+	op11ListLen         = 0xFB
+	op11SexpLen         = 0xFC
+	op11StructLen       = 0xFD
+	op11BlobLen         = 0xFE
+	op11ClobLen         = 0xFF
+)
+
+// Bytes following a FlexSym escape (a FlexInt zero).
+const (
+	flexSymZero   = 0x60 // Symbol zero ($0).
+	flexSymEmpty  = 0xA0 // The empty symbol ('').
+	flexSymSystem = 0xEE // A system symbol, followed by its 1-byte ID.
+	flexSymEnd    = 0xF0 // The end of a delimited struct.
+)
+
+// typedNulls11 maps the byte following an op11TypedNull to the type of the null.
+var typedNulls11 = []Type{
+	BoolType,
+	IntType,
+	FloatType,
+	DecimalType,
+	TimestampType,
+	StringType,
+	SymbolType,
+	BlobType,
+	ClobType,
+	ListType,
+	SexpType,
+	StructType,
+}
+
+// shortTimestampLengths11 holds the body lengths of the short-form timestamp
+// opcodes 0x80-0x8C.
+var shortTimestampLengths11 = []uint64{1, 2, 2, 4, 5, 6, 7, 8, 5, 5, 7, 8, 9}
+
+// Biases applied to 2- and 3-byte symbol addresses (opcodes 0xE2 and 0xE3).
+const (
+	symbolAddressBias2 = 256
+	symbolAddressBias3 = 65792
+)
+
+// E-expressions invoking macros at addresses below 64 are written as a single
+// opcode (0x00-0x3F). Opcodes 0x40-0x4F and 0x50-0x5F hold the low four bits of
+// larger addresses, the rest of which follow in one or two bytes.
+const (
+	op11MacroAddr12 = 0x40
+	op11MacroAddr20 = 0x50
+
+	macroAddressBias12 = 64
+	macroAddressBias20 = 4160
+	macroAddressMax20  = macroAddressBias20 + 1<<20
+)
+
+// How an argument to a variadic or optional parameter is encoded, as recorded
+// by two bits in an e-expression's argument encoding bitmap.
+const (
+	argNone11   = 0 // The argument is omitted.
+	argSingle11 = 1 // The argument is a single expression.
+	argGroup11  = 2 // The argument is an expression group.
+)
+
+// aebLen11 returns the number of bytes in the argument encoding bitmap of an
+// e-expression invoking m. Each parameter other than an ExactlyOne parameter
+// takes two bits.
+func aebLen11(m *Macro) int {
+	n := 0
+	for _, p := range m.params {
+		if p.Cardinality != ExactlyOne {
+			n++
+		}
+	}
+	return (2*n + 7) / 8
+}
+
+// argEncodings11 decodes an argument encoding bitmap, returning how the argument
+// for each of m's parameters is encoded.
+func argEncodings11(m *Macro, aeb []byte) ([]byte, error) {
+	encs := make([]byte, len(m.params))
+	i := uint(0)
+	for j, p := range m.params {
+		if p.Cardinality == ExactlyOne {
+			encs[j] = argSingle11
+			continue
+		}
+
+		enc := (aeb[i/4] >> (i % 4 * 2)) & 0x3
+		if enc > argGroup11 {
+			return nil, fmt.Errorf("invalid argument encoding for %v", p.Name)
+		}
+		encs[j] = enc
+		i++
+	}
+	return encs, nil
+}
+
+// appendArgEncodings11 appends the argument encoding bitmap recording how the
+// argument for each of m's parameters is encoded.
+func appendArgEncodings11(b []byte, m *Macro, encs []byte) []byte {
+	aeb := make([]byte, aebLen11(m))
+	i := uint(0)
+	for j, p := range m.params {
+		if p.Cardinality == ExactlyOne {
+			continue
+		}
+		if j < len(encs) {
+			aeb[i/4] |= encs[j] << (i % 4 * 2)
+		}
+		i++
+	}
+	return append(b, aeb...)
+}
+
+// appendMacroAddress11 appends the opcode (and any following bytes) of an
+// e-expression invoking the macro at the given address.
+func appendMacroAddress11(b []byte, addr uint64) []byte {
+	switch {
+	case addr < macroAddressBias12:
+		return append(b, byte(addr))
+	case addr < macroAddressBias20:
+		a := addr - macroAddressBias12
+		return append(b, op11MacroAddr12|byte(a&0xF), byte(a>>4))
+	case addr < macroAddressMax20:
+		a := addr - macroAddressBias20
+		return appendFixed(append(b, op11MacroAddr20|byte(a&0xF)), a>>4, 2)
+	default:
+		return appendFlexUint(append(b, op11MacroFlex), addr)
+	}
+}
+
+// V11SystemSymbolTable is the system symbol table for Ion v1.1. Its first nine
+// symbols match V1SystemSymbolTable.
+var V11SystemSymbolTable = NewSharedSymbolTable("$ion", 2, []string{
+	"$ion",
+	"$ion_1_0",
+	"$ion_symbol_table",
+	"name",
+	"version",
+	"imports",
+	"symbols",
+	"max_id",
+	"$ion_shared_symbol_table",
+	"$ion_encoding",
+	"$ion_literal",
+	"$ion_shared_module",
+	"macro",
+	"macro_table",
+	"symbol_table",
+	"module",
+	"retain",
+	"export",
+	"catalog_key",
+	"import",
+	"literal",
+	"if_none",
+	"if_some",
+	"if_single",
+	"if_multi",
+	"for",
+	"default",
+	"values",
+	"annotate",
+	"make_string",
+	"make_symbol",
+	"make_blob",
+	"make_decimal",
+	"make_timestamp",
+	"make_list",
+	"make_sexp",
+	"make_struct",
+	"make_field",
+	"parse_ion",
+	"repeat",
+	"delta",
+	"flatten",
+	"sum",
+	"set_symbols",
+	"add_symbols",
+	"set_macros",
+	"add_macros",
+	"use",
+	"meta",
+	"none",
+	"flex_symbol",
+	"flex_int",
+	"flex_uint",
+	"uint8",
+	"uint16",
+	"uint32",
+	"uint64",
+	"int8",
+	"int16",
+	"int32",
+	"int64",
+	"float16",
+	"float32",
+	"float64",
+	"encoding",
+})
+
+// FlexUInts and FlexInts are little-endian, variable-length integers. The number
+// of trailing zero bits in the encoding, plus one, gives the number of bytes; the
+// value occupies the remaining bits.
+
+// flexLen returns the number of bytes in the FlexUInt or FlexInt at the start of
+// bs, or 0 if bs does not yet hold enough bytes to tell.
+func flexLen(bs []byte) int {
+	for i, c := range bs {
+		if c != 0 {
+			return i*8 + bits.TrailingZeros8(c) + 1
+		}
+	}
+	return 0
+}
+
+// decodeFlexUint decodes the FlexUInt at the start of bs, returning its value and
+// length in bytes.
+func decodeFlexUint(bs []byte) (uint64, int, error) {
+	n := flexLen(bs)
+	if n == 0 || n > len(bs) {
+		return 0, 0, fmt.Errorf("ion: truncated FlexUInt")
+	}
+
+	if n <= 8 {
+		return fixedUint(bs[:n]) >> uint(n), n, nil
+	}
+
+	v := new(big.Int).Rsh(fixedUintBig(bs[:n]), uint(n))
+	if !v.IsUint64() {
+		return 0, 0, fmt.Errorf("ion: FlexUInt too large")
+	}
+	return v.Uint64(), n, nil
+}
+
+// decodeFlexInt decodes the FlexInt at the start of bs, returning its value and
+// length in bytes.
+func decodeFlexInt(bs []byte) (int64, int, error) {
+	n := flexLen(bs)
+	if n == 0 || n > len(bs) {
+		return 0, 0, fmt.Errorf("ion: truncated FlexInt")
+	}
+
+	if n <= 8 {
+		return fixedInt64(bs[:n]) >> uint(n), n, nil
+	}
+
+	v := new(big.Int).Rsh(fixedIntBig(bs[:n]), uint(n))
+	if !v.IsInt64() {
+		return 0, 0, fmt.Errorf("ion: FlexInt too large")
+	}
+	return v.Int64(), n, nil
+}
+
+// fixedUint decodes a little-endian unsigned integer of at most 8 bytes.
+func fixedUint(bs []byte) uint64 {
+	v := uint64(0)
+	for i := len(bs) - 1; i >= 0; i-- {
+		v = v<<8 | uint64(bs[i])
+	}
+	return v
+}
+
+// fixedInt64 decodes a little-endian two's complement integer of at most 8 bytes.
+func fixedInt64(bs []byte) int64 {
+	if len(bs) == 0 {
+		return 0
+	}
+	shift := uint(64 - 8*len(bs))
+	return int64(fixedUint(bs)<<shift) >> shift
+}
+
+// fixedUintBig decodes a little-endian unsigned integer of any length.
+func fixedUintBig(bs []byte) *big.Int {
+	be := make([]byte, len(bs))
+	for i, c := range bs {
+		be[len(bs)-1-i] = c
+	}
+	return new(big.Int).SetBytes(be)
+}
+
+// fixedIntBig decodes a little-endian two's complement integer of any length.
+func fixedIntBig(bs []byte) *big.Int {
+	v := fixedUintBig(bs)
+	if len(bs) > 0 && bs[len(bs)-1]&0x80 != 0 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(8*len(bs))))
+	}
+	return v
+}
+
+// fixedInt decodes a little-endian two's complement integer, returning an int64
+// if it fits or a *big.Int otherwise.
+func fixedInt(bs []byte) interface{} {
+	if len(bs) <= 8 {
+		return fixedInt64(bs)
+	}
+
+	v := fixedIntBig(bs)
+	if v.IsInt64() {
+		return v.Int64()
+	}
+	return v
+}
+
+// float16 converts an IEEE-754 half-precision float to a float64.
+func float16(h uint16) float64 {
+	exp := int(h>>10) & 0x1F
+	frac := float64(h & 0x3FF)
+
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(frac, -24)
+	case 0x1F:
+		if frac == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(frac+0x400, exp-25)
+	}
+
+	if h&0x8000 != 0 {
+		f = math.Copysign(f, -1)
+	}
+	return f
+}
+
+// bitField extracts n bits starting at bit off from the little-endian bit string bs.
+func bitField(bs []byte, off, n uint) uint64 {
+	v := uint64(0)
+	for i := uint(0); i < n; i++ {
+		bit := off + i
+		v |= uint64(bs[bit/8]>>(bit%8)&1) << i
+	}
+	return v
+}
+
+// flexUintLen returns the number of bytes needed to encode v as a FlexUInt.
+func flexUintLen(v uint64) uint64 {
+	n := (uint64(bits.Len64(v)) + 6) / 7
+	if n == 0 {
+		return 1
+	}
+	return n
+}
+
+// appendFlexUint appends v to b as a FlexUInt.
+func appendFlexUint(b []byte, v uint64) []byte {
+	n := flexUintLen(v)
+	if n <= 8 {
+		return appendFixed(b, v<<n|1<<(n-1), n)
+	}
+
+	x := new(big.Int).SetUint64(v)
+	x.Lsh(x, uint(n))
+	x.SetBit(x, int(n-1), 1)
+	return appendFixedBig(b, x, n)
+}
+
+// flexIntLen returns the number of bytes needed to encode v as a FlexInt.
+func flexIntLen(v int64) uint64 {
+	return (signedBitLen(v) + 6) / 7
+}
+
+// appendFlexInt appends v to b as a FlexInt.
+func appendFlexInt(b []byte, v int64) []byte {
+	n := flexIntLen(v)
+	if n <= 8 {
+		return appendFixed(b, uint64(v)<<n|1<<(n-1), n)
+	}
+
+	x := big.NewInt(v)
+	x.Lsh(x, uint(n))
+	x.Add(x, new(big.Int).Lsh(big.NewInt(1), uint(n-1)))
+	return appendFixedBig(b, x, n)
+}
+
+// fixedUintLen returns the number of bytes needed to encode v as a FixedUInt. Zero
+// needs no bytes at all.
+func fixedUintLen(v uint64) uint64 {
+	return (uint64(bits.Len64(v)) + 7) / 8
+}
+
+// fixedIntLen returns the number of bytes needed to encode v as a FixedInt. Zero
+// needs no bytes at all.
+func fixedIntLen(v int64) uint64 {
+	if v == 0 {
+		return 0
+	}
+	return (signedBitLen(v) + 7) / 8
+}
+
+// fixedIntBigLen returns the number of bytes needed to encode v as a FixedInt.
+func fixedIntBigLen(v *big.Int) uint64 {
+	switch v.Sign() {
+	case 0:
+		return 0
+	case 1:
+		return (uint64(v.BitLen()) + 8) / 8
+	}
+
+	// The magnitude of a negative two's complement number is one larger than
+	// that of its complement.
+	c := new(big.Int).Not(v)
+	return (uint64(c.BitLen()) + 8) / 8
+}
+
+// signedBitLen returns the number of bits needed to represent v in two's complement.
+func signedBitLen(v int64) uint64 {
+	if v < 0 {
+		v = ^v
+	}
+	return uint64(bits.Len64(uint64(v))) + 1
+}
+
+// appendFixed appends the low n bytes of v to b, least significant byte first.
+func appendFixed(b []byte, v uint64, n uint64) []byte {
+	for i := uint64(0); i < n; i++ {
+		b = append(b, byte(v))
+		v >>= 8
+	}
+	return b
+}
+
+// appendFixedBig appends v to b as an n-byte little-endian two's complement
+// integer.
+func appendFixedBig(b []byte, v *big.Int, n uint64) []byte {
+	if v.Sign() < 0 {
+		v = new(big.Int).Add(v, new(big.Int).Lsh(big.NewInt(1), uint(8*n)))
+	}
+
+	be := v.Bytes()
+	for i := uint64(0); i < n; i++ {
+		if i < uint64(len(be)) {
+			b = append(b, be[len(be)-1-int(i)])
+		} else {
+			b = append(b, 0)
+		}
+	}
+	return b
+}
+
+// appendHeader11 appends the opcode and, if needed, the FlexUInt length of a value.
+// Values of length 0-15 use op+length if op is non-zero; longer values (and those
+// without a short form) use opLen followed by the length.
+func appendHeader11(b []byte, op, opLen byte, length uint64) []byte {
+	if op != 0 && length <= 0x0F {
+		return append(b, op+byte(length))
+	}
+	b = append(b, opLen)
+	return appendFlexUint(b, length)
+}
+
+// header11Len returns the number of bytes appendHeader11 will append.
+func header11Len(op byte, length uint64) uint64 {
+	if op != 0 && length <= 0x0F {
+		return 1
+	}
+	return 1 + flexUintLen(length)
+}
+
+// setBitField sets n bits starting at bit off of the little-endian bit string bs.
+func setBitField(bs []byte, off, n uint, v uint64) {
+	for i := uint(0); i < n; i++ {
+		bit := off + i
+		bs[bit/8] |= byte(v>>i&1) << (bit % 8)
+	}
+}
@@ -25,18 +25,50 @@ type binaryReader struct {
 	reader
 
 	bits     bitstream
-	cat      Catalog
 	resetPos uint64
+
+	// The position of the current value, including its annotations.
+	rawStart uint64
+
+	// Once an Ion 1.1 version marker is seen, values are decoded by bits11,
+	// which shares its input with bits.
+	v11    bool
+	bits11 bitstream11
+
+	// When scanning, top-level scalars are skipped over rather than read.
+	scan bool
 }
 
-func newBinaryReaderBuf(in *bufio.Reader, cat Catalog) Reader {
-	r := &binaryReader{
-		cat: cat,
-	}
+func newBinaryReaderBuf(in *bufio.Reader, cat Catalog, mt *MacroTable) Reader {
+	r := newBinaryReader(cat, mt)
 	r.bits.Init(in)
 	return r
 }
 
+// newBinaryReaderBytes creates a binary reader that reads from the given bytes.
+func newBinaryReaderBytes(in []byte, cat Catalog, mt *MacroTable) Reader {
+	r := newBinaryReader(cat, mt)
+	r.bits.InitBytes(in)
+	return r
+}
+
+// newBinaryReaderBytesNoCopy creates a binary reader that reads directly from
+// the given bytes, returning strings and lobs as sub-slices of them.
+func newBinaryReaderBytesNoCopy(in []byte, cat Catalog, mt *MacroTable) Reader {
+	r := newBinaryReader(cat, mt)
+	r.bits.InitBytesNoCopy(in)
+	return r
+}
+
+func newBinaryReader(cat Catalog, mt *MacroTable) *binaryReader {
+	r := &binaryReader{}
+	r.cat = cat
+	r.macros = mt
+	r.defaultMacros = mt
+	r.bits11 = r.newBitstream11()
+	return r
+}
+
 // Reset causes the binary reader to start reading from the given input bytes
 // while skipping most of the initialization steps needed to prepare the
 // reader. Reset is most commonly called with the same bytes as the reader
@@ -63,8 +95,18 @@ func (r *binaryReader) Reset(in []byte) error {
 	r.value = nil
 	r.err = nil
 	r.eof = false
+	r.bytes = nil
+	noCopy := r.bits.noCopy
 	r.bits = bitstream{}
-	r.bits.InitBytes(in[r.resetPos:])
+	if noCopy {
+		r.bits.InitBytesNoCopy(in[r.resetPos:])
+	} else {
+		r.bits.InitBytes(in[r.resetPos:])
+	}
+	r.bits11 = r.newBitstream11()
+	r.applyLimits()
+	r.exp = nil
+	r.hoisted = false
 	return nil
 }
 
@@ -72,21 +114,46 @@ const invalidReset uint64 = 1<<64 - 1
 
 // Next moves the reader to the next value.
 func (r *binaryReader) Next() bool {
-	if r.eof || r.err != nil {
+	if r.eof || r.err != nil || r.atSpanEnd() {
 		return false
 	}
+	ok, err := r.nextExpanded()
+	if err != nil {
+		r.err = err
+		return false
+	}
+	if ok {
+		return r.checkNext()
+	}
 
 	r.clear()
 
 	done := false
 	for !done {
-		done, r.err = r.next()
+		if r.v11 {
+			done, r.err = r.next11()
+		} else {
+			done, r.err = r.next()
+		}
 		if r.err != nil {
 			return false
 		}
 	}
 
-	return !r.eof
+	return r.checkNext()
+}
+
+// checkNext checks the value Next moved to, if any, against the reader's
+// limits, returning true if there is one and it's within them.
+func (r *binaryReader) checkNext() bool {
+	if r.eof {
+		return false
+	}
+	if err := r.checkValue(r.Position().Offset); err != nil {
+		r.err = err
+		return false
+	}
+	return true
 }
 
 // Next consumes the next raw value from the stream, returning true if it
@@ -97,6 +164,19 @@ func (r *binaryReader) next() (bool, error) {
 	}
 
 	code := r.bits.Code()
+	if code != bitcodeFieldID && len(r.annotations) == 0 {
+		r.rawStart = r.bits.tagPos
+	}
+	if isScalarCode(code) {
+		if err := r.checkScalarSize(r.bits.len, r.rawStart); err != nil {
+			return false, err
+		}
+		if r.scan && r.ctx.peek() == ctxAtTopLevel {
+			r.valueType = scalarType(code)
+			return true, nil
+		}
+	}
+
 	switch code {
 	case bitcodeEOF:
 		r.eof = true
@@ -192,11 +272,19 @@ func (r *binaryReader) next() (bool, error) {
 	case bitcodeString:
 		r.valueType = StringType
 		if !r.bits.IsNull() {
-			val, err := r.bits.ReadString()
-			if err != nil {
-				return false, err
+			if r.bits.noCopy {
+				val, err := r.bits.ReadStringBytes()
+				if err != nil {
+					return false, err
+				}
+				r.setBytes(val)
+			} else {
+				val, err := r.bits.ReadString()
+				if err != nil {
+					return false, err
+				}
+				r.value = val
 			}
-			r.value = val
 		}
 		return true, nil
 
@@ -207,7 +295,7 @@ func (r *binaryReader) next() (bool, error) {
 			if err != nil {
 				return false, err
 			}
-			r.value = val
+			r.setLob(val)
 		}
 		return true, nil
 
@@ -218,7 +306,7 @@ func (r *binaryReader) next() (bool, error) {
 			if err != nil {
 				return false, err
 			}
-			r.value = val
+			r.setLob(val)
 		}
 		return true, nil
 
@@ -267,6 +355,47 @@ func (r *binaryReader) next() (bool, error) {
 	panic(fmt.Sprintf("invalid bitcode %v", code))
 }
 
+// Position returns the position of the current value, or of the reader if
+// there is no current value.
+func (r *binaryReader) Position() Position {
+	if r.valueType != NoType {
+		return Position{Offset: r.rawStart}
+	}
+	return Position{Offset: r.bits.pos}
+}
+
+// scalarType returns the type of scalars with the given code.
+func scalarType(code bitcode) Type {
+	switch code {
+	case bitcodeInt, bitcodeNegInt:
+		return IntType
+	case bitcodeFloat:
+		return FloatType
+	case bitcodeDecimal:
+		return DecimalType
+	case bitcodeTimestamp:
+		return TimestampType
+	case bitcodeSymbol:
+		return SymbolType
+	case bitcodeString:
+		return StringType
+	case bitcodeClob:
+		return ClobType
+	case bitcodeBlob:
+		return BlobType
+	}
+	panic(fmt.Sprintf("invalid scalar bitcode %v", code))
+}
+
+// setLob sets the current lob value, without copying it in no-copy mode.
+func (r *binaryReader) setLob(val []byte) {
+	if r.bits.noCopy {
+		r.setBytes(val)
+	} else {
+		r.value = val
+	}
+}
+
 func isIonSymbolTable(as []SymbolToken) bool {
 	return len(as) > 0 && as[0].Text != nil && *as[0].Text == "$ion_symbol_table"
 }
@@ -277,13 +406,31 @@ func (r *binaryReader) readBVM() error {
 	if err != nil {
 		return err
 	}
+	return r.setVersion(major, minor)
+}
 
+// SetVersion switches to the encoding for the given version, resetting the local
+// symbol table and the macro table.
+func (r *binaryReader) setVersion(major, minor byte) error {
 	switch major {
 	case 1:
 		switch minor {
 		case 0:
+			if r.v11 {
+				r.v11 = false
+				r.bits.state = bssBeforeValue
+				r.bits.clear()
+			}
 			r.lst = V1SystemSymbolTable
 			return nil
+
+		case 1:
+			if !r.v11 {
+				r.v11 = true
+				r.bits11 = r.newBitstream11()
+			}
+			r.lst = V11SystemSymbolTable
+			return nil
 		}
 	}
 
@@ -327,6 +474,9 @@ func (r *binaryReader) StepIn() error {
 	if r.err != nil {
 		return r.err
 	}
+	if ok, err := r.stepInExpanded(); ok {
+		return err
+	}
 
 	if r.valueType != ListType && r.valueType != SexpType && r.valueType != StructType {
 		return &UsageError{"Reader.StepIn", fmt.Sprintf("cannot step in to a %v", r.valueType)}
@@ -335,9 +485,18 @@ func (r *binaryReader) StepIn() error {
 		return &UsageError{"Reader.StepIn", "cannot step in to a null container"}
 	}
 
+	if err := r.checkDepth(r.Position().Offset); err != nil {
+		r.err = err
+		return err
+	}
+
 	r.ctx.push(containerTypeToCtx(r.valueType))
 	r.clear()
-	r.bits.StepIn()
+	if r.v11 {
+		r.bits11.StepIn()
+	} else {
+		r.bits.StepIn()
+	}
 
 	return nil
 }
@@ -350,8 +509,15 @@ func (r *binaryReader) StepOut() error {
 	if r.ctx.peek() == ctxAtTopLevel {
 		return &UsageError{"Reader.StepOut", "cannot step out of top-level datagram"}
 	}
+	if r.stepOutExpanded() {
+		return nil
+	}
 
-	if err := r.bits.StepOut(); err != nil {
+	if r.v11 {
+		if err := r.bits11.StepOut(); err != nil {
+			return err
+		}
+	} else if err := r.bits.StepOut(); err != nil {
 		return err
 	}
 
@@ -0,0 +1,396 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"fmt"
+)
+
+// newBitstream11 creates an Ion 1.1 bitstream sharing the reader's input.
+func (r *binaryReader) newBitstream11() bitstream11 {
+	return bitstream11{in: &r.bits, macros: r.macros, maxDepth: r.limits.MaxDepth}
+}
+
+// next11 consumes the next raw Ion 1.1 value from the stream, returning true if it
+// represents a user-facing value and false if it does not.
+func (r *binaryReader) next11() (bool, error) {
+	// Encoding directives may have changed the macros in effect.
+	r.bits11.macros = r.macros
+	if err := r.bits11.Next(); err != nil {
+		return false, err
+	}
+
+	code := r.bits11.Code()
+	r.rawStart = r.bits11.start
+
+	switch code {
+	case bitcodeEOF:
+		r.eof = true
+		return true, nil
+
+	case bitcodeBVM:
+		major, minor, err := r.bits11.ReadBVM()
+		if err != nil {
+			return false, err
+		}
+		return false, r.setVersion(major, minor)
+	}
+
+	if err := r.readFieldName11(); err != nil {
+		return false, err
+	}
+
+	if code == bitcodeEExpression {
+		fieldName := r.fieldName
+		vals, err := r.readEExpression11()
+		if err != nil {
+			return false, err
+		}
+
+		// Return the first value the e-expression expands to, if any.
+		r.expand(vals, fieldName)
+		ok, err := r.nextExpanded()
+		if err != nil || ok {
+			return ok, err
+		}
+		r.clear()
+		return false, nil
+	}
+
+	return r.readValue11(code)
+}
+
+// readFieldName11 resolves the current value's field name, if it has one.
+func (r *binaryReader) readFieldName11() error {
+	if name := r.bits11.FieldName(); name != nil {
+		st, err := r.symbolToken11(*name)
+		if err != nil {
+			return err
+		}
+		r.fieldName = &st
+	}
+	return nil
+}
+
+// readValue11 resolves the annotations of the current value, then reads it,
+// returning true if it's a user-facing value.
+func (r *binaryReader) readValue11(code bitcode) (bool, error) {
+	if isScalarCode(code) {
+		if err := r.checkScalarSize(r.bits11.len, r.rawStart); err != nil {
+			return false, err
+		}
+	}
+
+	for _, a := range r.bits11.Annotations() {
+		st, err := r.symbolToken11(a)
+		if err != nil {
+			return false, err
+		}
+		r.annotations = append(r.annotations, st)
+	}
+
+	null := r.bits11.IsNull()
+
+	switch code {
+	case bitcodeNull:
+		r.valueType = NullType
+
+	case bitcodeFalse, bitcodeTrue:
+		r.valueType = BoolType
+		if !null {
+			r.value = code == bitcodeTrue
+			r.bits11.done()
+		}
+
+	case bitcodeInt:
+		r.valueType = IntType
+		if !null {
+			val, err := r.bits11.ReadInt()
+			if err != nil {
+				return false, err
+			}
+			r.value = val
+		}
+
+	case bitcodeFloat:
+		r.valueType = FloatType
+		if !null {
+			val, err := r.bits11.ReadFloat()
+			if err != nil {
+				return false, err
+			}
+			r.value = val
+		}
+
+	case bitcodeDecimal:
+		r.valueType = DecimalType
+		if !null {
+			val, err := r.bits11.ReadDecimal()
+			if err != nil {
+				return false, err
+			}
+			r.value = val
+		}
+
+	case bitcodeTimestamp:
+		r.valueType = TimestampType
+		if !null {
+			val, err := r.bits11.ReadTimestamp()
+			if err != nil {
+				return false, err
+			}
+			r.value = val
+		}
+
+	case bitcodeSymbol:
+		r.valueType = SymbolType
+		if !null {
+			sym, err := r.bits11.ReadSymbol()
+			if err != nil {
+				return false, err
+			}
+			st, err := r.symbolToken11(sym)
+			if err != nil {
+				return false, err
+			}
+			r.value = &st
+		}
+
+	case bitcodeString:
+		r.valueType = StringType
+		if !null {
+			if r.bits.noCopy {
+				val, err := r.bits11.ReadStringBytes()
+				if err != nil {
+					return false, err
+				}
+				r.setBytes(val)
+			} else {
+				val, err := r.bits11.ReadString()
+				if err != nil {
+					return false, err
+				}
+				r.value = val
+			}
+		}
+
+	case bitcodeClob, bitcodeBlob:
+		r.valueType = ClobType
+		if code == bitcodeBlob {
+			r.valueType = BlobType
+		}
+		if !null {
+			val, err := r.bits11.ReadBytes()
+			if err != nil {
+				return false, err
+			}
+			r.setLob(val)
+		}
+
+	case bitcodeList:
+		r.valueType = ListType
+		if !null {
+			r.value = ListType
+		}
+
+	case bitcodeSexp:
+		r.valueType = SexpType
+		if !null {
+			r.value = SexpType
+		}
+
+		// If it's an encoding directive, install it and keep going.
+		if r.ctx.peek() == ctxAtTopLevel && isIonEncoding(r.annotations) {
+			return false, r.readEncodingDirective11()
+		}
+
+	case bitcodeStruct:
+		r.valueType = StructType
+		if !null {
+			r.value = StructType
+		}
+
+		// If it's a local symbol table, install it and keep going.
+		if r.ctx.peek() == ctxAtTopLevel && isIonSymbolTable(r.annotations) {
+			return false, r.readLocalSymbolTable11()
+		}
+
+	default:
+		panic(fmt.Sprintf("invalid bitcode %v", code))
+	}
+
+	return true, nil
+}
+
+// readEExpression11 reads the arguments of the current e-expression, returning
+// the values it expands to.
+func (r *binaryReader) readEExpression11() ([]Value, error) {
+	m := r.bits11.Macro()
+
+	aeb, err := r.bits11.StepInEExpression()
+	if err != nil {
+		return nil, err
+	}
+	encs, err := argEncodings11(m, aeb)
+	if err != nil {
+		return nil, &SyntaxError{Msg: err.Error(), Offset: r.bits.pos}
+	}
+
+	// Arguments are read as if they were the values of an sexp.
+	r.ctx.push(ctxInSexp)
+
+	args := make([][]Value, len(m.params))
+	for i, enc := range encs {
+		switch enc {
+		case argSingle11:
+			vals, ok, err := r.readExpression11()
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				msg := fmt.Sprintf("missing argument for parameter %v of %v", m.params[i].Name, m.displayName())
+				return nil, &SyntaxError{Msg: msg, Offset: r.bits.pos}
+			}
+			args[i] = vals
+
+		case argGroup11:
+			if err := r.bits11.StepInGroup(); err != nil {
+				return nil, err
+			}
+			for {
+				vals, ok, err := r.readExpression11()
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					break
+				}
+				args[i] = append(args[i], vals...)
+			}
+			if err := r.bits11.StepOut(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	r.ctx.pop()
+	r.clear()
+	if err := r.bits11.StepOut(); err != nil {
+		return nil, err
+	}
+	return m.Expand(args...)
+}
+
+// readExpression11 reads a single e-expression argument, which may itself be an
+// e-expression, returning the values it produces. It returns false if there are
+// no more expressions in the current argument list or expression group.
+func (r *binaryReader) readExpression11() ([]Value, bool, error) {
+	r.clear()
+	if err := r.bits11.Next(); err != nil {
+		return nil, false, err
+	}
+
+	switch code := r.bits11.Code(); code {
+	case bitcodeEOF:
+		return nil, false, nil
+
+	case bitcodeBVM:
+		return nil, false, &SyntaxError{Msg: "invalid BVM", Offset: r.bits.pos}
+
+	case bitcodeEExpression:
+		vals, err := r.readEExpression11()
+		return vals, true, err
+
+	default:
+		if _, err := r.readValue11(code); err != nil {
+			return nil, false, err
+		}
+	}
+
+	v, err := ReadValue(r)
+	if err != nil {
+		return nil, false, err
+	}
+	return []Value{v}, true, nil
+}
+
+// readLocalSymbolTable11 reads and installs an Ion 1.1 local symbol table.
+func (r *binaryReader) readLocalSymbolTable11() error {
+	if r.IsNull() {
+		r.clear()
+		r.lst = V11SystemSymbolTable
+		return nil
+	}
+
+	st, err := readLocalSymbolTable(r, r.cat)
+	if err != nil {
+		return err
+	}
+
+	r.lst = rebaseSymbolTable(st)
+	if r.resetPos == 0 {
+		r.resetPos = r.bits.pos
+	} else {
+		r.resetPos = invalidReset
+	}
+	return nil
+}
+
+// readEncodingDirective11 reads and installs an encoding directive.
+func (r *binaryReader) readEncodingDirective11() error {
+	lst, mt, err := readEncodingDirective(r, r.cat, r.lst, r.macroTable())
+	if err != nil {
+		return err
+	}
+
+	r.lst = lst
+	r.macros = mt
+	if r.resetPos == 0 {
+		r.resetPos = r.bits.pos
+	} else {
+		r.resetPos = invalidReset
+	}
+	return nil
+}
+
+// rebaseSymbolTable replaces the Ion 1.0 system symbols implicitly imported by a
+// local symbol table with the Ion 1.1 system symbols.
+func rebaseSymbolTable(st SymbolTable) SymbolTable {
+	imps := st.Imports()
+	if len(imps) == 0 || imps[0] != V1SystemSymbolTable {
+		return st
+	}
+
+	imps[0] = V11SystemSymbolTable
+	return NewLocalSymbolTable(imps, st.Symbols())
+}
+
+// symbolToken11 resolves an Ion 1.1 symbol to a SymbolToken.
+func (r *binaryReader) symbolToken11(s sym11) (SymbolToken, error) {
+	switch {
+	case s.text != nil:
+		return SymbolToken{Text: s.text, LocalSID: SymbolIDUnknown}, nil
+
+	case s.system:
+		text, ok := V11SystemSymbolTable.FindByID(s.id)
+		if !ok {
+			return SymbolToken{}, fmt.Errorf("ion: invalid system symbol ID %v", s.id)
+		}
+		return SymbolToken{Text: &text, LocalSID: SymbolIDUnknown}, nil
+	}
+
+	return NewSymbolTokenBySID(r.SymbolTable(), int64(s.id))
+}
@@ -0,0 +1,325 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var ivm11 = []byte{0xE0, 0x01, 0x01, 0xEA}
+
+// read11 reads the given Ion 1.1 binary (minus its IVM) into values.
+func read11(t *testing.T, bs ...byte) []Value {
+	vals, err := ReadValues(NewReaderBytes(append(append([]byte{}, ivm11...), bs...)))
+	require.NoError(t, err)
+	return vals
+}
+
+func TestReadBinary11Scalars(t *testing.T) {
+	test := func(name string, bs []byte, expected string) {
+		t.Run(name, func(t *testing.T) {
+			actual := read11(t, bs...)
+			require.Len(t, actual, 1)
+			assert.True(t, EquivalentValues(readSingleValue(t, []byte(expected)), actual[0]),
+				"expected %v, got %v", expected, actual[0])
+		})
+	}
+
+	test("null", []byte{0xEA}, "null")
+	test("null.int", []byte{0xEB, 0x01}, "null.int")
+	test("null.struct", []byte{0xEB, 0x0B}, "null.struct")
+	test("true", []byte{0x6E}, "true")
+	test("false", []byte{0x6F}, "false")
+	test("null.bool", []byte{0xEB, 0x00}, "null.bool")
+
+	test("int 0", []byte{0x60}, "0")
+	test("int 1", []byte{0x61, 0x01}, "1")
+	test("int -1", []byte{0x61, 0xFF}, "-1")
+	test("int 256", []byte{0x62, 0x00, 0x01}, "256")
+	test("int big", []byte{0xF6, 0x13, 0, 0, 0, 0, 0, 0, 0, 0, 0x01}, "18446744073709551616")
+
+	test("float 0", []byte{0x6A}, "0e0")
+	test("float16", []byte{0x6B, 0x00, 0x3C}, "1e0")
+	test("float32", []byte{0x6C, 0x00, 0x00, 0xC0, 0x3F}, "1.5e0")
+	test("float64", []byte{0x6D, 0, 0, 0, 0, 0, 0, 0x04, 0x40}, "2.5e0")
+
+	test("decimal 0", []byte{0x70}, "0d0")
+	test("decimal 1.5", []byte{0x72, 0xFF, 0x0F}, "1.5")
+	test("decimal -0", []byte{0x72, 0x01, 0x00}, "-0d0")
+	test("decimal long", []byte{0xF7, 0x05, 0x03, 0x9C}, "-100d1")
+
+	test("timestamp year", []byte{0x80, 0x1E}, "2000T")
+	test("timestamp day", []byte{0x82, 0x9E, 0x79}, "2000-03-15")
+	test("timestamp minute", []byte{0x83, 0x9E, 0x79, 0xCC, 0x0B}, "2000-03-15T12:30Z")
+	test("timestamp minute unknown", []byte{0x83, 0x9E, 0x79, 0xCC, 0x03}, "2000-03-15T12:30-00:00")
+	test("timestamp nanos", []byte{0x87, 0x9E, 0x79, 0xCC, 0xDB, 0x56, 0x34, 0x6F, 0x1D}, "2000-03-15T12:30:45.123456789Z")
+	test("timestamp offset", []byte{0x88, 0x9E, 0x79, 0xCC, 0x63, 0x02}, "2000-03-15T17:30+05:00")
+	test("timestamp millis offset", []byte{0x8A, 0x9E, 0x79, 0xD4, 0xC3, 0xB4, 0x7B, 0x00}, "2000-03-15T12:30:45.123-08:00")
+	test("timestamp long month", []byte{0xF8, 0x07, 0xD0, 0xC7, 0x00}, "2000-03T")
+	test("timestamp long unknown", []byte{0xF8, 0x0D, 0xD0, 0xC7, 0xBC, 0xE5, 0xFD, 0x3F}, "2000-03-15T11:30-00:00")
+	test("timestamp long fraction", []byte{0xF8, 0x15, 0xD0, 0xC7, 0xBC, 0xE5, 0x71, 0x57, 0x0B, 0x0B, 0x39, 0x30}, "2000-03-15T12:30:45.12345+01:00")
+
+	test("string", []byte{0x93, 'a', 'b', 'c'}, `"abc"`)
+	test("string long", []byte{0xF9, 0x07, 'a', 'b', 'c'}, `"abc"`)
+	test("symbol text", []byte{0xA1, 'x'}, "x")
+	test("symbol text long", []byte{0xFA, 0x03, 'x'}, "x")
+	test("symbol address", []byte{0xE1, 0x04}, "name")
+	test("symbol zero", []byte{0xE1, 0x00}, "$0")
+	test("system symbol", []byte{0xEE, 0x0A}, "$ion_encoding")
+	test("blob", []byte{0xFE, 0x05, 0x01, 0x02}, "{{AQI=}}")
+	test("clob", []byte{0xFF, 0x03, 'a'}, `{{"a"}}`)
+}
+
+func TestReadBinary11Containers(t *testing.T) {
+	test := func(name string, bs []byte, expected string) {
+		t.Run(name, func(t *testing.T) {
+			vals, err := ReadValues(NewReaderString(expected))
+			require.NoError(t, err)
+
+			actual := read11(t, bs...)
+			require.Equal(t, len(vals), len(actual))
+			for i := range vals {
+				assert.True(t, EquivalentValues(vals[i], actual[i]), "expected %v, got %v", vals[i], actual[i])
+			}
+		})
+	}
+
+	test("list", []byte{0xB2, 0x61, 0x01}, "[1]")
+	test("list long", []byte{0xFB, 0x05, 0x60, 0x6E}, "[0, true]")
+	test("delimited list", []byte{0xF1, 0x61, 0x01, 0x6E, 0xF0}, "[1, true]")
+	test("sexp", []byte{0xC1, 0x60}, "(0)")
+	test("delimited sexp", []byte{0xF2, 0xA1, '+', 0xF1, 0xF0, 0xF0}, "('+' [])")
+	test("empty struct", []byte{0xD0}, "{}")
+	test("struct", []byte{0xD3, 0x09, 0x61, 0x05}, "{name: 5}")
+	test("struct flexsym", []byte{0xD6, 0x09, 0x60, 0x01, 0xFF, 'a', 0x60}, "{name: 0, a: 0}")
+	test("struct long", []byte{0xFD, 0x05, 0x09, 0x6F}, "{name: false}")
+	test("delimited struct", []byte{0xF3, 0xFF, 'a', 0x6E, 0x09, 0x60, 0x01, 0xF0}, "{a: true, name: 0}")
+	test("nested delimited", []byte{0xF3, 0xFF, 'a', 0xF1, 0xF3, 0x01, 0xF0, 0xF0, 0x01, 0xF0}, "{a: [{}]}")
+	test("struct system field", []byte{0xF3, 0x01, 0xEE, 0x0A, 0x60, 0x01, 0xF0}, "{$ion_encoding: 0}")
+	test("struct nop field", []byte{0xD4, 0x09, 0xEC, 0x0B, 0x60}, "{version: 0}")
+	test("multiple", []byte{0x60, 0xEC, 0xED, 0x05, 0x00, 0x00, 0xB0, 0x6E}, "0 [] true")
+}
+
+func TestReadBinary11Annotations(t *testing.T) {
+	r := NewReaderBytes(append(append([]byte{}, ivm11...),
+		0xE7, 0xFF, 'a', 0x60, // a::0
+		0xE5, 0x09, 0x0B, 0x60, // name::version::0
+		0xE9, 0x0B, 0xFF, 'b', 0x01, 0xEE, 0x0A, 0x60, // b::$ion_encoding::0
+		0xE4, 0x09, 0xE4, 0x0B, 0x60, // name::version::0
+	))
+
+	expected := [][]string{{"a"}, {"name", "version"}, {"b", "$ion_encoding"}, {"name", "version"}}
+	for _, as := range expected {
+		require.True(t, r.Next())
+		actual, err := r.Annotations()
+		require.NoError(t, err)
+		require.Len(t, actual, len(as))
+		for i, a := range as {
+			assert.Equal(t, a, *actual[i].Text)
+		}
+	}
+	assert.False(t, r.Next())
+	assert.NoError(t, r.Err())
+}
+
+func TestReadBinary11Skipping(t *testing.T) {
+	r := NewReaderBytes(append(append([]byte{}, ivm11...),
+		0xF1, 0xF3, 0xFF, 'a', 0xF1, 0x60, 0xF0, 0x01, 0xF0, 0xF0, // [{a: [0]}]
+		0xF3, 0xFF, 'b', 0x61, 0x01, 0xFF, 'c', 0x61, 0x02, 0x01, 0xF0, // {b: 1, c: 2}
+		0x6E,
+	))
+
+	// Skip over the first (delimited) value entirely.
+	require.True(t, r.Next())
+	assert.Equal(t, ListType, r.Type())
+
+	// Step in to the second and out again after one field.
+	require.True(t, r.Next())
+	require.NoError(t, r.StepIn())
+	require.True(t, r.Next())
+	fn, err := r.FieldName()
+	require.NoError(t, err)
+	assert.Equal(t, "b", *fn.Text)
+	require.NoError(t, r.StepOut())
+
+	require.True(t, r.Next())
+	assert.Equal(t, BoolType, r.Type())
+	assert.False(t, r.Next())
+	assert.NoError(t, r.Err())
+}
+
+func TestReadBinary11EExpressions(t *testing.T) {
+	test := func(name string, bs []byte, expected string) {
+		t.Run(name, func(t *testing.T) {
+			assertValues(t, expected, read11(t, bs...))
+		})
+	}
+
+	test("none", []byte{0x00, 0x61, 0x05}, "5")
+	test("single", []byte{0x01, 0x01, 0x61, 0x01}, "1")
+	test("omitted", []byte{0x01, 0x00}, "")
+	test("group", []byte{0x01, 0x02, 0x09, 0x61, 0x01, 0x61, 0x02}, "1 2")
+	test("delimited group", []byte{0x01, 0x02, 0x01, 0x61, 0x01, 0xF1, 0xF0, 0xF0}, "1 []")
+	test("required", []byte{0x07, 0x61, 0x01, 0x61, 0x02}, "3")
+	test("system", []byte{0xEF, 0x07, 0x61, 0x01, 0x61, 0x02}, "3")
+	test("flex address", []byte{0xF4, 0x0F, 0x61, 0x01, 0x61, 0x02}, "3")
+	test("length prefixed", []byte{0xF5, 0x0F, 0x09, 0x61, 0x01, 0x61, 0x02}, "3")
+	test("nested", []byte{0x01, 0x01, 0x07, 0x61, 0x01, 0x61, 0x02}, "3")
+	test("in list", []byte{0xB5, 0x01, 0x01, 0x61, 0x01, 0x6E}, "[1, true]")
+	test("in struct", []byte{0xD8, 0x09, 0x01, 0x02, 0x09, 0x61, 0x01, 0x61, 0x02}, "{name: 1, name: 2}")
+	test("in delimited struct", []byte{0xF3, 0xFF, 'a', 0x07, 0x60, 0x60, 0x01, 0xF0}, "{a: 0}")
+	test("container argument", []byte{0x0E, 0x02, 0x0B, 0xB2, 0x61, 0x01, 0xC1, 0x6E}, "[1, true]")
+}
+
+func TestReadBinary11MacroAddresses(t *testing.T) {
+	var macros []*Macro
+	for i := 0; i < 70; i++ {
+		m, err := ParseMacro(fmt.Sprintf("(macro m%v () %v)", i, i), nil)
+		require.NoError(t, err)
+		macros = append(macros, m)
+	}
+	mt, err := NewMacroTable(macros...)
+	require.NoError(t, err)
+
+	bs := append(append([]byte{}, ivm11...),
+		0x05,       // m5
+		0x42, 0x00, // m66
+		0xEF, 0x07, 0x61, 0x01, 0x61, 0x02, // $ion::sum
+	)
+	vals, err := ReadValues(NewReaderMacros(bytes.NewReader(bs), nil, mt))
+	require.NoError(t, err)
+	assertValues(t, "5 66 3", vals)
+}
+
+func TestReadBinary11SkipEExpressions(t *testing.T) {
+	r := NewReaderBytes(append(append([]byte{}, ivm11...),
+		0xF1, 0x01, 0x02, 0x01, 0x61, 0x01, 0x61, 0x02, 0xF0, 0xF0, // [(:values (:: 1 2))]
+		0x01, 0x01, 0xB2, 0x61, 0x03, // (:values [3])
+		0xF5, 0x03, 0x07, 0x01, 0x61, 0x04, // (:values 4)
+		0x6E,
+	))
+
+	// Skip over the first list, then step out of the second partway through.
+	require.True(t, r.Next())
+	assert.Equal(t, ListType, r.Type())
+
+	require.True(t, r.Next())
+	require.NoError(t, r.StepIn())
+	require.True(t, r.Next())
+	require.NoError(t, r.StepOut())
+
+	require.True(t, r.Next())
+	val, err := r.Int64Value()
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), *val)
+
+	require.True(t, r.Next())
+	assert.Equal(t, BoolType, r.Type())
+	assert.False(t, r.Next())
+	assert.NoError(t, r.Err())
+}
+
+func TestReadBinary11SymbolTable(t *testing.T) {
+	next := byte(V11SystemSymbolTable.MaxID() + 1)
+
+	vals := read11(t,
+		0xE4, 0x07, // $ion_symbol_table::
+		0xD6, 0x0F, 0xB4, 0x93, 'f', 'o', 'o', // {symbols: ["foo"]}
+		0xE1, next,
+		0xE1, 0x04,
+	)
+	require.Len(t, vals, 2)
+	assert.Equal(t, "foo", *vals[0].(*SymbolValue).Symbol().Text)
+	assert.Equal(t, "name", *vals[1].(*SymbolValue).Symbol().Text)
+}
+
+func TestReadBinary11EncodingDirective(t *testing.T) {
+	next := byte(V11SystemSymbolTable.MaxID() + 1)
+
+	r := NewReaderBytes(append(append([]byte{}, ivm11...),
+		0xE4, 0x15, // $ion_encoding::
+		0xC8, 0xC7, 0xE1, 0x0F, 0xB4, 0x93, 'f', 'o', 'o', // ((symbol_table ["foo"]))
+		0xE1, next,
+		0xE4, 0x15, // $ion_encoding::
+		0xCA, 0xC9, 0xE1, 0x0F, 0xE1, 0x0A, 0xB4, 0x93, 'b', 'a', 'r', // ((symbol_table $ion_encoding ["bar"]))
+		0xE1, next+1,
+	))
+
+	vals, err := ReadValues(r)
+	require.NoError(t, err)
+	require.Len(t, vals, 2)
+	assert.Equal(t, "foo", *vals[0].(*SymbolValue).Symbol().Text)
+	assert.Equal(t, "bar", *vals[1].(*SymbolValue).Symbol().Text)
+
+	// As with local symbol tables, only the first directive can be skipped by Reset.
+	assert.Error(t, r.(*binaryReader).Reset(nil))
+}
+
+func TestReadBinary11VersionSwitch(t *testing.T) {
+	bs := []byte{
+		0xE0, 0x01, 0x00, 0xEA, 0x21, 0x01, // Ion 1.0: 1
+		0xE0, 0x01, 0x01, 0xEA, 0x61, 0x02, // Ion 1.1: 2
+		0xE0, 0x01, 0x00, 0xEA, 0x21, 0x03, // Ion 1.0: 3
+	}
+
+	r := NewReaderBytes(bs)
+	for _, expected := range []int64{1, 2, 3} {
+		require.True(t, r.Next())
+		val, err := r.Int64Value()
+		require.NoError(t, err)
+		assert.Equal(t, expected, *val)
+	}
+	assert.False(t, r.Next())
+	assert.NoError(t, r.Err())
+}
+
+func TestReadBinary11Errors(t *testing.T) {
+	test := func(name string, bs ...byte) {
+		t.Run(name, func(t *testing.T) {
+			r := NewReaderBytes(append(append([]byte{}, ivm11...), bs...))
+			for r.Next() {
+				if r.Type() == ListType || r.Type() == StructType {
+					require.NoError(t, r.StepIn())
+				}
+			}
+			assert.Error(t, r.Err())
+		})
+	}
+
+	test("invalid opcode", 0x69)
+	test("e-expression", 0x01)
+	test("unexpected end", 0xF0)
+	test("truncated", 0x62, 0x01)
+	test("truncated delimited", 0xF1, 0x60)
+	test("overrun", 0xB1, 0x61, 0x01)
+	test("invalid utf8", 0x91, 0xFF)
+	test("invalid typed null", 0xEB, 0x0C)
+	test("invalid timestamp", 0x81, 0x1E, 0x00)
+	test("annotated nop", 0xE7, 0xFF, 'a', 0xEC)
+	test("ivm in container", 0xF1, 0xE0, 0x01, 0x01, 0xEA, 0xF0)
+	test("field without value", 0xD1, 0x09)
+	test("unknown macro", 0x30)
+	test("annotated e-expression", 0xE7, 0xFF, 'a', 0x00)
+	test("missing argument", 0x07, 0x61, 0x01)
+	test("invalid argument encoding", 0x01, 0x03)
+	test("macro error", 0x07, 0x61, 0x01, 0x6E)
+	test("group overrun", 0xB4, 0x01, 0x02, 0x05, 0x61)
+}
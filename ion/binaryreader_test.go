@@ -16,6 +16,7 @@
 package ion
 
 import (
+	"bytes"
 	"math"
 	"math/big"
 	"testing"
@@ -632,3 +633,142 @@ func TestReadBinaryStringsWithReset(t *testing.T) {
 	_string(t, r, newString("hello world but longer"))
 	_eof(t, r)
 }
+
+func readBinaryNoCopy(ion []byte) ([]byte, Reader) {
+	in := append(append([]byte{}, prefixBytes...), ion...)
+	return in, NewReaderBytesNoCopy(in)
+}
+
+func TestReadBinaryNoCopy(t *testing.T) {
+	in, r := readBinaryNoCopy([]byte{
+		0x8F,
+		0x80,      // ""
+		0x81, 'a', // "a"
+		0xAF,
+		0xA0,           // {{}}
+		0xA2, 'h', 'i', // {{aGk=}}
+		0xB4, 0x21, 0x01, 0x21, 0x02, // [1, 2]
+		0x93, 'b', 'y', 'e', // {{"bye"}}
+	})
+
+	_null(t, r, StringType)
+	_string(t, r, newString(""))
+
+	require.True(t, r.Next())
+	str, err := r.(StringBytesReader).StringBytes()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("a"), str)
+	assert.Equal(t, 1, cap(str))
+
+	_null(t, r, BlobType)
+	_blob(t, r, []byte{})
+
+	require.True(t, r.Next())
+	val, err := r.ByteValue()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hi"), val)
+
+	// The value is a sub-slice of the input.
+	in[len(in)-11] = 'H'
+	assert.Equal(t, []byte("Hi"), val)
+
+	allocs := testing.AllocsPerRun(10, func() {
+		_, _ = r.ByteValue()
+	})
+	assert.Equal(t, 0.0, allocs)
+
+	// The list is skipped over.
+	_next(t, r, ListType)
+	_clob(t, r, []byte("bye"))
+	_eof(t, r)
+}
+
+func TestReadBinaryNoCopyAllocs(t *testing.T) {
+	in, _ := readBinaryNoCopy([]byte{
+		0x8B, 'h', 'e', 'l', 'l', 'o', ' ', 'w', 'o', 'r', 'l', 'd',
+		0xA4, 'd', 'a', 't', 'a',
+		0xBE, 0x90, 0x8E, 0x8E,
+		'a', 'n', 'o', 't', 'h', 'e', 'r', ' ', 's', 't', 'r', 'i', 'n', 'g',
+	})
+	r := NewReaderBytesNoCopy(in).(*binaryReader)
+
+	// Read the symbol table.
+	require.True(t, r.Next())
+	require.NoError(t, r.Reset(in))
+
+	allocs := testing.AllocsPerRun(10, func() {
+		require.NoError(t, r.Reset(in))
+		for r.Next() {
+			if r.Type() == StringType {
+				_, _ = r.StringBytes()
+			} else {
+				_, _ = r.ByteValue()
+			}
+		}
+	})
+	assert.Equal(t, 0.0, allocs)
+	require.NoError(t, r.Err())
+}
+
+func TestReadBinaryNoCopyTruncated(t *testing.T) {
+	in, _ := readBinaryNoCopy([]byte{
+		0xB4, 0x21, 0x01, 0x21, 0x02, // [1, 2]
+		0x85, 'h', 'e',
+	})
+
+	r := NewReaderBytesNoCopy(in)
+	_next(t, r, ListType)
+	assert.False(t, r.Next())
+
+	assert.IsType(t, &UnexpectedEOFError{}, r.Err())
+}
+
+func TestReadNoCopyText(t *testing.T) {
+	r := NewReaderBytesNoCopy([]byte(`"hello" {{aGk=}}`))
+
+	require.True(t, r.Next())
+	str, err := r.(StringBytesReader).StringBytes()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hello"), str)
+
+	_blob(t, r, []byte("hi"))
+	_eof(t, r)
+}
+
+func TestReadBinary11NoCopy(t *testing.T) {
+	buf := bytes.Buffer{}
+	w := NewBinaryWriter11(&buf)
+	require.NoError(t, w.WriteString("hello"))
+	require.NoError(t, w.WriteBlob([]byte("hi")))
+	require.NoError(t, w.Finish())
+
+	r := NewReaderBytesNoCopy(buf.Bytes())
+
+	require.True(t, r.Next())
+	str, err := r.(StringBytesReader).StringBytes()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hello"), str)
+
+	_blob(t, r, []byte("hi"))
+	_eof(t, r)
+}
+
+func TestBinaryReaderPosition(t *testing.T) {
+	bs := encodeBinary(t, `a [b, c::d]`)
+	r := NewReaderBytes(bs).(PositionReader)
+	assert.Equal(t, Position{}, r.Position())
+
+	require.True(t, r.Next())
+	start := r.Position().Offset
+	assert.True(t, start > 4)
+
+	require.True(t, r.Next())
+	assert.Equal(t, Position{Offset: start + 2}, r.Position())
+
+	require.NoError(t, r.StepIn())
+	require.True(t, r.Next())
+	require.True(t, r.Next())
+
+	// The position of an annotated value is that of its annotations.
+	assert.Equal(t, Position{Offset: start + 5}, r.Position())
+}
@@ -27,12 +27,24 @@ import (
 // A binaryWriter writes binary ion.
 type binaryWriter struct {
 	writer
-	bufs bufstack
+	buf encbuf
 
 	lst  SymbolTable
 	lstb SymbolTableBuilder
 
 	wroteLST bool
+
+	// In streaming mode, each top-level value is written out as soon as it's
+	// complete. flushedID is the max ID of the symbols written out so far.
+	streaming bool
+	flushedID uint64
+
+	// Whether a local symbol table is being written after the values it
+	// applies to, rather than a top-level value.
+	writingLST bool
+
+	// A symbol table that raw values can be written as is with.
+	rawLST *lst
 }
 
 // NewBinaryWriter creates a new binary writer that will construct a
@@ -44,10 +56,27 @@ func NewBinaryWriter(out io.Writer, sts ...SharedSymbolTable) Writer {
 		},
 		lstb: NewSymbolTableBuilder(sts...),
 	}
-	w.bufs.push(&datagram{})
 	return w
 }
 
+// NewBinaryWriterStreaming creates a new binary writer that writes each top-level
+// value to out as soon as it's complete, rather than buffering values until
+// Finish is called, so memory use is bounded by the size of the largest value.
+// The local symbol table is written before the first value, and values that use
+// new symbols are preceded by a local symbol table that appends them to it.
+func NewBinaryWriterStreaming(out io.Writer, sts ...SharedSymbolTable) Writer {
+	w := NewBinaryWriter(out, sts...).(*binaryWriter)
+	w.streaming = true
+	return w
+}
+
+// NewBinaryWriterCanonical creates a new binary writer that writes values in
+// canonical form (see NewCanonicalWriter), so that equivalent values are always
+// written as the same bytes. Values are buffered until Finish is called.
+func NewBinaryWriterCanonical(out io.Writer) Writer {
+	return NewCanonicalWriter(NewBinaryWriter(out))
+}
+
 // NewBinaryWriterLST creates a new binary writer with a pre-built local
 // symbol table.
 func NewBinaryWriterLST(out io.Writer, lst SymbolTable) Writer {
@@ -92,11 +121,8 @@ func (w *binaryWriter) WriteInt(val int64) error {
 		mag = uint64(-val)
 	}
 
-	length := uintLen(mag)
-	bufLength := length + tagLen(length)
-
-	buf := make([]byte, 0, bufLength)
-	buf = appendTag(buf, code, length)
+	var arr [9]byte
+	buf := appendTag(arr[:0], code, uintLen(mag))
 	buf = appendUint(buf, mag)
 
 	return w.writeValue("Writer.WriteInt", buf)
@@ -108,11 +134,8 @@ func (w *binaryWriter) WriteUint(val uint64) error {
 		return w.writeValue("Writer.WriteUint", []byte{0x20})
 	}
 
-	length := uintLen(val)
-	bufLength := length + tagLen(length)
-
-	buf := make([]byte, 0, bufLength)
-	buf = appendTag(buf, 0x20, length)
+	var arr [9]byte
+	buf := appendTag(arr[:0], 0x20, uintLen(val))
 	buf = appendUint(buf, val)
 
 	return w.writeValue("Writer.WriteUint", buf)
@@ -127,19 +150,18 @@ func (w *binaryWriter) WriteBigInt(val *big.Int) error {
 		return w.err
 	}
 
-	if w.err = w.writeBigInt(val); w.err != nil {
-		return w.err
-	}
+	w.writeBigInt(val)
 
 	w.err = w.endValue()
 	return w.err
 }
 
 // WriteBigInt writes the actual big integer value.
-func (w *binaryWriter) writeBigInt(val *big.Int) error {
+func (w *binaryWriter) writeBigInt(val *big.Int) {
 	sign := val.Sign()
 	if sign == 0 {
-		return w.write([]byte{0x20})
+		w.write([]byte{0x20})
+		return
 	}
 
 	code := byte(0x20)
@@ -148,22 +170,8 @@ func (w *binaryWriter) writeBigInt(val *big.Int) error {
 	}
 
 	bs := val.Bytes()
-
-	bl := uint64(len(bs))
-	if bl < 64 {
-		bufLength := bl + tagLen(bl)
-		buf := make([]byte, 0, bufLength)
-
-		buf = appendTag(buf, code, bl)
-		buf = append(buf, bs...)
-		return w.write(buf)
-	}
-
-	// no sense in copying, emit tag separately.
-	if err := w.writeTag(code, bl); err != nil {
-		return err
-	}
-	return w.write(bs)
+	w.writeTag(code, uint64(len(bs)))
+	w.write(bs)
 }
 
 // WriteFloat writes a floating-point value.
@@ -175,17 +183,18 @@ func (w *binaryWriter) WriteFloat(val float64) error {
 		return w.writeValue("Writer.WriteFloat", []byte{0x44, 0x7F, 0xC0, 0x00, 0x00})
 	}
 
+	var arr [9]byte
 	var bs []byte
 
 	// Can this be losslessly represented as a float32?
 	if val == float64(float32(val)) {
-		bs = make([]byte, 5)
+		bs = arr[:5]
 		bs[0] = 0x44
 
 		bits := math.Float32bits(float32(val))
 		binary.BigEndian.PutUint32(bs[1:], bits)
 	} else {
-		bs = make([]byte, 9)
+		bs = arr[:9]
 		bs[0] = 0x48
 
 		bits := math.Float64bits(val)
@@ -214,19 +223,24 @@ func (w *binaryWriter) WriteDecimal(val *Decimal) error {
 		vlength += bigIntLen(coef)
 	}
 
-	bufLength := vlength + tagLen(vlength)
-	buf := make([]byte, 0, bufLength)
+	if w.err != nil {
+		return w.err
+	}
+	if w.err = w.beginValue("Writer.WriteDecimal"); w.err != nil {
+		return w.err
+	}
 
-	buf = appendTag(buf, 0x50, vlength)
-	buf = appendVarInt(buf, int64(exp))
+	w.writeTag(0x50, vlength)
+	w.buf.bs = appendVarInt(w.buf.bs, int64(exp))
 
 	if val.isNegZero {
-		buf = append(buf, 0x80)
+		w.buf.bs = append(w.buf.bs, 0x80)
 	} else {
-		buf = appendBigInt(buf, coef)
+		w.buf.bs = appendBigInt(w.buf.bs, coef)
 	}
 
-	return w.writeValue("Writer.WriteDecimal", buf)
+	w.err = w.endValue()
+	return w.err
 }
 
 // WriteTimestamp writes a timestamp value.
@@ -235,15 +249,18 @@ func (w *binaryWriter) WriteTimestamp(val Timestamp) error {
 	offset /= 60
 	val.dateTime = val.dateTime.In(time.UTC)
 
-	vlength := timestampLen(offset, val)
-	bufLength := vlength + tagLen(vlength)
-
-	buf := make([]byte, 0, bufLength)
+	if w.err != nil {
+		return w.err
+	}
+	if w.err = w.beginValue("Writer.WriteTimestamp"); w.err != nil {
+		return w.err
+	}
 
-	buf = appendTag(buf, 0x60, vlength)
-	buf = appendTimestamp(buf, offset, val)
+	w.writeTag(0x60, timestampLen(offset, val))
+	w.buf.bs = appendTimestamp(w.buf.bs, offset, val)
 
-	return w.writeValue("Writer.WriteTimestamp", buf)
+	w.err = w.endValue()
+	return w.err
 }
 
 // WriteSymbol writes a symbol value given a SymbolToken.
@@ -276,11 +293,8 @@ func (w *binaryWriter) WriteSymbolFromString(val string) error {
 }
 
 func (w *binaryWriter) writeSymbolFromID(api string, id uint64) error {
-	vlength := uintLen(id)
-	bufLength := vlength + tagLen(vlength)
-	buf := make([]byte, 0, bufLength)
-
-	buf = appendTag(buf, 0x70, vlength)
+	var arr [9]byte
+	buf := appendTag(arr[:0], 0x70, uintLen(id))
 	buf = appendUint(buf, id)
 
 	return w.writeValue(api, buf)
@@ -292,14 +306,18 @@ func (w *binaryWriter) WriteString(val string) error {
 		return w.writeValue("Writer.WriteString", []byte{0x80})
 	}
 
-	vlength := uint64(len(val))
-	bufLength := vlength + tagLen(vlength)
-	buf := make([]byte, 0, bufLength)
+	if w.err != nil {
+		return w.err
+	}
+	if w.err = w.beginValue("Writer.WriteString"); w.err != nil {
+		return w.err
+	}
 
-	buf = appendTag(buf, 0x80, vlength)
-	buf = append(buf, val...)
+	w.writeTag(0x80, uint64(len(val)))
+	w.buf.bs = append(w.buf.bs, val...)
 
-	return w.writeValue("Writer.WriteString", buf)
+	w.err = w.endValue()
+	return w.err
 }
 
 // WriteClob writes a clob.
@@ -311,9 +329,8 @@ func (w *binaryWriter) WriteClob(val []byte) error {
 		return w.err
 	}
 
-	if w.err = w.writeLob(0x90, val); w.err != nil {
-		return w.err
-	}
+	w.writeTag(0x90, uint64(len(val)))
+	w.write(val)
 
 	w.err = w.endValue()
 	return w.err
@@ -328,33 +345,13 @@ func (w *binaryWriter) WriteBlob(val []byte) error {
 		return w.err
 	}
 
-	if w.err = w.writeLob(0xA0, val); w.err != nil {
-		return w.err
-	}
+	w.writeTag(0xA0, uint64(len(val)))
+	w.write(val)
 
 	w.err = w.endValue()
 	return w.err
 }
 
-func (w *binaryWriter) writeLob(code byte, val []byte) error {
-	vlength := uint64(len(val))
-
-	if vlength < 64 {
-		bufLength := vlength + tagLen(vlength)
-		buf := make([]byte, 0, bufLength)
-
-		buf = appendTag(buf, code, vlength)
-		buf = append(buf, val...)
-
-		return w.write(buf)
-	}
-
-	if err := w.writeTag(code, vlength); err != nil {
-		return err
-	}
-	return w.write(val)
-}
-
 // BeginList begins writing a list.
 func (w *binaryWriter) BeginList() error {
 	if w.err == nil {
@@ -413,42 +410,32 @@ func (w *binaryWriter) Finish() error {
 	}
 
 	w.clear()
-	w.wroteLST = false
 
-	seq := w.bufs.peek()
-	if seq != nil {
-		w.bufs.pop()
-		if w.bufs.peek() != nil {
-			panic("at top level but too many bufseqs")
-		}
+	switch {
+	case w.lst != nil:
+		// Values have already been written out along with the prebuilt
+		// local symbol table.
 
-		lst := w.lstb.Build()
-		if err := w.writeLST(lst); err != nil {
-			return err
+	case w.streaming:
+		// Values have already been written out; make sure there's at least a
+		// version marker, and start afresh with the next value.
+		if !w.wroteLST {
+			w.err = w.flushWithLST(w.writeStreamingLST)
 		}
-		if w.err = w.emit(seq); w.err != nil {
-			return w.err
-		}
-	}
 
-	return nil
-}
-
-// Emit emits the given node. If we're currently at the top level, that
-// means actually emitting to the output stream. If not, we emit append
-// to the current bufseq.
-func (w *binaryWriter) emit(node bufnode) error {
-	s := w.bufs.peek()
-	if s == nil {
-		return node.EmitTo(w.out)
+	default:
+		w.err = w.flushWithLST(func() error {
+			return w.writeLST(w.lstb.Build())
+		})
 	}
-	s.Append(node)
-	return nil
+
+	w.wroteLST = false
+	return w.err
 }
 
-// Write emits the given bytes as an atom.
-func (w *binaryWriter) write(bs []byte) error {
-	return w.emit(atom(bs))
+// Write appends the given bytes to the buffer.
+func (w *binaryWriter) write(bs []byte) {
+	w.buf.bs = append(w.buf.bs, bs...)
 }
 
 // WriteValue writes a serialized value to the output stream.
@@ -460,30 +447,21 @@ func (w *binaryWriter) writeValue(api string, val []byte) error {
 		return w.err
 	}
 
-	if w.err = w.write(val); w.err != nil {
-		return w.err
-	}
+	w.write(val)
 
 	w.err = w.endValue()
 	return w.err
 }
 
-// WriteTag writes out a type+length tag. Use me when you've already got the value to
-// be written as a []byte and don't want to copy it.
-func (w *binaryWriter) writeTag(code byte, length uint64) error {
-	tl := tagLen(length)
-
-	tag := make([]byte, 0, tl)
-	tag = appendTag(tag, code, length)
-
-	return w.write(tag)
+// WriteTag writes out a type+length tag, to be followed by a value of the given
+// length.
+func (w *binaryWriter) writeTag(code byte, length uint64) {
+	w.buf.bs = appendTag(w.buf.bs, code, length)
 }
 
 // WriteLST writes out a local symbol table.
 func (w *binaryWriter) writeLST(lst SymbolTable) error {
-	if err := w.write([]byte{0xE0, 0x01, 0x00, 0xEA}); err != nil {
-		return err
-	}
+	w.write([]byte{0xE0, 0x01, 0x00, 0xEA})
 	return lst.WriteTo(w)
 }
 
@@ -522,66 +500,156 @@ func (w *binaryWriter) beginValue(api string) error {
 			return &UsageError{api, "field name symbol token does not have defined text or symbol id."}
 		}
 
-		buf := make([]byte, 0, 10)
-		buf = appendVarUint(buf, id)
-		if err := w.write(buf); err != nil {
-			return err
-		}
+		w.buf.bs = appendVarUint(w.buf.bs, id)
 	}
 
 	if len(as) > 0 {
-		ids := make([]uint64, len(as))
-		idlen := uint64(0)
-
-		var id uint64
-		var err error
-		for i, a := range as {
-			if a.Text != nil {
-				id, err = w.resolve(api, *a.Text)
-				if err != nil {
-					return err
-				}
-			} else if a.LocalSID != SymbolIDUnknown {
-				id = uint64(a.LocalSID)
-			} else {
-				return &UsageError{api, "invalid annotation symbol token"}
-			}
+		return w.beginAnnotations(api, as)
+	}
 
-			ids[i] = id
-			idlen += varUintLen(id)
-		}
+	return nil
+}
 
-		bufLength := idlen + varUintLen(idlen)
-		buf := make([]byte, 0, bufLength)
+// BeginAnnotations begins an annotation wrapper holding the given annotations.
+// It's closed by endValue once the value it wraps has been written.
+func (w *binaryWriter) beginAnnotations(api string, as []SymbolToken) error {
+	var arr [4]uint64
+	ids := arr[:0]
+	idlen := uint64(0)
 
-		buf = appendVarUint(buf, idlen)
-		for _, id := range ids {
-			buf = appendVarUint(buf, id)
+	var id uint64
+	var err error
+	for _, a := range as {
+		if a.Text != nil {
+			id, err = w.resolve(api, *a.Text)
+			if err != nil {
+				return err
+			}
+		} else if a.LocalSID != SymbolIDUnknown {
+			id = uint64(a.LocalSID)
+		} else {
+			return &UsageError{api, "invalid annotation symbol token"}
 		}
 
-		// https://github.com/amazon-ion/ion-go/issues/120
-		w.bufs.push(&container{code: 0xE0})
-		if err := w.write(buf); err != nil {
-			return err
-		}
+		ids = append(ids, id)
+		idlen += varUintLen(id)
+	}
+
+	// https://github.com/amazon-ion/ion-go/issues/120
+	w.buf.begin(0xE0)
+	w.buf.bs = appendVarUint(w.buf.bs, idlen)
+	for _, id := range ids {
+		w.buf.bs = appendVarUint(w.buf.bs, id)
 	}
 
 	return nil
 }
 
-// EndValue ends the process of writing a value by flushing it and its annotations
-// up a level, if needed.
+// EndValue ends the process of writing a value by closing its annotation
+// wrapper, if it has one, and writing it out if it's a complete top-level value
+// that's ready to be written.
 func (w *binaryWriter) endValue() error {
-	seq := w.bufs.peek()
-	if seq != nil {
-		if c, ok := seq.(*container); ok && c.code == 0xE0 {
-			w.bufs.pop()
-			return w.emit(seq)
+	if code, ok := w.buf.peek(); ok && code == 0xE0 {
+		w.buf.end()
+	}
+
+	if w.ctx.peek() != ctxAtTopLevel || w.writingLST {
+		return nil
+	}
+
+	switch {
+	case w.lst != nil:
+		return w.flush(0)
+	case w.streaming:
+		return w.flushWithLST(w.writeStreamingLST)
+	}
+
+	// Otherwise values are buffered until Finish, when the local symbol table
+	// they use is complete.
+	return nil
+}
+
+// FlushWithLST writes out the buffered values, preceded by the local symbol
+// table written by writeLST. The table is appended to the buffer after the
+// values, then written out ahead of them.
+func (w *binaryWriter) flushWithLST(writeLST func() error) error {
+	mark := len(w.buf.bs)
+
+	w.writingLST = true
+	err := writeLST()
+	w.writingLST = false
+	if err != nil {
+		return err
+	}
+
+	return w.flush(mark)
+}
+
+// Flush writes out the contents of the buffer from mark onward, followed by the
+// contents before mark, and empties it.
+func (w *binaryWriter) flush(mark int) error {
+	mark = w.buf.flatten(mark)
+	if _, err := w.out.Write(w.buf.bs[mark:]); err != nil {
+		return err
+	}
+	if mark > 0 {
+		if _, err := w.out.Write(w.buf.bs[:mark]); err != nil {
+			return err
 		}
 	}
+	w.buf.reset()
 	return nil
 }
 
+// WriteStreamingLST writes out the symbols added to the local symbol table since
+// it was last written: the version marker and whole table if it hasn't been
+// written yet, or else a local symbol table appending the new symbols to it.
+func (w *binaryWriter) writeStreamingLST() error {
+	maxID := w.lstb.MaxID()
+	if !w.wroteLST {
+		w.wroteLST = true
+		w.flushedID = maxID
+		return w.writeLST(w.lstb.Build())
+	}
+	if maxID == w.flushedID {
+		return nil
+	}
+
+	ionSymbolTable := "$ion_symbol_table"
+	ionSymbolTableToken := SymbolToken{Text: &ionSymbolTable, LocalSID: 3}
+
+	if err := w.Annotation(ionSymbolTableToken); err != nil {
+		return err
+	}
+	if err := w.BeginStruct(); err != nil {
+		return err
+	}
+	if err := w.FieldName(NewSymbolTokenFromString("imports")); err != nil {
+		return err
+	}
+	if err := w.WriteSymbol(ionSymbolTableToken); err != nil {
+		return err
+	}
+	if err := w.FieldName(NewSymbolTokenFromString("symbols")); err != nil {
+		return err
+	}
+	if err := w.BeginList(); err != nil {
+		return err
+	}
+	for id := w.flushedID + 1; id <= maxID; id++ {
+		sym, _ := w.lstb.FindByID(id)
+		if err := w.WriteString(sym); err != nil {
+			return err
+		}
+	}
+	if err := w.EndList(); err != nil {
+		return err
+	}
+
+	w.flushedID = maxID
+	return w.EndStruct()
+}
+
 // Begin begins writing a new container.
 func (w *binaryWriter) begin(api string, t ctx, code byte) error {
 	if err := w.beginValue(api); err != nil {
@@ -589,24 +657,18 @@ func (w *binaryWriter) begin(api string, t ctx, code byte) error {
 	}
 
 	w.ctx.push(t)
-	w.bufs.push(&container{code: code})
+	w.buf.begin(code)
 
 	return nil
 }
 
-// End ends writing a container, emitting its buffered contents up a level in the stack.
+// End ends writing a container, filling in its tag now that its length is known.
 func (w *binaryWriter) end(api string, t ctx) error {
 	if w.ctx.peek() != t {
 		return &UsageError{api, "not in that kind of container"}
 	}
 
-	seq := w.bufs.peek()
-	if seq != nil {
-		w.bufs.pop()
-		if err := w.emit(seq); err != nil {
-			return err
-		}
-	}
+	w.buf.end()
 
 	w.clear()
 	w.ctx.pop()
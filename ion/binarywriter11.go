@@ -0,0 +1,968 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"time"
+)
+
+// BinaryWriter11Opts defines a set of bit flag options for Ion 1.1 binary writers.
+type BinaryWriter11Opts uint8
+
+const (
+	// BinaryWriter11Delimited writes lists, sexps, and structs as delimited
+	// containers terminated by an end marker rather than prefixing them with
+	// their length. Delimited containers don't need to be buffered in memory until
+	// they're finished, so values stream straight through to the output when the
+	// symbol table is known up front (see NewBinaryWriter11LST and
+	// BinaryWriter11InlineSymbols).
+	BinaryWriter11Delimited BinaryWriter11Opts = 1
+
+	// BinaryWriter11InlineSymbols writes the text of symbols inline rather than
+	// adding them to a local symbol table. Symbols already defined by the system
+	// symbol table or an imported (or provided) symbol table are still written as
+	// symbol IDs.
+	BinaryWriter11InlineSymbols BinaryWriter11Opts = 2
+)
+
+// A binaryWriter11 writes Ion 1.1 binary.
+type binaryWriter11 struct {
+	writer
+	bufs bufstack
+	opts BinaryWriter11Opts
+
+	// Exactly one of lst and lstb is set: lst if the symbol table is known up
+	// front, lstb if it is being built as values are written.
+	lst  SymbolTable
+	lstb SymbolTableBuilder
+
+	// The macros e-expressions refer to by address. System macros not in the
+	// table are referred to by their system addresses.
+	macros *MacroTable
+
+	// If set, the encoding directive written in place of a local symbol table.
+	enc *EncodingDirective
+
+	wroteIVM bool
+}
+
+// NewBinaryWriter11 creates a new Ion 1.1 binary writer that will construct a
+// local symbol table as it is written to.
+func NewBinaryWriter11(out io.Writer, sts ...SharedSymbolTable) Writer {
+	return NewBinaryWriter11Opts(out, 0, sts...)
+}
+
+// NewBinaryWriter11Opts creates a new Ion 1.1 binary writer with the given options.
+// Unless BinaryWriter11InlineSymbols is set, a local symbol table is constructed
+// as it is written to, and values are buffered until Finish is called.
+func NewBinaryWriter11Opts(out io.Writer, opts BinaryWriter11Opts, sts ...SharedSymbolTable) Writer {
+	imports := append([]SharedSymbolTable{V11SystemSymbolTable}, sts...)
+
+	w := &binaryWriter11{
+		writer: writer{
+			out: out,
+		},
+		opts: opts,
+	}
+
+	if opts&BinaryWriter11InlineSymbols != 0 {
+		w.lst = NewLocalSymbolTable(imports, nil)
+	} else {
+		w.lstb = NewSymbolTableBuilder(imports...)
+		w.bufs.push(&datagram{})
+	}
+	return w
+}
+
+// NewBinaryWriter11Macros creates a new Ion 1.1 binary writer with the given
+// options that can write e-expressions invoking the macros in the given table.
+// The table is not written out; readers must be created with the same table
+// (see NewReaderMacros).
+func NewBinaryWriter11Macros(out io.Writer, opts BinaryWriter11Opts, mt *MacroTable, sts ...SharedSymbolTable) MacroWriter {
+	w := NewBinaryWriter11Opts(out, opts, sts...).(*binaryWriter11)
+	w.macros = mt
+	return w
+}
+
+// NewBinaryWriter11LST creates a new Ion 1.1 binary writer with the given options
+// and a pre-built local symbol table. Symbols not defined by the table are an
+// error unless BinaryWriter11InlineSymbols is set.
+func NewBinaryWriter11LST(out io.Writer, opts BinaryWriter11Opts, lst SymbolTable) Writer {
+	return &binaryWriter11{
+		writer: writer{
+			out: out,
+		},
+		opts: opts,
+		lst:  rebaseSymbolTable(lst),
+	}
+}
+
+// NewBinaryWriter11Encoding creates a new Ion 1.1 binary writer with the given
+// options that starts its output with the given encoding directive, and writes
+// values using the symbols and macros it defines. Symbols not defined by the
+// directive are an error unless BinaryWriter11InlineSymbols is set. Readers
+// must have a Catalog providing any modules the directive imports.
+func NewBinaryWriter11Encoding(out io.Writer, opts BinaryWriter11Opts, d *EncodingDirective) MacroWriter {
+	w := &binaryWriter11{
+		writer: writer{
+			out: out,
+		},
+		opts: opts,
+		lst:  d.SymbolTable(),
+		enc:  d,
+	}
+	w.macros, w.err = d.MacroTable()
+	return w
+}
+
+// WriteNull writes an untyped null.
+func (w *binaryWriter11) WriteNull() error {
+	return w.writeValue("Writer.WriteNull", []byte{op11Null})
+}
+
+// WriteNullType writes a typed null.
+func (w *binaryWriter11) WriteNullType(t Type) error {
+	if t == NullType {
+		return w.WriteNull()
+	}
+
+	for i, nt := range typedNulls11 {
+		if nt == t {
+			return w.writeValue("Writer.WriteNullType", []byte{op11TypedNull, byte(i)})
+		}
+	}
+
+	if w.err == nil {
+		w.err = &UsageError{"Writer.WriteNullType", fmt.Sprintf("invalid type %v", t)}
+	}
+	return w.err
+}
+
+// WriteBool writes a bool.
+func (w *binaryWriter11) WriteBool(val bool) error {
+	b := byte(op11False)
+	if val {
+		b = op11True
+	}
+	return w.writeValue("Writer.WriteBool", []byte{b})
+}
+
+// WriteInt writes an integer.
+func (w *binaryWriter11) WriteInt(val int64) error {
+	length := fixedIntLen(val)
+
+	buf := make([]byte, 0, length+1)
+	buf = append(buf, op11Int+byte(length))
+	buf = appendFixed(buf, uint64(val), length)
+
+	return w.writeValue("Writer.WriteInt", buf)
+}
+
+// WriteUint writes an unsigned integer.
+func (w *binaryWriter11) WriteUint(val uint64) error {
+	if val <= math.MaxInt64 {
+		return w.WriteInt(int64(val))
+	}
+
+	// Nine bytes, to leave room for the sign bit.
+	buf := make([]byte, 0, 11)
+	buf = appendHeader11(buf, 0, op11IntLen, 9)
+	buf = appendFixed(buf, val, 9)
+
+	return w.writeValue("Writer.WriteUint", buf)
+}
+
+// WriteBigInt writes a big integer.
+func (w *binaryWriter11) WriteBigInt(val *big.Int) error {
+	if val.IsInt64() {
+		return w.WriteInt(val.Int64())
+	}
+
+	length := fixedIntBigLen(val)
+
+	buf := make([]byte, 0, length+header11Len(0, length))
+	buf = appendHeader11(buf, 0, op11IntLen, length)
+	buf = appendFixedBig(buf, val, length)
+
+	return w.writeValue("Writer.WriteBigInt", buf)
+}
+
+// WriteFloat writes a floating-point value.
+func (w *binaryWriter11) WriteFloat(val float64) error {
+	if val == 0 && !math.Signbit(val) {
+		// Positive zero is represented as just the opcode.
+		return w.writeValue("Writer.WriteFloat", []byte{op11Float})
+	}
+
+	var bs []byte
+
+	// Can this be losslessly represented as a float32?
+	if math.IsNaN(val) || val == float64(float32(val)) {
+		bs = make([]byte, 5)
+		bs[0] = op11Float + 2
+		binary.LittleEndian.PutUint32(bs[1:], math.Float32bits(float32(val)))
+	} else {
+		bs = make([]byte, 9)
+		bs[0] = op11Float + 3
+		binary.LittleEndian.PutUint64(bs[1:], math.Float64bits(val))
+	}
+
+	return w.writeValue("Writer.WriteFloat", bs)
+}
+
+// WriteDecimal writes a decimal value.
+func (w *binaryWriter11) WriteDecimal(val *Decimal) error {
+	coef, exp := val.CoEx()
+
+	// Positive 0. (aka 0d0) is represented as just the opcode.
+	if coef.Sign() == 0 && exp == 0 && !val.isNegZero {
+		return w.writeValue("Writer.WriteDecimal", []byte{op11Decimal})
+	}
+
+	// A zero coefficient is omitted entirely, unless it's negative zero, which
+	// is written as a single zero byte.
+	vlength := flexIntLen(int64(exp))
+	if val.isNegZero {
+		vlength++
+	} else {
+		vlength += fixedIntBigLen(coef)
+	}
+
+	buf := make([]byte, 0, vlength+header11Len(op11Decimal, vlength))
+	buf = appendHeader11(buf, op11Decimal, op11DecimalLen, vlength)
+	buf = appendFlexInt(buf, int64(exp))
+
+	if val.isNegZero {
+		buf = append(buf, 0x00)
+	} else {
+		buf = appendFixedBig(buf, coef, fixedIntBigLen(coef))
+	}
+
+	return w.writeValue("Writer.WriteDecimal", buf)
+}
+
+// WriteTimestamp writes a timestamp value.
+func (w *binaryWriter11) WriteTimestamp(val Timestamp) error {
+	return w.writeValue("Writer.WriteTimestamp", appendTimestamp11(nil, val))
+}
+
+// WriteSymbol writes a symbol value given a SymbolToken.
+func (w *binaryWriter11) WriteSymbol(val SymbolToken) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	var s sym11
+	if s, w.err = w.resolveToken("Writer.WriteSymbol", val); w.err != nil {
+		return w.err
+	}
+	return w.writeSymbol("Writer.WriteSymbol", s)
+}
+
+// WriteSymbolFromString writes a symbol value given a string. Unless the writer
+// was created with BinaryWriter11InlineSymbols, it's an error if the string is
+// not in the writer's pre-built symbol table.
+func (w *binaryWriter11) WriteSymbolFromString(val string) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	var s sym11
+	if id, ok := symbolIdentifier(val); ok {
+		s.id = uint64(id)
+	} else if s, w.err = w.resolve("Writer.WriteSymbolFromString", val); w.err != nil {
+		return w.err
+	}
+	return w.writeSymbol("Writer.WriteSymbolFromString", s)
+}
+
+func (w *binaryWriter11) writeSymbol(api string, s sym11) error {
+	if s.text != nil {
+		vlength := uint64(len(*s.text))
+		buf := make([]byte, 0, vlength+header11Len(op11SymbolText, vlength))
+
+		buf = appendHeader11(buf, op11SymbolText, op11SymbolTextLen, vlength)
+		buf = append(buf, *s.text...)
+
+		return w.writeValue(api, buf)
+	}
+
+	var buf []byte
+	switch {
+	case s.id < symbolAddressBias2:
+		buf = []byte{op11SymbolAddr1, byte(s.id)}
+	case s.id < symbolAddressBias3:
+		buf = appendFixed([]byte{op11SymbolAddr2}, s.id-symbolAddressBias2, 2)
+	case s.id < symbolAddressBias3+1<<24:
+		buf = appendFixed([]byte{op11SymbolAddr3}, s.id-symbolAddressBias3, 3)
+	default:
+		w.err = &UsageError{api, fmt.Sprintf("symbol ID %v is too large", s.id)}
+		return w.err
+	}
+
+	return w.writeValue(api, buf)
+}
+
+// WriteString writes a string.
+func (w *binaryWriter11) WriteString(val string) error {
+	vlength := uint64(len(val))
+	buf := make([]byte, 0, vlength+header11Len(op11String, vlength))
+
+	buf = appendHeader11(buf, op11String, op11StringLen, vlength)
+	buf = append(buf, val...)
+
+	return w.writeValue("Writer.WriteString", buf)
+}
+
+// WriteClob writes a clob.
+func (w *binaryWriter11) WriteClob(val []byte) error {
+	return w.writeLob("Writer.WriteClob", op11ClobLen, val)
+}
+
+// WriteBlob writes a blob.
+func (w *binaryWriter11) WriteBlob(val []byte) error {
+	return w.writeLob("Writer.WriteBlob", op11BlobLen, val)
+}
+
+func (w *binaryWriter11) writeLob(api string, code byte, val []byte) error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.err = w.beginValue(api); w.err != nil {
+		return w.err
+	}
+
+	// No sense in copying, emit the header separately.
+	header := appendHeader11(nil, 0, code, uint64(len(val)))
+	if w.err = w.write(header); w.err != nil {
+		return w.err
+	}
+	w.err = w.write(val)
+	return w.err
+}
+
+// BeginList begins writing a list.
+func (w *binaryWriter11) BeginList() error {
+	if w.err == nil {
+		w.err = w.begin("Writer.BeginList", ctxInList, op11List)
+	}
+	return w.err
+}
+
+// EndList finishes writing a list.
+func (w *binaryWriter11) EndList() error {
+	if w.err == nil {
+		w.err = w.end("Writer.EndList", ctxInList)
+	}
+	return w.err
+}
+
+// BeginSexp begins writing an s-expression.
+func (w *binaryWriter11) BeginSexp() error {
+	if w.err == nil {
+		w.err = w.begin("Writer.BeginSexp", ctxInSexp, op11Sexp)
+	}
+	return w.err
+}
+
+// EndSexp finishes writing an s-expression.
+func (w *binaryWriter11) EndSexp() error {
+	if w.err == nil {
+		w.err = w.end("Writer.EndSexp", ctxInSexp)
+	}
+	return w.err
+}
+
+// BeginStruct begins writing a struct.
+func (w *binaryWriter11) BeginStruct() error {
+	if w.err == nil {
+		w.err = w.begin("Writer.BeginStruct", ctxInStruct, op11Struct)
+	}
+	return w.err
+}
+
+// EndStruct finishes writing a struct.
+func (w *binaryWriter11) EndStruct() error {
+	if w.err == nil {
+		w.err = w.end("Writer.EndStruct", ctxInStruct)
+	}
+	return w.err
+}
+
+// BeginEExpression begins writing an e-expression.
+func (w *binaryWriter11) BeginEExpression(m *Macro) error {
+	if w.err == nil {
+		w.err = w.beginEExpression("Writer.BeginEExpression", m)
+	}
+	return w.err
+}
+
+// EndEExpression finishes writing an e-expression.
+func (w *binaryWriter11) EndEExpression() error {
+	if w.err == nil {
+		w.err = w.endEExpression("Writer.EndEExpression")
+	}
+	return w.err
+}
+
+// BeginExpressionGroup begins writing an expression group.
+func (w *binaryWriter11) BeginExpressionGroup() error {
+	if w.err == nil {
+		w.err = w.beginExpressionGroup("Writer.BeginExpressionGroup")
+	}
+	return w.err
+}
+
+// EndExpressionGroup finishes writing an expression group.
+func (w *binaryWriter11) EndExpressionGroup() error {
+	if w.err == nil {
+		if w.ctx.peek() != ctxInExpressionGroup {
+			w.err = &UsageError{"Writer.EndExpressionGroup", "not in an expression group"}
+		} else {
+			w.clear()
+			w.ctx.pop()
+		}
+	}
+	return w.err
+}
+
+// Finish finishes writing a datagram.
+func (w *binaryWriter11) Finish() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.ctx.peek() != ctxAtTopLevel {
+		return &UsageError{"Writer.Finish", "not at top level"}
+	}
+
+	w.clear()
+
+	if w.lstb != nil {
+		// Now that the local symbol table is complete, write it out followed
+		// by the buffered values.
+		seq := w.bufs.peek()
+		w.bufs.pop()
+		if w.bufs.peek() != nil {
+			panic("at top level but too many bufseqs")
+		}
+
+		if w.err = w.writeHeader(w.lstb.Build()); w.err != nil {
+			return w.err
+		}
+		if w.err = w.emit(seq); w.err != nil {
+			return w.err
+		}
+		w.bufs.push(&datagram{})
+	} else if !w.wroteIVM {
+		if w.err = w.writeHeader(w.lst); w.err != nil {
+			return w.err
+		}
+	}
+
+	w.wroteIVM = false
+	return nil
+}
+
+// Emit emits the given node. If we're not buffering anything, that means
+// actually emitting to the output stream. If we are, we append to the current
+// bufseq.
+func (w *binaryWriter11) emit(node bufnode) error {
+	s := w.bufs.peek()
+	if s == nil {
+		return node.EmitTo(w.out)
+	}
+	s.Append(node)
+	return nil
+}
+
+// Write emits the given bytes as an atom.
+func (w *binaryWriter11) write(bs []byte) error {
+	return w.emit(atom(bs))
+}
+
+// WriteValue writes a serialized value to the output stream.
+func (w *binaryWriter11) writeValue(api string, val []byte) error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.err = w.beginValue(api); w.err != nil {
+		return w.err
+	}
+
+	w.err = w.write(val)
+	return w.err
+}
+
+// WriteHeader writes out an Ion 1.1 version marker followed by the given local
+// symbol table, or the writer's encoding directive if it has one.
+func (w *binaryWriter11) writeHeader(lst SymbolTable) error {
+	w.wroteIVM = true
+	if err := w.write([]byte{op11IVM, 0x01, 0x01, 0xEA}); err != nil {
+		return err
+	}
+	if w.enc != nil {
+		return w.writeEncodingDirective()
+	}
+	return lst.WriteTo(w)
+}
+
+// WriteEncodingDirective writes out the writer's encoding directive. Until it has
+// been read, only the system symbols are defined, so any other symbols in the
+// directive are written inline.
+func (w *binaryWriter11) writeEncodingDirective() error {
+	lst, opts := w.lst, w.opts
+	defer func() {
+		w.lst, w.opts = lst, opts
+	}()
+
+	w.lst = V11SystemSymbolTable
+	w.opts |= BinaryWriter11InlineSymbols
+	return w.enc.WriteTo(w)
+}
+
+// BeginValue begins the process of writing a value by writing out
+// its field name and annotations.
+func (w *binaryWriter11) beginValue(api string) error {
+	// As with binaryWriter, these have to be recorded before calling
+	// writeHeader, which will end up using/modifying them.
+	name := w.fieldName
+	as := w.annotations
+	w.clear()
+
+	if w.lst != nil && !w.wroteIVM {
+		if err := w.writeHeader(w.lst); err != nil {
+			return err
+		}
+	}
+
+	if w.IsInStruct() {
+		if err := w.writeFieldName(api, name); err != nil {
+			return err
+		}
+	}
+	if w.ctx.peek() == ctxInEExpression {
+		if err := w.nextArg(api, false); err != nil {
+			return err
+		}
+	}
+
+	if len(as) > 0 {
+		return w.writeAnnotations(api, as)
+	}
+	return nil
+}
+
+// WriteFieldName writes out a field name. Length-prefixed structs start out with
+// symbol ID field names, switching to FlexSyms if a field name needs its text
+// written inline; delimited structs always use FlexSyms.
+func (w *binaryWriter11) writeFieldName(api string, name *SymbolToken) error {
+	if name == nil {
+		return &UsageError{api, "field name not set"}
+	}
+	if name.Text == nil && name.LocalSID == SymbolIDUnknown {
+		return &UsageError{api, "field name symbol token does not have defined text or symbol id."}
+	}
+
+	s, err := w.resolveToken(api, *name)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 0, 10)
+	if c, ok := w.bufs.peek().(*container11); ok && !c.flexSyms {
+		if s.text == nil && s.id != 0 {
+			return w.write(appendFlexUint(buf, s.id))
+		}
+
+		// A FlexUInt zero switches the rest of the struct to FlexSyms.
+		c.flexSyms = true
+		buf = appendFlexUint(buf, 0)
+	}
+
+	return w.write(appendFlexSym(buf, s))
+}
+
+// WriteAnnotations writes out a value's annotations. If they're all symbol IDs
+// they're written as FlexUInts; otherwise they're written as FlexSyms.
+func (w *binaryWriter11) writeAnnotations(api string, as []SymbolToken) error {
+	syms := make([]sym11, len(as))
+	flexSyms := false
+
+	for i, a := range as {
+		if a.Text == nil && a.LocalSID == SymbolIDUnknown {
+			return &UsageError{api, "invalid annotation symbol token"}
+		}
+
+		s, err := w.resolveToken(api, a)
+		if err != nil {
+			return err
+		}
+		syms[i] = s
+		flexSyms = flexSyms || s.text != nil
+	}
+
+	var body []byte
+	for _, s := range syms {
+		if flexSyms {
+			body = appendFlexSym(body, s)
+		} else {
+			body = appendFlexUint(body, s.id)
+		}
+	}
+
+	op := byte(op11Annotations1)
+	if flexSyms {
+		op = op11AnnotationSym1
+	}
+
+	var buf []byte
+	switch len(syms) {
+	case 1:
+		buf = append([]byte{op}, body...)
+	case 2:
+		buf = append([]byte{op + 1}, body...)
+	default:
+		buf = appendFlexUint([]byte{op + 2}, uint64(len(body)))
+		buf = append(buf, body...)
+	}
+
+	return w.write(buf)
+}
+
+// Begin begins writing a new container.
+func (w *binaryWriter11) begin(api string, t ctx, code byte) error {
+	if err := w.beginValue(api); err != nil {
+		return err
+	}
+
+	w.ctx.push(t)
+
+	if w.opts&BinaryWriter11Delimited != 0 {
+		return w.write([]byte{op11DelimitedList + (code-op11List)>>4})
+	}
+
+	w.bufs.push(&container11{
+		code:    code,
+		codeLen: op11ListLen + (code-op11List)>>4,
+	})
+	return nil
+}
+
+// End ends writing a container, either writing its end marker or emitting its
+// buffered contents up a level in the stack.
+func (w *binaryWriter11) end(api string, t ctx) error {
+	if w.ctx.peek() != t {
+		return &UsageError{api, "not in that kind of container"}
+	}
+
+	w.clear()
+	w.ctx.pop()
+
+	if w.opts&BinaryWriter11Delimited != 0 {
+		if t == ctxInStruct {
+			// The end of a delimited struct is escaped as a FlexSym.
+			return w.write([]byte{0x01, op11DelimitedEnd})
+		}
+		return w.write([]byte{op11DelimitedEnd})
+	}
+
+	seq := w.bufs.peek()
+	w.bufs.pop()
+	return w.emit(seq)
+}
+
+// BeginEExpression starts buffering an e-expression's arguments.
+func (w *binaryWriter11) beginEExpression(api string, m *Macro) error {
+	if len(w.annotations) > 0 {
+		return &UsageError{api, "e-expressions cannot be annotated"}
+	}
+	if err := w.beginValue(api); err != nil {
+		return err
+	}
+
+	w.ctx.push(ctxInEExpression)
+	w.bufs.push(&eexp11{m: m})
+	return nil
+}
+
+// EndEExpression fills in the header of the current e-expression now that all
+// of its arguments are known, and emits it up a level in the stack.
+func (w *binaryWriter11) endEExpression(api string) error {
+	if w.ctx.peek() != ctxInEExpression {
+		return &UsageError{api, "not in an e-expression"}
+	}
+	e := w.bufs.peek().(*eexp11)
+	m := e.m
+
+	encs := make([]byte, len(m.params))
+	for i, p := range m.params {
+		switch {
+		case i < len(e.args) && e.args[i].group && e.args[i].len > 0:
+			encs[i] = argGroup11
+		case i < len(e.args) && !e.args[i].group:
+			encs[i] = argSingle11
+		case p.Cardinality.optional():
+			encs[i] = argNone11
+		default:
+			return &UsageError{api, fmt.Sprintf("missing argument for parameter %v of %v", p.Name, m.displayName())}
+		}
+	}
+
+	mt := w.macros
+	if mt == nil {
+		mt = V11SystemMacroTable
+	}
+	if addr, ok := mt.Address(m); ok {
+		e.head = appendMacroAddress11(e.head, addr)
+	} else if addr, ok := V11SystemMacroTable.Address(m); ok {
+		e.head = append(e.head, op11SystemMacro, byte(addr))
+	} else {
+		return &UsageError{api, fmt.Sprintf("macro %v is not in the macro table", m.displayName())}
+	}
+	e.head = appendArgEncodings11(e.head, m, encs)
+
+	w.clear()
+	w.ctx.pop()
+	w.bufs.pop()
+	return w.emit(e)
+}
+
+// BeginExpressionGroup starts a new e-expression argument that's an expression
+// group.
+func (w *binaryWriter11) beginExpressionGroup(api string) error {
+	if w.ctx.peek() != ctxInEExpression {
+		return &UsageError{api, "expression groups can only be written as e-expression arguments"}
+	}
+	if len(w.annotations) > 0 {
+		return &UsageError{api, "expression groups cannot be annotated"}
+	}
+	if err := w.nextArg(api, true); err != nil {
+		return err
+	}
+
+	w.ctx.push(ctxInExpressionGroup)
+	return nil
+}
+
+// NextArg starts the next argument of the current e-expression. As in text,
+// any arguments beyond the macro's last parameter are passed to it if it's
+// variadic.
+func (w *binaryWriter11) nextArg(api string, group bool) error {
+	e := w.bufs.peek().(*eexp11)
+	params := e.m.params
+
+	switch n := len(e.args); {
+	case n < len(params):
+		if group && params[n].Cardinality == ExactlyOne {
+			msg := fmt.Sprintf("parameter %v of %v does not accept an expression group", params[n].Name, e.m.displayName())
+			return &UsageError{api, msg}
+		}
+		e.args = append(e.args, &arg11{group: group})
+	case n > 0 && params[n-1].Cardinality.variadic():
+		e.args[n-1].group = true
+	default:
+		return &UsageError{api, fmt.Sprintf("too many arguments for %v", e.m.displayName())}
+	}
+	return nil
+}
+
+// ResolveToken resolves a symbol token, preferring its text to its symbol ID.
+func (w *binaryWriter11) resolveToken(api string, tok SymbolToken) (sym11, error) {
+	if tok.Text != nil {
+		return w.resolve(api, *tok.Text)
+	}
+	if tok.LocalSID != SymbolIDUnknown {
+		return sym11{id: uint64(tok.LocalSID)}, nil
+	}
+	return sym11{}, &UsageError{api, "symbol token without defined text or symbol id is invalid"}
+}
+
+// Resolve resolves symbol text to its ID, or to itself if it's to be written inline.
+func (w *binaryWriter11) resolve(api, sym string) (sym11, error) {
+	if w.lstb != nil {
+		id, _ := w.lstb.Add(sym)
+		return sym11{id: id}, nil
+	}
+
+	if id, ok := w.lst.FindByName(sym); ok {
+		return sym11{id: id}, nil
+	}
+	if w.opts&BinaryWriter11InlineSymbols != 0 {
+		return sym11{text: &sym}, nil
+	}
+	return sym11{}, &UsageError{api, fmt.Sprintf("symbol '%v' not defined", sym)}
+}
+
+// appendFlexSym appends a symbol to b as a FlexSym.
+func appendFlexSym(b []byte, s sym11) []byte {
+	switch {
+	case s.text != nil && *s.text == "":
+		return append(b, 0x01, flexSymEmpty)
+	case s.text != nil:
+		b = appendFlexInt(b, -int64(len(*s.text)))
+		return append(b, *s.text...)
+	case s.system:
+		return append(b, 0x01, flexSymSystem, byte(s.id))
+	case s.id == 0:
+		return append(b, 0x01, flexSymZero)
+	}
+	return appendFlexInt(b, int64(s.id))
+}
+
+// appendTimestamp11 appends a timestamp value, using one of the short forms if
+// possible.
+func appendTimestamp11(b []byte, val Timestamp) []byte {
+	_, offset := val.dateTime.Zone()
+	offset /= 60
+	unknown := val.kind == TimezoneUnspecified
+
+	// Times are written in UTC, while dates are written as-is.
+	t := val.dateTime
+	precision := val.precision
+	if precision >= TimestampPrecisionMinute {
+		t = t.In(time.UTC)
+	}
+
+	digits := uint8(0)
+	fraction := 0
+	if precision == TimestampPrecisionNanosecond {
+		digits = val.numFractionalSeconds
+		fraction = val.TruncatedNanoseconds()
+		if digits == 0 {
+			precision = TimestampPrecisionSecond
+		}
+	}
+
+	if op, ok := shortTimestampOp11(t.Year(), precision, digits, offset, unknown); ok {
+		bs := make([]byte, shortTimestampLengths11[op-op11Timestamp])
+		setBitField(bs, 0, 7, uint64(t.Year()-1970))
+		if precision >= TimestampPrecisionMonth {
+			setBitField(bs, 7, 4, uint64(t.Month()))
+		}
+		if precision >= TimestampPrecisionDay {
+			setBitField(bs, 11, 5, uint64(t.Day()))
+		}
+
+		if precision >= TimestampPrecisionMinute {
+			setBitField(bs, 16, 5, uint64(t.Hour()))
+			setBitField(bs, 21, 6, uint64(t.Minute()))
+
+			secs := uint(28)
+			if op < op11Timestamp+8 {
+				if !unknown {
+					setBitField(bs, 27, 1, 1)
+				}
+			} else {
+				setBitField(bs, 27, 7, uint64(offset/15+56))
+				secs = 34
+			}
+
+			if precision >= TimestampPrecisionSecond {
+				setBitField(bs, secs, 6, uint64(t.Second()))
+				setBitField(bs, secs+6, uint(digits)*10/3, uint64(fraction))
+			}
+		}
+
+		b = append(b, op)
+		return append(b, bs...)
+	}
+
+	// The length of the body determines its precision.
+	vlength := uint64(2)
+	switch precision {
+	case TimestampPrecisionMonth, TimestampPrecisionDay:
+		vlength = 3
+	case TimestampPrecisionMinute:
+		vlength = 6
+	case TimestampPrecisionSecond:
+		vlength = 7
+	case TimestampPrecisionNanosecond:
+		vlength = 7 + flexUintLen(uint64(digits)) + fixedUintLen(uint64(fraction))
+	}
+
+	// Fill in all the fixed-size fields, then trim to the actual length.
+	bs := make([]byte, 7, 7+vlength)
+	setBitField(bs, 0, 14, uint64(t.Year()))
+	if precision >= TimestampPrecisionMonth {
+		setBitField(bs, 14, 4, uint64(t.Month()))
+	}
+	if precision >= TimestampPrecisionDay {
+		setBitField(bs, 18, 5, uint64(t.Day()))
+	}
+	if precision >= TimestampPrecisionMinute {
+		setBitField(bs, 23, 5, uint64(t.Hour()))
+		setBitField(bs, 28, 6, uint64(t.Minute()))
+		if unknown {
+			setBitField(bs, 34, 12, 0xFFF)
+		} else {
+			setBitField(bs, 34, 12, uint64(offset+1440))
+		}
+	}
+	if precision >= TimestampPrecisionSecond {
+		setBitField(bs, 46, 6, uint64(t.Second()))
+	}
+
+	if precision == TimestampPrecisionNanosecond {
+		bs = appendFlexUint(bs, uint64(digits))
+		bs = appendFixed(bs, uint64(fraction), fixedUintLen(uint64(fraction)))
+	}
+
+	b = appendHeader11(b, 0, op11TimestampLen, vlength)
+	return append(b, bs[:vlength]...)
+}
+
+// shortTimestampOp11 returns the opcode of the short-form timestamp that can
+// represent a timestamp with the given attributes, if any.
+func shortTimestampOp11(year int, precision TimestampPrecision, digits uint8, offset int, unknown bool) (byte, bool) {
+	if year < 1970 || year >= 1970+1<<7 {
+		return 0, false
+	}
+
+	switch precision {
+	case TimestampPrecisionYear:
+		return op11Timestamp, true
+	case TimestampPrecisionMonth:
+		return op11Timestamp + 1, true
+	case TimestampPrecisionDay:
+		return op11Timestamp + 2, true
+	}
+
+	// Minute, second, millisecond, microsecond, and nanosecond precision.
+	var n byte
+	switch {
+	case precision == TimestampPrecisionMinute:
+		n = 0
+	case precision == TimestampPrecisionSecond:
+		n = 1
+	case digits == 3 || digits == 6 || digits == 9:
+		n = 1 + digits/3
+	default:
+		return 0, false
+	}
+
+	if unknown || offset == 0 {
+		return op11Timestamp + 3 + n, true
+	}
+	if o := offset/15 + 56; offset%15 == 0 && o >= 0 && o < 1<<7 {
+		return op11Timestamp + 8 + n, true
+	}
+	return 0, false
+}
@@ -0,0 +1,636 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testBinaryWriter11 checks that the values written by f, minus the leading IVM, match eval.
+func testBinaryWriter11(t *testing.T, opts BinaryWriter11Opts, eval []byte, f func(w Writer)) {
+	buf := bytes.Buffer{}
+	w := NewBinaryWriter11Opts(&buf, opts)
+	f(w)
+	require.NoError(t, w.Finish())
+
+	bs := buf.Bytes()
+	require.True(t, bytes.HasPrefix(bs, ivm11), "no IVM in %v", fmtbytes(bs))
+	assert.Equal(t, fmtbytes(eval), fmtbytes(bs[len(ivm11):]))
+}
+
+func TestWriteBinary11Scalars(t *testing.T) {
+	test := func(name string, eval []byte, f func(w Writer) error) {
+		t.Run(name, func(t *testing.T) {
+			testBinaryWriter11(t, BinaryWriter11InlineSymbols, eval, func(w Writer) {
+				assert.NoError(t, f(w))
+			})
+		})
+	}
+
+	test("null", []byte{0xEA}, func(w Writer) error { return w.WriteNull() })
+	test("null.int", []byte{0xEB, 0x01}, func(w Writer) error { return w.WriteNullType(IntType) })
+	test("null.struct", []byte{0xEB, 0x0B}, func(w Writer) error { return w.WriteNullType(StructType) })
+	test("true", []byte{0x6E}, func(w Writer) error { return w.WriteBool(true) })
+	test("false", []byte{0x6F}, func(w Writer) error { return w.WriteBool(false) })
+
+	test("int 0", []byte{0x60}, func(w Writer) error { return w.WriteInt(0) })
+	test("int 1", []byte{0x61, 0x01}, func(w Writer) error { return w.WriteInt(1) })
+	test("int -1", []byte{0x61, 0xFF}, func(w Writer) error { return w.WriteInt(-1) })
+	test("int 128", []byte{0x62, 0x80, 0x00}, func(w Writer) error { return w.WriteInt(128) })
+	test("int -128", []byte{0x61, 0x80}, func(w Writer) error { return w.WriteInt(-128) })
+	test("int min", []byte{0x68, 0, 0, 0, 0, 0, 0, 0, 0x80}, func(w Writer) error { return w.WriteInt(math.MinInt64) })
+	test("uint max", []byte{0xF6, 0x13, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x00}, func(w Writer) error { return w.WriteUint(math.MaxUint64) })
+	test("bigint small", []byte{0x62, 0x00, 0x01}, func(w Writer) error { return w.WriteBigInt(big.NewInt(256)) })
+	test("bigint", []byte{0xF6, 0x13, 0, 0, 0, 0, 0, 0, 0, 0, 0x01}, func(w Writer) error {
+		return w.WriteBigInt(new(big.Int).Lsh(big.NewInt(1), 64))
+	})
+
+	test("float 0", []byte{0x6A}, func(w Writer) error { return w.WriteFloat(0) })
+	test("float32", []byte{0x6C, 0x00, 0x00, 0xC0, 0x3F}, func(w Writer) error { return w.WriteFloat(1.5) })
+	test("float64", []byte{0x6D, 0x9A, 0x99, 0x99, 0x99, 0x99, 0x99, 0xB9, 0x3F}, func(w Writer) error { return w.WriteFloat(0.1) })
+
+	test("decimal 0", []byte{0x70}, func(w Writer) error { return w.WriteDecimal(MustParseDecimal("0")) })
+	test("decimal 1.5", []byte{0x72, 0xFF, 0x0F}, func(w Writer) error { return w.WriteDecimal(MustParseDecimal("1.5")) })
+	test("decimal -0", []byte{0x72, 0x01, 0x00}, func(w Writer) error { return w.WriteDecimal(MustParseDecimal("-0")) })
+	test("decimal 0d5", []byte{0x71, 0x0B}, func(w Writer) error { return w.WriteDecimal(MustParseDecimal("0d5")) })
+	test("decimal -100d1", []byte{0x72, 0x03, 0x9C}, func(w Writer) error { return w.WriteDecimal(MustParseDecimal("-100d1")) })
+
+	test("string", []byte{0x93, 'a', 'b', 'c'}, func(w Writer) error { return w.WriteString("abc") })
+	test("string long", append([]byte{0xF9, 0x21}, strings.Repeat("a", 16)...), func(w Writer) error {
+		return w.WriteString(strings.Repeat("a", 16))
+	})
+	test("symbol text", []byte{0xA1, 'x'}, func(w Writer) error { return w.WriteSymbolFromString("x") })
+	test("symbol empty", []byte{0xA0}, func(w Writer) error { return w.WriteSymbolFromString("") })
+	test("symbol system", []byte{0xE1, 0x04}, func(w Writer) error { return w.WriteSymbolFromString("name") })
+	test("symbol id", []byte{0xE1, 0x00}, func(w Writer) error { return w.WriteSymbolFromString("$0") })
+	test("symbol 1.1 system", []byte{0xE1, 0x0A}, func(w Writer) error { return w.WriteSymbolFromString("$ion_encoding") })
+	test("symbol address 2", []byte{0xE2, 0x00, 0x01}, func(w Writer) error { return w.WriteSymbolFromString("$512") })
+	test("symbol address 3", []byte{0xE3, 0x00, 0x00, 0x00}, func(w Writer) error { return w.WriteSymbolFromString("$65792") })
+	test("blob", []byte{0xFE, 0x05, 0x01, 0x02}, func(w Writer) error { return w.WriteBlob([]byte{1, 2}) })
+	test("clob", []byte{0xFF, 0x03, 'a'}, func(w Writer) error { return w.WriteClob([]byte("a")) })
+}
+
+func TestWriteBinary11Timestamps(t *testing.T) {
+	test := func(eval []byte, ts string) {
+		t.Run(ts, func(t *testing.T) {
+			val := readSingleValue(t, []byte(ts)).(*TimestampValue).Timestamp()
+			testBinaryWriter11(t, 0, eval, func(w Writer) {
+				assert.NoError(t, w.WriteTimestamp(val))
+			})
+		})
+	}
+
+	test([]byte{0x80, 0x1E}, "2000T")
+	test([]byte{0x82, 0x9E, 0x79}, "2000-03-15")
+	test([]byte{0x83, 0x9E, 0x79, 0xCC, 0x0B}, "2000-03-15T12:30Z")
+	test([]byte{0x83, 0x9E, 0x79, 0xCC, 0x03}, "2000-03-15T12:30-00:00")
+	test([]byte{0x87, 0x9E, 0x79, 0xCC, 0xDB, 0x56, 0x34, 0x6F, 0x1D}, "2000-03-15T12:30:45.123456789Z")
+	test([]byte{0x88, 0x9E, 0x79, 0xCC, 0x63, 0x02}, "2000-03-15T17:30+05:00")
+	test([]byte{0x8A, 0x9E, 0x79, 0xD4, 0xC3, 0xB4, 0x7B, 0x00}, "2000-03-15T12:30:45.123-08:00")
+	test([]byte{0xF8, 0x07, 0x6C, 0xC7, 0x00}, "1900-03T")
+	test([]byte{0xF8, 0x15, 0xD0, 0xC7, 0xBC, 0xE5, 0x71, 0x57, 0x0B, 0x0B, 0x39, 0x30}, "2000-03-15T12:30:45.12345+01:00")
+}
+
+func TestWriteBinary11Containers(t *testing.T) {
+	writeStruct := func(w Writer) {
+		assert.NoError(t, w.BeginStruct())
+		assert.NoError(t, w.FieldName(NewSymbolTokenFromString("name")))
+		assert.NoError(t, w.WriteInt(0))
+		assert.NoError(t, w.FieldName(NewSymbolTokenFromString("a")))
+		assert.NoError(t, w.BeginList())
+		assert.NoError(t, w.WriteBool(true))
+		assert.NoError(t, w.EndList())
+		assert.NoError(t, w.EndStruct())
+
+		assert.NoError(t, w.BeginSexp())
+		assert.NoError(t, w.EndSexp())
+	}
+
+	t.Run("length-prefixed", func(t *testing.T) {
+		eval := []byte{
+			0xD7,       // {
+			0x09, 0x60, // name: 0,
+			0x01,                  // (switch to FlexSyms)
+			0xFF, 'a', 0xB1, 0x6E, // a: [true]
+			// }
+			0xC0, // ()
+		}
+		testBinaryWriter11(t, BinaryWriter11InlineSymbols, eval, writeStruct)
+	})
+
+	t.Run("delimited", func(t *testing.T) {
+		eval := []byte{
+			0xF3,       // {
+			0x09, 0x60, // name: 0,
+			0xFF, 'a', 0xF1, 0x6E, 0xF0, // a: [true]
+			0x01, 0xF0, // }
+			0xF2, 0xF0, // ()
+		}
+		testBinaryWriter11(t, BinaryWriter11InlineSymbols|BinaryWriter11Delimited, eval, writeStruct)
+	})
+
+	t.Run("long", func(t *testing.T) {
+		eval := []byte{0xFB, 0x21}
+		for i := 0; i < 16; i++ {
+			eval = append(eval, 0x60)
+		}
+		testBinaryWriter11(t, 0, eval, func(w Writer) {
+			assert.NoError(t, w.BeginList())
+			for i := 0; i < 16; i++ {
+				assert.NoError(t, w.WriteInt(0))
+			}
+			assert.NoError(t, w.EndList())
+		})
+	})
+}
+
+func TestWriteBinary11Annotations(t *testing.T) {
+	eval := []byte{
+		0xE4, 0x09, 0x60, // name::0
+		0xE5, 0x09, 0x0B, 0x60, // name::version::0
+		0xE6, 0x07, 0x09, 0x0B, 0x0F, 0x60, // name::version::symbols::0
+		0xE8, 0x09, 0xFF, 'a', 0x60, // name::a::0
+		0xE4, 0x01, 0x60, // $0::0
+	}
+	testBinaryWriter11(t, BinaryWriter11InlineSymbols, eval, func(w Writer) {
+		assert.NoError(t, w.Annotation(NewSymbolTokenFromString("name")))
+		assert.NoError(t, w.WriteInt(0))
+
+		assert.NoError(t, w.Annotations(NewSymbolTokenFromString("name"), NewSymbolTokenFromString("version")))
+		assert.NoError(t, w.WriteInt(0))
+
+		assert.NoError(t, w.Annotations(NewSymbolTokenFromString("name"), NewSymbolTokenFromString("version"), NewSymbolTokenFromString("symbols")))
+		assert.NoError(t, w.WriteInt(0))
+
+		assert.NoError(t, w.Annotations(NewSymbolTokenFromString("name"), NewSymbolTokenFromString("a")))
+		assert.NoError(t, w.WriteInt(0))
+
+		assert.NoError(t, w.Annotation(SymbolToken{LocalSID: 0}))
+		assert.NoError(t, w.WriteInt(0))
+	})
+}
+
+func TestWriteBinary11SymbolTable(t *testing.T) {
+	buf := bytes.Buffer{}
+	w := NewBinaryWriter11(&buf)
+	require.NoError(t, w.WriteSymbolFromString("foo"))
+	require.NoError(t, w.WriteSymbolFromString("name"))
+	require.NoError(t, w.WriteSymbolFromString("foo"))
+	require.NoError(t, w.Finish())
+
+	foo := byte(V11SystemSymbolTable.MaxID() + 1)
+	eval := append(append([]byte{}, ivm11...),
+		0xE4, 0x07, // $ion_symbol_table::
+		0xD6, 0x0F, 0xB4, 0x93, 'f', 'o', 'o', // {symbols: ["foo"]}
+		0xE1, foo,
+		0xE1, 0x04,
+		0xE1, foo,
+	)
+	assert.Equal(t, fmtbytes(eval), fmtbytes(buf.Bytes()))
+
+	vals, err := ReadValues(NewReaderBytes(buf.Bytes()))
+	require.NoError(t, err)
+	require.Len(t, vals, 3)
+	assert.Equal(t, "foo", *vals[2].(*SymbolValue).Symbol().Text)
+}
+
+func TestWriteBinary11LST(t *testing.T) {
+	lstb := NewSymbolTableBuilder()
+	lstb.Add("foo")
+	lst := lstb.Build()
+
+	t.Run("undefined", func(t *testing.T) {
+		w := NewBinaryWriter11LST(&bytes.Buffer{}, 0, lst)
+		assert.NoError(t, w.WriteSymbolFromString("foo"))
+		assert.Error(t, w.WriteSymbolFromString("bar"))
+		assert.Error(t, w.Finish())
+	})
+
+	t.Run("inline", func(t *testing.T) {
+		buf := bytes.Buffer{}
+		w := NewBinaryWriter11LST(&buf, BinaryWriter11InlineSymbols, lst)
+		require.NoError(t, w.WriteSymbolFromString("foo"))
+		require.NoError(t, w.WriteSymbolFromString("bar"))
+		require.NoError(t, w.Finish())
+
+		vals, err := ReadValues(NewReaderBytes(buf.Bytes()))
+		require.NoError(t, err)
+		require.Len(t, vals, 2)
+		assert.Equal(t, "foo", *vals[0].(*SymbolValue).Symbol().Text)
+		assert.Equal(t, "bar", *vals[1].(*SymbolValue).Symbol().Text)
+		assert.True(t, bytes.HasSuffix(buf.Bytes(), []byte{0xA3, 'b', 'a', 'r'}))
+	})
+}
+
+func TestWriteBinary11Streaming(t *testing.T) {
+	buf := bytes.Buffer{}
+	w := NewBinaryWriter11Opts(&buf, BinaryWriter11Delimited|BinaryWriter11InlineSymbols)
+
+	require.NoError(t, w.BeginList())
+	require.NoError(t, w.WriteInt(1))
+
+	// Nothing is buffered, so the start of the list is already written.
+	assert.Equal(t, fmtbytes(append(append([]byte{}, ivm11...), 0xF1, 0x61, 0x01)), fmtbytes(buf.Bytes()))
+
+	require.NoError(t, w.EndList())
+	require.NoError(t, w.Finish())
+}
+
+func TestWriteBinary11RoundTrip(t *testing.T) {
+	text := `null null.bool null.int null.float null.decimal null.timestamp null.string
+		null.symbol null.blob null.clob null.list null.sexp null.struct
+		true false 0 1 -1 127 128 -129 9223372036854775807 -9223372036854775808
+		18446744073709551616 -340282366920938463463374607431768211456
+		0e0 -0e0 1.5e0 0.1e0 -1e300 +inf -inf nan
+		0. -0. 1.5 -1.5 0d100 -0d-100 123456789012345678901234567890.0987654321
+		2000T 2000-03T 2000-03-15 2000-03-15T12:30Z 2000-03-15T12:30:45-00:00
+		2000-03-15T12:30:45.1Z 2000-03-15T12:30:45.123+01:00 2000-03-15T12:30:45.123456+05:07
+		1969-12-31T23:59:59.999999999-23:59 0001-01-01T00:00Z 9999-12-31T23:59:59.999Z
+		"" "abc" "a longer string of more than fifteen bytes" "é"
+		abc '' '$ion_encoding' name $0 'a longer symbol of more than fifteen bytes'
+		{{}} {{AQID}} {{""}} {{"abc"}}
+		[] [1, [2, [3]]] () (a + b) {} {a: 1, name: 2, '': 3, $0: 4, b: {c: [d]}}
+		a::b::c::1 $ion_encoding::2 name::version::symbols::imports::3 ''::4`
+
+	opts := map[string]BinaryWriter11Opts{
+		"default":   0,
+		"delimited": BinaryWriter11Delimited,
+		"inline":    BinaryWriter11InlineSymbols,
+		"both":      BinaryWriter11Delimited | BinaryWriter11InlineSymbols,
+	}
+	for name, opt := range opts {
+		t.Run(name, func(t *testing.T) {
+			buf := bytes.Buffer{}
+			w := NewBinaryWriter11Opts(&buf, opt)
+			writeFromReaderToWriter(t, NewReaderString(text), w)
+			require.NoError(t, w.Finish())
+
+			eq, err := Equivalent(NewReaderString(text), NewReaderBytes(buf.Bytes()))
+			require.NoError(t, err)
+			assert.True(t, eq)
+		})
+	}
+}
+
+func TestWriteBinary11EExpressions(t *testing.T) {
+	values, _ := V11SystemMacroTable.Find("values")
+	sum, _ := V11SystemMacroTable.Find("sum")
+
+	test := func(name string, eval []byte, f func(w MacroWriter)) {
+		t.Run(name, func(t *testing.T) {
+			testBinaryWriter11(t, BinaryWriter11InlineSymbols, eval, func(w Writer) {
+				f(w.(MacroWriter))
+			})
+		})
+	}
+
+	test("single", []byte{0x01, 0x01, 0x61, 0x01}, func(w MacroWriter) {
+		w.BeginEExpression(values)
+		w.WriteInt(1)
+		w.EndEExpression()
+	})
+	test("omitted", []byte{0x01, 0x00}, func(w MacroWriter) {
+		w.BeginEExpression(values)
+		w.EndEExpression()
+	})
+	test("group", []byte{0x01, 0x02, 0x09, 0x61, 0x01, 0x61, 0x02}, func(w MacroWriter) {
+		w.BeginEExpression(values)
+		w.BeginExpressionGroup()
+		w.WriteInt(1)
+		w.WriteInt(2)
+		w.EndExpressionGroup()
+		w.EndEExpression()
+	})
+	test("empty group", []byte{0x01, 0x00}, func(w MacroWriter) {
+		w.BeginEExpression(values)
+		w.BeginExpressionGroup()
+		w.EndExpressionGroup()
+		w.EndEExpression()
+	})
+	test("extra arguments", []byte{0x01, 0x02, 0x09, 0x61, 0x01, 0x61, 0x02}, func(w MacroWriter) {
+		w.BeginEExpression(values)
+		w.WriteInt(1)
+		w.WriteInt(2)
+		w.EndEExpression()
+	})
+	test("required", []byte{0x07, 0x61, 0x01, 0x01, 0x01, 0x61, 0x02}, func(w MacroWriter) {
+		w.BeginEExpression(sum)
+		w.WriteInt(1)
+		w.BeginEExpression(values)
+		w.WriteInt(2)
+		w.EndEExpression()
+		w.EndEExpression()
+	})
+	test("container argument", []byte{0x01, 0x01, 0xB2, 0x61, 0x01}, func(w MacroWriter) {
+		w.BeginEExpression(values)
+		w.BeginList()
+		w.WriteInt(1)
+		w.EndList()
+		w.EndEExpression()
+	})
+	test("in struct", []byte{0xD7, 0x01, 0xFF, 'a', 0x01, 0x01, 0x61, 0x01}, func(w MacroWriter) {
+		w.BeginStruct()
+		w.FieldName(NewSymbolTokenFromString("a"))
+		w.BeginEExpression(values)
+		w.WriteInt(1)
+		w.EndEExpression()
+		w.EndStruct()
+	})
+}
+
+func TestWriteBinary11MacroTable(t *testing.T) {
+	mt, err := ParseMacroTable("(macro point (x y) {x: (%x), y: (%y)})")
+	require.NoError(t, err)
+	point, _ := mt.Find("point")
+	values, _ := V11SystemMacroTable.Find("values")
+
+	buf := bytes.Buffer{}
+	w := NewBinaryWriter11Macros(&buf, BinaryWriter11InlineSymbols, mt)
+	require.NoError(t, w.BeginEExpression(point))
+	require.NoError(t, w.WriteInt(1))
+	require.NoError(t, w.WriteInt(2))
+	require.NoError(t, w.EndEExpression())
+	require.NoError(t, w.BeginEExpression(values))
+	require.NoError(t, w.WriteInt(3))
+	require.NoError(t, w.EndEExpression())
+	require.NoError(t, w.Finish())
+
+	expected := []byte{0x00, 0x61, 0x01, 0x61, 0x02, 0xEF, 0x01, 0x01, 0x61, 0x03}
+	assert.Equal(t, fmtbytes(expected), fmtbytes(buf.Bytes()[len(ivm11):]))
+
+	vals, err := ReadValues(NewReaderMacros(bytes.NewReader(buf.Bytes()), nil, mt))
+	require.NoError(t, err)
+	assertValues(t, "{x: 1, y: 2} 3", vals)
+}
+
+func TestWriteBinary11EExpressionRoundTrip(t *testing.T) {
+	mt, err := ParseMacroTable(`
+		(macro point (x y) {x: (%x), y: (%y)})
+		(macro tagged (tag v*) (.annotate (.. (%tag)) [(%v)]))`)
+	require.NoError(t, err)
+	text := `(:point 1 (:$ion::values a::b)) {a: (:tagged t (:: "s" [c, d]) {e: f}), g: (:point 2 3)}
+		[(:$ion::none), (:tagged u)] (:$ion::repeat 2 (:point 4 5))`
+
+	expected, err := ReadValues(NewReaderMacros(strings.NewReader(text), nil, mt))
+	require.NoError(t, err)
+
+	writeEExpressions := func(w MacroWriter) {
+		point, _ := mt.Find("point")
+		tagged, _ := mt.Find("tagged")
+		values, _ := V11SystemMacroTable.Find("values")
+		none, _ := V11SystemMacroTable.Find("none")
+		repeat, _ := V11SystemMacroTable.Find("repeat")
+
+		w.BeginEExpression(point)
+		w.WriteInt(1)
+		w.BeginEExpression(values)
+		w.Annotation(NewSymbolTokenFromString("a"))
+		w.WriteSymbolFromString("b")
+		w.EndEExpression()
+		w.EndEExpression()
+
+		w.BeginStruct()
+		w.FieldName(NewSymbolTokenFromString("a"))
+		w.BeginEExpression(tagged)
+		w.WriteSymbolFromString("t")
+		w.BeginExpressionGroup()
+		w.WriteString("s")
+		w.BeginList()
+		w.WriteSymbolFromString("c")
+		w.WriteSymbolFromString("d")
+		w.EndList()
+		w.EndExpressionGroup()
+		w.BeginStruct()
+		w.FieldName(NewSymbolTokenFromString("e"))
+		w.WriteSymbolFromString("f")
+		w.EndStruct()
+		w.EndEExpression()
+		w.FieldName(NewSymbolTokenFromString("g"))
+		w.BeginEExpression(point)
+		w.WriteInt(2)
+		w.WriteInt(3)
+		w.EndEExpression()
+		w.EndStruct()
+
+		w.BeginList()
+		w.BeginEExpression(none)
+		w.EndEExpression()
+		w.BeginEExpression(tagged)
+		w.WriteSymbolFromString("u")
+		w.EndEExpression()
+		w.EndList()
+
+		w.BeginEExpression(repeat)
+		w.WriteInt(2)
+		w.BeginEExpression(point)
+		w.WriteInt(4)
+		w.WriteInt(5)
+		w.EndEExpression()
+		w.EndEExpression()
+	}
+
+	opts := map[string]BinaryWriter11Opts{
+		"default":   0,
+		"delimited": BinaryWriter11Delimited,
+		"inline":    BinaryWriter11InlineSymbols,
+		"both":      BinaryWriter11Delimited | BinaryWriter11InlineSymbols,
+	}
+	for name, opt := range opts {
+		t.Run(name, func(t *testing.T) {
+			buf := bytes.Buffer{}
+			w := NewBinaryWriter11Macros(&buf, opt, mt)
+			writeEExpressions(w)
+			require.NoError(t, w.Finish())
+
+			actual, err := ReadValues(NewReaderMacros(bytes.NewReader(buf.Bytes()), nil, mt))
+			require.NoError(t, err)
+			require.Equal(t, len(expected), len(actual))
+			for i := range expected {
+				assert.True(t, EquivalentValues(expected[i], actual[i]), "expected %v, got %v", expected[i], actual[i])
+			}
+		})
+	}
+
+	t.Run("text", func(t *testing.T) {
+		buf := bytes.Buffer{}
+		w := NewTextWriter(&buf).(MacroWriter)
+		writeEExpressions(w)
+		require.NoError(t, w.Finish())
+
+		actual := readMacros(t, mt, buf.String())
+		require.Equal(t, len(expected), len(actual))
+		for i := range expected {
+			assert.True(t, EquivalentValues(expected[i], actual[i]), "expected %v, got %v", expected[i], actual[i])
+		}
+	})
+}
+
+func TestWriteBinary11EncodingDirective(t *testing.T) {
+	geoMacros, err := ParseMacroTable("(macro point (x y) {lat: (%x), lon: (%y)})")
+	require.NoError(t, err)
+	geo := NewModule("com.example.geo", 2, []string{"lat", "lon"}, geoMacros)
+	cat := NewModuleCatalog(geo)
+
+	twice, err := ParseMacro("(macro twice (v) [(%v), (%v)])", nil)
+	require.NoError(t, err)
+
+	d := &EncodingDirective{
+		Imports: []*Module{geo},
+		Symbols: []string{"s1"},
+		Macros:  []*Macro{twice},
+	}
+
+	for _, opts := range []BinaryWriter11Opts{0, BinaryWriter11Delimited} {
+		buf := bytes.Buffer{}
+		w := NewBinaryWriter11Encoding(&buf, opts, d)
+
+		point, _ := geoMacros.Find("point")
+		require.NoError(t, w.BeginEExpression(point))
+		require.NoError(t, w.WriteInt(1))
+		require.NoError(t, w.WriteInt(2))
+		require.NoError(t, w.EndEExpression())
+		require.NoError(t, w.BeginEExpression(twice))
+		require.NoError(t, w.WriteSymbolFromString("s1"))
+		require.NoError(t, w.EndEExpression())
+		require.NoError(t, w.WriteSymbolFromString("lat"))
+		require.NoError(t, w.Finish())
+
+		// The macros are invoked by their addresses in the directive's table.
+		bs := buf.Bytes()
+		assert.True(t, bytes.Contains(bs, []byte{0x00, 0x61, 0x01, 0x61, 0x02}), "%v", fmtbytes(bs))
+
+		r := NewReaderCat(bytes.NewReader(bs), cat)
+		vals, err := ReadValues(r)
+		require.NoError(t, err)
+		assertValues(t, "{lat: 1, lon: 2} [s1, s1] lat", vals)
+
+		st := r.SymbolTable()
+		systemMaxID := getSystemMaxID(st)
+		checkSymbol(t, "lat", systemMaxID+1, st)
+		checkSymbol(t, "s1", systemMaxID+3, st)
+
+		// Readers need the catalog to resolve the directive's imports.
+		_, err = ReadValues(NewReaderBytes(bs))
+		require.Error(t, err)
+		assert.Equal(t, "ion: module com.example.geo/2 not found in catalog", err.Error())
+	}
+
+	buf := bytes.Buffer{}
+	w := NewBinaryWriter11Encoding(&buf, 0, d)
+	err = w.WriteSymbolFromString("s2")
+	require.Error(t, err)
+	assert.Equal(t, "ion: usage error in Writer.WriteSymbolFromString: symbol 's2' not defined", err.Error())
+
+	values, _ := V11SystemMacroTable.Find("values")
+	w = NewBinaryWriter11Encoding(&buf, 0, &EncodingDirective{Macros: []*Macro{values}})
+	err = w.Finish()
+	require.Error(t, err)
+	assert.Equal(t, "ion: macro values has no definition", err.Error())
+
+	point, err := ParseMacro("(macro point (x y) [(%x), (%y)])", nil)
+	require.NoError(t, err)
+	w = NewBinaryWriter11Encoding(&buf, 0, &EncodingDirective{Imports: []*Module{geo}, Macros: []*Macro{point}})
+	err = w.WriteNull()
+	require.Error(t, err)
+	assert.Equal(t, "ion: duplicate macro name point", err.Error())
+}
+
+func TestWriteBinary11Finish(t *testing.T) {
+	buf := bytes.Buffer{}
+	w := NewBinaryWriter11(&buf)
+
+	require.NoError(t, w.WriteSymbolFromString("foo"))
+	require.NoError(t, w.Finish())
+	require.NoError(t, w.WriteSymbolFromString("bar"))
+	require.NoError(t, w.Finish())
+
+	vals, err := ReadValues(NewReaderBytes(buf.Bytes()))
+	require.NoError(t, err)
+	require.Len(t, vals, 2)
+	assert.Equal(t, "foo", *vals[0].(*SymbolValue).Symbol().Text)
+	assert.Equal(t, "bar", *vals[1].(*SymbolValue).Symbol().Text)
+
+	// An empty datagram is still marked as Ion 1.1.
+	buf.Reset()
+	require.NoError(t, NewBinaryWriter11Opts(&buf, BinaryWriter11InlineSymbols).Finish())
+	assert.Equal(t, ivm11, buf.Bytes())
+}
+
+func TestWriteBinary11Errors(t *testing.T) {
+	w := NewBinaryWriter11(&bytes.Buffer{})
+	require.NoError(t, w.BeginStruct())
+	assert.Error(t, w.WriteInt(0))
+
+	w = NewBinaryWriter11(&bytes.Buffer{})
+	require.NoError(t, w.BeginList())
+	assert.Error(t, w.EndStruct())
+
+	w = NewBinaryWriter11(&bytes.Buffer{})
+	require.NoError(t, w.BeginList())
+	assert.Error(t, w.Finish())
+
+	w = NewBinaryWriter11(&bytes.Buffer{})
+	assert.Error(t, w.WriteNullType(NoType))
+
+	w = NewBinaryWriter11(&bytes.Buffer{})
+	assert.Error(t, w.WriteSymbol(SymbolToken{LocalSID: SymbolIDUnknown}))
+
+	values, _ := V11SystemMacroTable.Find("values")
+	sum, _ := V11SystemMacroTable.Find("sum")
+	unknown, err := ParseMacro("(macro unknown () 1)", nil)
+	require.NoError(t, err)
+
+	mw := NewBinaryWriter11Macros(&bytes.Buffer{}, 0, nil)
+	require.NoError(t, mw.BeginEExpression(unknown))
+	assert.Error(t, mw.EndEExpression())
+
+	mw = NewBinaryWriter11Macros(&bytes.Buffer{}, 0, nil)
+	require.NoError(t, mw.BeginEExpression(sum))
+	require.NoError(t, mw.WriteInt(1))
+	assert.Error(t, mw.EndEExpression())
+
+	mw = NewBinaryWriter11Macros(&bytes.Buffer{}, 0, nil)
+	require.NoError(t, mw.BeginEExpression(sum))
+	assert.Error(t, mw.BeginExpressionGroup())
+
+	mw = NewBinaryWriter11Macros(&bytes.Buffer{}, 0, nil)
+	require.NoError(t, mw.BeginEExpression(sum))
+	require.NoError(t, mw.WriteInt(1))
+	require.NoError(t, mw.WriteInt(2))
+	assert.Error(t, mw.WriteInt(3))
+
+	mw = NewBinaryWriter11Macros(&bytes.Buffer{}, 0, nil)
+	require.NoError(t, mw.Annotation(NewSymbolTokenFromString("a")))
+	assert.Error(t, mw.BeginEExpression(values))
+
+	mw = NewBinaryWriter11Macros(&bytes.Buffer{}, 0, nil)
+	assert.Error(t, mw.BeginExpressionGroup())
+
+	mw = NewBinaryWriter11Macros(&bytes.Buffer{}, 0, nil)
+	require.NoError(t, mw.BeginEExpression(values))
+	assert.Error(t, mw.EndExpressionGroup())
+
+	mw = NewBinaryWriter11Macros(&bytes.Buffer{}, 0, nil)
+	require.NoError(t, mw.BeginEExpression(values))
+	assert.Error(t, mw.Finish())
+}
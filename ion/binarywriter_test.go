@@ -344,6 +344,86 @@ func TestWriteBinaryNulls(t *testing.T) {
 	})
 }
 
+func TestWriteBinaryStreaming(t *testing.T) {
+	buf := bytes.Buffer{}
+	w := NewBinaryWriterStreaming(&buf)
+
+	// Each value is written out as soon as it's complete.
+	require.NoError(t, w.WriteSymbolFromString("a"))
+	assert.Equal(t, []byte{
+		0xE0, 0x01, 0x00, 0xEA, // $ion_1_0
+		0xE7, 0x81, 0x83, 0xD4, // $ion_symbol_table::{
+		0x87, 0xB2, 0x81, 'a', // symbols:["a"]
+		// }
+		0x71, 0x0A, // a
+	}, buf.Bytes())
+	buf.Reset()
+
+	// No new symbols, so no local symbol table.
+	require.NoError(t, w.WriteSymbolFromString("a"))
+	assert.Equal(t, []byte{0x71, 0x0A}, buf.Bytes())
+	buf.Reset()
+
+	// New symbols are appended to the local symbol table.
+	require.NoError(t, w.WriteSymbolFromString("b"))
+	assert.Equal(t, []byte{
+		0xEA, 0x81, 0x83, 0xD7, // $ion_symbol_table::{
+		0x86, 0x71, 0x03, // imports:$ion_symbol_table
+		0x87, 0xB2, 0x81, 'b', // symbols:["b"]
+		// }
+		0x71, 0x0B, // b
+	}, buf.Bytes())
+	buf.Reset()
+
+	require.NoError(t, w.BeginList())
+	require.NoError(t, w.WriteSymbolFromString("c"))
+	assert.Empty(t, buf.Bytes())
+	require.NoError(t, w.EndList())
+	assert.NotEmpty(t, buf.Bytes())
+
+	require.NoError(t, w.Finish())
+}
+
+func TestWriteBinaryStreamingRoundTrip(t *testing.T) {
+	sst := NewSharedSymbolTable("shared", 1, []string{"s1", "s2"})
+
+	buf := bytes.Buffer{}
+	w := NewBinaryWriterStreaming(&buf, sst)
+	write := func(text string) {
+		vals, err := ReadValues(NewReaderString(text))
+		require.NoError(t, err)
+		for _, v := range vals {
+			require.NoError(t, v.MarshalIon(w))
+		}
+	}
+
+	write("s1::{a:b, s2:c}")
+	write("{a:[b, c], d:e::f}")
+	write("g")
+	require.NoError(t, w.Finish())
+
+	// A new datagram starts over with a new version marker and the whole table.
+	write("h::g")
+	require.NoError(t, w.Finish())
+
+	// And an empty one still has a version marker.
+	n := buf.Len()
+	require.NoError(t, w.Finish())
+	assert.Equal(t, []byte{0xE0, 0x01, 0x00, 0xEA}, buf.Bytes()[n:n+4])
+
+	r := NewReaderCat(bytes.NewReader(buf.Bytes()), NewCatalog(sst))
+	vals, err := ReadValues(r)
+	require.NoError(t, err)
+
+	var texts []string
+	for _, v := range vals {
+		text, err := MarshalText(v)
+		require.NoError(t, err)
+		texts = append(texts, string(text))
+	}
+	assert.Equal(t, []string{"s1::{a:b,s2:c}", "{a:[b,c],d:e::f}", "g", "h::g"}, texts)
+}
+
 func testBinaryWriter(t *testing.T, eval []byte, f func(w Writer)) {
 	val := writeBinary(t, f)
 
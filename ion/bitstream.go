@@ -17,7 +17,6 @@ package ion
 
 import (
 	"bufio"
-	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -58,6 +57,7 @@ const (
 	bitcodeStruct
 	bitcodeFieldID
 	bitcodeAnnotation
+	bitcodeEExpression
 )
 
 func (b bitcode) String() string {
@@ -100,6 +100,8 @@ func (b bitcode) String() string {
 		return "fieldid"
 	case bitcodeAnnotation:
 		return "annotation"
+	case bitcodeEExpression:
+		return "eexpression"
 	default:
 		return fmt.Sprintf("<invalid bitcode 0x%2X>", uint8(b))
 	}
@@ -115,6 +117,19 @@ type bitstream struct {
 	code bitcode
 	null bool
 	len  uint64
+
+	// Input that's already in memory is read directly from buf (indexed by
+	// pos) instead of from in. In no-copy mode, values are returned as
+	// sub-slices of it rather than copies.
+	inMem  bool
+	noCopy bool
+	buf    []byte
+
+	// The position of the current value's tag.
+	tagPos uint64
+
+	// The position past which reading fails with a LimitError, if not zero.
+	maxPos uint64
 }
 
 // Init initializes this stream with the given bufio.Reader.
@@ -124,7 +139,15 @@ func (b *bitstream) Init(in *bufio.Reader) {
 
 // InitBytes initializes this stream with the given bytes.
 func (b *bitstream) InitBytes(in []byte) {
-	b.in = bufio.NewReader(bytes.NewReader(in))
+	b.inMem = true
+	b.buf = in
+}
+
+// InitBytesNoCopy initializes this stream with the given bytes, which values
+// will be returned as sub-slices of rather than copied out of.
+func (b *bitstream) InitBytesNoCopy(in []byte) {
+	b.InitBytes(in)
+	b.noCopy = true
 }
 
 // Code returns the type code of the current value.
@@ -174,6 +197,7 @@ func (b *bitstream) Next() error {
 	}
 
 	// Otherwise it's time to read a value. Read the tag byte.
+	b.tagPos = b.pos
 	c, err := b.read()
 	if err != nil {
 		return err
@@ -197,7 +221,7 @@ func (b *bitstream) Next() error {
 		}
 		if length == 0 {
 			// Ordered structs must have at least one symbol/value pair.
-			return &SyntaxError{"ordered structs cannot be empty", b.pos - 1}
+			return &SyntaxError{Msg: "ordered structs cannot be empty", Offset: b.pos - 1}
 		}
 	}
 
@@ -212,7 +236,7 @@ func (b *bitstream) Next() error {
 		case 0:
 			// This value is actually a BVM. It's invalid if we're not at the top level.
 			if !b.stack.empty() {
-				return &SyntaxError{"invalid BVM in a container", b.pos - 1}
+				return &SyntaxError{Msg: "invalid BVM in a container", Offset: b.pos - 1}
 			}
 			b.code = bitcodeBVM
 			b.len = 3
@@ -260,7 +284,7 @@ func (b *bitstream) Next() error {
 
 	if length > rem {
 		msg := fmt.Sprintf("value overruns its container: %v vs %v", length, rem)
-		return &SyntaxError{msg, pos - 1}
+		return &SyntaxError{Msg: msg, Offset: pos - 1}
 	}
 
 	b.code = code
@@ -364,7 +388,7 @@ func (b *bitstream) ReadBVM() (byte, byte, error) {
 
 	if end != 0xEA {
 		msg := fmt.Sprintf("invalid BVM: 0xE0 0x%02X 0x%02X 0x%02X", major, minor, end)
-		return 0, 0, &SyntaxError{msg, b.pos - 4}
+		return 0, 0, &SyntaxError{Msg: msg, Offset: b.pos - 4}
 	}
 
 	b.state = bssBeforeValue
@@ -403,8 +427,8 @@ func (b *bitstream) ReadAnnotations(symbolTable SymbolTable) ([]SymbolToken, err
 
 	if annotFieldLength == 0 {
 		// An annotation with zero length is illegal because at least one annotation must be present.
-		return nil, &SyntaxError{"malformed annotation: at least one annotation must be specified",
-			b.pos - lengthOfAnnotFieldLength}
+		return nil, &SyntaxError{Msg: "malformed annotation: at least one annotation must be specified",
+			Offset: b.pos - lengthOfAnnotFieldLength}
 	}
 
 	remainingAnnotationLength := b.len - lengthOfAnnotFieldLength - annotFieldLength
@@ -412,7 +436,7 @@ func (b *bitstream) ReadAnnotations(symbolTable SymbolTable) ([]SymbolToken, err
 	if remainingAnnotationLength <= 0 {
 		// The size of the annotations is larger than the remaining free space inside the
 		// annotation container.
-		return nil, &SyntaxError{"malformed annotation", b.pos - lengthOfAnnotFieldLength}
+		return nil, &SyntaxError{Msg: "malformed annotation", Offset: b.pos - lengthOfAnnotFieldLength}
 	}
 
 	var as []SymbolToken
@@ -461,10 +485,10 @@ func (b *bitstream) validateAnnotatedValue(remainingLength uint64) error {
 
 	if code == bitcodeNull {
 		// It is illegal for an annotation to wrap a NOP Pad.
-		return &SyntaxError{"an annotation cannot wrap a NOP Pad", b.pos}
+		return &SyntaxError{Msg: "an annotation cannot wrap a NOP Pad", Offset: b.pos}
 	} else if code == bitcodeAnnotation {
 		// We cannot have an annotation directly wrapping another annotation.
-		return &SyntaxError{"an annotation cannot be the enclosed value of another annotation", b.pos}
+		return &SyntaxError{Msg: "an annotation cannot be the enclosed value of another annotation", Offset: b.pos}
 	}
 
 	// Adjust remainingLength because we just processed the first byte of the annotated data.
@@ -499,7 +523,7 @@ func (b *bitstream) validateAnnotatedValue(remainingLength uint64) error {
 	if length != remainingLength {
 		msg := fmt.Sprintf("annotation wrapper indicates the enclosed value's length to be %d "+
 			"but the enclosed value claims to have length %d", remainingLength, length)
-		return &SyntaxError{msg, b.pos}
+		return &SyntaxError{Msg: msg, Offset: b.pos}
 	}
 
 	return nil
@@ -549,7 +573,7 @@ func (b *bitstream) ReadInt() (interface{}, error) {
 
 	// Zero is always stored as positive; negative zero is illegal.
 	if isZero && b.code == bitcodeNegInt {
-		return 0, &SyntaxError{"integer zero cannot be negative", b.pos - b.len}
+		return 0, &SyntaxError{Msg: "integer zero cannot be negative", Offset: b.pos - b.len}
 	}
 
 	b.state = b.stateAfterValue()
@@ -583,7 +607,7 @@ func (b *bitstream) ReadFloat() (float64, error) {
 		ret = math.Float64frombits(ui)
 
 	default:
-		return 0, &SyntaxError{"invalid float size", b.pos - b.len}
+		return 0, &SyntaxError{Msg: "invalid float size", Offset: b.pos - b.len}
 	}
 
 	b.state = b.stateAfterValue()
@@ -637,7 +661,7 @@ func (b *bitstream) ReadTimestamp() (Timestamp, error) {
 		// component must also have a minute component. Hence, length cannot be zero at this point.
 		if i == 3 {
 			if length == 0 {
-				return Timestamp{}, &SyntaxError{"invalid timestamp - Hour cannot be present without minute", b.pos}
+				return Timestamp{}, &SyntaxError{Msg: "invalid timestamp - Hour cannot be present without minute", Offset: b.pos}
 			}
 		} else {
 			// Update precision as we read the timestamp.
@@ -682,16 +706,26 @@ func (b *bitstream) readNsecs(length uint64) (int, bool, uint8, error) {
 		return 0, false, 0, err
 	}
 
+	nsec, overflow, exponent, ok := fractionNsecs(d)
+	if !ok {
+		msg := fmt.Sprintf("invalid timestamp fraction: %v", d)
+		return 0, false, 0, &SyntaxError{Msg: msg, Offset: b.pos}
+	}
+	return nsec, overflow, exponent, nil
+}
+
+// fractionNsecs rounds the fractional seconds of a timestamp to nanoseconds,
+// returning the nanoseconds, whether they overflowed to the next second, and the
+// number of fractional digits. It returns false if d is not a valid fraction.
+func fractionNsecs(d *Decimal) (int, bool, uint8, bool) {
 	nsec, err := d.ShiftL(9).trunc()
 	if err != nil || nsec < 0 || nsec > 999999999 {
-		msg := fmt.Sprintf("invalid timestamp fraction: %v", d)
-		return 0, false, 0, &SyntaxError{msg, b.pos}
+		return 0, false, 0, false
 	}
 
 	nsec, err = d.ShiftL(9).round()
 	if err != nil {
-		msg := fmt.Sprintf("invalid timestamp fraction: %v", d)
-		return 0, false, 0, &SyntaxError{msg, b.pos}
+		return 0, false, 0, false
 	}
 
 	var exponent uint8
@@ -706,10 +740,10 @@ func (b *bitstream) readNsecs(length uint64) (int, bool, uint8, error) {
 
 	// Overflow to second.
 	if nsec == 1000000000 {
-		return 0, true, exponent, nil
+		return 0, true, exponent, true
 	}
 
-	return int(nsec), false, exponent, nil
+	return int(nsec), false, exponent, true
 }
 
 // ReadDecimal reads a decimal value of the given length: an exponent encoded as a
@@ -727,7 +761,7 @@ func (b *bitstream) readDecimal(length uint64) (*Decimal, error) {
 
 		if val > math.MaxInt32 || val < math.MinInt32 {
 			msg := fmt.Sprintf("decimal exponent out of range: %v", val)
-			return nil, &SyntaxError{msg, b.pos - vlength}
+			return nil, &SyntaxError{Msg: msg, Offset: b.pos - vlength}
 		}
 
 		exp = val
@@ -752,7 +786,7 @@ func (b *bitstream) ReadSymbolID() (uint64, error) {
 	}
 
 	if b.len > 8 {
-		return 0, &SyntaxError{"symbol id too large", b.pos}
+		return 0, &SyntaxError{Msg: "symbol id too large", Offset: b.pos}
 	}
 
 	bs, err := b.readN(b.len)
@@ -773,22 +807,34 @@ func (b *bitstream) ReadSymbolID() (uint64, error) {
 
 // ReadString reads a string value.
 func (b *bitstream) ReadString() (string, error) {
+	bs, err := b.ReadStringBytes()
+	if err != nil {
+		return "", err
+	}
+	return string(bs), nil
+}
+
+// ReadStringBytes reads a string value as its UTF-8 encoded bytes.
+func (b *bitstream) ReadStringBytes() ([]byte, error) {
 	if b.code != bitcodeString {
 		panic("not a string")
 	}
 
 	bs, err := b.readN(b.len)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	b.state = b.stateAfterValue()
 	b.clear()
 
-	if utf8.Valid(bs) {
-		return string(bs), nil
+	if !utf8.Valid(bs) {
+		return nil, &UnexpectedTokenError{Token: "string value contains non-UTF-8 runes", Offset: b.pos}
 	}
-	return "", &UnexpectedTokenError{"string value contains non-UTF-8 runes", b.pos}
+	if bs == nil {
+		bs = []byte{}
+	}
+	return bs, nil
 }
 
 // ReadBytes reads a blob or clob value.
@@ -862,7 +908,7 @@ func (b *bitstream) readVarUintLen(max uint64) (uint64, uint64, error) {
 
 	for {
 		if length >= max {
-			return 0, 0, &SyntaxError{"varuint too large", b.pos}
+			return 0, 0, &SyntaxError{Msg: "varuint too large", Offset: b.pos}
 		}
 
 		c, err := b.read1()
@@ -895,7 +941,7 @@ func (b *bitstream) skipVarUintLen(max uint64) (uint64, error) {
 	length := uint64(0)
 	for {
 		if length >= max {
-			return 0, &SyntaxError{"varuint too large", b.pos - length}
+			return 0, &SyntaxError{Msg: "varuint too large", Offset: b.pos - length}
 		}
 
 		c, err := b.read1()
@@ -929,7 +975,7 @@ func (b *bitstream) remaining() uint64 {
 // returning the value, the sign, and its actual length in bytes
 func (b *bitstream) readVarIntLen(max uint64) (int64, int64, uint64, error) {
 	if max == 0 {
-		return 0, 0, 0, &SyntaxError{"varint too large", b.pos}
+		return 0, 0, 0, &SyntaxError{Msg: "varint too large", Offset: b.pos}
 	}
 	if max > 10 {
 		max = 10
@@ -956,7 +1002,7 @@ func (b *bitstream) readVarIntLen(max uint64) (int64, int64, uint64, error) {
 
 	for {
 		if length >= max {
-			return 0, 0, 0, &SyntaxError{"varint too large", b.pos - length}
+			return 0, 0, 0, &SyntaxError{Msg: "varint too large", Offset: b.pos - length}
 		}
 
 		c, err := b.read1()
@@ -1013,18 +1059,33 @@ func parseTag(c int) (bitcode, uint64) {
 	return code, uint64(low)
 }
 
-// ReadN reads the next n bytes of input from the underlying stream.
+// ReadN reads the next n bytes of input from the underlying stream. In no-copy
+// mode, the bytes returned are a sub-slice of the input.
 func (b *bitstream) readN(n uint64) ([]byte, error) {
 	if n == 0 {
 		return nil, nil
 	}
+	if err := b.checkLimit(n); err != nil {
+		return nil, err
+	}
+
+	if b.inMem {
+		avail := b.avail()
+		if n > avail {
+			b.pos += avail
+			return nil, &UnexpectedEOFError{Offset: b.pos}
+		}
+		bs := b.slice(b.pos, b.pos+n)
+		b.pos += n
+		return bs, nil
+	}
 
 	bs := make([]byte, n)
 	actual, err := io.ReadFull(b.in, bs)
 	b.pos += uint64(actual)
 
 	if err == io.EOF || err == io.ErrUnexpectedEOF {
-		return nil, &UnexpectedEOFError{b.pos}
+		return nil, &UnexpectedEOFError{Offset: b.pos}
 	}
 	if err != nil {
 		return nil, &IOError{err}
@@ -1041,7 +1102,7 @@ func (b *bitstream) read1() (int, error) {
 		return 0, err
 	}
 	if c == -1 {
-		return 0, &UnexpectedEOFError{b.pos}
+		return 0, &UnexpectedEOFError{Offset: b.pos}
 	}
 	return c, nil
 }
@@ -1050,6 +1111,26 @@ func (b *bitstream) read1() (int, error) {
 // -1 instead of io.EOF if we've hit the end of the stream, because I find
 // that easier to reason about.
 func (b *bitstream) read() (int, error) {
+	if b.inMem {
+		if b.avail() == 0 {
+			b.pos++
+			return -1, nil
+		}
+		if err := b.checkLimit(1); err != nil {
+			return 0, err
+		}
+		c := b.buf[b.pos]
+		b.pos++
+		return int(c), nil
+	}
+
+	if b.maxPos != 0 && b.pos >= b.maxPos {
+		// Only fail if there's more input, rather than at the end.
+		if _, err := b.in.Peek(1); err == nil {
+			return 0, b.checkLimit(1)
+		}
+	}
+
 	c, err := b.in.ReadByte()
 	b.pos++
 
@@ -1063,8 +1144,28 @@ func (b *bitstream) read() (int, error) {
 	return int(c), nil
 }
 
+// checkLimit returns a LimitError if reading n more bytes would read past
+// maxPos.
+func (b *bitstream) checkLimit(n uint64) error {
+	if b.maxPos != 0 && b.pos+n > b.maxPos {
+		return &LimitError{"total size", b.maxPos, b.pos}
+	}
+	return nil
+}
+
 // Skip skips n bytes of input from the underlying stream.
 func (b *bitstream) skip(n uint64) error {
+	if err := b.checkLimit(n); err != nil {
+		return err
+	}
+	if b.inMem {
+		if avail := b.avail(); n > avail {
+			n = avail
+		}
+		b.pos += n
+		return nil
+	}
+
 	actual, err := b.in.Discard(int(n))
 	b.pos += uint64(actual)
 
@@ -1080,6 +1181,13 @@ func (b *bitstream) skip(n uint64) error {
 
 // PeekAtOffset returns the data at a certain offset without advancing the reader.
 func (b *bitstream) peekAtOffset(offset int) (byte, error) {
+	if b.inMem {
+		if uint64(offset) >= b.avail() {
+			return 0, io.EOF
+		}
+		return b.buf[b.pos+uint64(offset)], nil
+	}
+
 	data, err := b.in.Peek(offset + 1)
 	if err != nil {
 		return 0, err
@@ -1088,6 +1196,25 @@ func (b *bitstream) peekAtOffset(offset int) (byte, error) {
 	return data[offset], nil
 }
 
+// Avail returns the number of bytes of in-memory input remaining.
+func (b *bitstream) avail() uint64 {
+	if b.pos >= uint64(len(b.buf)) {
+		return 0
+	}
+	return uint64(len(b.buf)) - b.pos
+}
+
+// Slice returns the given range of in-memory input, copying it unless we're in
+// no-copy mode.
+func (b *bitstream) slice(start, end uint64) []byte {
+	if b.noCopy {
+		return b.buf[start:end:end]
+	}
+	bs := make([]byte, end-start)
+	copy(bs, b.buf[start:end])
+	return bs
+}
+
 // A bitnode represents a container value, including its type code and
 // the offset at which it (supposedly) ends.
 type bitnode struct {
@@ -0,0 +1,1096 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"unicode/utf8"
+)
+
+// A sym11 is a symbol as encoded in Ion 1.1 binary: a symbol ID, a system symbol
+// ID, or inline text.
+type sym11 struct {
+	id     uint64
+	system bool
+	text   *string
+}
+
+// A bitframe11 represents an Ion 1.1 container we're currently stepped in to.
+type bitframe11 struct {
+	code bitcode
+
+	// The offset at which a length-prefixed container ends.
+	end uint64
+
+	// Delimited containers are instead terminated by an end marker; done is set
+	// once it has been read.
+	delimited bool
+	done      bool
+
+	// Whether a struct's field names are FlexSyms rather than symbol IDs.
+	flexSyms bool
+
+	// Set for the arguments of an e-expression that isn't length-prefixed,
+	// which end once the last of them has been read.
+	args bool
+}
+
+// A bitstream11 is a low-level parser for Ion 1.1 binary values. It shares the
+// underlying input (and position) of an Ion 1.0 bitstream, so a reader can switch
+// between the two when it encounters a version marker.
+type bitstream11 struct {
+	in    *bitstream
+	stack []bitframe11
+	state bss
+
+	op   byte
+	code bitcode
+	null bool
+	len  uint64
+
+	fieldName   *sym11
+	annotations []sym11
+
+	// The position of the current value, including its annotations.
+	start uint64
+
+	// The maximum depth of nested containers, if not zero.
+	maxDepth int
+
+	// The macros e-expressions refer to by address, which are needed to tell
+	// where their arguments end, and the macro the current e-expression invokes.
+	macros *MacroTable
+	macro  *Macro
+}
+
+// Code returns the type code of the current value.
+func (b *bitstream11) Code() bitcode {
+	return b.code
+}
+
+// IsNull returns true if the current value is null.
+func (b *bitstream11) IsNull() bool {
+	return b.null
+}
+
+// Macro returns the macro invoked by the current e-expression.
+func (b *bitstream11) Macro() *Macro {
+	return b.macro
+}
+
+// FieldName returns the field name of the current value, if any.
+func (b *bitstream11) FieldName() *sym11 {
+	return b.fieldName
+}
+
+// Annotations returns the annotations of the current value.
+func (b *bitstream11) Annotations() []sym11 {
+	return b.annotations
+}
+
+// frame returns the innermost container we're stepped in to, or nil at the top level.
+func (b *bitstream11) frame() *bitframe11 {
+	if len(b.stack) == 0 {
+		return nil
+	}
+	return &b.stack[len(b.stack)-1]
+}
+
+// Next advances the stream to the next value, reading its field name,
+// annotations, and header.
+func (b *bitstream11) Next() error {
+	if b.state == bssOnValue {
+		if err := b.SkipValue(); err != nil {
+			return err
+		}
+	}
+	b.clear()
+
+	for {
+		f := b.frame()
+		if f != nil {
+			if f.done || (!f.delimited && b.in.pos == f.end) {
+				if b.fieldName != nil {
+					return &SyntaxError{Msg: "field name without a value", Offset: b.in.pos}
+				}
+				b.code = bitcodeEOF
+				return nil
+			}
+
+			if f.code == bitcodeStruct && b.fieldName == nil {
+				name, end, err := b.readFieldName(f)
+				if err != nil {
+					return err
+				}
+				if end {
+					f.done = true
+					b.code = bitcodeEOF
+					return nil
+				}
+				b.fieldName = &name
+			}
+
+			if !f.delimited && b.in.pos >= f.end {
+				return &SyntaxError{Msg: "value overruns its container", Offset: b.in.pos}
+			}
+		}
+
+		start := b.in.pos
+		c, err := b.in.read()
+		if err != nil {
+			return err
+		}
+		if c == -1 {
+			if f != nil || b.fieldName != nil || len(b.annotations) > 0 {
+				return &UnexpectedEOFError{Offset: start}
+			}
+			b.code = bitcodeEOF
+			return nil
+		}
+
+		op := byte(c)
+		if len(b.annotations) == 0 {
+			b.start = start
+		}
+
+		switch {
+		case op == op11DelimitedEnd:
+			if f == nil || !f.delimited || f.code == bitcodeStruct || len(b.annotations) > 0 {
+				return &InvalidTagByteError{op, start}
+			}
+			f.done = true
+			b.code = bitcodeEOF
+			return nil
+
+		case op == op11NOP || op == op11NOPLen:
+			if len(b.annotations) > 0 {
+				return &SyntaxError{Msg: "annotations on NOP padding", Offset: start}
+			}
+			if op == op11NOPLen {
+				n, err := b.readFlexUint()
+				if err != nil {
+					return err
+				}
+				if err := b.skip(n); err != nil {
+					return err
+				}
+			}
+			// A field whose value is NOP padding is skipped entirely.
+			b.fieldName = nil
+
+		case op >= op11Annotations1 && op <= op11AnnotationsSym:
+			if err := b.readAnnotations(op); err != nil {
+				return err
+			}
+
+		case op == op11IVM:
+			if f != nil || b.fieldName != nil || len(b.annotations) > 0 {
+				return &SyntaxError{Msg: "invalid BVM", Offset: start}
+			}
+			b.op = op
+			b.code = bitcodeBVM
+			b.len = 3
+			b.state = bssOnValue
+			return nil
+
+		default:
+			return b.readHeader(op, start)
+		}
+	}
+}
+
+// readHeader reads the header of the value with the given opcode.
+func (b *bitstream11) readHeader(op byte, start uint64) error {
+	code := bitcodeNone
+	length := uint64(0)
+
+	switch {
+	case op < op11Int, op == op11SystemMacro, op == op11MacroFlex, op == op11MacroFlexLen:
+		return b.readEExpressionHeader(op, start)
+
+	case op <= op11Int+8:
+		code, length = bitcodeInt, uint64(op-op11Int)
+
+	case op >= op11Float && op <= op11Float+3:
+		code, length = bitcodeFloat, []uint64{0, 2, 4, 8}[op-op11Float]
+
+	case op == op11True:
+		code = bitcodeTrue
+
+	case op == op11False:
+		code = bitcodeFalse
+
+	case op >= op11Decimal && op < op11Timestamp:
+		code, length = bitcodeDecimal, uint64(op-op11Decimal)
+
+	case op >= op11Timestamp && op < op11Timestamp+byte(len(shortTimestampLengths11)):
+		code, length = bitcodeTimestamp, shortTimestampLengths11[op-op11Timestamp]
+
+	case op >= op11String && op < op11SymbolText:
+		code, length = bitcodeString, uint64(op-op11String)
+
+	case op >= op11SymbolText && op < op11List:
+		code, length = bitcodeSymbol, uint64(op-op11SymbolText)
+
+	case op >= op11List && op < op11Sexp:
+		code, length = bitcodeList, uint64(op-op11List)
+
+	case op >= op11Sexp && op < op11Struct:
+		code, length = bitcodeSexp, uint64(op-op11Sexp)
+
+	case op >= op11Struct && op < op11IVM && op != op11Struct+1:
+		code, length = bitcodeStruct, uint64(op-op11Struct)
+
+	case op >= op11SymbolAddr1 && op <= op11SymbolAddr3:
+		code, length = bitcodeSymbol, uint64(op-op11IVM)
+
+	case op == op11Null:
+		code = bitcodeNull
+		b.null = true
+
+	case op == op11TypedNull:
+		t, err := b.in.read1()
+		if err != nil {
+			return err
+		}
+		if t >= len(typedNulls11) {
+			return &SyntaxError{Msg: fmt.Sprintf("invalid typed null 0x%02X", t), Offset: start}
+		}
+		code = bitcodes11[typedNulls11[t]]
+		b.null = true
+
+	case op == op11SystemSymbol:
+		code, length = bitcodeSymbol, 1
+
+	case op >= op11DelimitedList && op <= op11DelimitedStruct:
+		code = []bitcode{bitcodeList, bitcodeSexp, bitcodeStruct}[op-op11DelimitedList]
+
+	case op >= op11IntLen:
+		n, err := b.readFlexUint()
+		if err != nil {
+			return err
+		}
+		length = n
+
+		switch op {
+		case op11IntLen:
+			code = bitcodeInt
+		case op11DecimalLen:
+			code = bitcodeDecimal
+		case op11TimestampLen:
+			code = bitcodeTimestamp
+		case op11StringLen:
+			code = bitcodeString
+		case op11SymbolTextLen:
+			code = bitcodeSymbol
+		case op11ListLen:
+			code = bitcodeList
+		case op11SexpLen:
+			code = bitcodeSexp
+		case op11StructLen:
+			code = bitcodeStruct
+		case op11BlobLen:
+			code = bitcodeBlob
+		case op11ClobLen:
+			code = bitcodeClob
+		}
+	}
+
+	if code == bitcodeNone {
+		return &InvalidTagByteError{op, start}
+	}
+
+	if f := b.frame(); f != nil && !f.delimited && b.in.pos+length > f.end {
+		msg := fmt.Sprintf("value overruns its container: %v vs %v", length, f.end-b.in.pos)
+		return &SyntaxError{Msg: msg, Offset: start}
+	}
+
+	b.op = op
+	b.code = code
+	b.len = length
+	b.state = bssOnValue
+	return nil
+}
+
+// readEExpressionHeader reads the macro address (and, for opcode 0xF5, the length
+// of the arguments) of an e-expression.
+func (b *bitstream11) readEExpressionHeader(op byte, start uint64) error {
+	if len(b.annotations) > 0 {
+		return &SyntaxError{Msg: "e-expressions cannot be annotated", Offset: start}
+	}
+
+	mt := b.macros
+	if mt == nil {
+		mt = V11SystemMacroTable
+	}
+
+	var addr, length uint64
+	switch {
+	case op < op11MacroAddr12:
+		addr = uint64(op)
+
+	case op < op11MacroAddr20:
+		c, err := b.in.read1()
+		if err != nil {
+			return err
+		}
+		addr = macroAddressBias12 + (uint64(op&0xF) | uint64(c)<<4)
+
+	case op < op11Int:
+		bs, err := b.in.readN(2)
+		if err != nil {
+			return err
+		}
+		addr = macroAddressBias20 + (uint64(op&0xF) | fixedUint(bs)<<4)
+
+	case op == op11SystemMacro:
+		c, err := b.in.read1()
+		if err != nil {
+			return err
+		}
+		addr = uint64(c)
+		mt = V11SystemMacroTable
+
+	default:
+		a, err := b.readFlexUint()
+		if err != nil {
+			return err
+		}
+		addr = a
+
+		if op == op11MacroFlexLen {
+			if length, err = b.readFlexUint(); err != nil {
+				return err
+			}
+			if f := b.frame(); f != nil && !f.delimited && b.in.pos+length > f.end {
+				return &SyntaxError{Msg: "e-expression overruns its container", Offset: start}
+			}
+		}
+	}
+
+	m, ok := mt.Get(addr)
+	if !ok {
+		return &SyntaxError{Msg: fmt.Sprintf("invalid macro address %v", addr), Offset: start}
+	}
+
+	b.op = op
+	b.code = bitcodeEExpression
+	b.len = length
+	b.macro = m
+	b.state = bssOnValue
+	return nil
+}
+
+// bitcodes11 maps the types of typed nulls to bitcodes.
+var bitcodes11 = map[Type]bitcode{
+	BoolType:      bitcodeFalse,
+	IntType:       bitcodeInt,
+	FloatType:     bitcodeFloat,
+	DecimalType:   bitcodeDecimal,
+	TimestampType: bitcodeTimestamp,
+	StringType:    bitcodeString,
+	SymbolType:    bitcodeSymbol,
+	BlobType:      bitcodeBlob,
+	ClobType:      bitcodeClob,
+	ListType:      bitcodeList,
+	SexpType:      bitcodeSexp,
+	StructType:    bitcodeStruct,
+}
+
+// isDelimited returns true if the current value is a delimited container.
+func (b *bitstream11) isDelimited() bool {
+	return b.op >= op11DelimitedList && b.op <= op11DelimitedStruct && !b.null
+}
+
+// readFieldName reads the name of the next field in a struct, returning true if
+// it's instead the end of a delimited struct.
+func (b *bitstream11) readFieldName(f *bitframe11) (sym11, bool, error) {
+	if !f.flexSyms {
+		id, err := b.readFlexUint()
+		if err != nil {
+			return sym11{}, false, err
+		}
+		if id != 0 {
+			return sym11{id: id}, false, nil
+		}
+
+		// A zero switches the rest of the struct to FlexSym field names.
+		f.flexSyms = true
+	}
+
+	return b.readFlexSym(f.delimited)
+}
+
+// readFlexSym reads a FlexSym: a positive symbol ID, negative inline text length,
+// or zero followed by an escape byte. If allowEnd is set, the end-of-struct
+// escape is allowed and reported by returning true.
+func (b *bitstream11) readFlexSym(allowEnd bool) (sym11, bool, error) {
+	start := b.in.pos
+
+	n, err := b.readFlexInt()
+	if err != nil {
+		return sym11{}, false, err
+	}
+
+	switch {
+	case n > 0:
+		return sym11{id: uint64(n)}, false, nil
+
+	case n < 0:
+		bs, err := b.in.readN(uint64(-n))
+		if err != nil {
+			return sym11{}, false, err
+		}
+		if !utf8.Valid(bs) {
+			return sym11{}, false, &SyntaxError{Msg: "invalid UTF-8 in symbol text", Offset: start}
+		}
+		text := string(bs)
+		return sym11{text: &text}, false, nil
+	}
+
+	c, err := b.in.read1()
+	if err != nil {
+		return sym11{}, false, err
+	}
+
+	switch {
+	case c == flexSymZero:
+		return sym11{id: 0}, false, nil
+
+	case c == flexSymEmpty:
+		text := ""
+		return sym11{text: &text}, false, nil
+
+	case c == flexSymSystem:
+		id, err := b.in.read1()
+		if err != nil {
+			return sym11{}, false, err
+		}
+		return sym11{id: uint64(id), system: true}, false, nil
+
+	case c == flexSymEnd && allowEnd:
+		return sym11{}, true, nil
+	}
+
+	return sym11{}, false, &SyntaxError{Msg: fmt.Sprintf("invalid FlexSym escape 0x%02X", c), Offset: start}
+}
+
+// readAnnotations reads the annotations introduced by the given opcode.
+func (b *bitstream11) readAnnotations(op byte) error {
+	flexSyms := op >= op11AnnotationSym1
+
+	readOne := func() error {
+		if flexSyms {
+			s, _, err := b.readFlexSym(false)
+			if err != nil {
+				return err
+			}
+			b.annotations = append(b.annotations, s)
+			return nil
+		}
+
+		id, err := b.readFlexUint()
+		if err != nil {
+			return err
+		}
+		b.annotations = append(b.annotations, sym11{id: id})
+		return nil
+	}
+
+	switch op {
+	case op11Annotations1, op11AnnotationSym1:
+		return readOne()
+
+	case op11Annotations2, op11AnnotationSym2:
+		if err := readOne(); err != nil {
+			return err
+		}
+		return readOne()
+	}
+
+	length, err := b.readFlexUint()
+	if err != nil {
+		return err
+	}
+	if length == 0 {
+		return &SyntaxError{Msg: "malformed annotation: at least one annotation must be specified", Offset: b.in.pos}
+	}
+
+	end := b.in.pos + length
+	for b.in.pos < end {
+		if err := readOne(); err != nil {
+			return err
+		}
+	}
+	if b.in.pos != end {
+		return &SyntaxError{Msg: "malformed annotation", Offset: b.in.pos}
+	}
+	return nil
+}
+
+// SkipValue skips over the current value.
+func (b *bitstream11) SkipValue() error {
+	if b.state != bssOnValue {
+		return nil
+	}
+
+	switch {
+	case b.code == bitcodeEExpression && b.op != op11MacroFlexLen:
+		if err := b.skipEExpression(); err != nil {
+			return err
+		}
+	case b.isDelimited():
+		// Skipping a delimited container means reading its contents.
+		if b.maxDepth > 0 && len(b.stack) >= b.maxDepth {
+			return &LimitError{"depth", uint64(b.maxDepth), b.start}
+		}
+		b.StepIn()
+		if err := b.StepOut(); err != nil {
+			return err
+		}
+	default:
+		if err := b.skip(b.len); err != nil {
+			return err
+		}
+	}
+
+	b.state = bssBeforeValue
+	b.clear()
+	return nil
+}
+
+// skipEExpression skips over the arguments of the current e-expression.
+func (b *bitstream11) skipEExpression() error {
+	m := b.macro
+	aeb, err := b.StepInEExpression()
+	if err != nil {
+		return err
+	}
+	encs, err := argEncodings11(m, aeb)
+	if err != nil {
+		return &SyntaxError{Msg: err.Error(), Offset: b.in.pos}
+	}
+
+	for _, enc := range encs {
+		switch enc {
+		case argSingle11:
+			if err := b.Next(); err != nil {
+				return err
+			}
+			if b.code == bitcodeEOF {
+				return &SyntaxError{Msg: "missing e-expression argument", Offset: b.in.pos}
+			}
+			if err := b.SkipValue(); err != nil {
+				return err
+			}
+
+		case argGroup11:
+			if err := b.StepInGroup(); err != nil {
+				return err
+			}
+			if err := b.StepOut(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return b.StepOut()
+}
+
+// StepInEExpression steps in to the arguments of the current e-expression,
+// returning its argument encoding bitmap. The arguments are then read one at a
+// time with Next, and StepOut called once they've all been read.
+func (b *bitstream11) StepInEExpression() ([]byte, error) {
+	m := b.macro
+	f := bitframe11{code: bitcodeSexp}
+
+	if b.op == op11MacroFlexLen {
+		f.end = b.in.pos + b.len
+	} else {
+		// The arguments end wherever the last of them does, but can't overrun
+		// the container holding the e-expression.
+		f.args = true
+		f.end = math.MaxUint64
+		if p := b.frame(); p != nil && !p.delimited {
+			f.end = p.end
+		}
+	}
+
+	b.stack = append(b.stack, f)
+	b.state = bssBeforeValue
+	b.clear()
+
+	aeb, err := b.in.readN(uint64(aebLen11(m)))
+	if err != nil {
+		return nil, err
+	}
+	return aeb, nil
+}
+
+// StepInGroup steps in to an expression group passed as an e-expression argument:
+// a FlexUInt length followed by that many bytes of expressions or, if the length
+// is zero, expressions terminated by an end marker.
+func (b *bitstream11) StepInGroup() error {
+	length, err := b.readFlexUint()
+	if err != nil {
+		return err
+	}
+
+	f := bitframe11{code: bitcodeSexp}
+	if length == 0 {
+		f.delimited = true
+	} else {
+		f.end = b.in.pos + length
+		if p := b.frame(); p != nil && !p.delimited && f.end > p.end {
+			return &SyntaxError{Msg: "expression group overruns its container", Offset: b.in.pos}
+		}
+	}
+
+	b.stack = append(b.stack, f)
+	b.state = bssBeforeValue
+	b.clear()
+	return nil
+}
+
+// StepIn steps in to a container.
+func (b *bitstream11) StepIn() {
+	f := bitframe11{code: b.code}
+
+	if b.isDelimited() {
+		f.delimited = true
+		f.flexSyms = true
+	} else {
+		f.end = b.in.pos + b.len
+	}
+
+	b.stack = append(b.stack, f)
+	b.state = bssBeforeValue
+	b.clear()
+}
+
+// StepOut steps out of a container, skipping anything left in it.
+func (b *bitstream11) StepOut() error {
+	f := b.frame()
+	if f == nil {
+		panic("StepOut called at top level")
+	}
+
+	switch {
+	case f.args:
+		// The caller has read all of the arguments.
+	case f.delimited:
+		// Skipping nested values may grow (and reallocate) the stack, so look
+		// the frame up by index rather than holding on to f.
+		i := len(b.stack) - 1
+		for !b.stack[i].done {
+			if err := b.Next(); err != nil {
+				return err
+			}
+		}
+	case b.in.pos > f.end:
+		return &SyntaxError{Msg: "value overruns its container", Offset: b.in.pos}
+	default:
+		if err := b.skip(f.end - b.in.pos); err != nil {
+			return err
+		}
+	}
+
+	b.stack = b.stack[:len(b.stack)-1]
+	b.state = bssBeforeValue
+	b.clear()
+	return nil
+}
+
+// ReadBVM reads the remainder of a binary version marker.
+func (b *bitstream11) ReadBVM() (byte, byte, error) {
+	bs, err := b.in.readN(3)
+	if err != nil {
+		return 0, 0, err
+	}
+	if bs[2] != 0xEA {
+		msg := fmt.Sprintf("invalid BVM: 0xE0 0x%02X 0x%02X 0x%02X", bs[0], bs[1], bs[2])
+		return 0, 0, &SyntaxError{Msg: msg, Offset: b.in.pos - 4}
+	}
+
+	b.done()
+	return bs[0], bs[1], nil
+}
+
+// ReadInt reads an integer value.
+func (b *bitstream11) ReadInt() (interface{}, error) {
+	bs, err := b.in.readN(b.len)
+	if err != nil {
+		return nil, err
+	}
+
+	b.done()
+	return fixedInt(bs), nil
+}
+
+// ReadFloat reads a float value.
+func (b *bitstream11) ReadFloat() (float64, error) {
+	bs, err := b.in.readN(b.len)
+	if err != nil {
+		return 0, err
+	}
+
+	b.done()
+
+	switch len(bs) {
+	case 0:
+		return 0, nil
+	case 2:
+		return float16(binary.LittleEndian.Uint16(bs)), nil
+	case 4:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(bs))), nil
+	default:
+		return math.Float64frombits(binary.LittleEndian.Uint64(bs)), nil
+	}
+}
+
+// ReadDecimal reads a decimal value.
+func (b *bitstream11) ReadDecimal() (*Decimal, error) {
+	start := b.in.pos
+
+	bs, err := b.in.readN(b.len)
+	if err != nil {
+		return nil, err
+	}
+
+	b.done()
+
+	if len(bs) == 0 {
+		return NewDecimalInt(0), nil
+	}
+
+	exp, n, err := decodeFlexInt(bs)
+	if err != nil {
+		return nil, &SyntaxError{Msg: err.Error(), Offset: start}
+	}
+	if exp > math.MaxInt32 || exp < math.MinInt32 {
+		msg := fmt.Sprintf("decimal exponent out of range: %v", exp)
+		return nil, &SyntaxError{Msg: msg, Offset: start}
+	}
+
+	// A zero coefficient with a non-zero length is negative zero.
+	coef := fixedIntBig(bs[n:])
+	negZero := len(bs) > n && coef.Sign() == 0
+
+	return NewDecimal(coef, int32(exp), negZero), nil
+}
+
+// ReadTimestamp reads a timestamp value.
+func (b *bitstream11) ReadTimestamp() (Timestamp, error) {
+	start := b.in.pos
+	op := b.op
+
+	bs, err := b.in.readN(b.len)
+	if err != nil {
+		return Timestamp{}, err
+	}
+
+	b.done()
+
+	var ts Timestamp
+	if op == op11TimestampLen {
+		ts, err = longTimestamp11(bs)
+	} else {
+		ts, err = shortTimestamp11(op, bs)
+	}
+	if err != nil {
+		return Timestamp{}, &SyntaxError{Msg: err.Error(), Offset: start}
+	}
+	return ts, nil
+}
+
+// shortTimestamp11 decodes the body of a short-form timestamp.
+func shortTimestamp11(op byte, bs []byte) (Timestamp, error) {
+	ts := []int{1970 + int(bitField(bs, 0, 7)), 1, 1, 0, 0, 0}
+	precision := TimestampPrecisionYear
+	offset, sign := int64(0), int64(-1)
+	nsecs := 0
+	digits := uint8(0)
+
+	if op >= op11Timestamp+1 {
+		ts[1] = int(bitField(bs, 7, 4))
+		precision = TimestampPrecisionMonth
+	}
+	if op >= op11Timestamp+2 {
+		ts[2] = int(bitField(bs, 11, 5))
+		precision = TimestampPrecisionDay
+	}
+
+	if op >= op11Timestamp+3 {
+		ts[3] = int(bitField(bs, 16, 5))
+		ts[4] = int(bitField(bs, 21, 6))
+		precision = TimestampPrecisionMinute
+
+		// 0x83-0x87 have a single bit distinguishing UTC from an unknown offset;
+		// 0x88-0x8C have a 7-bit offset in 15-minute increments.
+		secs := uint(28)
+		withSeconds := op >= op11Timestamp+4 && op != op11Timestamp+8
+		fraction := op - op11Timestamp - 4
+		if op <= op11Timestamp+7 {
+			if bitField(bs, 27, 1) == 1 {
+				sign = 1
+			}
+		} else {
+			offset = (int64(bitField(bs, 27, 7)) - 56) * 15
+			sign = 1
+			secs = 34
+			fraction = op - op11Timestamp - 9
+		}
+
+		if withSeconds {
+			ts[5] = int(bitField(bs, secs, 6))
+			precision = TimestampPrecisionSecond
+
+			switch fraction {
+			case 1:
+				nsecs, digits = int(bitField(bs, secs+6, 10)), 3
+			case 2:
+				nsecs, digits = int(bitField(bs, secs+6, 20)), 6
+			case 3:
+				nsecs, digits = int(bitField(bs, secs+6, 30)), 9
+			}
+		}
+	}
+
+	if digits > 0 {
+		if nsecs >= int(math.Pow10(int(digits))) {
+			return Timestamp{}, fmt.Errorf("invalid timestamp fraction")
+		}
+		nsecs *= int(math.Pow10(9 - int(digits)))
+		precision = TimestampPrecisionNanosecond
+	}
+
+	return makeTimestamp11(ts, nsecs, false, offset, sign, precision, digits)
+}
+
+// longTimestamp11 decodes the body of a long-form timestamp.
+func longTimestamp11(bs []byte) (Timestamp, error) {
+	if len(bs) < 2 || len(bs) == 4 || len(bs) == 5 {
+		return Timestamp{}, fmt.Errorf("invalid timestamp length %v", len(bs))
+	}
+
+	ts := []int{int(bitField(bs, 0, 14)), 1, 1, 0, 0, 0}
+	precision := TimestampPrecisionYear
+	offset, sign := int64(0), int64(-1)
+	nsecs := 0
+	overflow := false
+	digits := uint8(0)
+
+	if len(bs) >= 3 {
+		ts[1] = int(bitField(bs, 14, 4))
+		precision = TimestampPrecisionMonth
+
+		// Month precision is distinguished from day precision by a zero day.
+		if day := int(bitField(bs, 18, 5)); day != 0 || len(bs) > 3 {
+			ts[2] = day
+			precision = TimestampPrecisionDay
+		}
+	}
+
+	if len(bs) >= 6 {
+		ts[3] = int(bitField(bs, 23, 5))
+		ts[4] = int(bitField(bs, 28, 6))
+		precision = TimestampPrecisionMinute
+
+		if o := bitField(bs, 34, 12); o != 0xFFF {
+			offset, sign = int64(o)-1440, 1
+		}
+	}
+
+	if len(bs) >= 7 {
+		ts[5] = int(bitField(bs, 46, 6))
+		precision = TimestampPrecisionSecond
+	}
+
+	if len(bs) > 7 {
+		scale, n, err := decodeFlexUint(bs[7:])
+		if err != nil {
+			return Timestamp{}, err
+		}
+		if scale > math.MaxInt32 {
+			return Timestamp{}, fmt.Errorf("invalid timestamp fraction")
+		}
+
+		d := NewDecimal(fixedUintBig(bs[7+n:]), -int32(scale), false)
+		var ok bool
+		if nsecs, overflow, digits, ok = fractionNsecs(d); !ok {
+			return Timestamp{}, fmt.Errorf("invalid timestamp fraction: %v", d)
+		}
+		if digits > 0 {
+			precision = TimestampPrecisionNanosecond
+		}
+	}
+
+	return makeTimestamp11(ts, nsecs, overflow, offset, sign, precision, digits)
+}
+
+// makeTimestamp11 validates the components of a timestamp and creates it.
+func makeTimestamp11(ts []int, nsecs int, overflow bool, offset, sign int64, precision TimestampPrecision, digits uint8) (Timestamp, error) {
+	if ts[0] < 1 || ts[1] < 1 || ts[1] > 12 || ts[3] > 23 || ts[4] > 59 || ts[5] > 59 || offset <= -24*60 || offset >= 24*60 {
+		return Timestamp{}, fmt.Errorf("invalid timestamp")
+	}
+	return tryCreateTimestamp(ts, nsecs, overflow, offset, sign, precision, digits)
+}
+
+// ReadString reads a string value.
+func (b *bitstream11) ReadString() (string, error) {
+	bs, err := b.ReadStringBytes()
+	if err != nil {
+		return "", err
+	}
+	return string(bs), nil
+}
+
+// ReadStringBytes reads a string value as its UTF-8 encoded bytes.
+func (b *bitstream11) ReadStringBytes() ([]byte, error) {
+	start := b.in.pos
+
+	bs, err := b.in.readN(b.len)
+	if err != nil {
+		return nil, err
+	}
+
+	b.done()
+
+	if !utf8.Valid(bs) {
+		return nil, &SyntaxError{Msg: "invalid UTF-8 in string", Offset: start}
+	}
+	if bs == nil {
+		bs = []byte{}
+	}
+	return bs, nil
+}
+
+// ReadSymbol reads a symbol value.
+func (b *bitstream11) ReadSymbol() (sym11, error) {
+	op := b.op
+
+	text, err := b.ReadString()
+	if err != nil {
+		return sym11{}, err
+	}
+
+	bs := []byte(text)
+	switch {
+	case op == op11SystemSymbol:
+		return sym11{id: uint64(bs[0]), system: true}, nil
+	case op == op11SymbolAddr1:
+		return sym11{id: fixedUint(bs)}, nil
+	case op == op11SymbolAddr2:
+		return sym11{id: fixedUint(bs) + symbolAddressBias2}, nil
+	case op == op11SymbolAddr3:
+		return sym11{id: fixedUint(bs) + symbolAddressBias3}, nil
+	}
+	return sym11{text: &text}, nil
+}
+
+// ReadBytes reads a blob or clob value.
+func (b *bitstream11) ReadBytes() ([]byte, error) {
+	bs, err := b.in.readN(b.len)
+	if err != nil {
+		return nil, err
+	}
+
+	b.done()
+
+	if bs == nil {
+		bs = []byte{}
+	}
+	return bs, nil
+}
+
+// done marks the current value as consumed.
+func (b *bitstream11) done() {
+	b.state = bssBeforeValue
+	b.clear()
+}
+
+// clear clears the current value.
+func (b *bitstream11) clear() {
+	b.op = 0
+	b.code = bitcodeNone
+	b.null = false
+	b.len = 0
+	b.macro = nil
+	b.fieldName = nil
+	b.annotations = nil
+}
+
+// readFlexUint reads a FlexUInt.
+func (b *bitstream11) readFlexUint() (uint64, error) {
+	start := b.in.pos
+
+	bs, err := b.readFlex()
+	if err != nil {
+		return 0, err
+	}
+
+	v, _, err := decodeFlexUint(bs)
+	if err != nil {
+		return 0, &SyntaxError{Msg: err.Error(), Offset: start}
+	}
+	return v, nil
+}
+
+// readFlexInt reads a FlexInt.
+func (b *bitstream11) readFlexInt() (int64, error) {
+	start := b.in.pos
+
+	bs, err := b.readFlex()
+	if err != nil {
+		return 0, err
+	}
+
+	v, _, err := decodeFlexInt(bs)
+	if err != nil {
+		return 0, &SyntaxError{Msg: err.Error(), Offset: start}
+	}
+	return v, nil
+}
+
+// readFlex reads the bytes of a FlexUInt or FlexInt.
+func (b *bitstream11) readFlex() ([]byte, error) {
+	var bs []byte
+	for {
+		c, err := b.in.read1()
+		if err != nil {
+			return nil, err
+		}
+		bs = append(bs, byte(c))
+
+		if n := flexLen(bs); n > 0 {
+			rest, err := b.in.readN(uint64(n - len(bs)))
+			if err != nil {
+				return nil, err
+			}
+			return append(bs, rest...), nil
+		}
+	}
+}
+
+// skip skips n bytes of input.
+func (b *bitstream11) skip(n uint64) error {
+	if n == 0 {
+		return nil
+	}
+	return b.in.skip(n)
+}
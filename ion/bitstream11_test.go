@@ -0,0 +1,93 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeFlexUint(t *testing.T) {
+	test := func(bs []byte, eval uint64, elen int) {
+		val, n, err := decodeFlexUint(bs)
+		require.NoError(t, err)
+		assert.Equal(t, eval, val)
+		assert.Equal(t, elen, n)
+	}
+
+	test([]byte{0x01}, 0, 1)
+	test([]byte{0x07}, 3, 1)
+	test([]byte{0xFF}, 127, 1)
+	test([]byte{0x02, 0x02}, 128, 2)
+	test([]byte{0x0A, 0xFF, 0xFF}, 0x3FC2, 2) // Trailing bytes are ignored.
+	test([]byte{0x04, 0x00, 0x80}, 1<<20, 3)
+	test([]byte{0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02}, 1<<63, 10)
+
+	_, _, err := decodeFlexUint([]byte{0x02})
+	assert.Error(t, err)
+	_, _, err = decodeFlexUint([]byte{0x00})
+	assert.Error(t, err)
+}
+
+func TestDecodeFlexInt(t *testing.T) {
+	test := func(bs []byte, eval int64, elen int) {
+		val, n, err := decodeFlexInt(bs)
+		require.NoError(t, err)
+		assert.Equal(t, eval, val)
+		assert.Equal(t, elen, n)
+	}
+
+	test([]byte{0x01}, 0, 1)
+	test([]byte{0x03}, 1, 1)
+	test([]byte{0xFF}, -1, 1)
+	test([]byte{0x7F}, 63, 1)
+	test([]byte{0x81}, -64, 1)
+	test([]byte{0x02, 0x01}, 64, 2)
+	test([]byte{0xFE, 0xFE}, -65, 2)
+}
+
+func TestFixedInt(t *testing.T) {
+	assert.Equal(t, int64(0), fixedInt(nil))
+	assert.Equal(t, int64(-1), fixedInt([]byte{0xFF}))
+	assert.Equal(t, int64(255), fixedInt([]byte{0xFF, 0x00}))
+	assert.Equal(t, int64(math.MinInt64), fixedInt([]byte{0, 0, 0, 0, 0, 0, 0, 0x80}))
+	assert.Equal(t, int64(-2), fixedInt([]byte{0xFE, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}))
+
+	expected, _ := new(big.Int).SetString("-18446744073709551616", 10)
+	assert.Equal(t, expected, fixedInt([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0xFF}))
+}
+
+func TestFloat16(t *testing.T) {
+	assert.Equal(t, 1.0, float16(0x3C00))
+	assert.Equal(t, -2.0, float16(0xC000))
+	assert.Equal(t, 65504.0, float16(0x7BFF))
+	assert.Equal(t, math.Ldexp(1, -24), float16(0x0001))
+	assert.True(t, math.IsInf(float16(0x7C00), 1))
+	assert.True(t, math.IsInf(float16(0xFC00), -1))
+	assert.True(t, math.IsNaN(float16(0x7E00)))
+	assert.True(t, math.Signbit(float16(0x8000)))
+}
+
+func TestBitField(t *testing.T) {
+	bs := []byte{0x9E, 0x79}
+	assert.Equal(t, uint64(30), bitField(bs, 0, 7))
+	assert.Equal(t, uint64(3), bitField(bs, 7, 4))
+	assert.Equal(t, uint64(15), bitField(bs, 11, 5))
+}
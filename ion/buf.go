@@ -17,15 +17,134 @@ package ion
 
 import (
 	"io"
+	"sort"
 )
 
 // Writing binary ion is a bit tricky: values are preceded by their length,
 // which can be hard to predict until we've actually written out the value.
 // To make matters worse, we can't predict the length of the /length/ ahead
 // of time in order to reserve space for it, because it uses a variable-length
-// encoding. To avoid copying bytes around all over the place, we write into
-// an in-memory tree structure, which we then blast out to the actual io.Writer
-// once all the relevant lengths are known.
+// encoding.
+//
+// Binary Ion 1.0 values are encoded into a single contiguous encbuf: each
+// container reserves one byte for its type+length tag, which is filled in when
+// the container ends. Any container of 14 or more bytes needs a longer tag than
+// that; those tags are set aside and spliced in with a single pass over the
+// buffer before it's written out, rather than shifting the container's contents
+// along at every level of nesting. Ion 1.1 e-expressions need their arguments to
+// be rearranged before they're written, so Ion 1.1 values are instead written
+// into an in-memory tree structure, which we then blast out to the actual
+// io.Writer once all the relevant lengths are known.
+
+// An encbuf is a growable byte buffer that binary values are encoded into,
+// along with a stack of the containers currently being written to it and the
+// long tags that have yet to be spliced in to it.
+type encbuf struct {
+	bs     []byte
+	frames []encframe
+	tags   []enctag
+}
+
+// An encframe is a container that's being written to an encbuf. Extra counts
+// the bytes that the long tags of the containers within it will add.
+type encframe struct {
+	code  byte
+	start int
+	extra uint64
+}
+
+// An enctag is a tag that's too long for the byte reserved for it at pos.
+type enctag struct {
+	pos    int
+	code   byte
+	length uint64
+}
+
+// Begin begins a new container with the given type code, reserving a byte
+// for its tag.
+func (b *encbuf) begin(code byte) {
+	b.frames = append(b.frames, encframe{code: code, start: len(b.bs)})
+	b.bs = append(b.bs, 0)
+}
+
+// Peek returns the type code of the innermost container, if there is one.
+func (b *encbuf) peek() (byte, bool) {
+	if len(b.frames) == 0 {
+		return 0, false
+	}
+	return b.frames[len(b.frames)-1].code, true
+}
+
+// End ends the innermost container, filling in its tag now that its length is
+// known, or setting it aside for flatten if it's too long for the reserved byte.
+func (b *encbuf) end() {
+	if len(b.frames) == 0 {
+		panic("end called on an empty encbuf")
+	}
+	f := b.frames[len(b.frames)-1]
+	b.frames = b.frames[:len(b.frames)-1]
+
+	length := uint64(len(b.bs)-f.start-1) + f.extra
+	extra := f.extra
+	if tl := tagLen(length); tl > 1 {
+		b.tags = append(b.tags, enctag{pos: f.start, code: f.code, length: length})
+		extra += tl - 1
+	} else {
+		appendTag(b.bs[f.start:f.start], f.code, length)
+	}
+
+	if len(b.frames) > 0 {
+		b.frames[len(b.frames)-1].extra += extra
+	}
+}
+
+// Flatten splices the long tags into the buffer, shifting everything after
+// each of them along, and returns where the byte at the given position ends
+// up. It must only be called between top-level values.
+func (b *encbuf) flatten(pos int) int {
+	if len(b.tags) == 0 {
+		return pos
+	}
+	sort.Slice(b.tags, func(i, j int) bool {
+		return b.tags[i].pos < b.tags[j].pos
+	})
+
+	grow := 0
+	newPos := pos
+	for _, t := range b.tags {
+		n := int(tagLen(t.length)) - 1
+		grow += n
+		if t.pos < pos {
+			newPos += n
+		}
+	}
+
+	// Work backward from the end, so that each byte is moved only once.
+	end := len(b.bs)
+	b.bs = append(b.bs, make([]byte, grow)...)
+	dst := len(b.bs)
+	var arr [11]byte
+	for i := len(b.tags) - 1; i >= 0; i-- {
+		t := b.tags[i]
+		dst -= end - (t.pos + 1)
+		copy(b.bs[dst:], b.bs[t.pos+1:end])
+
+		tag := appendTag(arr[:0], t.code, t.length)
+		dst -= len(tag)
+		copy(b.bs[dst:], tag)
+		end = t.pos
+	}
+
+	b.tags = b.tags[:0]
+	return newPos
+}
+
+// Reset empties the buffer, keeping its memory for reuse.
+func (b *encbuf) reset() {
+	b.bs = b.bs[:0]
+	b.frames = b.frames[:0]
+	b.tags = b.tags[:0]
+}
 
 // A bufnode is a node in the partially-serialized tree.
 type bufnode interface {
@@ -41,7 +160,8 @@ type bufseq interface {
 
 var _ bufnode = atom([]byte{})
 var _ bufseq = &datagram{}
-var _ bufseq = &container{}
+var _ bufseq = &container11{}
+var _ bufseq = &eexp11{}
 
 // An atom is a value that has been fully serialized and can be emitted directly.
 type atom []byte
@@ -82,32 +202,8 @@ func (d *datagram) EmitTo(w io.Writer) error {
 	return nil
 }
 
-// A container is a datagram that's preceded by a code+length tag.
-type container struct {
-	code byte
-	datagram
-}
-
-func (c *container) Len() uint64 {
-	if c.len < 0x0E {
-		return c.len + 1
-	}
-	return c.len + (varUintLen(c.len) + 1)
-}
-
-func (c *container) EmitTo(w io.Writer) error {
-	var arr [11]byte
-	buf := arr[:0]
-	buf = appendTag(buf, c.code, c.len)
-
-	if _, err := w.Write(buf); err != nil {
-		return err
-	}
-	return c.datagram.EmitTo(w)
-}
-
 // A bufstack is a stack of bufseqs, more or less matching the
-// stack of BeginList/Sexp/Struct calls made on a binaryWriter.
+// stack of BeginList/Sexp/Struct calls made on a binaryWriter11.
 // The top of the stack is the sequence we're currently writing
 // values into; when it's popped off, it will be appended to the
 // bufseq below it.
@@ -132,3 +228,89 @@ func (s *bufstack) pop() {
 	}
 	s.arr = s.arr[:len(s.arr)-1]
 }
+
+// A container11 is a datagram that's preceded by an Ion 1.1 opcode and, if it's
+// too long to fit in the opcode, a FlexUInt length.
+type container11 struct {
+	code    byte
+	codeLen byte
+	datagram
+
+	// Whether the struct's field names have switched from symbol IDs to FlexSyms.
+	flexSyms bool
+}
+
+func (c *container11) Len() uint64 {
+	return header11Len(c.code, c.len) + c.len
+}
+
+func (c *container11) EmitTo(w io.Writer) error {
+	var arr [11]byte
+	buf := appendHeader11(arr[:0], c.code, c.codeLen, c.len)
+
+	if _, err := w.Write(buf); err != nil {
+		return err
+	}
+	return c.datagram.EmitTo(w)
+}
+
+// An eexp11 is an Ion 1.1 e-expression. Its arguments are buffered separately
+// until it's finished, at which point its header (the macro address and argument
+// encoding bitmap) can be filled in.
+type eexp11 struct {
+	m    *Macro
+	head []byte
+	args []*arg11
+}
+
+// An arg11 is an argument to an e-expression: either a single expression or
+// an expression group.
+type arg11 struct {
+	group bool
+	datagram
+}
+
+func (e *eexp11) Append(n bufnode) {
+	e.args[len(e.args)-1].Append(n)
+}
+
+func (e *eexp11) Len() uint64 {
+	l := uint64(len(e.head))
+	for _, a := range e.args {
+		l += a.Len()
+	}
+	return l
+}
+
+func (e *eexp11) EmitTo(w io.Writer) error {
+	if _, err := w.Write(e.head); err != nil {
+		return err
+	}
+	for _, a := range e.args {
+		if err := a.EmitTo(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Omitted arguments take up no space; groups are prefixed with their length.
+func (a *arg11) Len() uint64 {
+	if !a.group {
+		return a.len
+	}
+	if a.len == 0 {
+		return 0
+	}
+	return flexUintLen(a.len) + a.len
+}
+
+func (a *arg11) EmitTo(w io.Writer) error {
+	if a.group && a.len > 0 {
+		var arr [10]byte
+		if _, err := w.Write(appendFlexUint(arr[:0], a.len)); err != nil {
+			return err
+		}
+	}
+	return a.datagram.EmitTo(w)
+}
@@ -20,51 +20,55 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
 )
 
-func TestBufnode(t *testing.T) {
-	root := container{code: 0xE0}
-	root.Append(atom([]byte{0x81, 0x83}))
+func TestEncbuf(t *testing.T) {
+	b := encbuf{}
+	b.begin(0xE0)
+	b.bs = append(b.bs, 0x81, 0x83)
 	{
-		symtab := &container{code: 0xD0}
+		b.begin(0xD0)
 		{
-			symtab.Append(atom([]byte{0x86})) // varUint(6)
+			b.bs = append(b.bs, 0x86) // varUint(6)
 			{
-				imps := &container{code: 0xB0}
+				b.begin(0xB0)
 				{
-					imp0 := &container{code: 0xD0}
+					b.begin(0xD0)
 					{
-						imp0.Append(atom([]byte{0x84})) // varUint(4)
-						imp0.Append(atom([]byte{0x85, 'b', 'o', 'g', 'u', 's'}))
-						imp0.Append(atom([]byte{0x85})) // varUint(5)
-						imp0.Append(atom([]byte{0x21, 0x2A}))
-						imp0.Append(atom([]byte{0x88})) // varUint(8)
-						imp0.Append(atom([]byte{0x21, 0x64}))
+						b.bs = append(b.bs, 0x84) // varUint(4)
+						b.bs = append(b.bs, 0x85, 'b', 'o', 'g', 'u', 's')
+						b.bs = append(b.bs, 0x85) // varUint(5)
+						b.bs = append(b.bs, 0x21, 0x2A)
+						b.bs = append(b.bs, 0x88) // varUint(8)
+						b.bs = append(b.bs, 0x21, 0x64)
 					}
-					imps.Append(imp0)
+					b.end()
 				}
-				symtab.Append(imps)
+				b.end()
 			}
 
-			symtab.Append(atom([]byte{0x87})) // varUint(7)
+			b.bs = append(b.bs, 0x87) // varUint(7)
 			{
-				syms := &container{code: 0xB0}
+				b.begin(0xB0)
 				{
-					syms.Append(atom([]byte{0x83, 'f', 'o', 'o'}))
-					syms.Append(atom([]byte{0x83, 'b', 'a', 'r'}))
+					b.bs = append(b.bs, 0x83, 'f', 'o', 'o')
+					b.bs = append(b.bs, 0x83, 'b', 'a', 'r')
 				}
-				symtab.Append(syms)
+				code, ok := b.peek()
+				assert.True(t, ok)
+				assert.Equal(t, byte(0xB0), code)
+				b.end()
 			}
 		}
-		root.Append(symtab)
+		b.end()
 	}
+	b.end()
 
-	buf := bytes.Buffer{}
+	_, ok := b.peek()
+	assert.False(t, ok)
 
-	require.NoError(t, root.EmitTo(&buf))
-
-	val := buf.Bytes()
+	assert.Equal(t, 0, b.flatten(0))
+	val := b.bs
 	eval := []byte{
 		// $ion_symbol_table::{
 		0xEE, 0x9F, 0x81, 0x83, 0xDE, 0x9B,
@@ -92,3 +96,25 @@ func TestBufnode(t *testing.T) {
 
 	assert.True(t, bytes.Equal(val, eval), "expected %v, got %v", fmtbytes(eval), fmtbytes(val))
 }
+
+func TestEncbufLongLength(t *testing.T) {
+	b := encbuf{}
+	b.begin(0xB0)
+	b.begin(0xB0)
+	for i := 0; i < 200; i++ {
+		b.bs = append(b.bs, 0x20)
+	}
+	b.end()
+	b.end()
+	b.bs = append(b.bs, 0x21, 0x01)
+
+	// Both lists' lengths need two more bytes, which flatten makes room for.
+	assert.Len(t, b.tags, 2)
+	assert.Equal(t, 206, b.flatten(202))
+	assert.Equal(t, []byte{0xBE, 0x01, 0xCB, 0xBE, 0x01, 0xC8, 0x20}, b.bs[:7])
+	assert.Equal(t, []byte{0x20, 0x21, 0x01}, b.bs[205:])
+	assert.Empty(t, b.tags)
+
+	b.reset()
+	assert.Empty(t, b.bs)
+}
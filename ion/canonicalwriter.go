@@ -0,0 +1,290 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// canonicalWriter buffers the values written to it, then writes them to an
+// underlying Writer in canonical form when Finish is called.
+type canonicalWriter struct {
+	ValueWriter
+	out Writer
+}
+
+// NewCanonicalWriter returns a Writer that writes values to w in a canonical
+// form, so that any two equivalent Ion values are written as the same sequence of
+// calls to w and hence, given a deterministic w, the same bytes. In canonical form:
+//
+//   - struct fields are sorted by name in UTF-8 byte order, and fields with the
+//     same name by the canonical text of their values
+//   - symbol tokens with known text are written by text, never by symbol ID, so
+//     symbol tables are built in the order symbols first appear
+//   - ints are written with WriteInt if they fit in an int64, else WriteBigInt
+//   - timestamps are converted to a fixed offset, a +00:00 offset is written as
+//     UTC, and any time components beyond the timestamp's precision are dropped
+//
+// Values are buffered until Finish is called.
+func NewCanonicalWriter(w Writer) Writer {
+	return &canonicalWriter{
+		ValueWriter: NewValueWriter(),
+		out:         w,
+	}
+}
+
+// Finish writes the values written since the last call to Finish to the
+// underlying writer in canonical form, then finishes it.
+func (w *canonicalWriter) Finish() error {
+	if err := w.ValueWriter.Finish(); err != nil {
+		return err
+	}
+
+	vals := w.Values()
+	w.ValueWriter = NewValueWriter()
+
+	for _, v := range vals {
+		if err := writeCanonical(w.out, v); err != nil {
+			return err
+		}
+	}
+	return w.out.Finish()
+}
+
+// writeCanonical writes the canonical form of the given value to w.
+func writeCanonical(w Writer, v Value) error {
+	if as := v.Annotations(); len(as) > 0 {
+		cas := make([]SymbolToken, len(as))
+		for i, a := range as {
+			cas[i] = canonicalSymbol(a)
+		}
+		if err := w.Annotations(cas...); err != nil {
+			return err
+		}
+	}
+
+	if v.IsNull() {
+		if v.Type() == NullType {
+			return w.WriteNull()
+		}
+		return w.WriteNullType(v.Type())
+	}
+
+	switch v := v.(type) {
+	case *BoolValue:
+		return w.WriteBool(v.Bool())
+
+	case *IntValue:
+		if i, ok := v.Int64(); ok {
+			return w.WriteInt(i)
+		}
+		return w.WriteBigInt(v.BigInt())
+
+	case *FloatValue:
+		return w.WriteFloat(v.Float())
+
+	case *DecimalValue:
+		return w.WriteDecimal(v.Decimal())
+
+	case *TimestampValue:
+		return w.WriteTimestamp(canonicalTimestamp(v.Timestamp()))
+
+	case *SymbolValue:
+		return w.WriteSymbol(canonicalSymbol(v.Symbol()))
+
+	case *StringValue:
+		return w.WriteString(v.Text())
+
+	case *ClobValue:
+		return w.WriteClob(v.Bytes())
+
+	case *BlobValue:
+		return w.WriteBlob(v.Bytes())
+
+	case *ListValue:
+		if err := w.BeginList(); err != nil {
+			return err
+		}
+		if err := writeCanonicalValues(w, v.Values()); err != nil {
+			return err
+		}
+		return w.EndList()
+
+	case *SexpValue:
+		if err := w.BeginSexp(); err != nil {
+			return err
+		}
+		if err := writeCanonicalValues(w, v.Values()); err != nil {
+			return err
+		}
+		return w.EndSexp()
+
+	case *StructValue:
+		fields, err := canonicalFields(v.Fields())
+		if err != nil {
+			return err
+		}
+		if err := w.BeginStruct(); err != nil {
+			return err
+		}
+		for _, f := range fields {
+			if err := w.FieldName(canonicalSymbol(f.Name)); err != nil {
+				return err
+			}
+			if err := writeCanonical(w, f.Value); err != nil {
+				return err
+			}
+		}
+		return w.EndStruct()
+	}
+
+	// Some other implementation of Value; let it write itself.
+	return v.MarshalIon(w)
+}
+
+// writeCanonicalValues writes the canonical forms of the given values to w.
+func writeCanonicalValues(w Writer, vs []Value) error {
+	for _, v := range vs {
+		if err := writeCanonical(w, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// canonicalFields returns a copy of the given struct fields in canonical order.
+func canonicalFields(fields []StructField) ([]StructField, error) {
+	type sortable struct {
+		field StructField
+		name  string
+		text  []byte
+	}
+
+	counts := map[string]int{}
+	fs := make([]sortable, len(fields))
+	for i, f := range fields {
+		fs[i] = sortable{field: f, name: symbolKey(f.Name)}
+		counts[fs[i].name]++
+	}
+
+	// Fields that share a name are ordered by their values' canonical text, so the
+	// order they were written in doesn't matter.
+	for i := range fs {
+		if counts[fs[i].name] > 1 {
+			text, err := canonicalText(fs[i].field.Value)
+			if err != nil {
+				return nil, err
+			}
+			fs[i].text = text
+		}
+	}
+
+	sort.SliceStable(fs, func(i, j int) bool {
+		if fs[i].name != fs[j].name {
+			return fs[i].name < fs[j].name
+		}
+		return bytes.Compare(fs[i].text, fs[j].text) < 0
+	})
+
+	sorted := make([]StructField, len(fs))
+	for i, f := range fs {
+		sorted[i] = f.field
+	}
+	return sorted, nil
+}
+
+// canonicalText returns the canonical text encoding of the given value.
+func canonicalText(v Value) ([]byte, error) {
+	buf := bytes.Buffer{}
+	w := NewTextWriterOpts(&buf, TextWriterQuietFinish)
+	if err := writeCanonical(w, v); err != nil {
+		return nil, err
+	}
+	if err := w.Finish(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// symbolKey returns the text of the given symbol token, or its symbol ID in $n
+// form if its text is unknown.
+func symbolKey(st SymbolToken) string {
+	if st.Text != nil {
+		return *st.Text
+	}
+	return "$" + strconv.FormatInt(st.LocalSID, 10)
+}
+
+// canonicalSymbol drops the symbol ID of a symbol token with known text, since
+// it depends on the symbol table the token was read with.
+func canonicalSymbol(st SymbolToken) SymbolToken {
+	if st.Text == nil {
+		return st
+	}
+	return SymbolToken{Text: st.Text, LocalSID: SymbolIDUnknown}
+}
+
+// canonicalTimestamp normalizes the time zone of the given timestamp and drops
+// any time components beyond its precision.
+func canonicalTimestamp(ts Timestamp) Timestamp {
+	t := ts.dateTime
+
+	if ts.precision <= TimestampPrecisionDay {
+		month, day := t.Month(), t.Day()
+		if ts.precision < TimestampPrecisionMonth {
+			month = time.January
+		}
+		if ts.precision < TimestampPrecisionDay {
+			day = 1
+		}
+		return NewDateTimestamp(time.Date(t.Year(), month, day, 0, 0, 0, 0, time.UTC), ts.precision)
+	}
+
+	kind := ts.kind
+	_, offset := t.Zone()
+	if kind == TimezoneLocal && offset == 0 {
+		kind = TimezoneUTC
+	}
+	if kind == TimezoneLocal {
+		t = t.In(time.FixedZone("", offset))
+	} else {
+		t = t.UTC()
+	}
+
+	precision := ts.precision
+	fraction := ts.numFractionalSeconds
+	if precision == TimestampPrecisionNanosecond && fraction == 0 {
+		precision = TimestampPrecisionSecond
+	}
+
+	switch precision {
+	case TimestampPrecisionMinute:
+		t = t.Truncate(time.Minute)
+	case TimestampPrecisionSecond:
+		t = t.Truncate(time.Second)
+	default:
+		unit := time.Nanosecond
+		for i := fraction; i < maxFractionalPrecision; i++ {
+			unit *= 10
+		}
+		t = t.Truncate(unit)
+	}
+
+	return NewTimestampWithFractionalSeconds(t, precision, kind, fraction)
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func canonicalTextOf(t *testing.T, f func(w Writer)) string {
+	buf := strings.Builder{}
+	w := NewTextWriterOpts(&buf, TextWriterCanonical|TextWriterPretty|TextWriterQuietFinish)
+	f(w)
+	require.NoError(t, w.Finish())
+	return buf.String()
+}
+
+func TestCanonicalWriterText(t *testing.T) {
+	test := func(in, expected string) {
+		t.Run(in, func(t *testing.T) {
+			vals, err := ReadValues(NewReaderString(in))
+			require.NoError(t, err)
+
+			actual := canonicalTextOf(t, func(w Writer) {
+				for _, v := range vals {
+					require.NoError(t, v.MarshalIon(w))
+				}
+			})
+			assert.Equal(t, expected, actual)
+		})
+	}
+
+	test("{b:1, a:2, c:{z:null, y:[3, {q:r, p:s}]}}", "{a:2,b:1,c:{y:[3,{p:s,q:r}],z:null}}")
+	test("{a:x::\"s\", b:(c d), a:1, a:null.int}", "{a:1,a:null.int,a:x::\"s\",b:(c d)}")
+	test("{'':1, aa:2, a:3, 'é':4, B:5}", "{'':1,B:5,a:3,aa:2,'é':4}")
+	test("b::a::{b:1, a:2}", "b::a::{a:2,b:1}")
+	test("2020-01-01T10:00+00:00", "2020-01-01T10:00Z")
+	test("2020-01-01T10:00:00.000-00:00", "2020-01-01T10:00:00.000-00:00")
+	test("2020-01-01T10:00:30+01:30", "2020-01-01T10:00:30+01:30")
+	test("1.50 1.5 1d2 -0. 1e0 -0e0 123456789012345678901234567890", "1.50\n1.5\n1d2\n-0.\n1e+0\n-0e+0\n123456789012345678901234567890")
+}
+
+func TestCanonicalWriterTimestamps(t *testing.T) {
+	test := func(ts Timestamp, expected string) {
+		actual := canonicalTextOf(t, func(w Writer) {
+			require.NoError(t, w.WriteTimestamp(ts))
+		})
+		assert.Equal(t, expected, actual)
+	}
+
+	est := time.FixedZone("EST", -5*60*60)
+
+	test(NewTimestamp(time.Date(2020, 1, 2, 23, 4, 5, 6, est), TimestampPrecisionDay, TimezoneLocal), "2020-01-02T")
+	test(NewTimestamp(time.Date(2020, 7, 2, 23, 4, 5, 6, est), TimestampPrecisionYear, TimezoneLocal), "2020T")
+	test(NewTimestamp(time.Date(2020, 1, 2, 3, 4, 5, 6, est), TimestampPrecisionMinute, TimezoneLocal), "2020-01-02T03:04-05:00")
+	test(NewTimestamp(time.Date(2020, 1, 2, 3, 4, 5, 6, est), TimestampPrecisionSecond, TimezoneUTC), "2020-01-02T08:04:05Z")
+	test(NewTimestamp(time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC), TimestampPrecisionSecond, TimezoneLocal), "2020-01-02T03:04:05Z")
+	test(NewTimestampWithFractionalSeconds(time.Date(2020, 1, 2, 3, 4, 5, 123456789, time.UTC), TimestampPrecisionNanosecond, TimezoneUTC, 3), "2020-01-02T03:04:05.123Z")
+	test(NewTimestampWithFractionalSeconds(time.Date(2020, 1, 2, 3, 4, 5, 123456789, time.UTC), TimestampPrecisionNanosecond, TimezoneUTC, 0), "2020-01-02T03:04:05Z")
+}
+
+func TestCanonicalWriterBinary(t *testing.T) {
+	// The same data, encoded with different local symbol tables and field orders.
+	lst := NewLocalSymbolTable(nil, []string{"zz", "b", "a", "x", "y"})
+	buf := bytes.Buffer{}
+	w := NewBinaryWriterLST(&buf, lst)
+	require.NoError(t, w.Annotation(NewSymbolTokenFromString("x")))
+	require.NoError(t, w.BeginStruct())
+	require.NoError(t, w.FieldName(NewSymbolTokenFromString("b")))
+	require.NoError(t, w.WriteSymbolFromString("y"))
+	require.NoError(t, w.FieldName(NewSymbolTokenFromString("a")))
+	require.NoError(t, w.WriteInt(1))
+	require.NoError(t, w.EndStruct())
+	require.NoError(t, w.Finish())
+
+	fromBinary, err := ReadValues(NewReaderBytes(buf.Bytes()))
+	require.NoError(t, err)
+	fromText, err := ReadValues(NewReaderString("x::{a:1, b:y}"))
+	require.NoError(t, err)
+
+	b1, err := MarshalCanonical(fromBinary[0])
+	require.NoError(t, err)
+	b2, err := MarshalCanonical(fromText[0])
+	require.NoError(t, err)
+	assert.Equal(t, b1, b2)
+
+	vals, err := ReadValues(NewReaderBytes(b1))
+	require.NoError(t, err)
+	require.Len(t, vals, 1)
+	text, err := MarshalText(vals[0])
+	require.NoError(t, err)
+	assert.Equal(t, "x::{a:1,b:y}", string(text))
+}
+
+func TestCanonicalWriterInts(t *testing.T) {
+	// Ints are written the same way however they were produced.
+	write := func(f func(w Writer) error) []byte {
+		buf := bytes.Buffer{}
+		w := NewBinaryWriterCanonical(&buf)
+		require.NoError(t, f(w))
+		require.NoError(t, w.Finish())
+		return buf.Bytes()
+	}
+
+	b1 := write(func(w Writer) error { return w.WriteInt(42) })
+	b2 := write(func(w Writer) error { return w.WriteUint(42) })
+	b3 := write(func(w Writer) error { return w.WriteBigInt(big.NewInt(42)) })
+	assert.Equal(t, b1, b2)
+	assert.Equal(t, b1, b3)
+}
+
+func TestCanonicalEncoder(t *testing.T) {
+	type item struct {
+		Z string            `ion:"z"`
+		A map[string]int    `ion:"a"`
+		M map[string]string `ion:"m,omitempty"`
+	}
+
+	buf := strings.Builder{}
+	e := NewEncoderOpts(NewTextWriterOpts(&buf, TextWriterQuietFinish), EncodeCanonical)
+	require.NoError(t, e.Encode(item{Z: "z", A: map[string]int{"y": 1, "x": 2}}))
+	require.NoError(t, e.Finish())
+	assert.Equal(t, `{a:{x:2,y:1},z:"z"}`, buf.String())
+
+	b1, err := MarshalCanonical(map[string]int{"a": 1, "b": 2, "c": 3})
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		b2, err := MarshalCanonical(map[string]int{"c": 3, "b": 2, "a": 1})
+		require.NoError(t, err)
+		assert.Equal(t, b1, b2)
+	}
+}
+
+func TestCanonicalWriterErrors(t *testing.T) {
+	w := NewBinaryWriterCanonical(&bytes.Buffer{})
+	require.NoError(t, w.BeginStruct())
+	assert.Error(t, w.WriteInt(1))
+	assert.Error(t, w.Finish())
+
+	w = NewTextWriterOpts(&bytes.Buffer{}, TextWriterCanonical)
+	require.NoError(t, w.BeginList())
+	assert.Error(t, w.Finish())
+}
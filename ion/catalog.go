@@ -28,24 +28,54 @@ type Catalog interface {
 	FindLatest(name string) SharedSymbolTable
 }
 
-// A basicCatalog wraps an in-memory collection of shared symbol tables.
+// A ModuleCatalog is a Catalog that also provides access to Ion 1.1 modules,
+// which encoding directives import symbols and macros from.
+type ModuleCatalog interface {
+	Catalog
+	FindExactModule(name string, version int) *Module
+	FindLatestModule(name string) *Module
+}
+
+// A basicCatalog wraps an in-memory collection of shared symbol tables and
+// modules.
 type basicCatalog struct {
 	ssts   map[string]SharedSymbolTable
 	latest map[string]SharedSymbolTable
+
+	modules       map[string]*Module
+	latestModules map[string]*Module
 }
 
 // NewCatalog creates a new basic catalog containing the given symbol tables.
 func NewCatalog(ssts ...SharedSymbolTable) Catalog {
-	cat := &basicCatalog{
-		ssts:   make(map[string]SharedSymbolTable),
-		latest: make(map[string]SharedSymbolTable),
-	}
+	cat := newBasicCatalog()
 	for _, sst := range ssts {
 		cat.add(sst)
 	}
 	return cat
 }
 
+// NewModuleCatalog creates a new basic catalog containing the given modules. Each
+// module's symbols are also available as a shared symbol table, so Ion 1.0 local
+// symbol tables can import them too.
+func NewModuleCatalog(modules ...*Module) ModuleCatalog {
+	cat := newBasicCatalog()
+	for _, m := range modules {
+		cat.addModule(m)
+		cat.add(m.SymbolTable())
+	}
+	return cat
+}
+
+func newBasicCatalog() *basicCatalog {
+	return &basicCatalog{
+		ssts:          make(map[string]SharedSymbolTable),
+		latest:        make(map[string]SharedSymbolTable),
+		modules:       make(map[string]*Module),
+		latestModules: make(map[string]*Module),
+	}
+}
+
 // Add adds a shared symbol table to the catalog.
 func (c *basicCatalog) add(sst SharedSymbolTable) {
 	key := fmt.Sprintf("%v/%v", sst.Name(), sst.Version())
@@ -57,6 +87,16 @@ func (c *basicCatalog) add(sst SharedSymbolTable) {
 	}
 }
 
+// AddModule adds a module to the catalog.
+func (c *basicCatalog) addModule(m *Module) {
+	c.modules[m.String()] = m
+
+	cur, ok := c.latestModules[m.Name()]
+	if !ok || m.Version() > cur.Version() {
+		c.latestModules[m.Name()] = m
+	}
+}
+
 // FindExact attempts to find a shared symbol table with the given name and version.
 func (c *basicCatalog) FindExact(name string, version int) SharedSymbolTable {
 	key := fmt.Sprintf("%v/%v", name, version)
@@ -68,6 +108,48 @@ func (c *basicCatalog) FindLatest(name string) SharedSymbolTable {
 	return c.latest[name]
 }
 
+// FindExactModule attempts to find a module with the given name and version.
+func (c *basicCatalog) FindExactModule(name string, version int) *Module {
+	return c.modules[fmt.Sprintf("%v/%v", name, version)]
+}
+
+// FindLatestModule finds the module with the given name and largest version.
+func (c *basicCatalog) FindLatestModule(name string) *Module {
+	return c.latestModules[name]
+}
+
+// findModule looks up a module in the catalog. If the catalog has no such module,
+// a shared symbol table with the same name and version is imported as a module
+// without any macros. If version is less than 1, the latest version is found.
+func findModule(cat Catalog, name string, version int) *Module {
+	if cat == nil {
+		return nil
+	}
+
+	if mc, ok := cat.(ModuleCatalog); ok {
+		var m *Module
+		if version < 1 {
+			m = mc.FindLatestModule(name)
+		} else {
+			m = mc.FindExactModule(name, version)
+		}
+		if m != nil {
+			return m
+		}
+	}
+
+	var sst SharedSymbolTable
+	if version < 1 {
+		sst = cat.FindLatest(name)
+	} else {
+		sst = cat.FindExact(name, version)
+	}
+	if sst == nil {
+		return nil
+	}
+	return NewModule(sst.Name(), sst.Version(), sst.Symbols(), nil)
+}
+
 // A System is a reader factory wrapping a catalog.
 type System struct {
 	Catalog Catalog
@@ -71,3 +71,30 @@ func TestCatalog(t *testing.T) {
 
 	assert.Equal(t, 10, i)
 }
+
+func TestModuleCatalog(t *testing.T) {
+	v1 := NewModule("com.example", 1, []string{"a"}, nil)
+	v2 := NewModule("com.example", 2, []string{"a", "b"}, nil)
+	cat := NewModuleCatalog(v2, v1)
+
+	assert.Equal(t, v1, cat.FindExactModule("com.example", 1))
+	assert.Equal(t, v2, cat.FindLatestModule("com.example"))
+	assert.Nil(t, cat.FindExactModule("com.example", 3))
+	assert.Nil(t, cat.FindLatestModule("missing"))
+
+	// Modules' symbols can also be imported by Ion 1.0 local symbol tables.
+	sst := cat.FindExact("com.example", 1)
+	require.NotNil(t, sst)
+	assert.Equal(t, []string{"a"}, sst.Symbols())
+	assert.Equal(t, 2, cat.FindLatest("com.example").Version())
+
+	// Plain shared symbol tables are imported as modules without macros.
+	m := findModule(NewCatalog(sst), "com.example", 0)
+	require.NotNil(t, m)
+	assert.Equal(t, []string{"a"}, m.Symbols())
+	assert.Equal(t, 0, m.Macros().Len())
+
+	assert.Equal(t, v2, findModule(cat, "com.example", 0))
+	assert.Nil(t, findModule(cat, "com.example", 3))
+	assert.Nil(t, findModule(nil, "com.example", 1))
+}
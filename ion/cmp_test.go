@@ -16,11 +16,7 @@
 package ion
 
 import (
-	"math"
 	"reflect"
-
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
 type ionEqual interface {
@@ -33,19 +29,12 @@ type ionTimestamp struct{ Timestamp }
 type ionSymbol struct{ *SymbolToken }
 
 func (thisFloat ionFloat) eq(other ionEqual) bool {
-	float1 := thisFloat.float64
-	float2 := other.(ionFloat).float64
-
-	return math.Signbit(float1) == math.Signbit(float2) &&
-		cmp.Equal(float1, float2, cmpopts.EquateNaNs())
+	return floatsEquivalent(thisFloat.float64, other.(ionFloat).float64)
 }
 
 func (thisDecimal ionDecimal) eq(other ionEqual) bool {
 	if val, ok := other.(ionDecimal); ok {
-		if thisDecimal.scale != val.scale || thisDecimal.isNegZero != val.isNegZero {
-			return false
-		}
-		return thisDecimal.Decimal.Equal(val.Decimal)
+		return decimalsEquivalent(thisDecimal.Decimal, val.Decimal)
 	}
 	return false
 }
@@ -25,6 +25,10 @@ const (
 	ctxInStruct
 	ctxInList
 	ctxInSexp
+
+	// Writers of Ion 1.1 e-expressions track the arguments they're writing.
+	ctxInEExpression
+	ctxInExpressionGroup
 )
 
 func ctxToContainerType(c ctx) Type {
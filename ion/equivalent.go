@@ -0,0 +1,194 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"bytes"
+	"math"
+)
+
+// Equivalent determines whether two Readers contain equivalent streams of Ion
+// values, according to the Ion data model. Each Reader is consumed from its current
+// position to the end of its current container (or stream); one top-level value is
+// held in memory at a time.
+//
+// Two values are equivalent if they have the same type, the same annotations, and
+// equivalent content. In particular:
+//   - decimals must have the same coefficient and exponent, so 1.0 is not
+//     equivalent to 1.00, and -0. is not equivalent to 0.;
+//   - timestamps must have the same instant, precision, and local offset;
+//   - floats are compared bitwise, so nan is equivalent to nan but -0e0 is not
+//     equivalent to 0e0;
+//   - symbols with known text are compared by text; symbols with unknown text are
+//     compared by their import source, if any;
+//   - struct fields are unordered, but each field (including duplicates) must have
+//     an equivalent counterpart in the other struct.
+//
+// An error is returned only if one of the Readers fails.
+func Equivalent(a, b Reader) (bool, error) {
+	for {
+		aok, bok := a.Next(), b.Next()
+
+		if err := a.Err(); err != nil {
+			return false, err
+		}
+		if err := b.Err(); err != nil {
+			return false, err
+		}
+
+		if !aok || !bok {
+			return aok == bok, nil
+		}
+
+		av, err := ReadValue(a)
+		if err != nil {
+			return false, err
+		}
+		bv, err := ReadValue(b)
+		if err != nil {
+			return false, err
+		}
+
+		if !EquivalentValues(av, bv) {
+			return false, nil
+		}
+	}
+}
+
+// EquivalentBytes determines whether two Ion documents, each of which may be
+// either text or binary, contain equivalent streams of Ion values. See Equivalent.
+func EquivalentBytes(a, b []byte) (bool, error) {
+	return Equivalent(NewReaderBytes(a), NewReaderBytes(b))
+}
+
+// EquivalentValues determines whether two in-memory values are equivalent,
+// according to the Ion data model. See Equivalent.
+func EquivalentValues(a, b Value) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	if a.Type() != b.Type() || a.IsNull() != b.IsNull() {
+		return false
+	}
+	if !symbolsEquivalent(a.Annotations(), b.Annotations()) {
+		return false
+	}
+	if a.IsNull() {
+		return true
+	}
+
+	switch a := a.(type) {
+	case *NullValue:
+		return true
+	case *BoolValue:
+		return a.Bool() == b.(*BoolValue).Bool()
+	case *IntValue:
+		return a.val.Cmp(b.(*IntValue).val) == 0
+	case *FloatValue:
+		return floatsEquivalent(a.Float(), b.(*FloatValue).Float())
+	case *DecimalValue:
+		return decimalsEquivalent(a.Decimal(), b.(*DecimalValue).Decimal())
+	case *TimestampValue:
+		return a.Timestamp().Equal(b.(*TimestampValue).Timestamp())
+	case *SymbolValue:
+		sym := b.(*SymbolValue).Symbol()
+		return a.val.Equal(&sym)
+	case *StringValue:
+		return a.Text() == b.(*StringValue).Text()
+	case *ClobValue:
+		return bytes.Equal(a.Bytes(), b.(*ClobValue).Bytes())
+	case *BlobValue:
+		return bytes.Equal(a.Bytes(), b.(*BlobValue).Bytes())
+	case *ListValue:
+		return sequencesEquivalent(a.Values(), b.(*ListValue).Values())
+	case *SexpValue:
+		return sequencesEquivalent(a.Values(), b.(*SexpValue).Values())
+	case *StructValue:
+		return fieldsEquivalent(a.Fields(), b.(*StructValue).Fields())
+	default:
+		return false
+	}
+}
+
+// floatsEquivalent compares two floats bitwise, treating all NaNs as equivalent.
+func floatsEquivalent(a, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+	return a == b && math.Signbit(a) == math.Signbit(b)
+}
+
+// decimalsEquivalent compares two decimals, taking precision and negative zero
+// into account.
+func decimalsEquivalent(a, b *Decimal) bool {
+	return a.scale == b.scale && a.isNegZero == b.isNegZero && a.Equal(b)
+}
+
+// symbolsEquivalent compares two lists of symbols (e.g., annotations) in order.
+func symbolsEquivalent(a, b []SymbolToken) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(&b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sequencesEquivalent compares the children of two lists or sexps in order.
+func sequencesEquivalent(a, b []Value) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !EquivalentValues(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldsEquivalent compares the fields of two structs, ignoring order. Since
+// equivalence is transitive, greedily matching each field in a to the first
+// unmatched equivalent field in b is sufficient.
+func fieldsEquivalent(a, b []StructField) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	matched := make([]bool, len(b))
+	for _, af := range a {
+		found := false
+		for i, bf := range b {
+			if matched[i] || !af.Name.Equal(&bf.Name) {
+				continue
+			}
+			if EquivalentValues(af.Value, bf.Value) {
+				matched[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
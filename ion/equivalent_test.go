@@ -0,0 +1,115 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEquivalentText(t *testing.T) {
+	test := func(a, b string, eq bool) {
+		t.Run(a+" vs "+b, func(t *testing.T) {
+			res, err := Equivalent(NewReaderString(a), NewReaderString(b))
+			require.NoError(t, err)
+			assert.Equal(t, eq, res)
+		})
+	}
+
+	test("1 2 3", "1 2 3", true)
+	test("1 2 3", "1 2", false)
+	test("1", "1.", false)
+	test("1.0", "1.0", true)
+	test("1.0", "1.00", false)
+	test("0.", "-0.", false)
+	test("-0.", "-0d0", true)
+	test("nan", "nan", true)
+	test("0e0", "-0e0", false)
+	test("1e0", "1.0e0", true)
+	test("2001T", "2001-01T", false)
+	test("2001-01-01T00:00Z", "2001-01-01T00:00+00:00", true)
+	test("2001-01-01T00:00Z", "2001-01-01T00:00-00:00", false)
+	test("2001-01-01T01:00+01:00", "2001-01-01T00:00Z", false)
+	test("2001-01-01T00:00:00.0Z", "2001-01-01T00:00:00.00Z", false)
+	test("null", "null.null", true)
+	test("null", "null.int", false)
+	test("null.list", "[]", false)
+	test("abc", `"abc"`, false)
+	test("'abc'", "abc", true)
+	test("$0", "$0", true)
+	test("$0", "''", false)
+	test("a::1", "a::1", true)
+	test("a::b::1", "b::a::1", false)
+	test("a::1", "1", false)
+	test(`{{"abc"}}`, "{{YWJj}}", false)
+	test("[1, 2]", "[1, 2]", true)
+	test("[1, 2]", "[2, 1]", false)
+	test("[1, 2]", "(1 2)", false)
+	test("{a:1, b:2}", "{b:2, a:1}", true)
+	test("{a:1, a:2}", "{a:2, a:1}", true)
+	test("{a:1, a:1}", "{a:1}", false)
+	test("{a:1, a:1, b:2}", "{a:1, b:2, b:2}", false)
+	test("{a:{b:[1.0, c::d]}}", "{a:{b:[1.0, c::d]}}", true)
+	test("{a:{b:[1.0, c::d]}}", "{a:{b:[1.00, c::d]}}", false)
+}
+
+func TestEquivalentBytes(t *testing.T) {
+	text := []byte(`a::{x:1.50,y:[2001-01-01T00:00:00.000Z,nan,-0.],z:(sym "str" {{YWJj}}),x:null.int}`)
+
+	bin, err := MarshalBinary(readSingleValue(t, text))
+	require.NoError(t, err)
+
+	res, err := EquivalentBytes(text, bin)
+	require.NoError(t, err)
+	assert.True(t, res)
+
+	res, err = EquivalentBytes(text, []byte(`a::{x:1.5,y:[2001-01-01T00:00:00.000Z,nan,-0.],z:(sym "str" {{YWJj}}),x:null.int}`))
+	require.NoError(t, err)
+	assert.False(t, res)
+}
+
+func TestEquivalentError(t *testing.T) {
+	_, err := EquivalentBytes([]byte("1 2"), []byte("1 {"))
+	assert.Error(t, err)
+}
+
+func TestEquivalentValues(t *testing.T) {
+	a := NewStruct()
+	a.Add("x", NewList(NewInt(1), NewSymbol("y")))
+	a.Add("x", NewNullValue(StringType))
+
+	b := NewStruct()
+	b.Add("x", NewNullValue(StringType))
+	b.Add("x", NewList(NewInt(1), NewSymbol("y")))
+
+	assert.True(t, EquivalentValues(a, b))
+
+	b.SetAnnotations(NewSymbolTokenFromString("z"))
+	assert.False(t, EquivalentValues(a, b))
+
+	assert.True(t, EquivalentValues(nil, nil))
+	assert.False(t, EquivalentValues(a, nil))
+}
+
+// readSingleValue reads the given Ion data into memory and returns its first value.
+func readSingleValue(t *testing.T, data []byte) Value {
+	vs, err := ReadValues(NewReaderBytes(data))
+	require.NoError(t, err)
+	require.Len(t, vs, 1)
+	return vs[0]
+}
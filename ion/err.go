@@ -42,20 +42,22 @@ func (e *IOError) Error() string {
 type SyntaxError struct {
 	Msg    string
 	Offset uint64
+	Location
 }
 
 func (e *SyntaxError) Error() string {
-	return fmt.Sprintf("ion: syntax error: %v (offset %v)", e.Msg, e.Offset)
+	return fmt.Sprintf("ion: syntax error: %v (%v)", e.Msg, e.describe(e.Offset))
 }
 
 // An UnexpectedEOFError is returned when a Reader unexpectedly encounters an
 // io.EOF error.
 type UnexpectedEOFError struct {
 	Offset uint64
+	Location
 }
 
 func (e *UnexpectedEOFError) Error() string {
-	return fmt.Sprintf("ion: unexpected end of input (offset %v)", e.Offset)
+	return fmt.Sprintf("ion: unexpected end of input (%v)", e.describe(e.Offset))
 }
 
 // An UnsupportedVersionError is returned when a Reader encounters a binary version
@@ -85,10 +87,11 @@ func (e *InvalidTagByteError) Error() string {
 type UnexpectedRuneError struct {
 	Rune   rune
 	Offset uint64
+	Location
 }
 
 func (e *UnexpectedRuneError) Error() string {
-	return fmt.Sprintf("ion: unexpected rune %q (offset %v)", e.Rune, e.Offset)
+	return fmt.Sprintf("ion: unexpected rune %q (%v)", e.Rune, e.describe(e.Offset))
 }
 
 // An UnexpectedTokenError is returned when a text Reader encounters an unexpected
@@ -96,8 +99,63 @@ func (e *UnexpectedRuneError) Error() string {
 type UnexpectedTokenError struct {
 	Token  string
 	Offset uint64
+	Location
 }
 
 func (e *UnexpectedTokenError) Error() string {
-	return fmt.Sprintf("ion: unexpected token '%v' (offset %v)", e.Token, e.Offset)
+	return fmt.Sprintf("ion: unexpected token '%v' (%v)", e.Token, e.describe(e.Offset))
+}
+
+// A Location is the line, column, and path at which a text Reader encountered an
+// error. It's embedded in the errors returned by Readers for invalid input, and is
+// left empty by binary Readers.
+type Location struct {
+	// Line and Column are the 1-based line and column of the error. Columns are
+	// counted in bytes.
+	Line   uint64
+	Column uint64
+
+	// Path is the path from the top-level value to the value being read, made of
+	// field names and list or sexp indexes (for example, ".orders[3].price"). It's
+	// empty for top-level values.
+	Path string
+}
+
+// errorLocation returns the offset and location of the given error, or nil if it
+// doesn't have one.
+func errorLocation(err error) (uint64, *Location) {
+	switch e := err.(type) {
+	case *SyntaxError:
+		return e.Offset, &e.Location
+	case *UnexpectedEOFError:
+		return e.Offset, &e.Location
+	case *UnexpectedRuneError:
+		return e.Offset, &e.Location
+	case *UnexpectedTokenError:
+		return e.Offset, &e.Location
+	}
+	return 0, nil
+}
+
+// describe describes the location of an error at the given offset.
+func (l *Location) describe(offset uint64) string {
+	desc := fmt.Sprintf("offset %v", offset)
+	if l.Line > 0 {
+		desc = fmt.Sprintf("line %v, column %v, %v", l.Line, l.Column, desc)
+	}
+	if l.Path != "" {
+		desc += ", path " + l.Path
+	}
+	return desc
+}
+
+// A MacroError is returned when a macro definition is invalid or a macro cannot be
+// expanded, for example because it was passed the wrong number of arguments.
+type MacroError struct {
+	Macro string
+	Msg   string
+}
+
+func (e *MacroError) Error() string {
+	return fmt.Sprintf("ion: macro %v: %v", e.Macro, e.Msg)
 }
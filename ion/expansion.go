@@ -0,0 +1,157 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+// An expansion holds the values an e-expression expanded to while a reader
+// iterates over them. The first frame holds the expanded values themselves;
+// later frames track the containers among them the reader has stepped in to.
+type expansion struct {
+	frames    []valueFrame
+	cur       Value
+	fieldName *SymbolToken
+}
+
+// macroTable returns the table e-expressions refer to unless they name a
+// system macro explicitly.
+func (r *reader) macroTable() *MacroTable {
+	if r.macros != nil {
+		return r.macros
+	}
+	return V11SystemMacroTable
+}
+
+// expand arranges for the reader to return the given values in place of the
+// e-expression that produced them. If the e-expression was the value of a
+// struct field, each of the values is given the field's name.
+func (r *reader) expand(vals []Value, fieldName *SymbolToken) {
+	r.exp = &expansion{
+		frames:    []valueFrame{{vals: vals}},
+		fieldName: fieldName,
+	}
+}
+
+// nextExpanded moves to the next value produced by the active expansion. It
+// returns false if there is no active expansion or if it has been used up, in
+// which case the caller should continue reading from the underlying stream.
+// Encoding directives produced at the top level are applied rather than returned.
+func (r *reader) nextExpanded() (bool, error) {
+	e := r.exp
+	if e == nil {
+		return false, nil
+	}
+
+	for {
+		f := &e.frames[len(e.frames)-1]
+		if f.next >= f.len() {
+			if len(e.frames) == 1 {
+				r.exp = nil
+				return false, nil
+			}
+			r.clear()
+			e.cur = nil
+			r.eof = true
+			return true, nil
+		}
+
+		r.clear()
+
+		var v Value
+		switch {
+		case f.fields != nil:
+			name := f.fields[f.next].Name
+			r.fieldName = &name
+			v = f.fields[f.next].Value
+		case len(e.frames) == 1:
+			r.fieldName = e.fieldName
+			v = f.vals[f.next]
+		default:
+			v = f.vals[f.next]
+		}
+		f.next++
+
+		if len(e.frames) == 1 && r.ctx.peek() == ctxAtTopLevel && isEncodingDirective(v) {
+			if err := r.applyEncodingDirective(v); err != nil {
+				return false, err
+			}
+			continue
+		}
+
+		e.cur = v
+		r.valueType = v.Type()
+		r.annotations = v.Annotations()
+		r.value = readerValueOf(v)
+		return true, nil
+	}
+}
+
+// isEncodingDirective returns true if v is an encoding directive.
+func isEncodingDirective(v Value) bool {
+	return v.Type() == SexpType && isIonEncoding(v.Annotations())
+}
+
+// applyEncodingDirective installs the symbols and macros established by an
+// encoding directive.
+func (r *reader) applyEncodingDirective(v Value) error {
+	lst, mt, err := evalEncodingDirective(v, r.cat, r.lst, r.macroTable())
+	if err != nil {
+		return err
+	}
+	r.lst = lst
+	r.macros = mt
+	return nil
+}
+
+// stepInExpanded steps in to the current value if it was produced by an
+// expansion, returning false if it was not.
+func (r *reader) stepInExpanded() (bool, error) {
+	e := r.exp
+	if e == nil {
+		return false, nil
+	}
+
+	f, err := valueFrameOf(e.cur, r.valueType)
+	if err != nil {
+		return true, err
+	}
+
+	r.ctx.push(containerTypeToCtx(r.valueType))
+	e.frames = append(e.frames, f)
+	e.cur = nil
+	r.clear()
+	return true, nil
+}
+
+// stepOutExpanded steps out of a container produced by an expansion, returning
+// false if the reader is not in one. Stepping out of the container that holds
+// an e-expression abandons the rest of its expansion.
+func (r *reader) stepOutExpanded() bool {
+	e := r.exp
+	if e == nil {
+		return false
+	}
+
+	if len(e.frames) == 1 {
+		r.exp = nil
+		return false
+	}
+
+	e.frames = e.frames[:len(e.frames)-1]
+	e.cur = nil
+	r.clear()
+	r.ctx.pop()
+	r.eof = false
+	return true
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+)
+
+// ReaderOpts defines a set of bit flag options for readers.
+type ReaderOpts uint8
+
+const (
+	// ReaderDetectGzip instructs the reader to decompress gzip-compressed input,
+	// which it recognizes by its magic number (neither text nor binary Ion can
+	// start with it). Readers of compressed input can't seek.
+	ReaderDetectGzip ReaderOpts = 1 << iota
+)
+
+// NewReaderOpts creates a new reader with the given catalog and options.
+func NewReaderOpts(in io.Reader, cat Catalog, opts ReaderOpts) Reader {
+	br, src, binary := openInput(in, opts&ReaderDetectGzip != 0)
+	if binary {
+		r := newBinaryReaderBuf(br, cat, nil).(*binaryReader)
+		r.src = src
+		return r
+	}
+
+	r := newTextReaderBuf(br, cat, nil).(*textReader)
+	r.src = src
+	return r
+}
+
+// openInput prepares the given input for a reader, returning a buffered reader
+// of it, the seekSource to seek within it (if any), and whether it holds binary
+// Ion. If gzipped is true, gzip-compressed input is decompressed on the fly,
+// and can't be sought within.
+func openInput(in io.Reader, gzipped bool) (*bufio.Reader, *seekSource, bool) {
+	src := newSeekSource(in)
+	br := bufio.NewReader(in)
+
+	bs, err := br.Peek(4)
+	if gzipped && hasGzipMagic(bs) {
+		src = nil
+		if z, zerr := gzip.NewReader(br); zerr == nil {
+			br = bufio.NewReader(z)
+		} else {
+			br = bufio.NewReader(&errInput{zerr})
+		}
+		bs, err = br.Peek(4)
+	}
+
+	return br, src, err == nil && hasBVM(bs)
+}
+
+// hasGzipMagic returns true if the given bytes start with the gzip magic number.
+func hasGzipMagic(bs []byte) bool {
+	return len(bs) >= 2 && bs[0] == 0x1f && bs[1] == 0x8b
+}
+
+// An errInput is an input whose every read fails with the given error.
+type errInput struct {
+	err error
+}
+
+func (e *errInput) Read(bs []byte) (int, error) {
+	return 0, e.err
+}
+
+// A gzipWriter is a Writer that writes gzip-compressed output.
+type gzipWriter struct {
+	Writer
+	out io.Writer
+	z   *gzip.Writer
+}
+
+// NewGzipWriter creates a Writer that writes gzip-compressed Ion to out, using
+// the Writer newWriter creates to write the uncompressed Ion. Finish finishes
+// writing the compressed stream as well; values written after that go into a
+// new gzip member, which readers will read as a continuation of the stream.
+func NewGzipWriter(out io.Writer, newWriter func(out io.Writer) Writer) Writer {
+	z := gzip.NewWriter(out)
+	return &gzipWriter{
+		Writer: newWriter(z),
+		out:    out,
+		z:      z,
+	}
+}
+
+// NewBinaryWriterGzip creates a new binary writer that writes gzip-compressed
+// output, as NewGzipWriter does.
+func NewBinaryWriterGzip(out io.Writer, sts ...SharedSymbolTable) Writer {
+	return NewGzipWriter(out, func(z io.Writer) Writer {
+		return NewBinaryWriter(z, sts...)
+	})
+}
+
+// NewTextWriterGzip creates a new text writer that writes gzip-compressed
+// output, as NewGzipWriter does.
+func NewTextWriterGzip(out io.Writer, sts ...SharedSymbolTable) Writer {
+	return NewGzipWriter(out, func(z io.Writer) Writer {
+		return NewTextWriter(z, sts...)
+	})
+}
+
+// Finish finishes writing values and closes the gzip stream.
+func (w *gzipWriter) Finish() error {
+	if err := w.Writer.Finish(); err != nil {
+		return err
+	}
+	if err := w.z.Close(); err != nil {
+		return &IOError{err}
+	}
+	w.z.Reset(w.out)
+	return nil
+}
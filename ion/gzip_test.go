@@ -0,0 +1,107 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipBytes(t *testing.T, bs []byte) []byte {
+	buf := bytes.Buffer{}
+	z := gzip.NewWriter(&buf)
+	_, err := z.Write(bs)
+	require.NoError(t, err)
+	require.NoError(t, z.Close())
+	return buf.Bytes()
+}
+
+func TestReadGzip(t *testing.T) {
+	test := func(name string, in []byte) {
+		t.Run(name, func(t *testing.T) {
+			r := NewReaderOpts(bytes.NewReader(gzipBytes(t, in)), nil, ReaderDetectGzip)
+			vals, err := ReadValues(r)
+			require.NoError(t, err)
+			require.Equal(t, 2, len(vals))
+
+			text, err := MarshalText(vals[1])
+			require.NoError(t, err)
+			assert.Equal(t, "{a:[1,2]}", string(text))
+
+			assert.IsType(t, &UsageError{}, r.(SeekableReader).Seek(Span{}))
+		})
+	}
+
+	test("text", []byte("hello {a:[1, 2]}"))
+	test("binary", encodeBinary(t, "hello {a:[1, 2]}"))
+}
+
+func TestReadGzipUncompressed(t *testing.T) {
+	in := encodeBinary(t, "hello {a:[1, 2]}")
+	r := NewReaderOpts(bytes.NewReader(in), nil, ReaderDetectGzip)
+	spans, texts := readSpans(t, r)
+	require.Equal(t, 2, len(spans))
+	seekSpans(t, r, spans, texts)
+}
+
+func TestReadGzipNotDetected(t *testing.T) {
+	r := NewReaderBytes(gzipBytes(t, []byte("hello")))
+	assert.False(t, r.Next())
+	assert.Error(t, r.Err())
+}
+
+func TestReadGzipCorrupt(t *testing.T) {
+	in := gzipBytes(t, []byte("hello"))
+	in[3] = 0xFF // Reserved flags.
+
+	r := NewReaderOpts(bytes.NewReader(in), nil, ReaderDetectGzip)
+	assert.False(t, r.Next())
+	assert.Error(t, r.Err())
+}
+
+func TestWriteGzip(t *testing.T) {
+	test := func(name string, newWriter func(out *bytes.Buffer) Writer) {
+		t.Run(name, func(t *testing.T) {
+			buf := bytes.Buffer{}
+			w := newWriter(&buf)
+
+			require.NoError(t, w.WriteString("hello"))
+			require.NoError(t, w.Finish())
+			assert.True(t, hasGzipMagic(buf.Bytes()))
+
+			// Values written after Finish go into a second gzip member.
+			require.NoError(t, w.WriteInt(42))
+			require.NoError(t, w.Finish())
+
+			vals, err := ReadValues(NewReaderOpts(&buf, nil, ReaderDetectGzip))
+			require.NoError(t, err)
+			require.Equal(t, 2, len(vals))
+			assert.Equal(t, StringType, vals[0].Type())
+			assert.Equal(t, IntType, vals[1].Type())
+		})
+	}
+
+	test("binary", func(out *bytes.Buffer) Writer { return NewBinaryWriterGzip(out) })
+	test("text", func(out *bytes.Buffer) Writer { return NewTextWriterGzip(out) })
+	test("json", func(out *bytes.Buffer) Writer {
+		return NewGzipWriter(out, func(z io.Writer) Writer { return NewJSONWriter(z) })
+	})
+}
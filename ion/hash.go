@@ -0,0 +1,301 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// Markers used when serializing values for the Ion Hash algorithm.
+const (
+	hashBeginMarker  = 0x0B
+	hashEndMarker    = 0x0E
+	hashEscapeMarker = 0x0C
+)
+
+// A HasherProvider returns a new hash.Hash each time it is called. The Ion Hash
+// algorithm needs one hash.Hash per struct field and per top-level value, so
+// HashReaders and HashWriters take a provider rather than a single hash.Hash.
+// The functions in the standard library's hash packages (e.g., sha256.New) are
+// suitable providers.
+type HasherProvider func() hash.Hash
+
+// hashValue tracks where the serialization of a single value is written.
+type hashValue struct {
+	// The hash that the value's serialization is written to.
+	sink hash.Hash
+
+	// Whether the sink belongs to this value alone (because it's a top-level value
+	// or a struct field) and must be finalized when the value ends.
+	own bool
+
+	// Whether the value is wrapped in annotations.
+	annotated bool
+}
+
+// A hashFrame tracks a container whose serialization is in progress.
+type hashFrame struct {
+	hashValue
+	typ Type
+
+	// The digests of the fields of a struct, which are sorted once the struct ends.
+	fields [][]byte
+}
+
+// A hasher implements the Ion Hash algorithm over a stream of value events. It is
+// shared by the hashReader and hashWriter.
+//
+// The serialization s(v) of a value is B || TQ || escape(representation) || E for
+// scalars, B || TQ || s(child1) || ... || E for lists and sexps, and
+// B || TQ || escape(sort(H(field1), ...)) || E for structs, where
+// H(field) = h(s(fieldname) || s(value)). Annotated values are wrapped as
+// B || 0xE0 || s(annotation1) || ... || s(value) || E.
+type hasher struct {
+	provider HasherProvider
+	stack    []*hashFrame
+	sum      []byte
+}
+
+// beginValue starts the serialization of a value, writing its field name and
+// annotations as needed.
+func (h *hasher) beginValue(name *SymbolToken, as []SymbolToken) (hashValue, error) {
+	var v hashValue
+
+	if len(h.stack) == 0 {
+		v.sink, v.own = h.provider(), true
+	} else if top := h.stack[len(h.stack)-1]; top.typ == StructType {
+		if name == nil {
+			return v, &UsageError{"Hash", "field name not set"}
+		}
+		v.sink, v.own = h.provider(), true
+		if err := writeHashSymbol(v.sink, *name); err != nil {
+			return v, err
+		}
+	} else {
+		v.sink = top.sink
+	}
+
+	if len(as) > 0 {
+		v.annotated = true
+		v.sink.Write([]byte{hashBeginMarker, 0xE0})
+		for _, a := range as {
+			if err := writeHashSymbol(v.sink, a); err != nil {
+				return v, err
+			}
+		}
+	}
+
+	return v, nil
+}
+
+// endValue finishes the serialization of a value, recording its digest if it
+// is a top-level value or a struct field.
+func (h *hasher) endValue(v hashValue) {
+	if v.annotated {
+		v.sink.Write([]byte{hashEndMarker})
+	}
+	if !v.own {
+		return
+	}
+
+	digest := v.sink.Sum(nil)
+	if len(h.stack) == 0 {
+		h.sum = digest
+	} else {
+		top := h.stack[len(h.stack)-1]
+		top.fields = append(top.fields, digest)
+	}
+}
+
+// scalar hashes a scalar (or null) value. The value is given in the same form
+// the shared reader struct stores it in; nil means null.
+func (h *hasher) scalar(name *SymbolToken, as []SymbolToken, t Type, val interface{}) error {
+	tq, repr, err := hashRepresentation(t, val)
+	if err != nil {
+		return err
+	}
+
+	v, err := h.beginValue(name, as)
+	if err != nil {
+		return err
+	}
+
+	v.sink.Write([]byte{hashBeginMarker, tq})
+	writeHashEscaped(v.sink, repr)
+	v.sink.Write([]byte{hashEndMarker})
+
+	h.endValue(v)
+	return nil
+}
+
+// begin starts hashing a container value.
+func (h *hasher) begin(name *SymbolToken, as []SymbolToken, t Type) error {
+	v, err := h.beginValue(name, as)
+	if err != nil {
+		return err
+	}
+
+	v.sink.Write([]byte{hashBeginMarker, binaryNulls[t] & 0xF0})
+	h.stack = append(h.stack, &hashFrame{hashValue: v, typ: t})
+
+	return nil
+}
+
+// end finishes hashing the current container value.
+func (h *hasher) end() {
+	f := h.stack[len(h.stack)-1]
+	h.stack = h.stack[:len(h.stack)-1]
+
+	if f.typ == StructType {
+		sort.Slice(f.fields, func(i, j int) bool {
+			return bytes.Compare(f.fields[i], f.fields[j]) < 0
+		})
+		for _, field := range f.fields {
+			writeHashEscaped(f.sink, field)
+		}
+	}
+	f.sink.Write([]byte{hashEndMarker})
+
+	h.endValue(f.hashValue)
+}
+
+// writeHashSymbol writes the serialization of a symbol used as a field name or
+// annotation.
+func writeHashSymbol(w hash.Hash, sym SymbolToken) error {
+	tq, repr, err := hashSymbolRepresentation(sym)
+	if err != nil {
+		return err
+	}
+
+	w.Write([]byte{hashBeginMarker, tq})
+	writeHashEscaped(w, repr)
+	w.Write([]byte{hashEndMarker})
+
+	return nil
+}
+
+// writeHashEscaped writes the given bytes, escaping any marker bytes.
+func writeHashEscaped(w hash.Hash, bs []byte) {
+	start := 0
+	for i, b := range bs {
+		if b == hashBeginMarker || b == hashEndMarker || b == hashEscapeMarker {
+			w.Write(bs[start:i])
+			w.Write([]byte{hashEscapeMarker})
+			start = i
+		}
+	}
+	w.Write(bs[start:])
+}
+
+// hashRepresentation returns the type qualifier and representation of a scalar
+// value, which are mostly the same as in the binary encoding.
+func hashRepresentation(t Type, val interface{}) (byte, []byte, error) {
+	if val == nil {
+		return binaryNulls[t], nil, nil
+	}
+
+	switch t {
+	case BoolType:
+		if val.(bool) {
+			return 0x11, nil, nil
+		}
+		return 0x10, nil, nil
+
+	case IntType:
+		var bi *big.Int
+		if i, ok := val.(int64); ok {
+			bi = big.NewInt(i)
+		} else {
+			bi = val.(*big.Int)
+		}
+		if bi.Sign() < 0 {
+			return 0x30, new(big.Int).Neg(bi).Bytes(), nil
+		}
+		return 0x20, bi.Bytes(), nil
+
+	case FloatType:
+		f := val.(float64)
+		if f == 0 && !math.Signbit(f) {
+			return 0x40, nil, nil
+		}
+		bits := math.Float64bits(f)
+		if math.IsNaN(f) {
+			bits = 0x7FF8000000000000
+		}
+		repr := make([]byte, 8)
+		binary.BigEndian.PutUint64(repr, bits)
+		return 0x40, repr, nil
+
+	case DecimalType:
+		d := val.(*Decimal)
+		coef, exp := d.CoEx()
+		if coef.Sign() == 0 && exp == 0 && !d.isNegZero {
+			return 0x50, nil, nil
+		}
+		repr := appendVarInt(nil, int64(exp))
+		if d.isNegZero {
+			repr = append(repr, 0x80)
+		} else {
+			repr = appendBigInt(repr, coef)
+		}
+		return 0x50, repr, nil
+
+	case TimestampType:
+		ts := val.(Timestamp)
+		_, offset := ts.dateTime.Zone()
+		ts.dateTime = ts.dateTime.In(time.UTC)
+		return 0x60, appendTimestamp(nil, offset/60, ts), nil
+
+	case SymbolType:
+		switch sym := val.(type) {
+		case *SymbolToken:
+			return hashSymbolRepresentation(*sym)
+		default:
+			return hashSymbolRepresentation(sym.(SymbolToken))
+		}
+
+	case StringType:
+		return 0x80, []byte(val.(string)), nil
+
+	case ClobType:
+		return 0x90, val.([]byte), nil
+
+	case BlobType:
+		return 0xA0, val.([]byte), nil
+
+	default:
+		return 0, nil, &UsageError{"Hash", fmt.Sprintf("cannot hash a %v as a scalar", t)}
+	}
+}
+
+// hashSymbolRepresentation returns the type qualifier and representation of a
+// symbol. Symbols with unknown text can't be hashed, other than $0.
+func hashSymbolRepresentation(sym SymbolToken) (byte, []byte, error) {
+	if sym.Text != nil {
+		return 0x70, []byte(*sym.Text), nil
+	}
+	if sym.LocalSID == 0 {
+		return 0x71, nil, nil
+	}
+	return 0, nil, fmt.Errorf("ion: cannot hash symbol with unknown text: %v", sym.String())
+}
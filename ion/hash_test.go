@@ -0,0 +1,110 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"hash"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// identityHash is a hash.Hash whose digest is its input, which makes it easy to
+// check the serialization the Ion Hash algorithm produces.
+type identityHash struct {
+	buf []byte
+}
+
+func newIdentityHash() hash.Hash {
+	return &identityHash{}
+}
+
+func (h *identityHash) Write(bs []byte) (int, error) {
+	h.buf = append(h.buf, bs...)
+	return len(bs), nil
+}
+
+func (h *identityHash) Sum(b []byte) []byte {
+	return append(b, h.buf...)
+}
+
+func (h *identityHash) Reset() {
+	h.buf = nil
+}
+
+func (h *identityHash) Size() int {
+	return len(h.buf)
+}
+
+func (h *identityHash) BlockSize() int {
+	return 1
+}
+
+func TestHashSerialization(t *testing.T) {
+	test := func(ion string, expected []byte) {
+		t.Run(ion, func(t *testing.T) {
+			r := NewHashReaderProvider(NewReaderString(ion), newIdentityHash)
+			for r.Next() {
+			}
+			require.NoError(t, r.Err())
+			assert.Equal(t, expected, r.Sum())
+		})
+	}
+
+	test("null", []byte{0x0B, 0x0F, 0x0E})
+	test("null.int", []byte{0x0B, 0x2F, 0x0E})
+	test("null.struct", []byte{0x0B, 0xDF, 0x0E})
+	test("false", []byte{0x0B, 0x10, 0x0E})
+	test("true", []byte{0x0B, 0x11, 0x0E})
+	test("0", []byte{0x0B, 0x20, 0x0E})
+	test("-1", []byte{0x0B, 0x30, 0x01, 0x0E})
+	test("256", []byte{0x0B, 0x20, 0x01, 0x00, 0x0E})
+	test("11", []byte{0x0B, 0x20, 0x0C, 0x0B, 0x0E})
+	test("0e0", []byte{0x0B, 0x40, 0x0E})
+	test("-0e0", []byte{0x0B, 0x40, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0E})
+	test("1e0", []byte{0x0B, 0x40, 0x3F, 0xF0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0E})
+	test("nan", []byte{0x0B, 0x40, 0x7F, 0xF8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0E})
+	test("0.", []byte{0x0B, 0x50, 0x0E})
+	test("0d1", []byte{0x0B, 0x50, 0x81, 0x0E})
+	test("-0.", []byte{0x0B, 0x50, 0x80, 0x80, 0x0E})
+	test("1.0", []byte{0x0B, 0x50, 0xC1, 0x0A, 0x0E})
+	test("-1.0", []byte{0x0B, 0x50, 0xC1, 0x8A, 0x0E})
+	test("2000T", []byte{0x0B, 0x60, 0xC0, 0x0F, 0xD0, 0x0E})
+	test("2000-01-01T00:00Z", []byte{0x0B, 0x60, 0x80, 0x0F, 0xD0, 0x81, 0x81, 0x80, 0x80, 0x0E})
+	test("a", []byte{0x0B, 0x70, 0x61, 0x0E})
+	test("$0", []byte{0x0B, 0x71, 0x0E})
+	test(`"a"`, []byte{0x0B, 0x80, 0x61, 0x0E})
+	test(`{{"a"}}`, []byte{0x0B, 0x90, 0x61, 0x0E})
+	test("{{CwwO}}", []byte{0x0B, 0xA0, 0x0C, 0x0B, 0x0C, 0x0C, 0x0C, 0x0E, 0x0E})
+	test("[]", []byte{0x0B, 0xB0, 0x0E})
+	test("[1, 2]", []byte{0x0B, 0xB0, 0x0B, 0x20, 0x01, 0x0E, 0x0B, 0x20, 0x02, 0x0E, 0x0E})
+	test("(a)", []byte{0x0B, 0xC0, 0x0B, 0x70, 0x61, 0x0E, 0x0E})
+	test("{}", []byte{0x0B, 0xD0, 0x0E})
+	test("{a:1}", []byte{0x0B, 0xD0, 0x0C, 0x0B, 0x70, 0x61, 0x0C, 0x0E, 0x0C, 0x0B, 0x20, 0x01, 0x0C, 0x0E, 0x0E})
+	test("{b:1, a:2}", []byte{0x0B, 0xD0,
+		0x0C, 0x0B, 0x70, 0x61, 0x0C, 0x0E, 0x0C, 0x0B, 0x20, 0x02, 0x0C, 0x0E,
+		0x0C, 0x0B, 0x70, 0x62, 0x0C, 0x0E, 0x0C, 0x0B, 0x20, 0x01, 0x0C, 0x0E,
+		0x0E})
+	test("a::1", []byte{0x0B, 0xE0, 0x0B, 0x70, 0x61, 0x0E, 0x0B, 0x20, 0x01, 0x0E, 0x0E})
+	test("a::b::[]", []byte{0x0B, 0xE0, 0x0B, 0x70, 0x61, 0x0E, 0x0B, 0x70, 0x62, 0x0E, 0x0B, 0xB0, 0x0E, 0x0E})
+}
+
+func TestHashUnknownSymbolText(t *testing.T) {
+	r := NewHashReader(NewReaderString("$ion_symbol_table::{imports:[{name:\"foo\", version:1, max_id:1}]} $10"))
+	assert.False(t, r.Next())
+	assert.Error(t, r.Err())
+}
@@ -0,0 +1,233 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"crypto/sha256"
+)
+
+// A HashReader is a Reader that computes the Ion Hash of each top-level value it
+// reads. Digests depend only on the Ion data model, so they are the same whether
+// the underlying data is text or binary and regardless of its symbol tables.
+//
+// Values that the caller skips over (including containers that are never stepped
+// in to) are still read and hashed in their entirety.
+type HashReader interface {
+	Reader
+
+	// Sum returns the Ion Hash of the most recently completed top-level value, or
+	// nil if no value has been completed yet. A scalar is complete as soon as the
+	// reader is positioned on it; a container is complete once the reader steps
+	// out of it or moves past it.
+	Sum() []byte
+}
+
+// hashReader wraps a Reader, hashing the values it reads.
+type hashReader struct {
+	Reader
+
+	h       hasher
+	pending bool
+	err     error
+}
+
+// NewHashReader creates a new HashReader that computes SHA-256 based Ion Hashes
+// of the values read from the given Reader.
+func NewHashReader(r Reader) HashReader {
+	return NewHashReaderProvider(r, sha256.New)
+}
+
+// NewHashReaderProvider creates a new HashReader that computes Ion Hashes using
+// hashes from the given provider.
+func NewHashReaderProvider(r Reader, hp HasherProvider) HashReader {
+	return &hashReader{
+		Reader: r,
+		h:      hasher{provider: hp},
+	}
+}
+
+// Sum returns the Ion Hash of the most recently completed top-level value.
+func (r *hashReader) Sum() []byte {
+	return r.h.sum
+}
+
+// Err returns the current error.
+func (r *hashReader) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.Reader.Err()
+}
+
+// Next moves the reader to the next value, hashing the current value first if it
+// is a container that hasn't been read yet.
+func (r *hashReader) Next() bool {
+	if r.err != nil {
+		return false
+	}
+	if r.err = r.finishPending(); r.err != nil {
+		return false
+	}
+
+	if !r.Reader.Next() {
+		return false
+	}
+
+	t := r.Reader.Type()
+	if IsContainer(t) && !r.Reader.IsNull() {
+		// Containers are hashed as they're stepped in to, or skipped over.
+		r.pending = true
+		return true
+	}
+
+	r.err = r.hashScalar(t)
+	return r.err == nil
+}
+
+// StepIn steps in to the current container value.
+func (r *hashReader) StepIn() error {
+	if r.err != nil {
+		return r.err
+	}
+	if !r.pending {
+		return r.Reader.StepIn()
+	}
+
+	t := r.Reader.Type()
+	name, as, err := r.nameAndAnnotations()
+	if err != nil {
+		return err
+	}
+	if err := r.Reader.StepIn(); err != nil {
+		return err
+	}
+
+	r.pending = false
+	if r.err = r.h.begin(name, as, t); r.err != nil {
+		return r.err
+	}
+
+	return nil
+}
+
+// StepOut steps out of the current container value, hashing any of its remaining
+// values first.
+func (r *hashReader) StepOut() error {
+	if r.err != nil {
+		return r.err
+	}
+	if len(r.h.stack) == 0 {
+		return r.Reader.StepOut()
+	}
+
+	for r.Next() {
+	}
+	if err := r.Err(); err != nil {
+		return err
+	}
+
+	if err := r.Reader.StepOut(); err != nil {
+		return err
+	}
+
+	r.h.end()
+	return nil
+}
+
+// finishPending reads and hashes the current container, if the caller didn't
+// step in to it.
+func (r *hashReader) finishPending() error {
+	if !r.pending {
+		return nil
+	}
+	if err := r.StepIn(); err != nil {
+		return err
+	}
+	return r.StepOut()
+}
+
+// hashScalar hashes the current scalar value.
+func (r *hashReader) hashScalar(t Type) error {
+	name, as, err := r.nameAndAnnotations()
+	if err != nil {
+		return err
+	}
+
+	val, err := readScalarForHash(r.Reader, t)
+	if err != nil {
+		return err
+	}
+
+	return r.h.scalar(name, as, t, val)
+}
+
+// nameAndAnnotations returns the current value's field name and annotations.
+func (r *hashReader) nameAndAnnotations() (*SymbolToken, []SymbolToken, error) {
+	name, err := r.Reader.FieldName()
+	if err != nil {
+		return nil, nil, err
+	}
+	as, err := r.Reader.Annotations()
+	if err != nil {
+		return nil, nil, err
+	}
+	return name, as, nil
+}
+
+// readScalarForHash reads the current scalar value in the form the hasher
+// expects, or nil if it is null.
+func readScalarForHash(r Reader, t Type) (interface{}, error) {
+	if r.IsNull() {
+		return nil, nil
+	}
+
+	switch t {
+	case NullType:
+		return nil, nil
+	case BoolType:
+		val, err := r.BoolValue()
+		if err != nil {
+			return nil, err
+		}
+		return *val, nil
+	case IntType:
+		return r.BigIntValue()
+	case FloatType:
+		val, err := r.FloatValue()
+		if err != nil {
+			return nil, err
+		}
+		return *val, nil
+	case DecimalType:
+		return r.DecimalValue()
+	case TimestampType:
+		val, err := r.TimestampValue()
+		if err != nil {
+			return nil, err
+		}
+		return *val, nil
+	case SymbolType:
+		return r.SymbolValue()
+	case StringType:
+		val, err := r.StringValue()
+		if err != nil {
+			return nil, err
+		}
+		return *val, nil
+	default:
+		return r.ByteValue()
+	}
+}
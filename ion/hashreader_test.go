@@ -0,0 +1,97 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const hashTestText = `a::{x:1.50,y:[2001-01-01T00:00:00.000Z,nan,-0.,"str"],z:(sym {{YWJj}} {{"clob"}}),x:null.int}
+					 123456789012345678901234567890
+					 b::c::'d'`
+
+func TestHashReaderEncodingIndependent(t *testing.T) {
+	bin, err := MarshalBinary(readSingleValue(t, []byte(`a::{x:1.50,y:[2001-01-01T00:00:00.000Z,nan,-0.,"str"],z:(sym {{YWJj}} {{"clob"}}),x:null.int}`)))
+	require.NoError(t, err)
+
+	text := NewHashReader(NewReaderString(`a::{x:null.int,z:(sym {{YWJj}} {{"clob"}}),y:[2001-01-01T00:00:00.000Z,nan,-0.,"str"],x:1.50}`))
+	require.True(t, text.Next())
+	require.False(t, text.Next())
+	require.NoError(t, text.Err())
+
+	binary := NewHashReader(NewReaderBytes(bin))
+	require.True(t, binary.Next())
+	require.False(t, binary.Next())
+	require.NoError(t, binary.Err())
+
+	assert.Len(t, text.Sum(), 32)
+	assert.Equal(t, text.Sum(), binary.Sum())
+}
+
+func TestHashReaderDistinguishesValues(t *testing.T) {
+	sum := func(ion string) []byte {
+		r := NewHashReader(NewReaderString(ion))
+		require.True(t, r.Next())
+		require.False(t, r.Next())
+		require.NoError(t, r.Err())
+		return r.Sum()
+	}
+
+	assert.Equal(t, sum("{a:1,b:2}"), sum("{b:2,a:1}"))
+	assert.NotEqual(t, sum("{a:1,b:2}"), sum("{a:2,b:1}"))
+	assert.NotEqual(t, sum("[1,2]"), sum("[2,1]"))
+	assert.NotEqual(t, sum("[1,2]"), sum("(1 2)"))
+	assert.NotEqual(t, sum("1.0"), sum("1.00"))
+	assert.NotEqual(t, sum("a::1"), sum("1"))
+	assert.NotEqual(t, sum("a"), sum(`"a"`))
+}
+
+func TestHashReaderStepIn(t *testing.T) {
+	skipped := NewHashReader(NewReaderString(hashTestText))
+	require.True(t, skipped.Next())
+	require.True(t, skipped.Next())
+	require.True(t, skipped.Next())
+	require.False(t, skipped.Next())
+	require.NoError(t, skipped.Err())
+
+	// Step in to the struct and read part of it; the rest gets hashed on StepOut.
+	r := NewHashReader(NewReaderString(hashTestText))
+	require.True(t, r.Next())
+	require.NoError(t, r.StepIn())
+	require.True(t, r.Next())
+	require.True(t, r.Next())
+	require.NoError(t, r.StepIn())
+	require.True(t, r.Next())
+	require.NoError(t, r.StepOut())
+	require.NoError(t, r.StepOut())
+	structSum := r.Sum()
+
+	// Hash just the struct, skipping over it without stepping in.
+	full := NewHashReader(NewReaderString(hashTestText[:strings.Index(hashTestText, "\n")]))
+	require.True(t, full.Next())
+	require.False(t, full.Next())
+	assert.Equal(t, structSum, full.Sum())
+
+	require.True(t, r.Next())
+	require.True(t, r.Next())
+	assert.Equal(t, skipped.Sum(), r.Sum())
+	require.False(t, r.Next())
+	assert.Error(t, r.StepOut())
+}
@@ -0,0 +1,249 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"crypto/sha256"
+	"math/big"
+)
+
+// A HashWriter is a Writer that computes the Ion Hash of each top-level value
+// written to it, in addition to passing the value on to an underlying Writer.
+type HashWriter interface {
+	Writer
+
+	// Sum returns the Ion Hash of the most recently completed top-level value, or
+	// nil if no value has been completed yet.
+	Sum() []byte
+}
+
+// hashWriter wraps a Writer, hashing the values written to it.
+type hashWriter struct {
+	Writer
+
+	h   hasher
+	err error
+
+	fieldName   *SymbolToken
+	annotations []SymbolToken
+}
+
+// NewHashWriter creates a new HashWriter that computes SHA-256 based Ion Hashes
+// of the values written to the given Writer.
+func NewHashWriter(w Writer) HashWriter {
+	return NewHashWriterProvider(w, sha256.New)
+}
+
+// NewHashWriterProvider creates a new HashWriter that computes Ion Hashes using
+// hashes from the given provider.
+func NewHashWriterProvider(w Writer, hp HasherProvider) HashWriter {
+	return &hashWriter{
+		Writer: w,
+		h:      hasher{provider: hp},
+	}
+}
+
+// Sum returns the Ion Hash of the most recently completed top-level value.
+func (w *hashWriter) Sum() []byte {
+	return w.h.sum
+}
+
+// FieldName sets the field name for the next value written.
+func (w *hashWriter) FieldName(val SymbolToken) error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.Writer.FieldName(val); err != nil {
+		return err
+	}
+	w.fieldName = &val
+	return nil
+}
+
+// Annotation adds an annotation to the next value written.
+func (w *hashWriter) Annotation(val SymbolToken) error {
+	return w.Annotations(val)
+}
+
+// Annotations adds one or more annotations to the next value written.
+func (w *hashWriter) Annotations(values ...SymbolToken) error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.Writer.Annotations(values...); err != nil {
+		return err
+	}
+	w.annotations = append(w.annotations, values...)
+	return nil
+}
+
+// WriteNull writes an untyped null.
+func (w *hashWriter) WriteNull() error {
+	return w.scalar(w.Writer.WriteNull, NullType, nil)
+}
+
+// WriteNullType writes a typed null.
+func (w *hashWriter) WriteNullType(t Type) error {
+	return w.scalar(func() error { return w.Writer.WriteNullType(t) }, t, nil)
+}
+
+// WriteBool writes a bool.
+func (w *hashWriter) WriteBool(val bool) error {
+	return w.scalar(func() error { return w.Writer.WriteBool(val) }, BoolType, val)
+}
+
+// WriteInt writes an integer.
+func (w *hashWriter) WriteInt(val int64) error {
+	return w.scalar(func() error { return w.Writer.WriteInt(val) }, IntType, val)
+}
+
+// WriteUint writes an unsigned integer.
+func (w *hashWriter) WriteUint(val uint64) error {
+	return w.scalar(func() error { return w.Writer.WriteUint(val) }, IntType, new(big.Int).SetUint64(val))
+}
+
+// WriteBigInt writes a big integer.
+func (w *hashWriter) WriteBigInt(val *big.Int) error {
+	return w.scalar(func() error { return w.Writer.WriteBigInt(val) }, IntType, val)
+}
+
+// WriteFloat writes a floating-point value.
+func (w *hashWriter) WriteFloat(val float64) error {
+	return w.scalar(func() error { return w.Writer.WriteFloat(val) }, FloatType, val)
+}
+
+// WriteDecimal writes an arbitrary-precision decimal value.
+func (w *hashWriter) WriteDecimal(val *Decimal) error {
+	return w.scalar(func() error { return w.Writer.WriteDecimal(val) }, DecimalType, val)
+}
+
+// WriteTimestamp writes a timestamp value.
+func (w *hashWriter) WriteTimestamp(val Timestamp) error {
+	return w.scalar(func() error { return w.Writer.WriteTimestamp(val) }, TimestampType, val)
+}
+
+// WriteSymbol writes a symbol given a SymbolToken.
+func (w *hashWriter) WriteSymbol(val SymbolToken) error {
+	return w.scalar(func() error { return w.Writer.WriteSymbol(val) }, SymbolType, val)
+}
+
+// WriteSymbolFromString writes a symbol value given a string.
+func (w *hashWriter) WriteSymbolFromString(val string) error {
+	return w.scalar(func() error { return w.Writer.WriteSymbolFromString(val) }, SymbolType, NewSymbolTokenFromString(val))
+}
+
+// WriteString writes a string.
+func (w *hashWriter) WriteString(val string) error {
+	return w.scalar(func() error { return w.Writer.WriteString(val) }, StringType, val)
+}
+
+// WriteClob writes a clob.
+func (w *hashWriter) WriteClob(val []byte) error {
+	return w.scalar(func() error { return w.Writer.WriteClob(val) }, ClobType, append([]byte{}, val...))
+}
+
+// WriteBlob writes a blob.
+func (w *hashWriter) WriteBlob(val []byte) error {
+	return w.scalar(func() error { return w.Writer.WriteBlob(val) }, BlobType, append([]byte{}, val...))
+}
+
+// BeginList begins writing a list.
+func (w *hashWriter) BeginList() error {
+	return w.begin(w.Writer.BeginList, ListType)
+}
+
+// EndList finishes writing a list.
+func (w *hashWriter) EndList() error {
+	return w.end(w.Writer.EndList)
+}
+
+// BeginSexp begins writing an s-expression.
+func (w *hashWriter) BeginSexp() error {
+	return w.begin(w.Writer.BeginSexp, SexpType)
+}
+
+// EndSexp finishes writing an s-expression.
+func (w *hashWriter) EndSexp() error {
+	return w.end(w.Writer.EndSexp)
+}
+
+// BeginStruct begins writing a struct.
+func (w *hashWriter) BeginStruct() error {
+	return w.begin(w.Writer.BeginStruct, StructType)
+}
+
+// EndStruct finishes writing a struct.
+func (w *hashWriter) EndStruct() error {
+	return w.end(w.Writer.EndStruct)
+}
+
+// scalar writes a scalar value to the underlying writer and hashes it.
+func (w *hashWriter) scalar(write func() error, t Type, val interface{}) error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := write(); err != nil {
+		return err
+	}
+
+	if d, ok := val.(*Decimal); ok && d == nil {
+		val = nil
+	} else if bi, ok := val.(*big.Int); ok && bi == nil {
+		val = nil
+	}
+
+	name, as := w.fieldName, w.annotations
+	w.clear()
+
+	w.err = w.h.scalar(name, as, t, val)
+	return w.err
+}
+
+// begin begins a container in the underlying writer and starts hashing it.
+func (w *hashWriter) begin(write func() error, t Type) error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := write(); err != nil {
+		return err
+	}
+
+	name, as := w.fieldName, w.annotations
+	w.clear()
+
+	w.err = w.h.begin(name, as, t)
+	return w.err
+}
+
+// end ends a container in the underlying writer and finishes hashing it.
+func (w *hashWriter) end(write func() error) error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := write(); err != nil {
+		return err
+	}
+
+	w.clear()
+	w.h.end()
+	return nil
+}
+
+// clear clears the field name and annotations after writing a value.
+func (w *hashWriter) clear() {
+	w.fieldName = nil
+	w.annotations = nil
+}
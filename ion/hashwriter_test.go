@@ -0,0 +1,75 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashWriterMatchesReader(t *testing.T) {
+	vs, err := ReadValues(NewReaderString(hashTestText))
+	require.NoError(t, err)
+
+	r := NewHashReader(NewValueReader(vs...))
+	buf := bytes.Buffer{}
+	w := NewHashWriter(NewBinaryWriter(&buf))
+
+	for _, v := range vs {
+		require.True(t, r.Next())
+		if IsContainer(r.Type()) {
+			require.NoError(t, r.StepIn())
+			require.NoError(t, r.StepOut())
+		}
+
+		require.NoError(t, v.MarshalIon(w))
+		assert.Equal(t, r.Sum(), w.Sum())
+	}
+	require.NoError(t, w.Finish())
+
+	eq, err := EquivalentBytes(buf.Bytes(), []byte(hashTestText))
+	require.NoError(t, err)
+	assert.True(t, eq)
+}
+
+func TestHashWriterSerialization(t *testing.T) {
+	buf := strings.Builder{}
+	w := NewHashWriterProvider(NewTextWriter(&buf), newIdentityHash)
+
+	require.NoError(t, w.Annotation(NewSymbolTokenFromString("a")))
+	require.NoError(t, w.BeginStruct())
+	require.NoError(t, w.FieldName(NewSymbolTokenFromString("b")))
+	require.NoError(t, w.WriteUint(1))
+	require.NoError(t, w.EndStruct())
+	require.NoError(t, w.Finish())
+
+	assert.Equal(t, "a::{b:1}\n", buf.String())
+	assert.Equal(t, []byte{0x0B, 0xE0, 0x0B, 0x70, 0x61, 0x0E, 0x0B, 0xD0,
+		0x0C, 0x0B, 0x70, 0x62, 0x0C, 0x0E, 0x0C, 0x0B, 0x20, 0x01, 0x0C, 0x0E,
+		0x0E, 0x0E}, w.Sum())
+}
+
+func TestHashWriterErrors(t *testing.T) {
+	w := NewHashWriter(NewTextWriter(&strings.Builder{}))
+	assert.Error(t, w.FieldName(NewSymbolTokenFromString("a")))
+
+	assert.Error(t, w.WriteSymbol(SymbolToken{LocalSID: 10}))
+	assert.Error(t, w.WriteInt(1))
+}
@@ -0,0 +1,524 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrNotInIndex is returned when looking up a value that isn't in an Index.
+var ErrNotInIndex = errors.New("ion: value not found in index")
+
+// An Index records the Span of each top-level value in an Ion stream, so that
+// the Nth value, or the value with a given key, can be read without scanning
+// the stream from the start. An Index can be written out as Ion alongside the
+// stream it indexes, and read back in later.
+//
+// A value's key is the value of a chosen field, if the value is a struct that
+// has it. Keys are compared as Ion, so the key 42 is different from the key
+// "42". If several values have the same key, the first one wins.
+type Index struct {
+	field string
+	spans []Span
+	keys  []Value
+	byKey map[string]int
+}
+
+// NewIndex indexes the remaining top-level values read by the given Reader,
+// which must be a SeekableReader. If field is not empty, values are also
+// indexed by the value of that field.
+func NewIndex(r Reader, field string) (*Index, error) {
+	sr, ok := r.(SeekableReader)
+	if !ok {
+		return nil, &UsageError{"NewIndex", "reader is not seekable"}
+	}
+
+	ix := &Index{field: field, byKey: map[string]int{}}
+	for r.Next() {
+		span, err := sr.Span()
+		if err != nil {
+			return nil, err
+		}
+
+		var key Value
+		if field != "" {
+			if key, err = readIndexKey(r, field); err != nil {
+				return nil, err
+			}
+		}
+		if err := ix.add(span, key); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// readIndexKey reads the value of the given field of the current value, or
+// returns nil if it's not a struct with that field.
+func readIndexKey(r Reader, field string) (Value, error) {
+	if r.Type() != StructType || r.IsNull() {
+		return nil, nil
+	}
+	if err := r.StepIn(); err != nil {
+		return nil, err
+	}
+
+	var key Value
+	for key == nil && r.Next() {
+		name, err := r.FieldName()
+		if err != nil {
+			return nil, err
+		}
+		if name == nil || name.Text == nil || *name.Text != field {
+			continue
+		}
+		if key, err = ReadValue(r); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.StepOut(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// add adds a value to the index.
+func (ix *Index) add(span Span, key Value) error {
+	ix.spans = append(ix.spans, span)
+	ix.keys = append(ix.keys, key)
+	if key == nil {
+		return nil
+	}
+
+	text, err := MarshalText(key)
+	if err != nil {
+		return err
+	}
+	if _, ok := ix.byKey[string(text)]; !ok {
+		ix.byKey[string(text)] = len(ix.spans) - 1
+	}
+	return nil
+}
+
+// Field returns the name of the field values are indexed by, if any.
+func (ix *Index) Field() string {
+	return ix.field
+}
+
+// Len returns the number of values in the index.
+func (ix *Index) Len() int {
+	return len(ix.spans)
+}
+
+// Span returns the Span of the nth value, counting from zero.
+func (ix *Index) Span(n int) (Span, error) {
+	if n < 0 || n >= len(ix.spans) {
+		return Span{}, ErrNotInIndex
+	}
+	return ix.spans[n], nil
+}
+
+// Find returns the position of the first value with the given key.
+func (ix *Index) Find(key interface{}) (int, bool) {
+	text, err := MarshalText(key)
+	if err != nil {
+		return 0, false
+	}
+	n, ok := ix.byKey[string(text)]
+	return n, ok
+}
+
+// NewReaderAtIndex creates a new seekable reader that reads the given number
+// of bytes from the given io.ReaderAt, positioned so that the following call
+// to Next moves to the nth value in the given index of it. As with
+// SeekableReader.Seek, that value is treated as the whole of the input.
+func NewReaderAtIndex(in io.ReaderAt, size int64, ix *Index, n int) (SeekableReader, error) {
+	span, err := ix.Span(n)
+	if err != nil {
+		return nil, err
+	}
+
+	r := NewReaderAt(in, size).(SeekableReader)
+	if err := r.Seek(span); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// NewReaderAtKey is like NewReaderAtIndex, but reads the first value with the
+// given key.
+func NewReaderAtKey(in io.ReaderAt, size int64, ix *Index, key interface{}) (SeekableReader, error) {
+	n, ok := ix.Find(key)
+	if !ok {
+		return nil, ErrNotInIndex
+	}
+	return NewReaderAtIndex(in, size, ix, n)
+}
+
+// WriteTo writes out the index as a single Ion struct, listing the symbol
+// tables its values use and the position of each value:
+//
+//	{
+//	  field: "id",
+//	  symbol_tables: [$ion_symbol_table::{symbols:["id", "name"]}],
+//	  values: [{offset: 4, symbol_table: 0, key: 42}, ...]
+//	}
+//
+// Text values also have a line and column. Values that use only the system
+// symbol table have no symbol_table. Symbol tables that were appended to an
+// earlier one are written out with all their symbols.
+func (ix *Index) WriteTo(w Writer) error {
+	var tables []SymbolTable
+	ids := map[SymbolTable]int{}
+	for _, span := range ix.spans {
+		if isSystemOnly(span.lst) {
+			continue
+		}
+		if _, ok := ids[span.lst]; !ok {
+			ids[span.lst] = len(tables)
+			tables = append(tables, span.lst)
+		}
+	}
+
+	if err := w.BeginStruct(); err != nil {
+		return err
+	}
+
+	if ix.field != "" {
+		if err := w.FieldName(NewSymbolTokenFromString("field")); err != nil {
+			return err
+		}
+		if err := w.WriteString(ix.field); err != nil {
+			return err
+		}
+	}
+
+	if err := w.FieldName(NewSymbolTokenFromString("symbol_tables")); err != nil {
+		return err
+	}
+	if err := w.BeginList(); err != nil {
+		return err
+	}
+	for _, st := range tables {
+		if err := writeIndexTable(w, st); err != nil {
+			return err
+		}
+	}
+	if err := w.EndList(); err != nil {
+		return err
+	}
+
+	if err := w.FieldName(NewSymbolTokenFromString("values")); err != nil {
+		return err
+	}
+	if err := w.BeginList(); err != nil {
+		return err
+	}
+	for i, span := range ix.spans {
+		if err := writeIndexEntry(w, span, ids, ix.keys[i]); err != nil {
+			return err
+		}
+	}
+	if err := w.EndList(); err != nil {
+		return err
+	}
+
+	return w.EndStruct()
+}
+
+// writeIndexTable writes out a symbol table used by an index. Tables that
+// append to an earlier local symbol table import it as an unnamed table that
+// ReadIndex couldn't find, so they're written out flattened instead, with all
+// their symbols (including any imported from shared tables) listed as local.
+func writeIndexTable(w Writer, st SymbolTable) error {
+	appended := false
+	for _, imp := range st.Imports() {
+		if imp.Name() == "" {
+			appended = true
+		}
+	}
+	if !appended {
+		return st.WriteTo(w)
+	}
+
+	ionSymbolTableText := "$ion_symbol_table"
+	if err := w.Annotation(SymbolToken{Text: &ionSymbolTableText, LocalSID: 3}); err != nil {
+		return err
+	}
+	if err := w.BeginStruct(); err != nil {
+		return err
+	}
+	if err := w.FieldName(NewSymbolTokenFromString("symbols")); err != nil {
+		return err
+	}
+	if err := w.BeginList(); err != nil {
+		return err
+	}
+
+	for id := V1SystemSymbolTable.MaxID() + 1; id <= st.MaxID(); id++ {
+		var err error
+		if sym, ok := st.FindByID(id); ok {
+			err = w.WriteString(sym)
+		} else {
+			err = w.WriteNullType(StringType)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := w.EndList(); err != nil {
+		return err
+	}
+	return w.EndStruct()
+}
+
+// isSystemOnly returns true if the given symbol table defines no symbols beyond
+// those of the system symbol table.
+func isSystemOnly(st SymbolTable) bool {
+	return st == nil || (len(st.Imports()) <= 1 && len(st.Symbols()) == 0)
+}
+
+// writeIndexEntry writes out the position of a value in an index.
+func writeIndexEntry(w Writer, span Span, ids map[SymbolTable]int, key Value) error {
+	if err := w.BeginStruct(); err != nil {
+		return err
+	}
+
+	if err := w.FieldName(NewSymbolTokenFromString("offset")); err != nil {
+		return err
+	}
+	if err := w.WriteUint(span.offset); err != nil {
+		return err
+	}
+
+	if span.line > 0 {
+		if err := w.FieldName(NewSymbolTokenFromString("line")); err != nil {
+			return err
+		}
+		if err := w.WriteUint(span.line); err != nil {
+			return err
+		}
+		if err := w.FieldName(NewSymbolTokenFromString("column")); err != nil {
+			return err
+		}
+		if err := w.WriteUint(span.column); err != nil {
+			return err
+		}
+	}
+
+	if id, ok := ids[span.lst]; ok {
+		if err := w.FieldName(NewSymbolTokenFromString("symbol_table")); err != nil {
+			return err
+		}
+		if err := w.WriteInt(int64(id)); err != nil {
+			return err
+		}
+	}
+
+	if key != nil {
+		if err := w.FieldName(NewSymbolTokenFromString("key")); err != nil {
+			return err
+		}
+		if err := key.MarshalIon(w); err != nil {
+			return err
+		}
+	}
+
+	return w.EndStruct()
+}
+
+// ReadIndex reads an index written by Index.WriteTo, using the given catalog,
+// if any, to resolve the shared symbol tables its symbol tables import.
+func ReadIndex(r Reader, cat Catalog) (*Index, error) {
+	if !r.Next() {
+		if err := r.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrNoInput
+	}
+	if r.Type() != StructType || r.IsNull() {
+		return nil, fmt.Errorf("ion: index is a %v, not a struct", r.Type())
+	}
+	if err := r.StepIn(); err != nil {
+		return nil, err
+	}
+
+	ix := &Index{byKey: map[string]int{}}
+	var tables []SymbolTable
+	for r.Next() {
+		name, err := r.FieldName()
+		if err != nil {
+			return nil, err
+		}
+		if name == nil || name.Text == nil {
+			return nil, fmt.Errorf("ion: field name is nil")
+		}
+
+		switch *name.Text {
+		case "field":
+			val, err := r.StringValue()
+			if err != nil {
+				return nil, err
+			}
+			if val != nil {
+				ix.field = *val
+			}
+		case "symbol_tables":
+			tables, err = readIndexTables(r, cat)
+		case "values":
+			err = readIndexEntries(r, tables, ix)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.StepOut(); err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// readIndexTables reads the symbol tables of an index.
+func readIndexTables(r Reader, cat Catalog) ([]SymbolTable, error) {
+	if err := r.StepIn(); err != nil {
+		return nil, err
+	}
+
+	var tables []SymbolTable
+	for r.Next() {
+		if r.Type() != StructType || r.IsNull() {
+			return nil, fmt.Errorf("ion: index symbol table is a %v, not a struct", r.Type())
+		}
+		st, err := readLocalSymbolTable(r, cat)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, st)
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.StepOut(); err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+// readIndexEntries reads the positions of the values in an index, which must
+// come after its symbol tables.
+func readIndexEntries(r Reader, tables []SymbolTable, ix *Index) error {
+	if err := r.StepIn(); err != nil {
+		return err
+	}
+
+	for r.Next() {
+		span, key, err := readIndexEntry(r, tables)
+		if err != nil {
+			return err
+		}
+		if err := ix.add(span, key); err != nil {
+			return err
+		}
+	}
+	if err := r.Err(); err != nil {
+		return err
+	}
+
+	return r.StepOut()
+}
+
+// readIndexEntry reads the position of a value in an index.
+func readIndexEntry(r Reader, tables []SymbolTable) (Span, Value, error) {
+	if r.Type() != StructType || r.IsNull() {
+		return Span{}, nil, fmt.Errorf("ion: index entry is a %v, not a struct", r.Type())
+	}
+	if err := r.StepIn(); err != nil {
+		return Span{}, nil, err
+	}
+
+	span := Span{}
+	var key Value
+	for r.Next() {
+		name, err := r.FieldName()
+		if err != nil {
+			return Span{}, nil, err
+		}
+		if name == nil || name.Text == nil {
+			return Span{}, nil, fmt.Errorf("ion: field name is nil")
+		}
+
+		switch *name.Text {
+		case "offset":
+			err = readIndexUint(r, &span.offset)
+		case "line":
+			err = readIndexUint(r, &span.line)
+		case "column":
+			err = readIndexUint(r, &span.column)
+		case "symbol_table":
+			var id uint64
+			if err = readIndexUint(r, &id); err == nil {
+				if id >= uint64(len(tables)) {
+					return Span{}, nil, fmt.Errorf("ion: index entry has unknown symbol table %v", id)
+				}
+				span.lst = tables[id]
+			}
+		case "key":
+			key, err = ReadValue(r)
+		}
+		if err != nil {
+			return Span{}, nil, err
+		}
+	}
+	if err := r.Err(); err != nil {
+		return Span{}, nil, err
+	}
+
+	if err := r.StepOut(); err != nil {
+		return Span{}, nil, err
+	}
+	return span, key, nil
+}
+
+// readIndexUint reads a non-negative int field of an index entry.
+func readIndexUint(r Reader, v *uint64) error {
+	if r.Type() != IntType || r.IsNull() {
+		return fmt.Errorf("ion: index field is a %v, not an int", r.Type())
+	}
+	i, err := r.Int64Value()
+	if err != nil {
+		return err
+	}
+	if *i < 0 {
+		return fmt.Errorf("ion: index field is negative: %v", *i)
+	}
+	*v = uint64(*i)
+	return nil
+}
@@ -0,0 +1,207 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const indexTestText = `$ion_symbol_table::{symbols:["id", "name"]}
+{id:1, name:"one"}
+{id:"two", name:two}
+$ion_symbol_table::{symbols:["other"]}
+{other:3, id:3}
+[no, key]
+{id:1, name:"duplicate"}
+`
+
+// roundTripIndex writes out the given index in both binary and text, checking
+// that each can be read back in.
+func roundTripIndex(t *testing.T, ix *Index) []*Index {
+	var out []*Index
+	for _, text := range []bool{false, true} {
+		buf := bytes.Buffer{}
+		var w Writer
+		if text {
+			w = NewTextWriter(&buf)
+		} else {
+			w = NewBinaryWriter(&buf)
+		}
+		require.NoError(t, ix.WriteTo(w))
+		require.NoError(t, w.Finish())
+
+		read, err := ReadIndex(NewReaderBytes(buf.Bytes()), nil)
+		require.NoError(t, err)
+		out = append(out, read)
+	}
+	return out
+}
+
+// readIndexed reads the nth value in the given index of the given input.
+func readIndexed(t *testing.T, in []byte, ix *Index, n int) string {
+	r, err := NewReaderAtIndex(bytes.NewReader(in), int64(len(in)), ix, n)
+	require.NoError(t, err)
+
+	require.True(t, r.Next())
+	v, err := ReadValue(r)
+	require.NoError(t, err)
+	assert.False(t, r.Next())
+	require.NoError(t, r.Err())
+
+	text, err := MarshalText(v)
+	require.NoError(t, err)
+	return string(text)
+}
+
+func testIndex(t *testing.T, in []byte) {
+	ix, err := NewIndex(NewReaderBytes(in), "id")
+	require.NoError(t, err)
+
+	expected := []string{
+		`{id:1,name:"one"}`,
+		`{id:"two",name:two}`,
+		`{other:3,id:3}`,
+		`[no,key]`,
+		`{id:1,name:"duplicate"}`,
+	}
+
+	for _, ix := range append(roundTripIndex(t, ix), ix) {
+		assert.Equal(t, "id", ix.Field())
+		require.Equal(t, len(expected), ix.Len())
+
+		for i := len(expected) - 1; i >= 0; i-- {
+			assert.Equal(t, expected[i], readIndexed(t, in, ix, i))
+		}
+
+		for key, n := range map[interface{}]int{1: 0, "two": 1, 3: 2} {
+			found, ok := ix.Find(key)
+			assert.True(t, ok, key)
+			assert.Equal(t, n, found, key)
+		}
+
+		r, err := NewReaderAtKey(bytes.NewReader(in), int64(len(in)), ix, "two")
+		require.NoError(t, err)
+		require.True(t, r.Next())
+		assert.Equal(t, StructType, r.Type())
+
+		_, ok := ix.Find("1")
+		assert.False(t, ok)
+		_, err = NewReaderAtKey(bytes.NewReader(in), int64(len(in)), ix, 2)
+		assert.Equal(t, ErrNotInIndex, err)
+		_, err = NewReaderAtIndex(bytes.NewReader(in), int64(len(in)), ix, len(expected))
+		assert.Equal(t, ErrNotInIndex, err)
+	}
+}
+
+func TestIndexBinary(t *testing.T) {
+	// Encode each part separately, so that the binary has several local symbol
+	// tables too.
+	var in []byte
+	for _, part := range strings.Split(indexTestText, "$ion_symbol_table::") {
+		if part != "" {
+			in = append(in, encodeBinary(t, part[strings.Index(part, "}")+1:])...)
+		}
+	}
+	testIndex(t, in)
+}
+
+func TestIndexText(t *testing.T) {
+	testIndex(t, []byte(indexTestText))
+
+	ix, err := NewIndex(NewReaderString(indexTestText), "")
+	require.NoError(t, err)
+	span, err := ix.Span(2)
+	require.NoError(t, err)
+
+	read := roundTripIndex(t, ix)[0]
+	readSpan, err := read.Span(2)
+	require.NoError(t, err)
+	assert.Equal(t, span.line, readSpan.line)
+	assert.Equal(t, span.column, readSpan.column)
+	assert.Equal(t, span.offset, readSpan.offset)
+}
+
+func TestIndexNoField(t *testing.T) {
+	in := encodeBinary(t, "a b c")
+	ix, err := NewIndex(NewReaderBytes(in), "")
+	require.NoError(t, err)
+
+	for _, ix := range append(roundTripIndex(t, ix), ix) {
+		assert.Equal(t, "", ix.Field())
+		require.Equal(t, 3, ix.Len())
+		assert.Equal(t, "b", readIndexed(t, in, ix, 1))
+
+		_, ok := ix.Find("b")
+		assert.False(t, ok)
+	}
+}
+
+func TestIndexNotSeekable(t *testing.T) {
+	_, err := NewIndex(&valueReader{}, "")
+	assert.IsType(t, &UsageError{}, err)
+}
+
+func TestReadIndexErrors(t *testing.T) {
+	test := func(text string) {
+		t.Run(text, func(t *testing.T) {
+			_, err := ReadIndex(NewReaderString(text), nil)
+			assert.Error(t, err)
+		})
+	}
+
+	test("")
+	test("[]")
+	test("{values:[1]}")
+	test("{values:[{offset:-1}]}")
+	test("{values:[{offset:1, symbol_table:0}]}")
+	test(fmt.Sprintf("{values:[{offset:%v}]}", `"x"`))
+}
+
+func TestIndexStreamed(t *testing.T) {
+	// A streaming writer appends to its local symbol table as it goes.
+	buf := bytes.Buffer{}
+	enc := NewBinaryEncoderStreaming(&buf)
+	for i := 0; i < 5; i++ {
+		v := map[string]interface{}{"id": i * 10, fmt.Sprintf("f%v", i): fmt.Sprintf("s%v", i)}
+		require.NoError(t, enc.Encode(v))
+	}
+	require.NoError(t, enc.Finish())
+	in := buf.Bytes()
+
+	ix, err := NewIndex(NewReaderBytes(in), "id")
+	require.NoError(t, err)
+
+	for _, ix := range append(roundTripIndex(t, ix), ix) {
+		require.Equal(t, 5, ix.Len())
+		for i := 0; i < 5; i++ {
+			r, err := NewReaderAtKey(bytes.NewReader(in), int64(len(in)), ix, i*10)
+			require.NoError(t, err)
+
+			require.True(t, r.Next())
+			v, err := ReadValue(r)
+			require.NoError(t, err)
+			s := v.(*StructValue).Get(fmt.Sprintf("f%v", i))
+			require.NotNil(t, s)
+			assert.Equal(t, fmt.Sprintf("s%v", i), s.(*StringValue).Text())
+		}
+	}
+}
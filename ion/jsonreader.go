@@ -0,0 +1,442 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// JSONReaderOpts defines a set of bit flag options for JSON readers.
+type JSONReaderOpts uint8
+
+const (
+	// JSONReaderFloats reads every JSON number with a fraction or an exponent as
+	// a float.
+	JSONReaderFloats JSONReaderOpts = 1
+
+	// JSONReaderDecimals reads every JSON number with a fraction or an exponent
+	// as a decimal, preserving its precision. It takes precedence over
+	// JSONReaderFloats.
+	JSONReaderDecimals JSONReaderOpts = 2
+
+	// JSONReaderValueStream allows any number of whitespace-separated top-level
+	// values, as in newline-delimited JSON, rather than exactly one.
+	JSONReaderValueStream JSONReaderOpts = 4
+)
+
+// NewJSONReader returns a new reader that only accepts JSON as defined by RFC
+// 8259. Ion-only syntax such as comments, annotations, unquoted or quoted
+// symbols, typed nulls, s-expressions, e-expressions, timestamps, blobs and
+// clobs, long strings, nan and infinities, non-decimal integers, and trailing
+// commas is reported as a SyntaxError. Field names are always read as text.
+//
+// Following the Ion specification's rules for reading JSON as Ion, integers are
+// read as ints, numbers with a fraction (but no exponent) as decimals, and
+// numbers with an exponent as floats. Exactly one top-level value is allowed;
+// any data after it is reported by the following call to Next.
+func NewJSONReader(in io.Reader) Reader {
+	return NewJSONReaderOpts(in, 0)
+}
+
+// NewJSONReaderOpts returns a new JSON reader with the given options.
+func NewJSONReaderOpts(in io.Reader, opts JSONReaderOpts) Reader {
+	tr := newTextReaderBuf(bufio.NewReader(in), nil, nil).(*textReader)
+	tr.tok.json = true
+	tr.jsonOpts = opts
+	return tr
+}
+
+// mapNumber applies the number mapping options to a JSON number of the given
+// type, returning the text and type to parse it as.
+func (o JSONReaderOpts) mapNumber(val string, tt Type) (string, Type) {
+	switch {
+	case tt == IntType:
+		return val, tt
+	case o&JSONReaderDecimals != 0:
+		return strings.NewReplacer("e", "d", "E", "d").Replace(val), DecimalType
+	case o&JSONReaderFloats != 0:
+		return val, FloatType
+	}
+	return val, tt
+}
+
+// verifyJSON checks that the token just read is allowed by the JSON grammar in
+// the reader's current state.
+func (t *textReader) verifyJSON() error {
+	tok := t.tok.Token()
+	pos := t.jsonTokenOffset(tok)
+
+	prev := t.jsonPrev
+	t.jsonPrev = tok
+
+	if ext := jsonExtension(tok); ext != "" {
+		return &SyntaxError{Msg: ext + " are not allowed in JSON", Offset: pos}
+	}
+
+	switch tok {
+	case tokenEOF:
+		if t.ctx.peek() == ctxAtTopLevel && !t.jsonValue && t.jsonOpts&JSONReaderValueStream == 0 {
+			return &SyntaxError{Msg: "unexpected end of JSON input", Offset: pos}
+		}
+
+	case tokenCloseBrace, tokenCloseBracket:
+		if prev == tokenComma {
+			return &SyntaxError{Msg: "trailing commas are not allowed in JSON", Offset: pos}
+		}
+
+	case tokenSymbol:
+		if t.state == trsBeforeFieldName {
+			return &SyntaxError{Msg: "field names must be quoted strings in JSON", Offset: pos}
+		}
+		return t.verifyJSONValue(pos)
+
+	case tokenString, tokenNumber, tokenOpenBrace, tokenOpenBracket:
+		if t.state == trsBeforeTypeAnnotations {
+			return t.verifyJSONValue(pos)
+		}
+	}
+	return nil
+}
+
+// verifyJSONValue checks that a value may start at the given offset, which is
+// only a problem if it's a second top-level value.
+func (t *textReader) verifyJSONValue(pos uint64) error {
+	if t.ctx.peek() != ctxAtTopLevel {
+		return nil
+	}
+	if t.jsonValue && t.jsonOpts&JSONReaderValueStream == 0 {
+		return &SyntaxError{Msg: "unexpected data after top-level JSON value", Offset: pos}
+	}
+	t.jsonValue = true
+	return nil
+}
+
+// verifyJSONSymbol checks that an unquoted symbol starting at the given offset
+// is one of the JSON literals true, false, or null.
+func (t *textReader) verifyJSONSymbol(val string, annotation, ws bool, pos uint64) error {
+	switch {
+	case annotation:
+		return &SyntaxError{Msg: "annotations are not allowed in JSON", Offset: pos}
+
+	case val == "true", val == "false":
+		return nil
+
+	case val == "null":
+		if !ws {
+			c, err := t.tok.peek()
+			if err != nil {
+				return err
+			}
+			if c == '.' {
+				return &SyntaxError{Msg: "typed nulls are not allowed in JSON", Offset: pos}
+			}
+		}
+		return nil
+
+	case val == "nan":
+		return &SyntaxError{Msg: "nan is not allowed in JSON", Offset: pos}
+	}
+	return &SyntaxError{Msg: fmt.Sprintf("unquoted symbol '%v' is not allowed in JSON", val), Offset: pos}
+}
+
+// finishJSONValue finishes reading the current value. Rather than skipping over
+// a container the caller didn't step in to, it steps through it so that its
+// contents are checked too.
+func (t *textReader) finishJSONValue() error {
+	if t.state != trsBeforeContainer || !t.tok.unfinished {
+		return nil
+	}
+
+	if err := t.StepIn(); err != nil {
+		return err
+	}
+	for t.Next() {
+	}
+	if t.err != nil {
+		return t.err
+	}
+	return t.StepOut()
+}
+
+// jsonTokenOffset returns the offset at which the current token starts.
+func (t *textReader) jsonTokenOffset(tok token) uint64 {
+	pos := t.tok.Pos()
+	switch tok {
+	case tokenSymbol, tokenSymbolOperator, tokenDot, tokenNumber, tokenBinary, tokenHex, tokenTimestamp:
+		// These are left unread for ReadValue.
+		return pos
+	case tokenOpenDoubleBrace, tokenOpenEExpression:
+		return pos - 2
+	case tokenOpenExpressionGroup, tokenLongString:
+		return pos - 3
+	case tokenFloatInf, tokenFloatMinusInf:
+		return pos - 4
+	default:
+		return pos - 1
+	}
+}
+
+// jsonExtension describes the Ion extension to JSON a token begins, or returns
+// "" if the token may appear in JSON.
+func jsonExtension(tok token) string {
+	switch tok {
+	case tokenSymbolQuoted:
+		return "quoted symbols"
+	case tokenSymbolOperator, tokenDot:
+		return "operator symbols"
+	case tokenLongString:
+		return "long strings"
+	case tokenOpenParen, tokenCloseParen:
+		return "s-expressions"
+	case tokenOpenEExpression, tokenOpenExpressionGroup:
+		return "e-expressions"
+	case tokenOpenDoubleBrace:
+		return "blobs and clobs"
+	case tokenBinary:
+		return "binary integers"
+	case tokenHex:
+		return "hexadecimal integers"
+	case tokenTimestamp:
+		return "timestamps"
+	case tokenFloatInf, tokenFloatMinusInf:
+		return "infinities"
+	}
+	return ""
+}
+
+// noJSONCommentsHandler is a commentHandler that returns an error if a comment
+// is found. A '/' that doesn't start a comment is left for the caller.
+func (t *tokenizer) noJSONCommentsHandler() (bool, error) {
+	c, err := t.peek()
+	if err != nil {
+		return false, err
+	}
+	if c == '/' || c == '*' {
+		return false, &SyntaxError{Msg: "comments are not allowed in JSON", Offset: t.pos - 1}
+	}
+	return false, nil
+}
+
+// readJSONNumber reads a number following the JSON grammar, determining its
+// type by Ion's rules for reading JSON.
+func (t *tokenizer) readJSONNumber() (string, Type, error) {
+	w := strings.Builder{}
+
+	c, err := t.read()
+	if err != nil {
+		return "", NoType, err
+	}
+
+	if c == '-' {
+		w.WriteByte('-')
+		if c, err = t.read(); err != nil {
+			return "", NoType, err
+		}
+	}
+
+	if !isDigit(c) {
+		return "", NoType, t.invalidChar(c)
+	}
+	if c == '0' {
+		w.WriteByte('0')
+		if c, err = t.read(); err != nil {
+			return "", NoType, err
+		}
+		if isDigit(c) {
+			return "", NoType, &SyntaxError{Msg: "invalid leading zeroes", Offset: t.pos - 1}
+		}
+	} else if c, err = t.readJSONDigits(c, &w); err != nil {
+		return "", NoType, err
+	}
+
+	tt := IntType
+
+	if c == '.' {
+		tt = DecimalType
+		w.WriteByte('.')
+
+		if c, err = t.read(); err != nil {
+			return "", NoType, err
+		}
+		if !isDigit(c) {
+			return "", NoType, &SyntaxError{Msg: "expected a digit after the decimal point", Offset: t.pos - 1}
+		}
+		if c, err = t.readJSONDigits(c, &w); err != nil {
+			return "", NoType, err
+		}
+	}
+
+	if c == 'e' || c == 'E' {
+		tt = FloatType
+		w.WriteByte(byte(c))
+
+		if c, err = t.read(); err != nil {
+			return "", NoType, err
+		}
+		if c == '+' || c == '-' {
+			w.WriteByte(byte(c))
+			if c, err = t.read(); err != nil {
+				return "", NoType, err
+			}
+		}
+		if !isDigit(c) {
+			return "", NoType, &SyntaxError{Msg: "expected a digit in the exponent", Offset: t.pos - 1}
+		}
+		if c, err = t.readJSONDigits(c, &w); err != nil {
+			return "", NoType, err
+		}
+	}
+
+	switch c {
+	case '_':
+		return "", NoType, &SyntaxError{Msg: "underscores are not allowed in JSON numbers", Offset: t.pos - 1}
+	case 'd', 'D':
+		return "", NoType, &SyntaxError{Msg: "decimal exponents are not allowed in JSON numbers", Offset: t.pos - 1}
+	}
+
+	ok, err := t.isStopChar(c)
+	if err != nil {
+		return "", NoType, err
+	}
+	if !ok {
+		return "", NoType, t.invalidChar(c)
+	}
+	t.unread(c)
+
+	return w.String(), tt, nil
+}
+
+// readJSONDigits reads a run of decimal digits starting with c, returning the
+// first character after them.
+func (t *tokenizer) readJSONDigits(c int, w *strings.Builder) (int, error) {
+	for isDigit(c) {
+		w.WriteByte(byte(c))
+
+		var err error
+		if c, err = t.read(); err != nil {
+			return 0, err
+		}
+	}
+	return c, nil
+}
+
+// readJSONString reads a JSON string, whose opening quote has been read.
+func (t *tokenizer) readJSONString() (string, error) {
+	start := t.pos - 1
+	ret := strings.Builder{}
+
+	for {
+		c, err := t.read()
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case c == -1:
+			return "", t.invalidChar(c)
+
+		case c == '"':
+			str := ret.String()
+			if !utf8.ValidString(str) {
+				return "", &SyntaxError{Msg: "invalid UTF-8 in JSON string", Offset: start}
+			}
+			return str, nil
+
+		case c == '\\':
+			r, err := t.readJSONEscapedChar()
+			if err != nil {
+				return "", err
+			}
+			ret.WriteRune(r)
+
+		case c < 0x20:
+			msg := fmt.Sprintf("unescaped control character %U in JSON string", c)
+			return "", &SyntaxError{Msg: msg, Offset: t.pos - 1}
+
+		default:
+			ret.WriteByte(byte(c))
+		}
+	}
+}
+
+// readJSONEscapedChar reads one of the escape sequences JSON allows, whose
+// backslash has been read. Escaped UTF-16 surrogates must come in pairs.
+func (t *tokenizer) readJSONEscapedChar() (rune, error) {
+	c, err := t.read()
+	if err != nil {
+		return 0, err
+	}
+
+	switch c {
+	case '"', '\\', '/':
+		return rune(c), nil
+	case 'b':
+		return '\b', nil
+	case 'f':
+		return '\f', nil
+	case 'n':
+		return '\n', nil
+	case 'r':
+		return '\r', nil
+	case 't':
+		return '\t', nil
+	case 'u':
+		return t.readJSONUnicodeEscape()
+	case -1:
+		return 0, t.invalidChar(c)
+	}
+
+	msg := fmt.Sprintf("invalid escape sequence '\\%c' in JSON string", c)
+	return 0, &SyntaxError{Msg: msg, Offset: t.pos - 2}
+}
+
+// readJSONUnicodeEscape reads the four hex digits of a '\u' escape, and the
+// low surrogate that must follow if they encode a high surrogate.
+func (t *tokenizer) readJSONUnicodeEscape() (rune, error) {
+	start := t.pos - 2
+
+	r, err := t.readHexEscapeSeq(4)
+	if err != nil {
+		return 0, err
+	}
+	if !utf16.IsSurrogate(r) {
+		return r, nil
+	}
+
+	cs, err := t.peekN(2)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	if r < 0xDC00 && len(cs) == 2 && cs[0] == '\\' && cs[1] == 'u' {
+		if err := t.skipN(2); err != nil {
+			return 0, err
+		}
+		r2, err := t.readHexEscapeSeq(4)
+		if err != nil {
+			return 0, err
+		}
+		if pair := utf16.DecodeRune(r, r2); pair != utf8.RuneError {
+			return pair, nil
+		}
+	}
+
+	return 0, &SyntaxError{Msg: "unpaired surrogate in JSON string", Offset: start}
+}
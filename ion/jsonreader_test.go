@@ -0,0 +1,183 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadJSON(t *testing.T) {
+	r := NewJSONReader(strings.NewReader(`{
+		"a": [0, -12, 123456789012345678901234567890, 1.50, -0.5, 2e3, 2.5E-1],
+		"b": [true, false, null],
+		"c": "x\"\\\/\b\f\n\r\té😀",
+		"$10": {},
+		"d": []
+	}`))
+
+	big, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	str := "x\"\\/\b\f\n\r\té\U0001F600"
+
+	_struct(t, r, func(t *testing.T, r Reader) {
+		_list(t, r, func(t *testing.T, r Reader) {
+			_int(t, r, 0)
+			_int(t, r, -12)
+			_bigInt(t, r, big)
+			_decimal(t, r, MustParseDecimal("1.50"))
+			_decimal(t, r, MustParseDecimal("-0.5"))
+			_float(t, r, 2000)
+			_float(t, r, 0.25)
+			_eof(t, r)
+		})
+		_list(t, r, func(t *testing.T, r Reader) {
+			_bool(t, r, true)
+			_bool(t, r, false)
+			_null(t, r, NullType)
+			_eof(t, r)
+		})
+		_string(t, r, &str)
+
+		require.True(t, r.Next())
+		name, err := r.FieldName()
+		require.NoError(t, err)
+		assert.Equal(t, "$10", *name.Text)
+		assert.Equal(t, StructType, r.Type())
+
+		_next(t, r, ListType)
+		_eof(t, r)
+	})
+	_eof(t, r)
+}
+
+func TestReadJSONNumberMapping(t *testing.T) {
+	test := func(opts JSONReaderOpts, f func(t *testing.T, r Reader)) {
+		r := NewJSONReaderOpts(strings.NewReader("[1, 1.5, 1e2, 2.50E-1]"), opts)
+		_list(t, r, f)
+		_eof(t, r)
+	}
+
+	test(JSONReaderFloats, func(t *testing.T, r Reader) {
+		_int(t, r, 1)
+		_float(t, r, 1.5)
+		_float(t, r, 100)
+		_float(t, r, 0.25)
+		_eof(t, r)
+	})
+	test(JSONReaderDecimals, func(t *testing.T, r Reader) {
+		_int(t, r, 1)
+		_decimal(t, r, MustParseDecimal("1.5"))
+		_decimal(t, r, MustParseDecimal("1d2"))
+		_decimal(t, r, MustParseDecimal("2.50d-1"))
+		_eof(t, r)
+	})
+	test(JSONReaderFloats|JSONReaderDecimals, func(t *testing.T, r Reader) {
+		_int(t, r, 1)
+		_decimal(t, r, MustParseDecimal("1.5"))
+		_decimal(t, r, MustParseDecimal("1d2"))
+		_decimal(t, r, MustParseDecimal("2.50d-1"))
+		_eof(t, r)
+	})
+}
+
+func TestReadJSONValueStream(t *testing.T) {
+	r := NewJSONReaderOpts(strings.NewReader("{\"a\": 1}\n[2]\n\"three\"\n"), JSONReaderValueStream)
+	_next(t, r, StructType)
+	_next(t, r, ListType)
+	_next(t, r, StringType)
+	_eof(t, r)
+
+	r = NewJSONReaderOpts(strings.NewReader(" \n"), JSONReaderValueStream)
+	_eof(t, r)
+}
+
+func TestReadJSONSkipped(t *testing.T) {
+	// Containers that aren't stepped in to are still checked.
+	r := NewJSONReader(strings.NewReader(`{"a": [1, {"b": [2, 'c']}]}`))
+	require.True(t, r.Next())
+	require.NoError(t, r.StepIn())
+	require.True(t, r.Next())
+	require.False(t, r.Next())
+	assert.Error(t, r.Err())
+
+	r = NewJSONReader(strings.NewReader(`[[1], {"a": (b)}]`))
+	require.True(t, r.Next())
+	require.NoError(t, r.StepIn())
+	require.True(t, r.Next())
+	assert.Error(t, r.StepOut())
+
+	r = NewJSONReader(strings.NewReader(`[[1, 2], {"a": [true]}] `))
+	require.True(t, r.Next())
+	assert.False(t, r.Next())
+	assert.NoError(t, r.Err())
+}
+
+func TestReadJSONErrors(t *testing.T) {
+	test := func(str, msg string, offset uint64) {
+		t.Run(str, func(t *testing.T) {
+			_, err := ReadValues(NewJSONReader(strings.NewReader(str)))
+			require.Error(t, err)
+
+			serr, ok := err.(*SyntaxError)
+			require.True(t, ok, "expected a SyntaxError, got %v", err)
+			assert.Equal(t, msg, serr.Msg)
+			assert.Equal(t, offset, serr.Offset)
+		})
+	}
+
+	test("", "unexpected end of JSON input", 0)
+	test("  ", "unexpected end of JSON input", 2)
+	test("1 2", "unexpected data after top-level JSON value", 2)
+	test("{} []", "unexpected data after top-level JSON value", 3)
+
+	test("// hi\n1", "comments are not allowed in JSON", 0)
+	test("[1, /* hi */ 2]", "comments are not allowed in JSON", 4)
+	test("a::1", "annotations are not allowed in JSON", 0)
+	test("[1, abc]", "unquoted symbol 'abc' is not allowed in JSON", 4)
+	test("'abc'", "quoted symbols are not allowed in JSON", 0)
+	test("[+]", "operator symbols are not allowed in JSON", 1)
+	test("null.int", "typed nulls are not allowed in JSON", 0)
+	test("nan", "nan is not allowed in JSON", 0)
+	test("[1, +inf]", "infinities are not allowed in JSON", 4)
+	test("-inf", "infinities are not allowed in JSON", 0)
+	test("'''abc'''", "long strings are not allowed in JSON", 0)
+	test("(a b)", "s-expressions are not allowed in JSON", 0)
+	test("(:values 1)", "e-expressions are not allowed in JSON", 0)
+	test("{{aGVsbG8=}}", "blobs and clobs are not allowed in JSON", 0)
+	test("[2020-01-01T]", "timestamps are not allowed in JSON", 1)
+	test("0x1F", "hexadecimal integers are not allowed in JSON", 0)
+	test("0b101", "binary integers are not allowed in JSON", 0)
+	test("[1,]", "trailing commas are not allowed in JSON", 3)
+	test(`{"a": 1,}`, "trailing commas are not allowed in JSON", 8)
+	test("{a: 1}", "field names must be quoted strings in JSON", 1)
+
+	test("1_000", "underscores are not allowed in JSON numbers", 1)
+	test("1.5d2", "decimal exponents are not allowed in JSON numbers", 3)
+	test("1.", "expected a digit after the decimal point", 2)
+	test("1e", "expected a digit in the exponent", 2)
+	test("-01", "invalid leading zeroes", 2)
+
+	test(`"\a"`, "invalid escape sequence '\\a' in JSON string", 1)
+	test(`"\x41"`, "invalid escape sequence '\\x' in JSON string", 1)
+	test("\"a\tb\"", "unescaped control character U+0009 in JSON string", 2)
+	test(`"\ud83d"`, "unpaired surrogate in JSON string", 1)
+	test(`"\ude00"`, "unpaired surrogate in JSON string", 1)
+	test("\"\xff\"", "invalid UTF-8 in JSON string", 0)
+}
@@ -0,0 +1,539 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"unicode/utf8"
+)
+
+// JSONWriterOpts defines a set of bit flag options for JSON writers.
+type JSONWriterOpts uint8
+
+const (
+	// JSONWriterPretty enables pretty-printing mode.
+	JSONWriterPretty JSONWriterOpts = 1
+
+	// JSONWriterEscapeHTML escapes <, >, and & in strings, as encoding/json does,
+	// so the output can be safely embedded in HTML.
+	JSONWriterEscapeHTML JSONWriterOpts = 2
+
+	// JSONWriterEscapeNonASCII escapes all non-ASCII characters in strings, so the
+	// output is pure ASCII.
+	JSONWriterEscapeNonASCII JSONWriterOpts = 4
+)
+
+// jsonWriter is a writer that down-converts Ion values to JSON.
+type jsonWriter struct {
+	writer
+	opts           JSONWriterOpts
+	needsSeparator bool
+	emptyContainer bool
+	emptyStream    bool
+	indent         int
+}
+
+// NewJSONWriter returns a new writer that writes JSON, following the Ion
+// specification's rules for down-converting Ion to JSON:
+//
+//   - annotations are dropped
+//   - nulls of any type are written as null
+//   - nan and infinite floats are written as null
+//   - decimals are written as JSON numbers
+//   - timestamps are written as strings holding their Ion text form
+//   - symbols (including field names) are written as strings
+//   - clobs are written as strings, with each byte read as a code point
+//   - blobs are written as base64-encoded strings
+//   - lists and sexps are written as arrays, and structs as objects
+//
+// Top-level values are separated by newlines.
+func NewJSONWriter(out io.Writer) Writer {
+	return NewJSONWriterOpts(out, 0)
+}
+
+// NewJSONWriterOpts returns a new JSON writer with the given options.
+func NewJSONWriterOpts(out io.Writer, opts JSONWriterOpts) Writer {
+	return &jsonWriter{
+		writer:      writer{out: out},
+		opts:        opts,
+		emptyStream: true,
+	}
+}
+
+// WriteNull writes an untyped null.
+func (w *jsonWriter) WriteNull() error {
+	return w.writeValue("Writer.WriteNull", "null")
+}
+
+// WriteNullType writes a typed null, which is down-converted to an untyped null.
+func (w *jsonWriter) WriteNullType(t Type) error {
+	return w.writeValue("Writer.WriteNullType", "null")
+}
+
+// WriteBool writes a boolean value.
+func (w *jsonWriter) WriteBool(val bool) error {
+	return w.writeValue("Writer.WriteBool", strconv.FormatBool(val))
+}
+
+// WriteInt writes an integer value.
+func (w *jsonWriter) WriteInt(val int64) error {
+	return w.writeValue("Writer.WriteInt", strconv.FormatInt(val, 10))
+}
+
+// WriteUint writes an unsigned integer value.
+func (w *jsonWriter) WriteUint(val uint64) error {
+	return w.writeValue("Writer.WriteUint", strconv.FormatUint(val, 10))
+}
+
+// WriteBigInt writes a (big) integer value.
+func (w *jsonWriter) WriteBigInt(val *big.Int) error {
+	return w.writeValue("Writer.WriteBigInt", val.String())
+}
+
+// WriteFloat writes a floating-point value. Since JSON has no way to represent
+// them, nan and infinities are written as null.
+func (w *jsonWriter) WriteFloat(val float64) error {
+	return w.writeValue("Writer.WriteFloat", formatJSONFloat(val))
+}
+
+// WriteDecimal writes an arbitrary-precision decimal value as a JSON number.
+func (w *jsonWriter) WriteDecimal(val *Decimal) error {
+	return w.writeValue("Writer.WriteDecimal", jsonDecimal(val))
+}
+
+// jsonDecimal returns the given decimal as a JSON number. Rather than spelling
+// out zeros, positive exponents are written in exponent form, as are negative
+// ones that would need more than a few leading zeros after the decimal point.
+// Negative zero keeps its sign, as it does for floats.
+func jsonDecimal(d *Decimal) string {
+	sign := ""
+	if d.isNegZero {
+		sign = "-"
+	}
+
+	n, exp := d.CoEx()
+	digits := len(new(big.Int).Abs(n).String())
+	if exp <= 0 && int64(-exp) <= int64(digits)+6 {
+		bs, _ := d.MarshalJSON()
+		return sign + string(bs)
+	}
+	return sign + n.String() + "e" + strconv.FormatInt(int64(exp), 10)
+}
+
+// WriteTimestamp writes a timestamp as a string.
+func (w *jsonWriter) WriteTimestamp(val Timestamp) error {
+	return w.WriteString(val.String())
+}
+
+// WriteSymbol writes a symbol as a string. A symbol with unknown text is written
+// as its symbol ID, as in Ion text.
+func (w *jsonWriter) WriteSymbol(val SymbolToken) error {
+	text, err := jsonSymbolText(val)
+	if err != nil {
+		if w.err == nil {
+			w.err = err
+		}
+		return w.err
+	}
+	return w.WriteString(text)
+}
+
+// WriteSymbolFromString writes a symbol as a string.
+func (w *jsonWriter) WriteSymbolFromString(val string) error {
+	return w.WriteString(val)
+}
+
+// WriteString writes a string.
+func (w *jsonWriter) WriteString(val string) error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.err = w.beginValue("Writer.WriteString"); w.err != nil {
+		return w.err
+	}
+
+	if w.err = w.writeString(val); w.err != nil {
+		return w.err
+	}
+
+	w.endValue()
+	return nil
+}
+
+// WriteClob writes a clob as a string, each byte of which is read as a Unicode
+// code point.
+func (w *jsonWriter) WriteClob(val []byte) error {
+	rs := make([]rune, len(val))
+	for i, c := range val {
+		rs[i] = rune(c)
+	}
+	return w.WriteString(string(rs))
+}
+
+// WriteBlob writes a blob as a base64-encoded string.
+func (w *jsonWriter) WriteBlob(val []byte) error {
+	return w.WriteString(base64.StdEncoding.EncodeToString(val))
+}
+
+// BeginList begins writing a list as an array.
+func (w *jsonWriter) BeginList() error {
+	if w.err == nil {
+		w.err = w.begin("Writer.BeginList", ctxInList, '[')
+	}
+	return w.err
+}
+
+// EndList finishes writing a list.
+func (w *jsonWriter) EndList() error {
+	if w.err == nil {
+		w.err = w.end("Writer.EndList", ctxInList, ']')
+	}
+	return w.err
+}
+
+// BeginSexp begins writing an s-expression as an array.
+func (w *jsonWriter) BeginSexp() error {
+	if w.err == nil {
+		w.err = w.begin("Writer.BeginSexp", ctxInSexp, '[')
+	}
+	return w.err
+}
+
+// EndSexp finishes writing an s-expression.
+func (w *jsonWriter) EndSexp() error {
+	if w.err == nil {
+		w.err = w.end("Writer.EndSexp", ctxInSexp, ']')
+	}
+	return w.err
+}
+
+// BeginStruct begins writing a struct as an object.
+func (w *jsonWriter) BeginStruct() error {
+	if w.err == nil {
+		w.err = w.begin("Writer.BeginStruct", ctxInStruct, '{')
+	}
+	return w.err
+}
+
+// EndStruct finishes writing a struct.
+func (w *jsonWriter) EndStruct() error {
+	if w.err == nil {
+		w.err = w.end("Writer.EndStruct", ctxInStruct, '}')
+	}
+	return w.err
+}
+
+// Finish finishes writing the current datagram.
+func (w *jsonWriter) Finish() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.ctx.peek() != ctxAtTopLevel {
+		return &UsageError{"Writer.Finish", "not at top level"}
+	}
+
+	if !w.emptyStream {
+		if w.err = writeRawChar('\n', w.out); w.err != nil {
+			return w.err
+		}
+		w.needsSeparator = false
+		w.emptyStream = true
+	}
+
+	w.clear()
+	return nil
+}
+
+// pretty returns true if we're pretty-printing.
+func (w *jsonWriter) pretty() bool {
+	return w.opts&JSONWriterPretty == JSONWriterPretty
+}
+
+// writeValue writes a raw JSON value to the output stream.
+func (w *jsonWriter) writeValue(api string, val string) error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.err = w.beginValue(api); w.err != nil {
+		return w.err
+	}
+
+	if w.err = writeRawString(val, w.out); w.err != nil {
+		return w.err
+	}
+
+	w.endValue()
+	return nil
+}
+
+// beginValue begins the process of writing a value, by writing out a separator
+// (if needed) and field name (if in a struct). Annotations are dropped.
+func (w *jsonWriter) beginValue(api string) error {
+	name := w.fieldName
+	w.clear()
+
+	if w.needsSeparator {
+		if err := w.writeSeparator(); err != nil {
+			return err
+		}
+	}
+
+	if w.emptyContainer && w.pretty() {
+		if err := writeRawChar('\n', w.out); err != nil {
+			return err
+		}
+	}
+
+	if w.pretty() {
+		if err := w.writeIndent(); err != nil {
+			return err
+		}
+	}
+
+	if w.IsInStruct() {
+		if err := w.writeFieldName(api, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeSeparator writes out the character or characters that separate values.
+func (w *jsonWriter) writeSeparator() error {
+	if w.ctx.peek() == ctxAtTopLevel {
+		return writeRawChar('\n', w.out)
+	}
+	if w.pretty() {
+		return writeRawString(",\n", w.out)
+	}
+	return writeRawChar(',', w.out)
+}
+
+// writeFieldName writes a field name inside a struct.
+func (w *jsonWriter) writeFieldName(api string, name *SymbolToken) error {
+	if name == nil {
+		return &UsageError{api, "field name not set"}
+	}
+
+	text, err := jsonSymbolText(*name)
+	if err != nil {
+		return err
+	}
+	if err := w.writeString(text); err != nil {
+		return err
+	}
+
+	sep := ":"
+	if w.pretty() {
+		sep = ": "
+	}
+	return writeRawString(sep, w.out)
+}
+
+// writeString writes out a quoted and escaped JSON string.
+func (w *jsonWriter) writeString(s string) error {
+	if err := writeRawChar('"', w.out); err != nil {
+		return err
+	}
+	if err := writeJSONEscapedString(s, w.opts, w.out); err != nil {
+		return err
+	}
+	return writeRawChar('"', w.out)
+}
+
+// endValue finishes the process of writing a value.
+func (w *jsonWriter) endValue() {
+	w.needsSeparator = true
+	w.emptyContainer = false
+	w.emptyStream = false
+}
+
+// begin starts writing a container of the given type.
+func (w *jsonWriter) begin(api string, t ctx, c byte) error {
+	if err := w.beginValue(api); err != nil {
+		return err
+	}
+
+	w.ctx.push(t)
+	w.indent++
+	w.needsSeparator = false
+	w.emptyContainer = true
+
+	return writeRawChar(c, w.out)
+}
+
+// end finishes writing a container of the given type.
+func (w *jsonWriter) end(api string, t ctx, c byte) error {
+	if w.ctx.peek() != t {
+		return &UsageError{api, "not in that kind of container"}
+	}
+
+	w.indent--
+
+	if !w.emptyContainer && w.pretty() {
+		if err := writeRawChar('\n', w.out); err != nil {
+			return err
+		}
+		if err := w.writeIndent(); err != nil {
+			return err
+		}
+	}
+
+	if err := writeRawChar(c, w.out); err != nil {
+		return err
+	}
+
+	w.clear()
+	w.ctx.pop()
+	w.endValue()
+
+	return nil
+}
+
+// writeIndent writes out tabs to indent a pretty-printed value.
+func (w *jsonWriter) writeIndent() error {
+	for i := 0; i < w.indent; i++ {
+		if err := writeRawChar('\t', w.out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonSymbolText returns the text a symbol is down-converted to.
+func jsonSymbolText(val SymbolToken) (string, error) {
+	switch {
+	case val.Text != nil:
+		return *val.Text, nil
+	case val.LocalSID != SymbolIDUnknown:
+		return fmt.Sprintf("$%v", val.LocalSID), nil
+	}
+	return "", fmt.Errorf("ion: invalid symbol token")
+}
+
+// formatJSONFloat formats a float the way encoding/json does, except that nan and
+// infinities, which JSON can't represent, are formatted as null.
+func formatJSONFloat(val float64) string {
+	if math.IsNaN(val) || math.IsInf(val, 0) {
+		return "null"
+	}
+
+	abs := math.Abs(val)
+	format := byte('f')
+	if abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+
+	bs := strconv.AppendFloat(nil, val, format, -1, 64)
+	if format == 'e' {
+		// Clean up e-09 to e-9.
+		n := len(bs)
+		if n >= 4 && bs[n-4] == 'e' && bs[n-3] == '-' && bs[n-2] == '0' {
+			bs[n-2] = bs[n-1]
+			bs = bs[:n-1]
+		}
+	}
+	return string(bs)
+}
+
+// writeJSONEscapedString writes out a string, escaping it as JSON requires along
+// with any other characters the options ask for. Invalid UTF-8 is replaced with
+// U+FFFD.
+func writeJSONEscapedString(s string, opts JSONWriterOpts, out io.Writer) error {
+	start := 0
+	flush := func(i int) error {
+		if start < i {
+			if err := writeRawString(s[start:i], out); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			var esc string
+			switch {
+			case c == '"':
+				esc = `\"`
+			case c == '\\':
+				esc = `\\`
+			case c == '\n':
+				esc = `\n`
+			case c == '\r':
+				esc = `\r`
+			case c == '\t':
+				esc = `\t`
+			case c < 0x20, opts&JSONWriterEscapeHTML != 0 && (c == '<' || c == '>' || c == '&'):
+				esc = fmt.Sprintf(`\u%04x`, c)
+			}
+
+			if esc != "" {
+				if err := flush(i); err != nil {
+					return err
+				}
+				if err := writeRawString(esc, out); err != nil {
+					return err
+				}
+				start = i + 1
+			}
+			i++
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		var esc string
+		switch {
+		case r == utf8.RuneError && size == 1:
+			esc = `\ufffd`
+		case r == '\u2028' || r == '\u2029':
+			// Valid JSON, but not valid JavaScript.
+			esc = fmt.Sprintf(`\u%04x`, r)
+		case opts&JSONWriterEscapeNonASCII != 0:
+			esc = jsonEscapeRune(r)
+		}
+
+		if esc != "" {
+			if err := flush(i); err != nil {
+				return err
+			}
+			if err := writeRawString(esc, out); err != nil {
+				return err
+			}
+			start = i + size
+		}
+		i += size
+	}
+
+	return flush(len(s))
+}
+
+// jsonEscapeRune escapes a rune as one \u escape, or two if it lies outside the
+// Basic Multilingual Plane.
+func jsonEscapeRune(r rune) string {
+	if r < 0x10000 {
+		return fmt.Sprintf(`\u%04x`, r)
+	}
+	r -= 0x10000
+	return fmt.Sprintf(`\u%04x\u%04x`, 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+}
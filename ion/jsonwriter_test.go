@@ -0,0 +1,213 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteJSONScalars(t *testing.T) {
+	testJSONWriter(t, 0, `[null,null,true,false,-5,18446744073709551615,123456789012345678901234567890]`, func(w Writer) {
+		require.NoError(t, w.BeginList())
+		require.NoError(t, w.WriteNull())
+		require.NoError(t, w.WriteNullType(StructType))
+		require.NoError(t, w.WriteBool(true))
+		require.NoError(t, w.WriteBool(false))
+		require.NoError(t, w.WriteInt(-5))
+		require.NoError(t, w.WriteUint(math.MaxUint64))
+		bi, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+		require.NoError(t, w.WriteBigInt(bi))
+		require.NoError(t, w.EndList())
+	})
+}
+
+func TestWriteJSONFloat(t *testing.T) {
+	test := func(val float64, expected string) {
+		testJSONWriter(t, 0, expected, func(w Writer) {
+			require.NoError(t, w.WriteFloat(val))
+		})
+	}
+
+	test(0, "0")
+	test(1.5, "1.5")
+	test(-100, "-100")
+	test(1e21, "1e+21")
+	test(1e-7, "1e-7")
+	test(math.NaN(), "null")
+	test(math.Inf(1), "null")
+	test(math.Inf(-1), "null")
+}
+
+func TestWriteJSONDecimal(t *testing.T) {
+	test := func(val, expected string) {
+		testJSONWriter(t, 0, expected, func(w Writer) {
+			require.NoError(t, w.WriteDecimal(MustParseDecimal(val)))
+		})
+	}
+
+	test("1.", "1")
+	test("1.50", "1.5")
+	test("-1.5d-3", "-0.0015")
+	test("15d2", "15e2")
+	test("0d5", "0e5")
+	test("1d100", "1e100")
+	test("-1d1000000000", "-1e1000000000")
+	test("1d-7", "0.0000001")
+	test("1d-8", "1e-8")
+	test("123d-9", "0.000000123")
+	test("123d-10", "123e-10")
+
+	test("-0.", "-0")
+	test("-0.0", "-0")
+	test("-0d3", "-0e3")
+	test("-0d-20", "-0e-20")
+}
+
+func TestWriteJSONText(t *testing.T) {
+	testJSONWriter(t, 0, `["2001-01-02T03:04Z","foo","$10","bar","{{b}}","AQID"]`, func(w Writer) {
+		ts, err := NewTimestampFromStr("2001-01-02T03:04Z", TimestampPrecisionMinute, TimezoneUTC)
+		require.NoError(t, err)
+
+		require.NoError(t, w.BeginList())
+		require.NoError(t, w.WriteTimestamp(ts))
+		require.NoError(t, w.WriteSymbol(NewSymbolTokenFromString("foo")))
+		require.NoError(t, w.WriteSymbol(SymbolToken{LocalSID: 10}))
+		require.NoError(t, w.WriteSymbolFromString("bar"))
+		require.NoError(t, w.WriteClob([]byte("{{b}}")))
+		require.NoError(t, w.WriteBlob([]byte{1, 2, 3}))
+		require.NoError(t, w.EndList())
+	})
+
+	// Clob bytes are read as code points.
+	testJSONWriter(t, 0, `"é"`, func(w Writer) {
+		require.NoError(t, w.WriteClob([]byte{0xE9}))
+	})
+}
+
+func TestWriteJSONEscapes(t *testing.T) {
+	test := func(opts JSONWriterOpts, val, expected string) {
+		testJSONWriter(t, opts, expected, func(w Writer) {
+			require.NoError(t, w.WriteString(val))
+		})
+	}
+
+	test(0, "a\"b\\c\n\r\t\x00\x1f", `"a\"b\\c\n\r\t\u0000\u001f"`)
+	test(0, "<&>", `"<&>"`)
+	test(0, "caf\u00e9 \u2028\u2029", "\"caf\u00e9 \\u2028\\u2029\"")
+	test(0, "bad \xff", `"bad \ufffd"`)
+	test(JSONWriterEscapeHTML, "<&>", `"\u003c\u0026\u003e"`)
+	test(JSONWriterEscapeNonASCII, "caf\u00e9 \U0001F600", `"caf\u00e9 \ud83d\ude00"`)
+}
+
+func TestWriteJSONContainers(t *testing.T) {
+	testJSONWriter(t, 0, `{"a":[1,["+",2]],"$1":{},"":[]}`, func(w Writer) {
+		require.NoError(t, w.Annotation(NewSymbolTokenFromString("dropped")))
+		require.NoError(t, w.BeginStruct())
+
+		require.NoError(t, w.FieldName(NewSymbolTokenFromString("a")))
+		require.NoError(t, w.BeginList())
+		require.NoError(t, w.WriteInt(1))
+		require.NoError(t, w.Annotation(NewSymbolTokenFromString("dropped")))
+		require.NoError(t, w.BeginSexp())
+		require.NoError(t, w.WriteSymbolFromString("+"))
+		require.NoError(t, w.WriteInt(2))
+		require.NoError(t, w.EndSexp())
+		require.NoError(t, w.EndList())
+
+		require.NoError(t, w.FieldName(SymbolToken{LocalSID: 1}))
+		require.NoError(t, w.BeginStruct())
+		require.NoError(t, w.EndStruct())
+
+		require.NoError(t, w.FieldName(NewSymbolTokenFromString("")))
+		require.NoError(t, w.BeginSexp())
+		require.NoError(t, w.EndSexp())
+
+		require.NoError(t, w.EndStruct())
+	})
+}
+
+func TestWriteJSONPretty(t *testing.T) {
+	expected := "{\n\t\"a\": [\n\t\t1,\n\t\t2\n\t],\n\t\"b\": {}\n}\n[]"
+	testJSONWriter(t, JSONWriterPretty, expected, func(w Writer) {
+		require.NoError(t, w.BeginStruct())
+		require.NoError(t, w.FieldName(NewSymbolTokenFromString("a")))
+		require.NoError(t, w.BeginList())
+		require.NoError(t, w.WriteInt(1))
+		require.NoError(t, w.WriteInt(2))
+		require.NoError(t, w.EndList())
+		require.NoError(t, w.FieldName(NewSymbolTokenFromString("b")))
+		require.NoError(t, w.BeginStruct())
+		require.NoError(t, w.EndStruct())
+		require.NoError(t, w.EndStruct())
+		require.NoError(t, w.BeginList())
+		require.NoError(t, w.EndList())
+	})
+}
+
+func TestWriteJSONErrors(t *testing.T) {
+	buf := strings.Builder{}
+	w := NewJSONWriter(&buf)
+	assert.Error(t, w.FieldName(NewSymbolTokenFromString("a")))
+
+	w = NewJSONWriter(&buf)
+	require.NoError(t, w.BeginStruct())
+	assert.Error(t, w.WriteInt(1))
+	assert.Error(t, w.Finish())
+
+	w = NewJSONWriter(&buf)
+	require.NoError(t, w.BeginList())
+	assert.Error(t, w.EndStruct())
+	assert.Error(t, w.Finish())
+
+	w = NewJSONWriter(&buf)
+	assert.Error(t, w.WriteSymbol(SymbolToken{LocalSID: SymbolIDUnknown}))
+}
+
+func TestWriteJSONValues(t *testing.T) {
+	text := `a::{b: (c 1.5 2e0), d: [null.int, nan, 2001T, {{aGVsbG8=}}, {{"hi"}}], e: +inf}
+		'$ion_symbol_table'`
+	vals, err := ReadValues(NewReaderString(text))
+	require.NoError(t, err)
+
+	buf := strings.Builder{}
+	e := NewJSONEncoder(&buf)
+	for _, v := range vals {
+		require.NoError(t, e.Encode(v))
+	}
+	require.NoError(t, e.Finish())
+
+	expected := `{"b":["c",1.5,2],"d":[null,null,"2001T","aGVsbG8=","hi"],"e":null}` + "\n" + `"$ion_symbol_table"` + "\n"
+	assert.Equal(t, expected, buf.String())
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		assert.True(t, json.Valid([]byte(line)), line)
+	}
+}
+
+func testJSONWriter(t *testing.T, opts JSONWriterOpts, expected string, f func(Writer)) {
+	buf := strings.Builder{}
+	w := NewJSONWriterOpts(&buf, opts)
+	f(w)
+	require.NoError(t, w.Finish())
+	assert.Equal(t, expected+"\n", buf.String())
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import "io"
+
+// A LenientReader is a text Reader that carries on past syntax errors rather
+// than giving up on the rest of its input.
+//
+// When a LenientReader encounters a syntax error, it records the error (with
+// its Location) and abandons the top-level value it was reading. If it was
+// stepped in to that value, Next returns false until the caller steps back out to
+// the top level, and ReadValues drops the partial value. The reader then skips
+// ahead past the delimiter that closes the abandoned value, taking strings,
+// symbols, lobs, and comments into account, and carries on reading top-level
+// values from there. An error outside of any container abandons just the bad
+// token. Other errors, such as I/O errors, are still reported by Err.
+type LenientReader interface {
+	Reader
+
+	// Errors returns the syntax errors the Reader has recovered from so far.
+	Errors() []error
+}
+
+// NewLenientReader creates a new LenientReader that reads text Ion from the
+// given input.
+func NewLenientReader(in io.Reader) LenientReader {
+	return NewLenientReaderCat(in, nil)
+}
+
+// NewLenientReaderCat creates a new LenientReader with the given catalog.
+func NewLenientReaderCat(in io.Reader, cat Catalog) LenientReader {
+	br, src, _ := openInput(in, false)
+	t := newTextReaderBuf(br, cat, nil).(*textReader)
+	t.src = src
+	t.lenient = true
+	return t
+}
+
+// Errors returns the syntax errors a lenient reader has recovered from.
+func (t *textReader) Errors() []error {
+	return t.errs
+}
+
+// recoverFrom records a syntax error, returning false if the reader isn't lenient.
+func (t *textReader) recoverFrom(err error) bool {
+	if !t.lenient {
+		return false
+	}
+	t.errs = append(t.errs, err)
+	if !t.recovering {
+		t.recovering = true
+		t.unclosed = t.tok.depth
+		if t.tok.errDepth > t.unclosed {
+			t.unclosed = t.tok.errDepth
+		}
+		// A stray closing delimiter has already closed a container.
+		if e, ok := err.(*UnexpectedTokenError); ok && t.unclosed > 0 {
+			if e.Token == "]" || e.Token == ")" || e.Token == "}" {
+				t.unclosed--
+			}
+		}
+	}
+	t.tok.errDepth = 0
+	t.clear()
+	return true
+}
+
+// resync skips ahead to the end of the top-level value that was abandoned
+// after an error. It returns false if there are no more values to read.
+func (t *textReader) resync() bool {
+	t.recovering = false
+	t.exp = nil
+	t.clear()
+	t.tok.SetFinished()
+	t.state = trsBeforeTypeAnnotations
+
+	depth := t.unclosed
+	t.unclosed = 0
+
+	// Make sure a bad token the tokenizer didn't consume can't stop us twice.
+	if depth == 0 && t.tok.pos == t.resyncedAt {
+		c, err := t.tok.read()
+		if err != nil {
+			t.explode(err)
+			return false
+		}
+		if c == -1 {
+			t.eof = true
+			return false
+		}
+	}
+
+	for depth > 0 {
+		c, _, err := t.tok.skipWhitespace()
+		if err != nil {
+			t.explode(err)
+			return false
+		}
+
+		switch c {
+		case -1:
+			t.eof = true
+			return false
+
+		case ']', ')', '}':
+			depth--
+
+		case '[', '(':
+			depth++
+
+		case '{':
+			if c, err = t.tok.peek(); err == nil && c == '{' {
+				if _, err = t.tok.read(); err == nil {
+					err = t.tok.skipBlobHelper()
+				}
+			} else {
+				depth++
+			}
+
+		case '"':
+			err = t.tok.skipStringHelper()
+
+		case '\'':
+			var long bool
+			if long, err = t.tok.IsTripleQuote(); err == nil {
+				if long {
+					err = t.tok.skipLongStringHelper(t.tok.skipCommentsHandler)
+				} else {
+					err = t.tok.skipSymbolQuotedHelper()
+				}
+			}
+		}
+
+		// Anything malformed in the rest of the value is part of the same error,
+		// but running out of input isn't.
+		if _, ok := err.(*UnexpectedEOFError); ok {
+			t.eof = true
+			return false
+		}
+		if _, loc := errorLocation(err); err != nil && loc == nil {
+			t.explode(err)
+			return false
+		}
+	}
+
+	t.resyncedAt = t.tok.pos
+	return true
+}
+
+// errorCount returns the number of errors r has recovered from, if it's a
+// LenientReader.
+func errorCount(r Reader) int {
+	if lr, ok := r.(LenientReader); ok {
+		return len(lr.Errors())
+	}
+	return 0
+}
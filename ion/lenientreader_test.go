@@ -0,0 +1,170 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readLenient reads the given text with a lenient reader, returning the text
+// of the values it read and the errors it recovered from.
+func readLenient(t *testing.T, str string) ([]string, []error) {
+	r := NewLenientReader(strings.NewReader(str))
+	vals, err := ReadValues(r)
+	require.NoError(t, err)
+
+	var texts []string
+	for _, v := range vals {
+		text, err := MarshalText(v)
+		require.NoError(t, err)
+		texts = append(texts, string(text))
+	}
+	return texts, r.Errors()
+}
+
+func TestLenientReader(t *testing.T) {
+	texts, errs := readLenient(t, strings.Join([]string{
+		`{a:1}`,
+		`{b:[1, 2, 1.2.3],`,
+		`  c:"d"}`,
+		`e::f`,
+		`[g, h`,
+		`  i j]`,
+		`}`,
+		`"k" 'l`,
+		`m`,
+	}, "\n"))
+
+	assert.Equal(t, []string{"{a:1}", "e::f", `"k"`, "m"}, texts)
+	require.Len(t, errs, 4)
+
+	_, loc := errorLocation(errs[0])
+	require.NotNil(t, loc)
+	assert.Equal(t, Location{Line: 2, Column: 14, Path: ".b[2]"}, *loc)
+
+	_, loc = errorLocation(errs[1])
+	require.NotNil(t, loc)
+	assert.Equal(t, Location{Line: 6, Column: 2, Path: "[2]"}, *loc)
+
+	// The list closed on the line before, so the brace is an error of its own.
+	_, loc = errorLocation(errs[2])
+	require.NotNil(t, loc)
+	assert.Equal(t, Location{Line: 7, Column: 1}, *loc)
+
+	_, loc = errorLocation(errs[3])
+	require.NotNil(t, loc)
+	assert.Equal(t, uint64(8), loc.Line)
+}
+
+func TestLenientReaderSameLine(t *testing.T) {
+	texts, errs := readLenient(t, "1 [2,, 3] {a: 4} 5")
+	assert.Equal(t, []string{"1", "{a:4}", "5"}, texts)
+	require.Len(t, errs, 1)
+	_, loc := errorLocation(errs[0])
+	require.NotNil(t, loc)
+	assert.Equal(t, Location{Line: 1, Column: 6, Path: "[1]"}, *loc)
+
+	texts, errs = readLenient(t, "{a:1} {b:2,,} {c:3}")
+	assert.Equal(t, []string{"{a:1}", "{c:3}"}, texts)
+	assert.Len(t, errs, 1)
+
+	// Stray tokens outside of any container cost just themselves.
+	texts, errs = readLenient(t, "1 ] 2 } 3 , 4")
+	assert.Equal(t, []string{"1", "2", "3", "4"}, texts)
+	assert.Len(t, errs, 3)
+}
+
+func TestLenientReaderBadValues(t *testing.T) {
+	texts, errs := readLenient(t, "{{ bad }} 11")
+	assert.Equal(t, []string{"11"}, texts)
+	require.Len(t, errs, 1)
+	assert.IsType(t, &SyntaxError{}, errs[0])
+
+	texts, errs = readLenient(t, "{a:[2020-13-01T]} {b:[{{ bad }}, 2]} 11")
+	assert.Equal(t, []string{"11"}, texts)
+	assert.Len(t, errs, 2)
+}
+
+func TestLenientReaderIndented(t *testing.T) {
+	texts, errs := readLenient(t, strings.Join([]string{
+		`{`,
+		`  a: [1, 2,, 3],`,
+		`  b: "]}",`,
+		`  c: '''}''', // ]`,
+		`  d: {{ +/+/ }},`,
+		`  /* } */ e: 'f}'`,
+		`}`,
+		`  {g: 5}`,
+		`  [(h i`,
+		`    j)]`,
+		`    k`,
+	}, "\n"))
+
+	assert.Equal(t, []string{"{g:5}", "[(h i j)]", "k"}, texts)
+	require.Len(t, errs, 1)
+	_, loc := errorLocation(errs[0])
+	require.NotNil(t, loc)
+	assert.Equal(t, Location{Line: 2, Column: 12, Path: ".a[2]"}, *loc)
+}
+
+func TestLenientReaderStepOut(t *testing.T) {
+	r := NewLenientReader(strings.NewReader("[1, {a:(2 ]}] 3"))
+	require.True(t, r.Next())
+	require.NoError(t, r.StepIn())
+	_int(t, r, 1)
+
+	require.True(t, r.Next())
+	require.NoError(t, r.StepIn())
+	require.True(t, r.Next())
+	require.NoError(t, r.StepIn())
+	_int(t, r, 2)
+
+	// The rest of the value is abandoned.
+	assert.False(t, r.Next())
+	require.NoError(t, r.StepOut())
+	assert.False(t, r.Next())
+	require.NoError(t, r.StepOut())
+	assert.False(t, r.Next())
+	require.NoError(t, r.StepOut())
+
+	_int(t, r, 3)
+	_eof(t, r)
+	assert.Len(t, r.Errors(), 1)
+	assert.Error(t, r.StepOut())
+}
+
+func TestLenientReaderEOF(t *testing.T) {
+	texts, errs := readLenient(t, "1 (2\n   3")
+	assert.Equal(t, []string{"1"}, texts)
+	require.Len(t, errs, 1)
+	assert.IsType(t, &UnexpectedEOFError{}, errs[0])
+
+	texts, errs = readLenient(t, "1\n2")
+	assert.Equal(t, []string{"1", "2"}, texts)
+	assert.Empty(t, errs)
+}
+
+func TestStrictReaderStopsAtError(t *testing.T) {
+	r := NewReaderString("1 ]\n2")
+	_int(t, r, 1)
+	assert.False(t, r.Next())
+	assert.IsType(t, &UnexpectedTokenError{}, r.Err())
+	assert.False(t, r.Next())
+}
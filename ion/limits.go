@@ -0,0 +1,227 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// ReaderLimits bounds the resources a Reader spends on its input, for reading
+// input from untrusted sources. A Reader returns a LimitError as soon as its
+// input exceeds one of them. Zero fields impose no limit.
+type ReaderLimits struct {
+	// MaxDepth limits how deeply containers may be nested, whether they're
+	// stepped in to or skipped over.
+	MaxDepth int
+
+	// MaxScalarSize limits the size, in bytes of input, of a single scalar value:
+	// a string, symbol, lob, number, or timestamp.
+	MaxScalarSize uint64
+
+	// MaxAnnotations limits the number of annotations on a single value.
+	MaxAnnotations int
+
+	// MaxLocalSymbols limits the number of symbols a local symbol table may
+	// define, including any it imports other than the system symbols.
+	MaxLocalSymbols int
+
+	// MaxIntBits limits the size, in bits, of the magnitude of an int or of the
+	// coefficient of a decimal.
+	MaxIntBits int
+
+	// MaxDecimalExponent limits the magnitude of the exponent of a decimal.
+	MaxDecimalExponent int
+
+	// MaxBytes limits the total number of bytes of input the Reader consumes.
+	MaxBytes uint64
+}
+
+// A LimitError is returned when a Reader's input exceeds one of its ReaderLimits.
+type LimitError struct {
+	Limit  string
+	Max    uint64
+	Offset uint64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("ion: input exceeds %v limit of %v (offset %v)", e.Limit, e.Max, e.Offset)
+}
+
+// NewReaderLimits creates a new reader that reads text or binary Ion from the
+// given input, within the given limits.
+func NewReaderLimits(in io.Reader, limits ReaderLimits) Reader {
+	br, src, binary := openInput(in, false)
+	if binary {
+		r := newBinaryReaderBuf(br, nil, nil).(*binaryReader)
+		r.src = src
+		r.setLimits(limits)
+		return r
+	}
+
+	t := newTextReaderBuf(br, nil, nil).(*textReader)
+	t.src = src
+	t.setLimits(limits)
+	return t
+}
+
+// NewReaderBytesLimits creates a new reader for the given bytes, within the
+// given limits.
+func NewReaderBytesLimits(in []byte, limits ReaderLimits) Reader {
+	if hasBVM(in) {
+		r := newBinaryReaderBytes(in, nil, nil).(*binaryReader)
+		r.setLimits(limits)
+		return r
+	}
+	return NewReaderLimits(bytes.NewReader(in), limits)
+}
+
+// NewDecoderLimits creates a new decoder that reads text or binary Ion from the
+// given input, within the given limits.
+func NewDecoderLimits(in io.Reader, limits ReaderLimits) *Decoder {
+	return NewDecoder(NewReaderLimits(in, limits))
+}
+
+// readerLimits returns the limits the reader enforces.
+func (r *reader) readerLimits() *ReaderLimits {
+	return &r.limits
+}
+
+// limitsOf returns the limits r enforces, or nil if it doesn't.
+func limitsOf(r Reader) *ReaderLimits {
+	if l, ok := r.(interface{ readerLimits() *ReaderLimits }); ok {
+		return l.readerLimits()
+	}
+	return nil
+}
+
+// positionOf returns r's Position, or the zero Position if r isn't a
+// PositionReader.
+func positionOf(r Reader) Position {
+	if pr, ok := r.(PositionReader); ok {
+		return pr.Position()
+	}
+	return Position{}
+}
+
+// setLimits sets the limits a binary reader enforces.
+func (r *binaryReader) setLimits(limits ReaderLimits) {
+	r.limits = limits
+	r.applyLimits()
+}
+
+// applyLimits passes the reader's limits on to its bitstreams.
+func (r *binaryReader) applyLimits() {
+	r.bits.maxPos = r.limits.MaxBytes
+	r.bits11.maxDepth = r.limits.MaxDepth
+}
+
+// setLimits sets the limits a text reader enforces.
+func (t *textReader) setLimits(limits ReaderLimits) {
+	t.limits = limits
+	t.applyLimits()
+}
+
+// applyLimits passes the reader's limits on to its tokenizer.
+func (t *textReader) applyLimits() {
+	t.tok.maxPos = t.limits.MaxBytes
+	t.tok.maxScalar = t.limits.MaxScalarSize
+	t.tok.maxDepth = t.limits.MaxDepth
+	t.tok.depth = len(t.ctx.arr)
+}
+
+// checkDepth returns a LimitError if stepping in to a container at the given
+// offset would nest containers too deeply.
+func (r *reader) checkDepth(offset uint64) error {
+	if max := r.limits.MaxDepth; max > 0 && len(r.ctx.arr) >= max {
+		return &LimitError{"depth", uint64(max), offset}
+	}
+	return nil
+}
+
+// checkScalarSize returns a LimitError if a scalar of the given size at the given
+// offset is too big.
+func (r *reader) checkScalarSize(size, offset uint64) error {
+	if max := r.limits.MaxScalarSize; max > 0 && size > max {
+		return &LimitError{"scalar size", max, offset}
+	}
+	return nil
+}
+
+// isScalarCode returns true if the given code is that of a scalar whose size
+// counts towards MaxScalarSize.
+func isScalarCode(code bitcode) bool {
+	switch code {
+	case bitcodeInt, bitcodeNegInt, bitcodeFloat, bitcodeDecimal, bitcodeTimestamp,
+		bitcodeSymbol, bitcodeString, bitcodeClob, bitcodeBlob:
+		return true
+	}
+	return false
+}
+
+// checkValue returns a LimitError if the current value, which starts at the
+// given offset, exceeds the reader's limits.
+func (r *reader) checkValue(offset uint64) error {
+	l := &r.limits
+	if l.MaxAnnotations > 0 && len(r.annotations) > l.MaxAnnotations {
+		return &LimitError{"annotations", uint64(l.MaxAnnotations), offset}
+	}
+
+	switch val := r.value.(type) {
+	case int64:
+		if l.MaxIntBits > 0 && l.MaxIntBits < 64 && big.NewInt(val).BitLen() > l.MaxIntBits {
+			return &LimitError{"int size", uint64(l.MaxIntBits), offset}
+		}
+
+	case *big.Int:
+		if l.MaxIntBits > 0 && val.BitLen() > l.MaxIntBits {
+			return &LimitError{"int size", uint64(l.MaxIntBits), offset}
+		}
+
+	case *Decimal:
+		if l.MaxIntBits > 0 && val.n.BitLen() > l.MaxIntBits {
+			return &LimitError{"int size", uint64(l.MaxIntBits), offset}
+		}
+		exp := -int64(val.scale)
+		if exp < 0 {
+			exp = -exp
+		}
+		if l.MaxDecimalExponent > 0 && exp > int64(l.MaxDecimalExponent) {
+			return &LimitError{"decimal exponent", uint64(l.MaxDecimalExponent), offset}
+		}
+	}
+	return nil
+}
+
+// checkLocalSymbols returns a LimitError if the given local symbol table, read
+// by r, defines too many symbols.
+func checkLocalSymbols(r Reader, st SymbolTable) error {
+	l := limitsOf(r)
+	if l == nil || l.MaxLocalSymbols <= 0 {
+		return nil
+	}
+
+	n := st.MaxID()
+	if imps := st.Imports(); len(imps) > 0 && (imps[0] == V1SystemSymbolTable || imps[0] == V11SystemSymbolTable) {
+		n -= imps[0].MaxID()
+	}
+	if n > uint64(l.MaxLocalSymbols) {
+		return &LimitError{"local symbols", uint64(l.MaxLocalSymbols), positionOf(r).Offset}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readLimited reads the given text, both as text and encoded as binary, with
+// the given limits, checking whether it fails with a LimitError.
+func readLimited(t *testing.T, text string, limits ReaderLimits, elimit string) {
+	bs := encodeBinary(t, text)
+
+	test := func(name string, r Reader) {
+		t.Run(name, func(t *testing.T) {
+			_, err := ReadValues(r)
+			if elimit == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.IsType(t, &LimitError{}, err)
+			assert.Equal(t, elimit, err.(*LimitError).Limit)
+		})
+	}
+	test("text", NewReaderLimits(strings.NewReader(text), limits))
+	test("binary", NewReaderBytesLimits(bs, limits))
+	test("binary stream", NewReaderLimits(bytes.NewReader(bs), limits))
+}
+
+func TestReaderLimitsDepth(t *testing.T) {
+	limits := ReaderLimits{MaxDepth: 3}
+	readLimited(t, `[[[1]]] {a:(b)}`, limits, "")
+	readLimited(t, `[[[[1]]]]`, limits, "depth")
+	readLimited(t, `{a:{b:{c:{d:e}}}}`, limits, "depth")
+}
+
+func TestReaderLimitsDepthSkipped(t *testing.T) {
+	// Containers that are skipped rather than stepped in to count too.
+	text := strings.Repeat("[", 10) + strings.Repeat("]", 10)
+
+	r := NewReaderLimits(strings.NewReader(text+" 1"), ReaderLimits{MaxDepth: 5})
+	require.True(t, r.Next())
+	assert.False(t, r.Next())
+	require.IsType(t, &LimitError{}, r.Err())
+
+	r = NewReaderLimits(strings.NewReader(text+" 1"), ReaderLimits{MaxDepth: 10})
+	require.True(t, r.Next())
+	_int(t, r, 1)
+}
+
+func TestReaderLimitsScalarSize(t *testing.T) {
+	limits := ReaderLimits{MaxScalarSize: 16}
+	readLimited(t, `"short" {{aGVsbG8=}} 12345 2020-01-01T`, limits, "")
+	readLimited(t, `"`+strings.Repeat("a", 100)+`"`, limits, "scalar size")
+	readLimited(t, strings.Repeat("1", 100), limits, "scalar size")
+	readLimited(t, `{{`+strings.Repeat("aaaa", 25)+`}}`, limits, "scalar size")
+	readLimited(t, `'`+strings.Repeat("a", 100)+`'`, limits, "scalar size")
+}
+
+func TestReaderLimitsAnnotations(t *testing.T) {
+	limits := ReaderLimits{MaxAnnotations: 2}
+	readLimited(t, `a::b::1 [c::d::2]`, limits, "")
+	readLimited(t, `a::b::c::1`, limits, "annotations")
+	readLimited(t, `[a::b::c::1]`, limits, "annotations")
+}
+
+func TestReaderLimitsLocalSymbols(t *testing.T) {
+	limits := ReaderLimits{MaxLocalSymbols: 3}
+	readLimited(t, `a b c`, limits, "")
+
+	// Only binary input defines its symbols in a local symbol table.
+	bs := encodeBinary(t, `a b c d`)
+	_, err := ReadValues(NewReaderBytesLimits(bs, limits))
+	require.IsType(t, &LimitError{}, err)
+	assert.Equal(t, "local symbols", err.(*LimitError).Limit)
+
+	text := `$ion_symbol_table::{symbols:["a", "b", "c", "d"]} $10`
+	_, err = ReadValues(NewReaderLimits(strings.NewReader(text), limits))
+	require.IsType(t, &LimitError{}, err)
+
+	// Appending symbols counts the ones already defined.
+	text = `$ion_symbol_table::{symbols:["a", "b"]} $ion_symbol_table::{imports:$ion_symbol_table, symbols:["c", "d"]}`
+	_, err = ReadValues(NewReaderLimits(strings.NewReader(text), limits))
+	require.IsType(t, &LimitError{}, err)
+}
+
+func TestReaderLimitsNumbers(t *testing.T) {
+	limits := ReaderLimits{MaxIntBits: 64, MaxDecimalExponent: 10}
+	readLimited(t, `18446744073709551615 -18446744073709551615 1.5 1d10 1d-10`, limits, "")
+	readLimited(t, `18446744073709551616`, limits, "int size")
+	readLimited(t, `[18446744073709551616]`, limits, "int size")
+	readLimited(t, `184467440737095516160d-1`, limits, "int size")
+	readLimited(t, `1d11`, limits, "decimal exponent")
+	readLimited(t, `1d-11`, limits, "decimal exponent")
+
+	readLimited(t, `255 -255`, ReaderLimits{MaxIntBits: 8}, "")
+	readLimited(t, `256`, ReaderLimits{MaxIntBits: 8}, "int size")
+}
+
+func TestReaderLimitsBytes(t *testing.T) {
+	text := `{a:"hello", b:[1, 2, 3]}`
+	readLimited(t, text, ReaderLimits{MaxBytes: 100}, "")
+	readLimited(t, text+" "+text+" "+text+" "+text, ReaderLimits{MaxBytes: 60}, "total size")
+
+	// Input of exactly the maximum size is fine.
+	_, err := ReadValues(NewReaderLimits(strings.NewReader(text), ReaderLimits{MaxBytes: uint64(len(text))}))
+	assert.NoError(t, err)
+	bs := encodeBinary(t, text)
+	_, err = ReadValues(NewReaderBytesLimits(bs, ReaderLimits{MaxBytes: uint64(len(bs))}))
+	assert.NoError(t, err)
+	_, err = ReadValues(NewReaderLimits(bytes.NewReader(bs), ReaderLimits{MaxBytes: uint64(len(bs))}))
+	assert.NoError(t, err)
+}
+
+func TestDecoderLimits(t *testing.T) {
+	var val interface{}
+	d := NewDecoderLimits(strings.NewReader(`[[[1]]]`), ReaderLimits{MaxDepth: 2})
+	err := d.DecodeTo(&val)
+	require.IsType(t, &LimitError{}, err)
+	assert.Equal(t, "depth", err.(*LimitError).Limit)
+}
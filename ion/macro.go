@@ -0,0 +1,335 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A ParameterCardinality describes how many values a macro parameter accepts.
+type ParameterCardinality uint8
+
+const (
+	// ExactlyOne parameters accept a single value. This is the default when a
+	// parameter has no cardinality modifier (or the '!' modifier).
+	ExactlyOne ParameterCardinality = iota
+	// ZeroOrOne parameters ('?') accept an optional single value.
+	ZeroOrOne
+	// ZeroOrMore parameters ('*') accept any number of values.
+	ZeroOrMore
+	// OneOrMore parameters ('+') accept one or more values.
+	OneOrMore
+)
+
+// String returns the TDL modifier for the cardinality.
+func (c ParameterCardinality) String() string {
+	switch c {
+	case ExactlyOne:
+		return "!"
+	case ZeroOrOne:
+		return "?"
+	case ZeroOrMore:
+		return "*"
+	case OneOrMore:
+		return "+"
+	default:
+		return fmt.Sprintf("<unknown cardinality %v>", uint8(c))
+	}
+}
+
+// accepts returns true if the cardinality allows n values.
+func (c ParameterCardinality) accepts(n int) bool {
+	switch c {
+	case ExactlyOne:
+		return n == 1
+	case ZeroOrOne:
+		return n <= 1
+	case OneOrMore:
+		return n >= 1
+	default:
+		return true
+	}
+}
+
+// describe returns a description of the number of values the cardinality
+// allows, for use in error messages.
+func (c ParameterCardinality) describe() string {
+	switch c {
+	case ExactlyOne:
+		return "exactly one value"
+	case ZeroOrOne:
+		return "at most one value"
+	case OneOrMore:
+		return "at least one value"
+	default:
+		return "any number of values"
+	}
+}
+
+// optional returns true if an argument for the parameter may be omitted entirely.
+func (c ParameterCardinality) optional() bool {
+	return c == ZeroOrOne || c == ZeroOrMore
+}
+
+// variadic returns true if the parameter may be passed more than one value.
+func (c ParameterCardinality) variadic() bool {
+	return c == ZeroOrMore || c == OneOrMore
+}
+
+// A MacroParameter is a named parameter of a Macro.
+type MacroParameter struct {
+	Name        string
+	Cardinality ParameterCardinality
+}
+
+// String returns the parameter as it would be written in TDL.
+func (p MacroParameter) String() string {
+	if p.Cardinality == ExactlyOne {
+		return p.Name
+	}
+	return p.Name + p.Cardinality.String()
+}
+
+// A Macro is an Ion 1.1 macro: a template that, given some arguments, expands
+// to a stream of zero or more values. Macros are invoked from an Ion stream by
+// encoding expressions (e-expressions), which readers expand transparently.
+type Macro struct {
+	name   string
+	params []MacroParameter
+
+	// User macros are defined by a compiled TDL template, kept alongside the
+	// definition it was compiled from; system macros are implemented natively.
+	def  Value
+	body tdlExpr
+	fn   func(args [][]Value) ([]Value, error)
+}
+
+// ParseMacro compiles a single macro definition written in the template
+// definition language, for example:
+//
+//	(macro point (x y) {x: (%x), y: (%y)})
+//
+// The template may invoke system macros and, if mt is non-nil, the macros in mt.
+func ParseMacro(tdl string, mt *MacroTable) (*Macro, error) {
+	vs, err := ReadValues(NewReaderString(tdl))
+	if err != nil {
+		return nil, err
+	}
+	if len(vs) != 1 {
+		return nil, fmt.Errorf("ion: expected a single macro definition, found %v values", len(vs))
+	}
+	return compileMacro(vs[0], mt)
+}
+
+// Name returns the macro's name, or "" if it is anonymous.
+func (m *Macro) Name() string {
+	return m.name
+}
+
+// Parameters returns the macro's signature.
+func (m *Macro) Parameters() []MacroParameter {
+	return m.params
+}
+
+// String returns the macro's signature as it would be written in TDL.
+func (m *Macro) String() string {
+	name := m.name
+	if name == "" {
+		name = "null"
+	}
+
+	ps := make([]string, len(m.params))
+	for i, p := range m.params {
+		ps[i] = p.String()
+	}
+	return fmt.Sprintf("(macro %v (%v) ...)", name, strings.Join(ps, " "))
+}
+
+// Expand invokes the macro with one argument per parameter, returning the values
+// it expands to. Trailing optional arguments may be omitted. The returned values
+// may share structure with the macro definition and should not be modified.
+func (m *Macro) Expand(args ...[]Value) ([]Value, error) {
+	if len(args) > len(m.params) {
+		return nil, m.errorf("expected at most %v arguments, found %v", len(m.params), len(args))
+	}
+
+	full := make([][]Value, len(m.params))
+	copy(full, args)
+
+	for i, p := range m.params {
+		if !p.Cardinality.accepts(len(full[i])) {
+			return nil, m.errorf("parameter %v expects %v, found %v", p.Name, p.Cardinality.describe(), len(full[i]))
+		}
+	}
+
+	if m.fn != nil {
+		return m.fn(full)
+	}
+	if m.body == nil {
+		return nil, nil
+	}
+	return m.body.eval(full)
+}
+
+// collectArgs gathers raw argument groups into one per parameter. Arguments
+// beyond the last parameter are appended to it if it is variadic.
+func (m *Macro) collectArgs(args [][]Value) ([][]Value, error) {
+	n := len(m.params)
+	if len(args) <= n {
+		return args, nil
+	}
+	if n == 0 || !m.params[n-1].Cardinality.variadic() {
+		return nil, m.errorf("expected at most %v arguments, found %v", n, len(args))
+	}
+
+	rest := append([]Value{}, args[n-1]...)
+	for _, a := range args[n:] {
+		rest = append(rest, a...)
+	}
+	return append(args[:n-1:n-1], rest), nil
+}
+
+func (m *Macro) errorf(format string, args ...interface{}) error {
+	return &MacroError{m.displayName(), fmt.Sprintf(format, args...)}
+}
+
+func (m *Macro) displayName() string {
+	if m.name == "" {
+		return "<anonymous>"
+	}
+	return m.name
+}
+
+// A MacroTable is an ordered collection of macros. A macro's address is its
+// position in the table; named macros can also be looked up by name.
+type MacroTable struct {
+	macros []*Macro
+	index  map[string]int
+}
+
+// NewMacroTable creates a macro table containing the given macros in order.
+// It returns an error if two macros share a name.
+func NewMacroTable(macros ...*Macro) (*MacroTable, error) {
+	mt := &MacroTable{index: map[string]int{}}
+	for _, m := range macros {
+		if err := mt.add(m); err != nil {
+			return nil, err
+		}
+	}
+	return mt, nil
+}
+
+// ParseMacroTable compiles a sequence of macro definitions written in the
+// template definition language. The definitions may optionally be wrapped in a
+// (macro_table ...) clause. Each definition may invoke system macros and any
+// macro defined before it.
+func ParseMacroTable(tdl string) (*MacroTable, error) {
+	vs, err := ReadValues(NewReaderString(tdl))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(vs) == 1 {
+		if s, ok := vs[0].(*SexpValue); ok && s.Len() > 0 && isSymbolText(s.Get(0), "macro_table") {
+			vs = s.Values()[1:]
+		}
+	}
+
+	mt, _ := NewMacroTable()
+	if err := mt.compile(vs); err != nil {
+		return nil, err
+	}
+	return mt, nil
+}
+
+// compile compiles the given macro definitions and adds them to the table.
+func (t *MacroTable) compile(defs []Value) error {
+	for _, def := range defs {
+		m, err := compileMacro(def, t)
+		if err != nil {
+			return err
+		}
+		if err := t.add(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add appends a macro to the table.
+func (t *MacroTable) add(m *Macro) error {
+	if m.name != "" {
+		if _, ok := t.index[m.name]; ok {
+			return fmt.Errorf("ion: duplicate macro name %v", m.name)
+		}
+		t.index[m.name] = len(t.macros)
+	}
+	t.macros = append(t.macros, m)
+	return nil
+}
+
+// Len returns the number of macros in the table.
+func (t *MacroTable) Len() int {
+	if t == nil {
+		return 0
+	}
+	return len(t.macros)
+}
+
+// Macros returns the macros in the table, in address order.
+func (t *MacroTable) Macros() []*Macro {
+	if t == nil {
+		return nil
+	}
+	return t.macros
+}
+
+// Get returns the macro at the given address.
+func (t *MacroTable) Get(addr uint64) (*Macro, bool) {
+	if addr >= uint64(t.Len()) {
+		return nil, false
+	}
+	return t.macros[addr], true
+}
+
+// Find returns the macro with the given name.
+func (t *MacroTable) Find(name string) (*Macro, bool) {
+	if t == nil {
+		return nil, false
+	}
+	i, ok := t.index[name]
+	if !ok {
+		return nil, false
+	}
+	return t.macros[i], true
+}
+
+// Address returns the address of the given macro in the table.
+func (t *MacroTable) Address(m *Macro) (uint64, bool) {
+	if t == nil {
+		return 0, false
+	}
+	if i, ok := t.index[m.name]; ok && m.name != "" {
+		return uint64(i), t.macros[i] == m
+	}
+	for i, mm := range t.macros {
+		if mm == m {
+			return uint64(i), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readMacros reads the given Ion text, expanding any e-expressions using mt.
+func readMacros(t *testing.T, mt *MacroTable, text string) []Value {
+	vals, err := ReadValues(NewReaderMacros(strings.NewReader(text), nil, mt))
+	require.NoError(t, err)
+	return vals
+}
+
+// assertValues asserts that actual holds the values written in expected.
+func assertValues(t *testing.T, expected string, actual []Value) {
+	vals, err := ReadValues(NewReaderString(expected))
+	require.NoError(t, err)
+
+	require.Equal(t, len(vals), len(actual), "expected %v, got %v", expected, actual)
+	for i := range vals {
+		assert.True(t, EquivalentValues(vals[i], actual[i]), "expected %v, got %v", vals[i], actual[i])
+	}
+}
+
+func TestParseMacro(t *testing.T) {
+	m, err := ParseMacro("(macro point (x y? z* w+) {x: (%x)})", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "point", m.Name())
+	assert.Equal(t, []MacroParameter{
+		{"x", ExactlyOne},
+		{"y", ZeroOrOne},
+		{"z", ZeroOrMore},
+		{"w", OneOrMore},
+	}, m.Parameters())
+	assert.Equal(t, "(macro point (x y? z* w+) ...)", m.String())
+
+	m, err = ParseMacro("(macro null (x '!') (%x))", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "", m.Name())
+	assert.Equal(t, []MacroParameter{{"x", ExactlyOne}}, m.Parameters())
+	assert.Equal(t, "(macro null (x) ...)", m.String())
+}
+
+func TestParseMacroErrors(t *testing.T) {
+	test := func(tdl string) {
+		t.Run(tdl, func(t *testing.T) {
+			_, err := ParseMacro(tdl, nil)
+			assert.Error(t, err)
+		})
+	}
+
+	test("")
+	test("(macro a () 1) (macro b () 2)")
+	test("[macro a () 1]")
+	test("(macro)")
+	test("(function a () 1)")
+	test("(macro 1 () 1)")
+	test("(macro a [] 1)")
+	test("(macro a (x x) 1)")
+	test("(macro a (1) 1)")
+	test("(macro a (b::x) 1)")
+	test("(macro a () 1 2)")
+}
+
+func TestMacroExpand(t *testing.T) {
+	m, err := ParseMacro("(macro m (a b? c*) [(%a), (%b), (%c)])", nil)
+	require.NoError(t, err)
+
+	one := []Value{NewInt(1)}
+	two := []Value{NewInt(2), NewInt(3)}
+
+	vals, err := m.Expand(one)
+	require.NoError(t, err)
+	assertValues(t, "[1]", vals)
+
+	vals, err = m.Expand(one, nil, two)
+	require.NoError(t, err)
+	assertValues(t, "[1, 2, 3]", vals)
+
+	_, err = m.Expand()
+	assert.IsType(t, &MacroError{}, err)
+	assert.Equal(t, "ion: macro m: parameter a expects exactly one value, found 0", err.Error())
+
+	_, err = m.Expand(one, two)
+	assert.Equal(t, "ion: macro m: parameter b expects at most one value, found 2", err.Error())
+
+	_, err = m.Expand(one, one, one, one)
+	assert.Equal(t, "ion: macro m: expected at most 3 arguments, found 4", err.Error())
+}
+
+func TestMacroCollectArgs(t *testing.T) {
+	m, err := ParseMacro("(macro m (a b*) [(%a), (%b)])", nil)
+	require.NoError(t, err)
+
+	args, err := m.collectArgs([][]Value{{NewInt(1)}, {NewInt(2)}, {NewInt(3), NewInt(4)}})
+	require.NoError(t, err)
+	require.Len(t, args, 2)
+	assert.Len(t, args[1], 3)
+
+	m, err = ParseMacro("(macro m (a b?) [(%a), (%b)])", nil)
+	require.NoError(t, err)
+
+	_, err = m.collectArgs([][]Value{{NewInt(1)}, {NewInt(2)}, {NewInt(3)}})
+	assert.Error(t, err)
+}
+
+func TestMacroTable(t *testing.T) {
+	mt, err := ParseMacroTable(`
+		(macro_table
+			(macro one () 1)
+			(macro null () 2)
+			(macro three () (.one)))`)
+	require.NoError(t, err)
+	require.Equal(t, 3, mt.Len())
+
+	one, ok := mt.Find("one")
+	require.True(t, ok)
+	assert.Equal(t, one, mt.Macros()[0])
+
+	m, ok := mt.Get(1)
+	require.True(t, ok)
+	assert.Equal(t, "", m.Name())
+
+	addr, ok := mt.Address(m)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), addr)
+
+	_, ok = mt.Get(3)
+	assert.False(t, ok)
+	_, ok = mt.Find("two")
+	assert.False(t, ok)
+	_, ok = mt.Address(V11SystemMacroTable.Macros()[0])
+	assert.False(t, ok)
+
+	three, ok := mt.Find("three")
+	require.True(t, ok)
+	vals, err := three.Expand()
+	require.NoError(t, err)
+	assertValues(t, "1", vals)
+
+	_, err = NewMacroTable(one, one)
+	assert.Error(t, err)
+
+	_, err = ParseMacroTable("(macro a () 1) (macro a () 2)")
+	assert.Error(t, err)
+
+	_, err = ParseMacroTable("(macro a () (.b)) (macro b () 2)")
+	assert.Error(t, err)
+
+	var nilTable *MacroTable
+	assert.Equal(t, 0, nilTable.Len())
+	_, ok = nilTable.Find("one")
+	assert.False(t, ok)
+}
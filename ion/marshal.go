@@ -31,6 +31,10 @@ type EncoderOpts uint
 const (
 	// EncodeSortMaps instructs the encoder to write map keys in sorted order.
 	EncodeSortMaps EncoderOpts = 1
+
+	// EncodeCanonical instructs the encoder to write values in canonical form (see
+	// NewCanonicalWriter). Values are buffered until Finish is called.
+	EncodeCanonical EncoderOpts = 2
 )
 
 // Marshaler is the interface implemented by types that can marshal themselves to Ion.
@@ -112,6 +116,23 @@ func MarshalBinary(v interface{}, ssts ...SharedSymbolTable) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// MarshalCanonical marshals values to binary ion in canonical form (see
+// NewCanonicalWriter), so that equivalent values always marshal to the same
+// bytes. Use it to sign or content-address Ion data.
+func MarshalCanonical(v interface{}) ([]byte, error) {
+	buf := bytes.Buffer{}
+	e := NewEncoderOpts(NewBinaryWriterCanonical(&buf), EncodeCanonical)
+
+	if err := e.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := e.Finish(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
 // MarshalBinaryLST marshals values to binary ion with a fixed local symbol table.
 func MarshalBinaryLST(v interface{}, lst SymbolTable) ([]byte, error) {
 	buf := bytes.Buffer{}
@@ -140,8 +161,9 @@ func MarshalTo(w Writer, v interface{}) error {
 
 // An Encoder writes Ion values to an output stream.
 type Encoder struct {
-	w    Writer
-	opts EncoderOpts
+	w     Writer
+	opts  EncoderOpts
+	types *TypeRegistry
 }
 
 // NewEncoder creates a new encoder.
@@ -151,6 +173,9 @@ func NewEncoder(w Writer) *Encoder {
 
 // NewEncoderOpts creates a new encoder with the specified options.
 func NewEncoderOpts(w Writer, opts EncoderOpts) *Encoder {
+	if _, ok := w.(*canonicalWriter); !ok && opts&EncodeCanonical != 0 {
+		w = NewCanonicalWriter(w)
+	}
 	return &Encoder{
 		w:    w,
 		opts: opts,
@@ -162,11 +187,23 @@ func NewTextEncoder(w io.Writer) *Encoder {
 	return NewEncoder(NewTextWriter(w))
 }
 
+// NewJSONEncoder creates a new Encoder that down-converts to JSON (see
+// NewJSONWriter).
+func NewJSONEncoder(w io.Writer) *Encoder {
+	return NewEncoder(NewJSONWriter(w))
+}
+
 // NewBinaryEncoder creates a new binary Encoder.
 func NewBinaryEncoder(w io.Writer, ssts ...SharedSymbolTable) *Encoder {
 	return NewEncoder(NewBinaryWriter(w, ssts...))
 }
 
+// NewBinaryEncoderStreaming creates a new binary Encoder that writes each
+// top-level value out as soon as it's encoded (see NewBinaryWriterStreaming).
+func NewBinaryEncoderStreaming(w io.Writer, ssts ...SharedSymbolTable) *Encoder {
+	return NewEncoder(NewBinaryWriterStreaming(w, ssts...))
+}
+
 // NewBinaryEncoderLST creates a new binary Encoder with a fixed local symbol table.
 func NewBinaryEncoderLST(w io.Writer, lst SymbolTable) *Encoder {
 	return NewEncoder(NewBinaryWriterLST(w, lst))
@@ -198,12 +235,21 @@ func (m *Encoder) encodeValue(v reflect.Value, hint Type) error {
 	}
 
 	t := v.Type()
+	if enc := m.typeEncoder(t); enc != nil {
+		return enc(m.w, v.Interface())
+	}
+	if t.Kind() == reflect.Ptr && m.typeEncoder(t.Elem()) != nil {
+		return m.encodePtr(v, hint)
+	}
 	if t.Kind() != reflect.Ptr && v.CanAddr() && reflect.PtrTo(t).Implements(marshalerType) {
 		return v.Addr().Interface().(Marshaler).MarshalIon(m.w)
 	}
 	if t.Implements(marshalerType) {
 		return v.Interface().(Marshaler).MarshalIon(m.w)
 	}
+	if ok, err := m.encodeStdType(v); ok {
+		return err
+	}
 
 	switch t.Kind() {
 	case reflect.Bool:
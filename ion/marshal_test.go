@@ -349,3 +349,85 @@ func TestMarshalValuesWithAnnotation(t *testing.T) {
 	test(buildValue([]int{3, 5, 7}), "list", "'symbols or string'::annotations::[3,5,7]")
 	test(buildValue(map[string]int{"b": 2, "a": 1}), "struct", "'symbols or string'::annotations::{a:1,b:2}")
 }
+
+type benchNode struct {
+	Name  string     `ion:"name"`
+	Depth int        `ion:"depth"`
+	Tags  []string   `ion:"tags"`
+	Child *benchNode `ion:"child,omitempty"`
+}
+
+func newBenchNested(depth int) *benchNode {
+	var n *benchNode
+	for i := depth; i > 0; i-- {
+		n = &benchNode{Name: "node", Depth: i, Tags: []string{"a", "b"}, Child: n}
+	}
+	return n
+}
+
+type benchWide struct {
+	ID      int64     `ion:"id"`
+	Name    string    `ion:"name"`
+	Email   string    `ion:"email"`
+	Active  bool      `ion:"active"`
+	Score   float64   `ion:"score"`
+	Balance *Decimal  `ion:"balance"`
+	Created time.Time `ion:"created"`
+	Count1  int       `ion:"count1"`
+	Count2  int       `ion:"count2"`
+	Count3  int       `ion:"count3"`
+	Count4  int       `ion:"count4"`
+	Label1  string    `ion:"label1"`
+	Label2  string    `ion:"label2"`
+	Label3  string    `ion:"label3"`
+	Label4  string    `ion:"label4"`
+	Data    []byte    `ion:"data"`
+	Ratings []int     `ion:"ratings"`
+	Notes   string    `ion:"notes"`
+	Flag1   bool      `ion:"flag1"`
+	Flag2   bool      `ion:"flag2"`
+}
+
+func newBenchWide(n int) []benchWide {
+	vs := make([]benchWide, n)
+	for i := range vs {
+		vs[i] = benchWide{
+			ID:      int64(i),
+			Name:    "name",
+			Email:   "name@example.com",
+			Active:  i%2 == 0,
+			Score:   float64(i) / 3,
+			Balance: MustParseDecimal("1234.56"),
+			Created: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+			Count1:  i,
+			Count2:  i * 2,
+			Count3:  i * 3,
+			Count4:  i * 4,
+			Label1:  "label",
+			Label2:  "label",
+			Label3:  "label",
+			Label4:  "label",
+			Data:    []byte("some bytes"),
+			Ratings: []int{1, 2, 3, 4, 5},
+			Notes:   strings.Repeat("note ", 10),
+		}
+	}
+	return vs
+}
+
+func benchmarkMarshalBinary(b *testing.B, v interface{}) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := MarshalBinary(v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalBinaryNested(b *testing.B) {
+	benchmarkMarshalBinary(b, newBenchNested(100))
+}
+
+func BenchmarkMarshalBinaryWide(b *testing.B) {
+	benchmarkMarshalBinary(b, newBenchWide(100))
+}
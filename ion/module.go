@@ -0,0 +1,181 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import "fmt"
+
+// A Module is a named, versioned collection of symbols and macros that Ion 1.1
+// encoding directives can import from a Catalog (see ModuleCatalog). Modules take
+// the place of Ion 1.0 shared symbol tables, which can be imported as modules
+// without any macros.
+type Module struct {
+	name    string
+	version int
+	symbols []string
+	macros  *MacroTable
+}
+
+// NewModule creates a new module. Either symbols or macros may be empty.
+func NewModule(name string, version int, symbols []string, macros *MacroTable) *Module {
+	syms := make([]string, len(symbols))
+	copy(syms, symbols)
+
+	if macros == nil {
+		macros, _ = NewMacroTable()
+	}
+
+	return &Module{
+		name:    name,
+		version: version,
+		symbols: syms,
+		macros:  macros,
+	}
+}
+
+// Name returns the name of the module.
+func (m *Module) Name() string {
+	return m.name
+}
+
+// Version returns the version of the module.
+func (m *Module) Version() int {
+	return m.version
+}
+
+// Symbols returns the symbols the module defines, in order.
+func (m *Module) Symbols() []string {
+	return m.symbols
+}
+
+// Macros returns the macros the module defines.
+func (m *Module) Macros() *MacroTable {
+	return m.macros
+}
+
+// SymbolTable returns the module's symbols as a shared symbol table, which Ion 1.0
+// local symbol tables can import.
+func (m *Module) SymbolTable() SharedSymbolTable {
+	return NewSharedSymbolTable(m.name, m.version, m.symbols)
+}
+
+// String returns a string representation of the module.
+func (m *Module) String() string {
+	return fmt.Sprintf("%v/%v", m.name, m.version)
+}
+
+// An EncodingDirective is an Ion 1.1 encoding directive, which replaces the
+// symbols and macros in effect for the values that follow it. Its symbol table
+// holds the symbols of each of its imported modules followed by its own Symbols,
+// and its macro table likewise holds the imported modules' macros followed by its
+// own Macros.
+//
+// Written to a stream, a directive looks like:
+//
+//	$ion_encoding::(
+//	  (import 'com.example.geo' "com.example.geo" 2)
+//	  (symbol_table 'com.example.geo' ["lat", "lon"])
+//	  (macro_table 'com.example.geo' (macro point (x y) {x: (%x), y: (%y)}))
+//	)
+//
+// Readers resolve the imports using their Catalog, which must implement
+// ModuleCatalog to provide macros.
+type EncodingDirective struct {
+	Imports []*Module
+	Symbols []string
+	Macros  []*Macro
+}
+
+// SymbolTable returns the symbol table in effect after the directive.
+func (d *EncodingDirective) SymbolTable() SymbolTable {
+	var syms []string
+	for _, m := range d.Imports {
+		syms = append(syms, m.symbols...)
+	}
+	syms = append(syms, d.Symbols...)
+	return NewLocalSymbolTable([]SharedSymbolTable{V11SystemSymbolTable}, syms)
+}
+
+// MacroTable returns the macro table in effect after the directive. It returns an
+// error if two of the macros share a name.
+func (d *EncodingDirective) MacroTable() (*MacroTable, error) {
+	mt, _ := NewMacroTable()
+	for _, m := range d.Imports {
+		for _, mm := range m.macros.Macros() {
+			if err := mt.add(mm); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, m := range d.Macros {
+		if err := mt.add(m); err != nil {
+			return nil, err
+		}
+	}
+	return mt, nil
+}
+
+// WriteTo writes the directive out to the given writer. Each of the directive's
+// own macros must have been compiled from a TDL definition.
+func (d *EncodingDirective) WriteTo(w Writer) error {
+	v, err := d.value()
+	if err != nil {
+		return err
+	}
+	return v.MarshalIon(w)
+}
+
+// value builds the $ion_encoding s-expression representing the directive.
+func (d *EncodingDirective) value() (Value, error) {
+	var clauses, symbols, macros []Value
+
+	symbols = append(symbols, NewSymbol("symbol_table"))
+	macros = append(macros, NewSymbol("macro_table"))
+
+	for _, m := range d.Imports {
+		clauses = append(clauses, NewSexp(
+			NewSymbol("import"),
+			NewSymbol(m.name),
+			NewString(m.name),
+			NewInt(int64(m.version)),
+		))
+		symbols = append(symbols, NewSymbol(m.name))
+		macros = append(macros, NewSymbol(m.name))
+	}
+
+	if len(d.Symbols) > 0 {
+		syms := make([]Value, len(d.Symbols))
+		for i, s := range d.Symbols {
+			syms[i] = NewString(s)
+		}
+		symbols = append(symbols, NewList(syms...))
+	}
+
+	for _, m := range d.Macros {
+		if m.def == nil {
+			return nil, fmt.Errorf("ion: macro %v has no definition", m.displayName())
+		}
+		macros = append(macros, m.def)
+	}
+
+	if len(symbols) > 1 {
+		clauses = append(clauses, NewSexp(symbols...))
+	}
+	if len(macros) > 1 {
+		clauses = append(clauses, NewSexp(macros...))
+	}
+
+	return encodingDirective(clauses...)[0], nil
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModule(t *testing.T) {
+	syms := []string{"a", "b"}
+	m := NewModule("com.example", 3, syms, nil)
+	syms[0] = "changed"
+
+	assert.Equal(t, "com.example", m.Name())
+	assert.Equal(t, 3, m.Version())
+	assert.Equal(t, []string{"a", "b"}, m.Symbols())
+	assert.Equal(t, 0, m.Macros().Len())
+	assert.Equal(t, "com.example/3", m.String())
+
+	sst := m.SymbolTable()
+	assert.Equal(t, "com.example", sst.Name())
+	assert.Equal(t, 3, sst.Version())
+	assert.Equal(t, uint64(2), sst.MaxID())
+}
+
+func TestEncodingDirectiveTables(t *testing.T) {
+	mt, err := ParseMacroTable("(macro one () 1) (macro two () 2)")
+	require.NoError(t, err)
+	three, err := ParseMacro("(macro three () 3)", nil)
+	require.NoError(t, err)
+
+	d := &EncodingDirective{
+		Imports: []*Module{NewModule("com.example", 1, []string{"a", "b"}, mt)},
+		Symbols: []string{"c"},
+		Macros:  []*Macro{three},
+	}
+
+	st := d.SymbolTable()
+	systemMaxID := getSystemMaxID(st)
+	assert.Equal(t, V11SystemSymbolTable.MaxID(), systemMaxID)
+	checkSymbol(t, "a", systemMaxID+1, st)
+	checkSymbol(t, "b", systemMaxID+2, st)
+	checkSymbol(t, "c", systemMaxID+3, st)
+
+	dmt, err := d.MacroTable()
+	require.NoError(t, err)
+	require.Equal(t, 3, dmt.Len())
+	for i, name := range []string{"one", "two", "three"} {
+		m, ok := dmt.Get(uint64(i))
+		require.True(t, ok)
+		assert.Equal(t, name, m.Name())
+	}
+}
+
+func TestEncodingDirectiveWriteTo(t *testing.T) {
+	mt, err := ParseMacroTable("(macro one () 1)")
+	require.NoError(t, err)
+	cat := NewModuleCatalog(NewModule("com.example", 1, []string{"a"}, mt))
+
+	pair, err := ParseMacro("(macro pair (x y) [(%x), (%y)])", nil)
+	require.NoError(t, err)
+
+	d := &EncodingDirective{
+		Imports: []*Module{cat.FindLatestModule("com.example")},
+		Symbols: []string{"b", "c"},
+		Macros:  []*Macro{pair},
+	}
+
+	buf := strings.Builder{}
+	w := NewTextWriter(&buf)
+	require.NoError(t, d.WriteTo(w))
+	require.NoError(t, w.Finish())
+
+	expected := `$ion_encoding::((import 'com.example' "com.example" 1) (symbol_table 'com.example' ["b","c"]) ` +
+		`(macro_table 'com.example' (macro pair (x y) [('%' x),('%' y)])))`
+	assert.Equal(t, expected, strings.TrimSpace(buf.String()))
+
+	// Text writers can write directives (and e-expressions) once they've written
+	// an Ion 1.1 version marker.
+	buf.Reset()
+	w = NewTextWriter(&buf)
+	require.NoError(t, w.WriteSymbolFromString("$ion_1_1"))
+	require.NoError(t, d.WriteTo(w))
+	mw := w.(MacroWriter)
+	require.NoError(t, mw.BeginEExpression(pair))
+	require.NoError(t, mw.WriteSymbolFromString("b"))
+	require.NoError(t, mw.WriteInt(2))
+	require.NoError(t, mw.EndEExpression())
+	require.NoError(t, w.Finish())
+
+	vals, err := ReadValues(NewReaderCat(strings.NewReader(buf.String()), cat))
+	require.NoError(t, err)
+	assertValues(t, "[b, 2]", vals)
+
+	// Binary writers write the same directive.
+	bin := bytes.Buffer{}
+	bw := NewBinaryWriter11Opts(&bin, BinaryWriter11InlineSymbols)
+	require.NoError(t, d.WriteTo(bw))
+	require.NoError(t, bw.WriteSymbolFromString("b"))
+	require.NoError(t, bw.Finish())
+
+	r := NewReaderCat(bytes.NewReader(bin.Bytes()), cat)
+	vals, err = ReadValues(r)
+	require.NoError(t, err)
+	assertValues(t, "b", vals)
+	checkSymbol(t, "c", getSystemMaxID(r.SymbolTable())+3, r.SymbolTable())
+}
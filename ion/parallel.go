@@ -0,0 +1,247 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"bufio"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// ParallelDecoderOpts defines a set of bit flag options for parallel decoders.
+type ParallelDecoderOpts uint8
+
+const (
+	// DecodeInOrder delivers values in the order they appear in the input,
+	// rather than as soon as they're decoded.
+	DecodeInOrder ParallelDecoderOpts = 1
+)
+
+// parallelBatchSize is the number of bytes of input, give or take a value,
+// that each goroutine decodes at a time.
+const parallelBatchSize = 1 << 20
+
+// A ParallelDecoder decodes the top-level values of a large binary Ion input
+// using several goroutines.
+//
+// It first scans through the input for the boundaries of its top-level values,
+// which, thanks to their length prefixes, doesn't require reading them. It
+// divides the values into batches of roughly a megabyte each, which its
+// goroutines decode with the local symbol table in effect at the start of their
+// batch. Only a few batches per goroutine are in memory at any one time.
+//
+// Ion 1.1 input is not supported.
+type ParallelDecoder struct {
+	in        io.ReaderAt
+	size      int64
+	workers   int
+	opts      ParallelDecoderOpts
+	batchSize uint64
+}
+
+// A parallelBatch is a range of the input holding whole top-level values.
+type parallelBatch struct {
+	start, end uint64
+	lst        SymbolTable
+
+	// The decoded values and the error decoding them, if any. In order mode,
+	// done is closed once they're ready.
+	vals []interface{}
+	err  error
+	done chan struct{}
+}
+
+// NewParallelDecoder creates a new decoder that decodes the given number of
+// bytes of binary Ion read from in, using the given number of goroutines. If
+// workers is zero or less, it uses runtime.GOMAXPROCS(0) goroutines.
+func NewParallelDecoder(in io.ReaderAt, size int64, workers int, opts ParallelDecoderOpts) *ParallelDecoder {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	return &ParallelDecoder{
+		in:        in,
+		size:      size,
+		workers:   workers,
+		opts:      opts,
+		batchSize: parallelBatchSize,
+	}
+}
+
+// Decode decodes each top-level value as Decoder.DecodeTo does, into a new value
+// returned by newValue, which must be a non-nil pointer. It passes each of them
+// to fn, which is called from the calling goroutine, one value at a time.
+//
+// Decode stops at the first error from reading the input, decoding a value, or
+// fn, and returns it. Values that come before the error in the input may still be
+// passed to fn first.
+func (d *ParallelDecoder) Decode(newValue func() interface{}, fn func(v interface{}) error) error {
+	ordered := d.opts&DecodeInOrder != 0
+
+	quit := make(chan struct{})
+	work := make(chan *parallelBatch, d.workers)
+	queue := make(chan *parallelBatch, 2*d.workers)
+	results := make(chan *parallelBatch, d.workers)
+
+	// Scan for batches, handing them out to the workers and, in order mode,
+	// queueing them up for delivery.
+	var scanErr error
+	scanned := make(chan struct{})
+	go func() {
+		defer close(scanned)
+		defer close(queue)
+		defer close(work)
+
+		scanErr = d.scan(func(b *parallelBatch) bool {
+			if ordered {
+				b.done = make(chan struct{})
+				select {
+				case queue <- b:
+				case <-quit:
+					return false
+				}
+			}
+			select {
+			case work <- b:
+				return true
+			case <-quit:
+				return false
+			}
+		})
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < d.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range work {
+				d.decodeBatch(b, newValue)
+				if ordered {
+					close(b.done)
+					continue
+				}
+				select {
+				case results <- b:
+				case <-quit:
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Deliver the values, then wait for everything to wind down.
+	var err error
+	if ordered {
+		for b := range queue {
+			<-b.done
+			if err = d.deliver(b, fn); err != nil {
+				break
+			}
+		}
+	} else {
+		for b := range results {
+			if err = d.deliver(b, fn); err != nil {
+				break
+			}
+		}
+	}
+
+	close(quit)
+	<-scanned
+	wg.Wait()
+
+	if err != nil {
+		return err
+	}
+	return scanErr
+}
+
+// deliver passes the values in the given batch to fn, returning the error
+// decoding them, if any.
+func (d *ParallelDecoder) deliver(b *parallelBatch, fn func(v interface{}) error) error {
+	for _, v := range b.vals {
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	return b.err
+}
+
+// scan scans the input for the boundaries of its top-level values, passing each
+// batch of them to emit until it returns false.
+func (d *ParallelDecoder) scan(emit func(b *parallelBatch) bool) error {
+	in := bufio.NewReader(io.NewSectionReader(d.in, 0, d.size))
+	if bs, err := in.Peek(4); err == nil && !hasBVM(bs) {
+		return &UsageError{"ParallelDecoder.Decode", "input is not binary Ion"}
+	}
+
+	r := newBinaryReaderBuf(in, nil, nil).(*binaryReader)
+	r.scan = true
+
+	var cur *parallelBatch
+	for r.Next() {
+		span, err := r.Span()
+		if err != nil {
+			return err
+		}
+
+		if cur != nil && span.offset-cur.start < d.batchSize {
+			continue
+		}
+		if cur != nil {
+			cur.end = span.offset
+			if !emit(cur) {
+				return nil
+			}
+		}
+		cur = &parallelBatch{start: span.offset, lst: span.lst}
+	}
+	if err := r.Err(); err != nil {
+		return err
+	}
+
+	if cur != nil {
+		cur.end = uint64(d.size)
+		emit(cur)
+	}
+	return nil
+}
+
+// decodeBatch decodes the values in the given batch.
+func (d *ParallelDecoder) decodeBatch(b *parallelBatch, newValue func() interface{}) {
+	in := io.NewSectionReader(d.in, int64(b.start), int64(b.end-b.start))
+	r := newBinaryReaderBuf(bufio.NewReader(in), nil, nil).(*binaryReader)
+	r.bits.pos = b.start
+	r.lst = b.lst
+
+	dec := NewDecoder(r)
+	for {
+		v := newValue()
+		err := dec.DecodeTo(v)
+		if err == ErrNoInput {
+			return
+		}
+		if err != nil {
+			b.err = err
+			return
+		}
+		b.vals = append(b.vals, v)
+	}
+}
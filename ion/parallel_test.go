@@ -0,0 +1,179 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type parallelItem struct {
+	ID   int    `ion:"id"`
+	Name string `ion:"name"`
+}
+
+// encodeParallelItems encodes n items as binary, starting a new local symbol
+// table every ten items.
+func encodeParallelItems(t *testing.T, n int) []byte {
+	var buf []byte
+	for i := 0; i < n; i += 10 {
+		text := strings.Builder{}
+		for j := i; j < i+10 && j < n; j++ {
+			fmt.Fprintf(&text, "{id:%v, name:\"item %v\", f%v:true} ", j, j, j)
+		}
+		buf = append(buf, encodeBinary(t, text.String())...)
+	}
+	return buf
+}
+
+// decodeParallel decodes the given binary items in parallel.
+func decodeParallel(t *testing.T, bs []byte, opts ParallelDecoderOpts) ([]int, error) {
+	d := NewParallelDecoder(bytes.NewReader(bs), int64(len(bs)), 4, opts)
+	d.batchSize = 64
+
+	var ids []int
+	err := d.Decode(
+		func() interface{} { return &parallelItem{} },
+		func(v interface{}) error {
+			item := v.(*parallelItem)
+			assert.Equal(t, fmt.Sprintf("item %v", item.ID), item.Name)
+			ids = append(ids, item.ID)
+			return nil
+		})
+	return ids, err
+}
+
+func TestParallelDecoderInOrder(t *testing.T) {
+	ids, err := decodeParallel(t, encodeParallelItems(t, 100), DecodeInOrder)
+	require.NoError(t, err)
+
+	require.Equal(t, 100, len(ids))
+	for i, id := range ids {
+		assert.Equal(t, i, id)
+	}
+}
+
+func TestParallelDecoderUnordered(t *testing.T) {
+	ids, err := decodeParallel(t, encodeParallelItems(t, 100), 0)
+	require.NoError(t, err)
+
+	sort.Ints(ids)
+	require.Equal(t, 100, len(ids))
+	for i, id := range ids {
+		assert.Equal(t, i, id)
+	}
+}
+
+func TestParallelDecoderOneBatch(t *testing.T) {
+	bs := encodeParallelItems(t, 25)
+	d := NewParallelDecoder(bytes.NewReader(bs), int64(len(bs)), 0, DecodeInOrder)
+
+	var ids []int
+	err := d.Decode(
+		func() interface{} { return &parallelItem{} },
+		func(v interface{}) error {
+			ids = append(ids, v.(*parallelItem).ID)
+			return nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, 25, len(ids))
+}
+
+func TestParallelDecoderEmpty(t *testing.T) {
+	for _, bs := range [][]byte{nil, {0xE0, 0x01, 0x00, 0xEA}} {
+		ids, err := decodeParallel(t, bs, DecodeInOrder)
+		require.NoError(t, err)
+		assert.Empty(t, ids)
+	}
+}
+
+func TestParallelDecoderDecodeError(t *testing.T) {
+	bs := append(encodeParallelItems(t, 50), encodeBinary(t, "{id:\"bad\"}")...)
+	bs = append(bs, encodeParallelItems(t, 50)...)
+
+	ids, err := decodeParallel(t, bs, DecodeInOrder)
+	assert.Error(t, err)
+	assert.Equal(t, 50, len(ids))
+
+	_, err = decodeParallel(t, bs, 0)
+	assert.Error(t, err)
+}
+
+func TestParallelDecoderScanError(t *testing.T) {
+	bs := encodeParallelItems(t, 50)
+	bs = append(bs, 0xDE)
+
+	ids, err := decodeParallel(t, bs, DecodeInOrder)
+	assert.Error(t, err)
+	assert.True(t, len(ids) <= 50)
+}
+
+func TestParallelDecoderCallbackError(t *testing.T) {
+	bs := encodeParallelItems(t, 100)
+	stop := errors.New("stop")
+
+	for _, opts := range []ParallelDecoderOpts{0, DecodeInOrder} {
+		d := NewParallelDecoder(bytes.NewReader(bs), int64(len(bs)), 4, opts)
+		d.batchSize = 64
+
+		n := 0
+		err := d.Decode(
+			func() interface{} { return &parallelItem{} },
+			func(v interface{}) error {
+				n++
+				if n == 5 {
+					return stop
+				}
+				return nil
+			})
+		assert.Equal(t, stop, err)
+		assert.Equal(t, 5, n)
+	}
+}
+
+func TestParallelDecoderText(t *testing.T) {
+	bs := []byte("{id:1, name:\"item 1\"}")
+	_, err := decodeParallel(t, bs, DecodeInOrder)
+	assert.IsType(t, &UsageError{}, err)
+}
+
+func TestParallelDecoderScalars(t *testing.T) {
+	bs := encodeBinary(t, `1 "two" 3.0 four 5e0 2020T {six:6} [7] null.int 0x09 {{Cg==}}`)
+	d := NewParallelDecoder(bytes.NewReader(bs), int64(len(bs)), 3, DecodeInOrder)
+	d.batchSize = 4
+
+	var vals []interface{}
+	err := d.Decode(
+		func() interface{} { return new(interface{}) },
+		func(v interface{}) error {
+			vals = append(vals, *v.(*interface{}))
+			return nil
+		})
+	require.NoError(t, err)
+
+	require.Equal(t, 11, len(vals))
+	assert.Equal(t, 1, vals[0])
+	assert.Equal(t, "two", vals[1])
+	assert.Equal(t, 9, vals[9])
+	assert.Equal(t, []byte("\n"), vals[10])
+}
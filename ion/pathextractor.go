@@ -0,0 +1,441 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrStopExtraction may be returned by a PathCallback to stop matching early.
+	// PathExtractor.Match then returns nil, leaving the Reader positioned on the
+	// value that was passed to the callback.
+	ErrStopExtraction = errors.New("ion: stop extraction")
+)
+
+// A PathCallback is invoked with the Reader positioned on a value that matches a
+// search path. The callback may read the value (including stepping in to and back
+// out of it), but must not move the Reader on to another value.
+//
+// The returned stepOut tells the PathExtractor how many containers to step out of
+// once the callback returns, skipping the rest of their contents; zero continues
+// with the next value. It must not be greater than the depth of the matched value
+// (relative to where matching started).
+type PathCallback func(r Reader) (stepOut int, err error)
+
+// pathComponentKind identifies what a PathComponent matches on.
+type pathComponentKind uint8
+
+const (
+	pathField pathComponentKind = iota
+	pathIndex
+	pathWildcard
+)
+
+// A PathComponent matches a single step of a search path: a value at a given
+// depth. Create them with PathField, PathIndex, or PathWildcard.
+type PathComponent struct {
+	kind        pathComponentKind
+	field       string
+	index       int
+	annotations []string
+}
+
+// PathField creates a PathComponent matching struct fields with the given name.
+func PathField(name string) PathComponent {
+	return PathComponent{kind: pathField, field: name}
+}
+
+// PathIndex creates a PathComponent matching the value at the given (zero-based)
+// position in its container.
+func PathIndex(index int) PathComponent {
+	return PathComponent{kind: pathIndex, index: index}
+}
+
+// PathWildcard creates a PathComponent matching any value.
+func PathWildcard() PathComponent {
+	return PathComponent{kind: pathWildcard}
+}
+
+// WithAnnotations returns a copy of the component that additionally requires the
+// matched value to have exactly the given annotations.
+func (c PathComponent) WithAnnotations(as ...string) PathComponent {
+	c.annotations = append([]string{}, as...)
+	return c
+}
+
+// String returns the component in search path syntax.
+func (c PathComponent) String() string {
+	var s string
+	for _, a := range c.annotations {
+		s += symbolText(a) + "::"
+	}
+
+	switch c.kind {
+	case pathIndex:
+		return s + fmt.Sprint(c.index)
+	case pathWildcard:
+		return s + "*"
+	default:
+		if c.field == "*" {
+			return s + "$ion_extractor_field::*"
+		}
+		return s + symbolText(c.field)
+	}
+}
+
+// matches returns true if the reader's current value, at the given position in
+// its container, matches this component.
+func (c PathComponent) matches(r Reader, ordinal int) (bool, error) {
+	switch c.kind {
+	case pathIndex:
+		if c.index != ordinal {
+			return false, nil
+		}
+	case pathField:
+		name, err := r.FieldName()
+		if err != nil {
+			return false, err
+		}
+		if name == nil || name.Text == nil || *name.Text != c.field {
+			return false, nil
+		}
+	}
+
+	return annotationsMatch(r, c.annotations)
+}
+
+// annotationsMatch returns true if the reader's current value has exactly the
+// given annotations. Nil means any annotations are acceptable.
+func annotationsMatch(r Reader, as []string) (bool, error) {
+	if as == nil {
+		return true, nil
+	}
+
+	actual, err := r.Annotations()
+	if err != nil {
+		return false, err
+	}
+	if len(actual) != len(as) {
+		return false, nil
+	}
+	for i, a := range actual {
+		if a.Text == nil || *a.Text != as[i] {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// A SearchPath identifies values by their position within nested containers.
+type SearchPath struct {
+	// Annotations, if non-nil, must exactly match the annotations of the
+	// outermost (top-level) value along the path.
+	Annotations []string
+
+	// Components match values at successive depths. An empty path matches
+	// the top-level values themselves.
+	Components []PathComponent
+}
+
+// NewSearchPath creates a SearchPath from the given components.
+func NewSearchPath(components ...PathComponent) SearchPath {
+	return SearchPath{Components: components}
+}
+
+// ParseSearchPath parses a search path written as an Ion s-expression, in the same
+// syntax used by other Ion implementations. Symbols and strings match field names,
+// ints match positions within containers, and the symbol * matches anything (use
+// $ion_extractor_field::* to match a field named "*"). Other annotations on a
+// component must match the annotations of the value; annotations on the
+// s-expression itself must match those of the top-level value.
+//
+//	(foo 2 *)
+//	(orders * A::price)
+//	top::()
+func ParseSearchPath(path string) (SearchPath, error) {
+	sp := SearchPath{}
+
+	r := NewReaderString(path)
+	if !r.Next() {
+		if err := r.Err(); err != nil {
+			return sp, err
+		}
+		return sp, &UsageError{"ParseSearchPath", "empty search path"}
+	}
+	if r.Type() != SexpType || r.IsNull() {
+		return sp, &UsageError{"ParseSearchPath", "search path must be an s-expression"}
+	}
+
+	as, err := r.Annotations()
+	if err != nil {
+		return sp, err
+	}
+	sp.Annotations, err = annotationTexts(as)
+	if err != nil {
+		return sp, err
+	}
+
+	if err := r.StepIn(); err != nil {
+		return sp, err
+	}
+	for r.Next() {
+		c, err := readPathComponent(r)
+		if err != nil {
+			return sp, err
+		}
+		sp.Components = append(sp.Components, c)
+	}
+	if err := r.Err(); err != nil {
+		return sp, err
+	}
+	if err := r.StepOut(); err != nil {
+		return sp, err
+	}
+
+	if r.Next() {
+		return sp, &UsageError{"ParseSearchPath", "search path must be a single s-expression"}
+	}
+	return sp, r.Err()
+}
+
+// readPathComponent reads a single search path component.
+func readPathComponent(r Reader) (PathComponent, error) {
+	as, err := r.Annotations()
+	if err != nil {
+		return PathComponent{}, err
+	}
+	texts, err := annotationTexts(as)
+	if err != nil {
+		return PathComponent{}, err
+	}
+
+	literalField := false
+	if len(texts) > 0 && texts[0] == "$ion_extractor_field" {
+		literalField = true
+		texts = texts[1:]
+	}
+
+	var c PathComponent
+
+	switch r.Type() {
+	case IntType:
+		i, err := r.IntValue()
+		if err != nil {
+			return c, err
+		}
+		if i == nil || *i < 0 {
+			return c, &UsageError{"ParseSearchPath", "index must be a non-negative int"}
+		}
+		c = PathIndex(*i)
+
+	case SymbolType:
+		sym, err := r.SymbolValue()
+		if err != nil {
+			return c, err
+		}
+		if sym == nil || sym.Text == nil {
+			return c, &UsageError{"ParseSearchPath", "field name must have known text"}
+		}
+		if *sym.Text == "*" && !literalField {
+			c = PathWildcard()
+		} else {
+			c = PathField(*sym.Text)
+		}
+
+	case StringType:
+		str, err := r.StringValue()
+		if err != nil {
+			return c, err
+		}
+		if str == nil {
+			return c, &UsageError{"ParseSearchPath", "field name must not be null"}
+		}
+		c = PathField(*str)
+
+	default:
+		return c, &UsageError{"ParseSearchPath", fmt.Sprintf("invalid search path component type %v", r.Type())}
+	}
+
+	if len(texts) > 0 {
+		c = c.WithAnnotations(texts...)
+	}
+	return c, nil
+}
+
+// annotationTexts returns the text of the given annotations.
+func annotationTexts(as []SymbolToken) ([]string, error) {
+	if len(as) == 0 {
+		return nil, nil
+	}
+
+	texts := make([]string, len(as))
+	for i, a := range as {
+		if a.Text == nil {
+			return nil, &UsageError{"ParseSearchPath", "annotation must have known text"}
+		}
+		texts[i] = *a.Text
+	}
+	return texts, nil
+}
+
+// String returns the path in search path syntax.
+func (p SearchPath) String() string {
+	s := ""
+	for _, a := range p.Annotations {
+		s += symbolText(a) + "::"
+	}
+
+	s += "("
+	for i, c := range p.Components {
+		if i > 0 {
+			s += " "
+		}
+		s += c.String()
+	}
+	return s + ")"
+}
+
+// symbolText returns the given text as it should be written as an Ion symbol.
+func symbolText(text string) string {
+	buf := strings.Builder{}
+	_ = writeSymbol(NewSymbolTokenFromString(text), &buf)
+	return buf.String()
+}
+
+// A registeredPath is a search path along with its callback.
+type registeredPath struct {
+	path     SearchPath
+	callback PathCallback
+}
+
+// A PathExtractor matches values in an Ion stream against a set of search paths,
+// invoking a callback for each match. Containers that no search path can match
+// inside are skipped over without being stepped in to, which in binary Ion means
+// their contents are never parsed.
+//
+//	pe := ion.NewPathExtractor()
+//	err := pe.RegisterString("(orders * price)", func(r ion.Reader) (int, error) {
+//		d, err := r.DecimalValue()
+//		total = total.Add(d)
+//		return 0, err
+//	})
+//	...
+//	err = pe.Match(ion.NewReader(in))
+type PathExtractor struct {
+	paths []*registeredPath
+}
+
+// NewPathExtractor creates a new PathExtractor with no search paths.
+func NewPathExtractor() *PathExtractor {
+	return &PathExtractor{}
+}
+
+// Register adds a search path and the callback to invoke for values that match it.
+func (e *PathExtractor) Register(path SearchPath, cb PathCallback) {
+	e.paths = append(e.paths, &registeredPath{path, cb})
+}
+
+// RegisterString parses a search path (see ParseSearchPath) and adds it along with
+// the callback to invoke for values that match it.
+func (e *PathExtractor) RegisterString(path string, cb PathCallback) error {
+	sp, err := ParseSearchPath(path)
+	if err != nil {
+		return err
+	}
+	e.Register(sp, cb)
+	return nil
+}
+
+// Match reads all remaining values at the Reader's current depth, invoking
+// callbacks for any that match (or contain values that match) the registered
+// search paths. Search paths are relative to the depth the Reader is at when
+// Match is called; typically the top level.
+func (e *PathExtractor) Match(r Reader) error {
+	_, err := e.match(r, 0, e.paths)
+	if err == ErrStopExtraction {
+		return nil
+	}
+	return err
+}
+
+// match matches the values at the given depth against the given paths, all of
+// which have matched the containers enclosing this depth. It returns the number
+// of containers still to be stepped out of after stepping out of this one.
+func (e *PathExtractor) match(r Reader, depth int, paths []*registeredPath) (int, error) {
+	for ordinal := 0; r.Next(); ordinal++ {
+		var deeper []*registeredPath
+
+		for _, p := range paths {
+			ok, err := p.matches(r, depth, ordinal)
+			if err != nil {
+				return 0, err
+			}
+			if !ok {
+				continue
+			}
+
+			if len(p.path.Components) > depth {
+				deeper = append(deeper, p)
+				continue
+			}
+
+			stepOut, err := p.callback(r)
+			if err != nil {
+				return 0, err
+			}
+			if stepOut > 0 {
+				if stepOut > depth {
+					return 0, &UsageError{"PathExtractor.Match", "callback stepped out past the starting depth"}
+				}
+				return stepOut - 1, nil
+			}
+		}
+
+		// The callback may have consumed the value, in which case we can't step in.
+		if len(deeper) == 0 || !IsContainer(r.Type()) || r.IsNull() {
+			continue
+		}
+
+		if err := r.StepIn(); err != nil {
+			return 0, err
+		}
+		stepOut, err := e.match(r, depth+1, deeper)
+		if err != nil {
+			return 0, err
+		}
+		if err := r.StepOut(); err != nil {
+			return 0, err
+		}
+		if stepOut > 0 {
+			return stepOut - 1, nil
+		}
+	}
+
+	return 0, r.Err()
+}
+
+// matches returns true if the reader's current value, at the given depth and
+// position, matches the corresponding part of this path.
+func (p *registeredPath) matches(r Reader, depth, ordinal int) (bool, error) {
+	if depth == 0 {
+		return annotationsMatch(r, p.path.Annotations)
+	}
+	return p.path.Components[depth-1].matches(r, ordinal)
+}
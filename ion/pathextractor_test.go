@@ -0,0 +1,150 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const pathTestText = `{orders:[{id:1,price:1.50,tags:[a,b]},{id:2,price:A::2.25},{id:3,price:3.00}],'*':star}
+					  top::{orders:[{id:4,price:4.00}]}
+					  [10,11,12]`
+
+// collect registers each path, recording the text of every matched value.
+func collect(t *testing.T, r Reader, paths ...string) []string {
+	var matched []string
+
+	pe := NewPathExtractor()
+	for _, path := range paths {
+		require.NoError(t, pe.RegisterString(path, func(r Reader) (int, error) {
+			matched = append(matched, currentValueText(t, r))
+			return 0, nil
+		}))
+	}
+	require.NoError(t, pe.Match(r))
+
+	return matched
+}
+
+func currentValueText(t *testing.T, r Reader) string {
+	v, err := ReadValue(r)
+	require.NoError(t, err)
+	text, err := MarshalText(v)
+	require.NoError(t, err)
+	return string(text)
+}
+
+func TestPathExtractor(t *testing.T) {
+	test := func(paths []string, expected ...string) {
+		t.Run(paths[0], func(t *testing.T) {
+			assert.Equal(t, expected, collect(t, NewReaderString(pathTestText), paths...))
+
+			// Binary readers should match exactly the same values.
+			vs, err := ReadValues(NewReaderString(pathTestText))
+			require.NoError(t, err)
+			bin, err := MarshalBinary(NewList(vs...))
+			require.NoError(t, err)
+			r := NewReaderBytes(bin)
+			require.True(t, r.Next())
+			require.NoError(t, r.StepIn())
+			assert.Equal(t, expected, collect(t, r, paths...))
+		})
+	}
+
+	test([]string{"(orders * price)"}, "1.50", "A::2.25", "3.00", "4.00")
+	test([]string{"(orders 1 id)"}, "2")
+	test([]string{"(orders * A::price)"}, "A::2.25")
+	test([]string{"top::(orders * id)"}, "4")
+	test([]string{"(orders 0 tags *)"}, "a", "b")
+	test([]string{"(1)"}, "star", "11")
+	test([]string{"($ion_extractor_field::*)"}, "star")
+	test([]string{"(*)"}, "[{id:1,price:1.50,tags:[a,b]},{id:2,price:A::2.25},{id:3,price:3.00}]", "star",
+		"[{id:4,price:4.00}]", "10", "11", "12")
+	test([]string{"top::()"}, "top::{orders:[{id:4,price:4.00}]}")
+	test([]string{"(orders * id)", "(orders * price)"}, "1", "1.50", "2", "A::2.25", "3", "3.00", "4", "4.00")
+	test([]string{"(nope)"})
+}
+
+func TestPathExtractorStepOut(t *testing.T) {
+	var ids []string
+
+	pe := NewPathExtractor()
+	pe.Register(NewSearchPath(PathField("orders"), PathWildcard(), PathField("id")), func(r Reader) (int, error) {
+		ids = append(ids, currentValueText(t, r))
+		// Skip the rest of this order, and all remaining orders in the list.
+		return 2, nil
+	})
+
+	require.NoError(t, pe.Match(NewReaderString(pathTestText)))
+	assert.Equal(t, []string{"1", "4"}, ids)
+
+	var firsts []string
+
+	pe = NewPathExtractor()
+	pe.Register(NewSearchPath(PathWildcard()), func(r Reader) (int, error) {
+		firsts = append(firsts, currentValueText(t, r))
+		// Skip the rest of the top-level container.
+		return 1, nil
+	})
+
+	require.NoError(t, pe.Match(NewReaderString(pathTestText)))
+	assert.Equal(t, []string{"[{id:1,price:1.50,tags:[a,b]},{id:2,price:A::2.25},{id:3,price:3.00}]",
+		"[{id:4,price:4.00}]", "10"}, firsts)
+}
+
+func TestPathExtractorStop(t *testing.T) {
+	count := 0
+
+	pe := NewPathExtractor()
+	require.NoError(t, pe.RegisterString("(orders * id)", func(r Reader) (int, error) {
+		count++
+		return 0, ErrStopExtraction
+	}))
+
+	r := NewReaderString(pathTestText)
+	require.NoError(t, pe.Match(r))
+	assert.Equal(t, 1, count)
+
+	// The reader is left on the value passed to the callback.
+	assert.Equal(t, "1", currentValueText(t, r))
+}
+
+func TestPathExtractorErrors(t *testing.T) {
+	pe := NewPathExtractor()
+	require.NoError(t, pe.RegisterString("()", func(r Reader) (int, error) {
+		return 1, nil
+	}))
+	assert.Error(t, pe.Match(NewReaderString("1")))
+
+	for _, path := range []string{"", "[a]", "(a) (b)", "(1.5)", "(-1)", "(null.symbol)"} {
+		_, err := ParseSearchPath(path)
+		assert.Error(t, err, path)
+	}
+}
+
+func TestSearchPathString(t *testing.T) {
+	for _, path := range []string{"(a 1 *)", "top::(A::b $ion_extractor_field::*)", "()", "('x y' z)"} {
+		sp, err := ParseSearchPath(path)
+		require.NoError(t, err)
+		assert.Equal(t, path, sp.String())
+	}
+
+	sp := SearchPath{Components: []PathComponent{PathField("*"), PathField("$4")}}
+	assert.Equal(t, "($ion_extractor_field::* '$4')", sp.String())
+}
@@ -0,0 +1,367 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"fmt"
+)
+
+// A RawValue is the binary Ion 1.0 encoding of a single value, including its
+// annotations but not its field name, along with the symbol table that resolves
+// the symbol IDs it contains.
+type RawValue struct {
+	Bytes       []byte
+	SymbolTable SymbolTable
+}
+
+// A RawReader is a Reader that can return the encoded bytes of the value it's
+// positioned on, so that the value can be copied to a Writer without decoding
+// and re-encoding it.
+//
+// Binary Readers are RawReaders, but only Readers created by NewReaderBytes or
+// NewReaderBytesNoCopy, which read from memory, can return raw values.
+type RawReader interface {
+	Reader
+
+	// RawValue returns the encoded bytes of the current value. If the current
+	// value is a container, the Reader remains positioned on it. The bytes are
+	// a sub-slice of the Reader's input if it was created by NewReaderBytesNoCopy,
+	// and a copy otherwise.
+	RawValue() (*RawValue, error)
+}
+
+// A RawWriter is a Writer that can splice the encoded bytes of a value into its
+// output.
+//
+// Binary Ion 1.0 Writers are RawWriters. If the symbol IDs in a raw value mean
+// the same thing in the writer's symbol table, its bytes are written as is;
+// otherwise the value is re-encoded with its symbol IDs remapped to the writer's
+// symbol table, copying everything else as is.
+type RawWriter interface {
+	Writer
+
+	// WriteRaw writes the given raw value, using the field name and adding the
+	// annotations that have been set for it, like any other value.
+	WriteRaw(val *RawValue) error
+}
+
+// WriteRawValue writes the given raw value to the given Writer, splicing it in
+// if the Writer is a RawWriter and otherwise decoding it and writing it out
+// value by value.
+func WriteRawValue(w Writer, val *RawValue) error {
+	if rw, ok := w.(RawWriter); ok {
+		return rw.WriteRaw(val)
+	}
+	if err := checkRaw("WriteRawValue", val.Bytes); err != nil {
+		return err
+	}
+
+	r := newBinaryReader(nil, nil)
+	r.bits.InitBytesNoCopy(val.Bytes)
+	r.lst = val.symbolTable()
+
+	if !r.Next() {
+		return r.Err()
+	}
+	v, err := ReadValue(r)
+	if err != nil {
+		return err
+	}
+	return v.MarshalIon(w)
+}
+
+func (v *RawValue) symbolTable() SymbolTable {
+	if v.SymbolTable == nil {
+		return V1SystemSymbolTable
+	}
+	return v.SymbolTable
+}
+
+// CheckRaw checks that the given bytes hold a single binary value.
+func checkRaw(api string, bs []byte) error {
+	b := bitstream{}
+	b.InitBytesNoCopy(bs)
+
+	if err := b.Next(); err != nil {
+		return err
+	}
+	switch code := b.Code(); {
+	case code == bitcodeEOF, code == bitcodeBVM, code == bitcodeNull && !b.IsNull():
+		return &UsageError{api, "raw value does not hold a value"}
+	}
+
+	if b.len > b.avail() {
+		return &UnexpectedEOFError{Offset: uint64(len(bs))}
+	}
+	if err := b.SkipValue(); err != nil {
+		return err
+	}
+	if err := b.Next(); err != nil {
+		return err
+	}
+	if b.Code() != bitcodeEOF {
+		return &UsageError{api, "raw value holds more than one value"}
+	}
+	return nil
+}
+
+// RawValue returns the encoded bytes of the current value.
+func (r *binaryReader) RawValue() (*RawValue, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.valueType == NoType {
+		return nil, &UsageError{"Reader.RawValue", "no current value"}
+	}
+	if !r.bits.inMem {
+		return nil, &UsageError{"Reader.RawValue", "raw values are only available when reading from bytes"}
+	}
+	if r.v11 || r.exp != nil {
+		return nil, &UsageError{"Reader.RawValue", "raw values are not available for Ion 1.1"}
+	}
+
+	// Scalars have already been read, but containers haven't.
+	end := r.bits.pos
+	if r.bits.state == bssOnValue {
+		end += r.bits.len
+	}
+
+	return &RawValue{
+		Bytes:       r.bits.slice(r.rawStart, end),
+		SymbolTable: r.lst,
+	}, nil
+}
+
+// WriteRaw writes a raw value.
+func (w *binaryWriter) WriteRaw(val *RawValue) error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.err = checkRaw("Writer.WriteRaw", val.Bytes); w.err != nil {
+		return w.err
+	}
+
+	st := val.symbolTable()
+	if w.symbolsMatch(st) && (len(w.annotations) == 0 || val.Bytes[0]>>4 != 0xE) {
+		if w.err = w.beginValue("Writer.WriteRaw"); w.err != nil {
+			return w.err
+		}
+		w.write(val.Bytes)
+	} else if w.err = w.transcodeRaw(val.Bytes, st); w.err != nil {
+		return w.err
+	}
+
+	w.err = w.endValue()
+	return w.err
+}
+
+// SymbolsMatch returns true if every symbol ID in the given symbol table has the
+// same text in the writer's symbol table, so values using them can be written
+// as is.
+func (w *binaryWriter) symbolsMatch(st SymbolTable) bool {
+	if t, ok := st.(*lst); ok && t == w.rawLST {
+		return true
+	}
+
+	for id := uint64(1); id <= st.MaxID(); id++ {
+		text, ok := st.FindByID(id)
+		if !ok {
+			return false
+		}
+
+		var wtext string
+		if w.lst != nil {
+			wtext, ok = w.lst.FindByID(id)
+		} else {
+			wtext, ok = w.lstb.FindByID(id)
+		}
+		if !ok || text != wtext {
+			return false
+		}
+	}
+
+	if t, ok := st.(*lst); ok {
+		w.rawLST = t
+	}
+	return true
+}
+
+// TranscodeRaw begins writing the given raw value, re-encoding it with its
+// symbol IDs remapped to the writer's symbol table.
+func (w *binaryWriter) transcodeRaw(bs []byte, st SymbolTable) error {
+	const api = "Writer.WriteRaw"
+
+	b := bitstream{}
+	b.InitBytesNoCopy(bs)
+
+	if err := b.Next(); err != nil {
+		return err
+	}
+	if b.Code() == bitcodeAnnotation {
+		as, err := w.remapAnnotations(api, &b, st)
+		if err != nil {
+			return err
+		}
+		w.annotations = append(w.annotations, as...)
+
+		if err := b.Next(); err != nil {
+			return err
+		}
+	}
+
+	if err := w.beginValue(api); err != nil {
+		return err
+	}
+	return w.transcode(api, &b, st)
+}
+
+// Transcode writes out the value the given bitstream is positioned on, remapping
+// the symbol IDs it contains.
+func (w *binaryWriter) transcode(api string, b *bitstream, st SymbolTable) error {
+	code := b.Code()
+	if b.IsNull() {
+		w.write(b.buf[b.tagPos:b.pos])
+		return b.SkipValue()
+	}
+
+	switch code {
+	case bitcodeSymbol:
+		sid, err := b.ReadSymbolID()
+		if err != nil {
+			return err
+		}
+		id, err := w.remap(api, st, sid)
+		if err != nil {
+			return err
+		}
+
+		var arr [9]byte
+		buf := appendTag(arr[:0], 0x70, uintLen(id))
+		w.write(appendUint(buf, id))
+		return nil
+
+	case bitcodeList, bitcodeSexp, bitcodeStruct:
+		return w.transcodeContainer(api, b, st)
+	}
+
+	// Anything else has no symbol IDs in it, so can be copied as is.
+	w.write(b.buf[b.tagPos : b.pos+b.len])
+	return b.SkipValue()
+}
+
+// TranscodeContainer writes out the container the given bitstream is positioned
+// on, remapping the symbol IDs it contains.
+func (w *binaryWriter) transcodeContainer(api string, b *bitstream, st SymbolTable) error {
+	switch b.Code() {
+	case bitcodeList:
+		w.buf.begin(0xB0)
+	case bitcodeSexp:
+		w.buf.begin(0xC0)
+	default:
+		w.buf.begin(0xD0)
+	}
+	b.StepIn()
+
+	var fieldID uint64
+	for {
+		if err := b.Next(); err != nil {
+			return err
+		}
+
+		switch b.Code() {
+		case bitcodeEOF:
+			if err := b.StepOut(); err != nil {
+				return err
+			}
+			w.buf.end()
+			return nil
+
+		case bitcodeFieldID:
+			sid, err := b.ReadFieldID()
+			if err != nil {
+				return err
+			}
+			if fieldID, err = w.remap(api, st, sid); err != nil {
+				return err
+			}
+			continue
+
+		case bitcodeNull:
+			if !b.IsNull() {
+				// NOP padding, along with its field name if it has one.
+				if err := b.SkipValue(); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if b.stack.peek().code == bitcodeStruct {
+			w.buf.bs = appendVarUint(w.buf.bs, fieldID)
+		}
+
+		if b.Code() != bitcodeAnnotation {
+			if err := w.transcode(api, b, st); err != nil {
+				return err
+			}
+			continue
+		}
+
+		as, err := w.remapAnnotations(api, b, st)
+		if err != nil {
+			return err
+		}
+		if err := w.beginAnnotations(api, as); err != nil {
+			return err
+		}
+		if err := b.Next(); err != nil {
+			return err
+		}
+		if err := w.transcode(api, b, st); err != nil {
+			return err
+		}
+		w.buf.end()
+	}
+}
+
+// RemapAnnotations reads the annotations the given bitstream is positioned on,
+// returning them as symbol IDs in the writer's symbol table.
+func (w *binaryWriter) remapAnnotations(api string, b *bitstream, st SymbolTable) ([]SymbolToken, error) {
+	as, err := b.ReadAnnotations(st)
+	if err != nil {
+		return nil, err
+	}
+	for i, a := range as {
+		id, err := w.remap(api, st, uint64(a.LocalSID))
+		if err != nil {
+			return nil, err
+		}
+		as[i] = SymbolToken{LocalSID: int64(id)}
+	}
+	return as, nil
+}
+
+// Remap maps a symbol ID in the given symbol table to the writer's symbol table.
+func (w *binaryWriter) remap(api string, st SymbolTable, sid uint64) (uint64, error) {
+	if sid == 0 {
+		return 0, nil
+	}
+	text, ok := st.FindByID(sid)
+	if !ok {
+		return 0, &UsageError{api, fmt.Sprintf("symbol $%v has unknown text", sid)}
+	}
+	return w.resolveFromSymbolTable(api, text)
+}
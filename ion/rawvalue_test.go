@@ -0,0 +1,212 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readRaw reads the raw values of the top-level values in the given text,
+// after encoding it as binary.
+func readRaw(t *testing.T, text string) []*RawValue {
+	vals, err := ReadValues(NewReaderString(text))
+	require.NoError(t, err)
+	bs, err := MarshalBinary(vals)
+	require.NoError(t, err)
+
+	// MarshalBinary wraps the values in a list; read them from inside it.
+	r := NewReaderBytes(bs).(RawReader)
+	require.True(t, r.Next())
+	require.NoError(t, r.StepIn())
+
+	var raws []*RawValue
+	for r.Next() {
+		raw, err := r.RawValue()
+		require.NoError(t, err)
+		raws = append(raws, raw)
+	}
+	require.NoError(t, r.Err())
+	require.NoError(t, r.StepOut())
+	return raws
+}
+
+func writeRawText(t *testing.T, raws []*RawValue, w Writer, buf *bytes.Buffer) string {
+	for _, raw := range raws {
+		require.NoError(t, WriteRawValue(w, raw))
+	}
+	require.NoError(t, w.Finish())
+	return roundTripText(t, buf.Bytes())
+}
+
+const rawTestData = `a::{b:c,d:[e::f,"g",1.5,null.symbol,$0],h:(i j),k:{{aGk=}}} true 12 l`
+
+func TestReadRawValue(t *testing.T) {
+	raws := readRaw(t, `1 a::b [c] {d:e}`)
+	require.Len(t, raws, 4)
+
+	assert.Equal(t, []byte{0x21, 0x01}, raws[0].Bytes)
+	for _, raw := range raws {
+		assert.NotNil(t, raw.SymbolTable)
+	}
+
+	// The annotation wrapper is included.
+	assert.Equal(t, byte(0xE4), raws[1].Bytes[0])
+}
+
+func TestReadRawValueSkipsContainer(t *testing.T) {
+	bs, err := MarshalBinary([]interface{}{[]int{1, 2}, "x"})
+	require.NoError(t, err)
+
+	r := NewReaderBytesNoCopy(bs).(RawReader)
+	require.True(t, r.Next())
+	require.NoError(t, r.StepIn())
+
+	require.True(t, r.Next())
+	raw, err := r.RawValue()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xB4, 0x21, 0x01, 0x21, 0x02}, raw.Bytes)
+
+	// The reader is still positioned on the list, and can skip or step in to it.
+	require.NoError(t, r.StepIn())
+	_int(t, r, 1)
+	require.NoError(t, r.StepOut())
+	_string(t, r, newString("x"))
+	_eof(t, r)
+}
+
+func TestReadRawValueErrors(t *testing.T) {
+	bs, err := MarshalBinary(1)
+	require.NoError(t, err)
+
+	r := NewReaderBytes(bs).(RawReader)
+	_, err = r.RawValue()
+	assert.IsType(t, &UsageError{}, err)
+
+	r = NewReader(bytes.NewReader(bs)).(RawReader)
+	require.True(t, r.Next())
+	_, err = r.RawValue()
+	assert.IsType(t, &UsageError{}, err)
+
+	_, ok := NewReaderString("1").(RawReader)
+	assert.False(t, ok)
+}
+
+func TestWriteRawSameSymbols(t *testing.T) {
+	raws := readRaw(t, rawTestData)
+	lst := raws[0].SymbolTable
+
+	buf := bytes.Buffer{}
+	w := NewBinaryWriterLST(&buf, lst)
+	for _, raw := range raws {
+		require.NoError(t, w.(RawWriter).WriteRaw(raw))
+	}
+	require.NoError(t, w.Finish())
+
+	// The values are spliced in as is, following the symbol table.
+	var eval []byte
+	for _, raw := range raws {
+		eval = append(eval, raw.Bytes...)
+	}
+	assert.True(t, bytes.HasSuffix(buf.Bytes(), eval))
+
+	assert.Equal(t, rawTestData, roundTripText(t, buf.Bytes()))
+}
+
+func TestWriteRawRemapsSymbols(t *testing.T) {
+	raws := readRaw(t, rawTestData)
+
+	// Use up some symbol IDs so that they're all different.
+	buf := bytes.Buffer{}
+	w := NewBinaryWriter(&buf)
+	require.NoError(t, w.WriteSymbolFromString("z"))
+	require.NoError(t, w.WriteSymbolFromString("y"))
+
+	text := writeRawText(t, raws, w, &buf)
+	assert.Equal(t, "z y "+rawTestData, text)
+}
+
+func TestWriteRawStreaming(t *testing.T) {
+	raws := readRaw(t, rawTestData)
+
+	buf := bytes.Buffer{}
+	w := NewBinaryWriterStreaming(&buf)
+	assert.Equal(t, rawTestData, writeRawText(t, raws, w, &buf))
+}
+
+func TestWriteRawFieldNamesAndAnnotations(t *testing.T) {
+	raws := readRaw(t, `a::b c`)
+
+	buf := bytes.Buffer{}
+	w := NewBinaryWriter(&buf).(RawWriter)
+	require.NoError(t, w.BeginStruct())
+	require.NoError(t, w.FieldName(NewSymbolTokenFromString("x")))
+	require.NoError(t, w.Annotation(NewSymbolTokenFromString("y")))
+	require.NoError(t, w.WriteRaw(raws[0]))
+	require.NoError(t, w.FieldName(NewSymbolTokenFromString("z")))
+	require.NoError(t, w.Annotation(NewSymbolTokenFromString("y")))
+	require.NoError(t, w.WriteRaw(raws[1]))
+	require.NoError(t, w.EndStruct())
+
+	assert.Equal(t, "{x:y::a::b,z:y::c}", writeRawText(t, nil, w, &buf))
+}
+
+func TestWriteRawValueText(t *testing.T) {
+	raws := readRaw(t, rawTestData)
+
+	buf := strings.Builder{}
+	w := NewTextWriter(&buf)
+	for _, raw := range raws {
+		require.NoError(t, WriteRawValue(w, raw))
+	}
+	require.NoError(t, w.Finish())
+
+	assert.Equal(t, rawTestData, strings.TrimSpace(strings.ReplaceAll(buf.String(), "\n", " ")))
+}
+
+func TestWriteRawErrors(t *testing.T) {
+	test := func(name string, bs []byte) {
+		t.Run(name, func(t *testing.T) {
+			w := NewBinaryWriter(&bytes.Buffer{})
+			st := NewLocalSymbolTable(nil, []string{"a"})
+			assert.Error(t, w.(RawWriter).WriteRaw(&RawValue{Bytes: bs, SymbolTable: st}))
+		})
+	}
+
+	test("empty", nil)
+	test("two values", []byte{0x21, 0x01, 0x21, 0x02})
+	test("truncated", []byte{0x83, 'a'})
+	test("bvm", []byte{0xE0, 0x01, 0x00, 0xEA})
+	test("unknown symbol", []byte{0x71, 0x0B})
+}
+
+// roundTripText reads the given binary values and writes them back out as text.
+func roundTripText(t *testing.T, bs []byte) string {
+	vals, err := ReadValues(NewReaderBytes(bs))
+	require.NoError(t, err)
+
+	var texts []string
+	for _, v := range vals {
+		text, err := MarshalText(v)
+		require.NoError(t, err)
+		texts = append(texts, string(text))
+	}
+	return strings.Join(texts, " ")
+}
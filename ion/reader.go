@@ -16,7 +16,6 @@
 package ion
 
 import (
-	"bufio"
 	"bytes"
 	"io"
 	"math"
@@ -165,8 +164,45 @@ type Reader interface {
 	SymbolTable() SymbolTable
 }
 
+// A StringBytesReader is a Reader that can return the text of a string without
+// converting it to a Go string.
+//
+// The Readers created by this package are StringBytesReaders; those created by
+// NewReaderBytesNoCopy return sub-slices of their input rather than copies.
+type StringBytesReader interface {
+	Reader
+
+	// StringBytes returns the current value as a byte slice holding its UTF-8 encoded text
+	// (if that makes sense). Returns `nil` for Ion null string. It returns an error if the
+	// current value is not an Ion string.
+	StringBytes() ([]byte, error)
+}
+
+// A PositionReader is a Reader that can report where it is in its input.
+//
+// The Readers created by this package are PositionReaders.
+type PositionReader interface {
+	Reader
+
+	// Position returns the position of the current value in the input, or the
+	// position of the Reader if there is no current value, for use in diagnostics.
+	Position() Position
+}
+
+// A Position is a location in a Reader's input.
+type Position struct {
+	// Offset is the byte offset from the point at which the Reader started.
+	Offset uint64
+
+	// Line and Column are the 1-based line and column (counted in bytes) for
+	// text Readers, and zero for binary Readers.
+	Line   uint64
+	Column uint64
+}
+
 // NewReader creates a new Ion reader of the appropriate type by peeking
-// at the first several bytes of input for a binary version marker.
+// at the first several bytes of input for a binary version marker. To read
+// gzip-compressed input, see NewReaderOpts.
 func NewReader(in io.Reader) Reader {
 	return NewReaderCat(in, nil)
 }
@@ -178,19 +214,56 @@ func NewReaderString(str string) Reader {
 
 // NewReaderBytes creates a new reader for the given bytes.
 func NewReaderBytes(in []byte) Reader {
+	if hasBVM(in) {
+		return newBinaryReaderBytes(in, nil, nil)
+	}
 	return NewReader(bytes.NewReader(in))
 }
 
+// NewReaderBytesNoCopy creates a new reader that reads binary Ion directly from
+// the given bytes, without copying them. Skipping over values is a matter of
+// index arithmetic, and the byte slices returned by ByteValue and StringBytes are
+// sub-slices of in rather than copies, as are the blobs and clobs in any Values
+// read with ReadValue. The caller must therefore not modify in while the reader,
+// or any slice it has returned, is still in use, and must not modify the slices
+// it returns. Text Ion is read as if by NewReaderBytes.
+func NewReaderBytesNoCopy(in []byte) Reader {
+	return NewReaderBytesNoCopyCat(in, nil)
+}
+
+// NewReaderBytesNoCopyCat creates a new reader with the given catalog that reads
+// directly from the given bytes, as NewReaderBytesNoCopy does.
+func NewReaderBytesNoCopyCat(in []byte, cat Catalog) Reader {
+	if hasBVM(in) {
+		return newBinaryReaderBytesNoCopy(in, cat, nil)
+	}
+	return NewReaderCat(bytes.NewReader(in), cat)
+}
+
 // NewReaderCat creates a new reader with the given catalog.
 func NewReaderCat(in io.Reader, cat Catalog) Reader {
-	br := bufio.NewReader(in)
+	return NewReaderMacros(in, cat, nil)
+}
 
-	bs, err := br.Peek(4)
-	if err == nil && bs[0] == 0xE0 && bs[3] == 0xEA {
-		return newBinaryReaderBuf(br, cat)
+// NewReaderMacros creates a new reader with the given catalog whose Ion 1.1
+// e-expressions invoke the macros in the given table. If mt is nil,
+// e-expressions may only invoke the system macros.
+func NewReaderMacros(in io.Reader, cat Catalog, mt *MacroTable) Reader {
+	br, src, binary := openInput(in, false)
+	if binary {
+		r := newBinaryReaderBuf(br, cat, mt).(*binaryReader)
+		r.src = src
+		return r
 	}
 
-	return newTextReaderBuf(br, cat)
+	r := newTextReaderBuf(br, cat, mt).(*textReader)
+	r.src = src
+	return r
+}
+
+// hasBVM returns true if the given bytes start with a binary version marker.
+func hasBVM(bs []byte) bool {
+	return len(bs) >= 4 && bs[0] == 0xE0 && bs[3] == 0xEA
 }
 
 // A reader holds common implementation stuff to both the text and binary readers.
@@ -199,11 +272,39 @@ type reader struct {
 	eof bool
 	err error
 
+	cat         Catalog
 	lst         SymbolTable
 	fieldName   *SymbolToken
 	annotations []SymbolToken
 	valueType   Type
 	value       interface{}
+
+	// Strings and lobs read without copying are kept in bytes, with value
+	// set to their type rather than holding a copy.
+	bytes []byte
+
+	// Readers that can seek read from src. After seeking to a span, the
+	// reader is hoisted: the value the span identifies is the only one it
+	// reads, and hoistRead records whether it's been read yet.
+	src       *seekSource
+	hoisted   bool
+	hoistRead bool
+
+	// The limits the reader enforces on its input.
+	limits ReaderLimits
+
+	// Values produced by an Ion 1.1 e-expression are read from exp until it's
+	// used up; macros holds the macros e-expressions may invoke. Encoding
+	// directives replace macros, and version markers reset it to defaultMacros.
+	exp           *expansion
+	macros        *MacroTable
+	defaultMacros *MacroTable
+}
+
+// Position returns the zero Position; Readers that know where they are in
+// their input override it.
+func (r *reader) Position() Position {
+	return Position{}
 }
 
 // Err returns the current error.
@@ -354,7 +455,17 @@ func (r *reader) ByteValue() ([]byte, error) {
 	if r.value == nil {
 		return nil, nil
 	}
-	return r.value.([]byte), nil
+	if val, ok := r.value.([]byte); ok {
+		return val, nil
+	}
+	return r.bytes, nil
+}
+
+// setBytes sets the current string or lob value to the given bytes, without
+// copying them.
+func (r *reader) setBytes(bs []byte) {
+	r.value = r.valueType
+	r.bytes = bs
 }
 
 // Clear clears the current value from the reader.
@@ -363,6 +474,7 @@ func (r *reader) clear() {
 	r.annotations = nil
 	r.valueType = NoType
 	r.value = nil
+	r.bytes = nil
 }
 
 // IsInStruct returns true if we are currently in a struct.
@@ -384,10 +496,34 @@ func (r *reader) StringValue() (*string, error) {
 		return nil, nil
 	}
 
-	val := r.value.(string)
+	val, ok := r.value.(string)
+	if !ok {
+		val = string(r.bytes)
+	}
 	return &val, nil
 }
 
+// StringBytes returns the current value as a byte slice holding its UTF-8
+// encoded text.
+func (r *reader) StringBytes() ([]byte, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	if r.valueType != StringType {
+		return nil, &UsageError{"Reader.StringBytes", "value is not a string"}
+	}
+
+	if r.value == nil {
+		return nil, nil
+	}
+
+	if val, ok := r.value.(string); ok {
+		return []byte(val), nil
+	}
+	return r.bytes, nil
+}
+
 // SymbolValue returns the current value as a symbol token.
 func (r *reader) SymbolValue() (*SymbolToken, error) {
 	if r.err != nil {
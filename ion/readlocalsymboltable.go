@@ -47,7 +47,11 @@ func readLocalSymbolTable(r Reader, cat Catalog) (SymbolTable, error) {
 		return nil, err
 	}
 
-	return NewLocalSymbolTable(imps, syms), nil
+	st := NewLocalSymbolTable(imps, syms)
+	if err := checkLocalSymbols(r, st); err != nil {
+		return nil, err
+	}
+	return st, nil
 }
 
 // ReadImports reads the imports field of a local symbol table.
@@ -60,7 +64,7 @@ func readImports(r Reader, cat Catalog) ([]SharedSymbolTable, error) {
 
 		if val.LocalSID == 3 {
 			// Special case that imports the current local symbol table.
-			if r.SymbolTable() == nil || r.SymbolTable() == V1SystemSymbolTable {
+			if r.SymbolTable() == nil || r.SymbolTable() == V1SystemSymbolTable || r.SymbolTable() == V11SystemSymbolTable {
 				return nil, nil
 			}
 
@@ -194,8 +198,16 @@ func readSymbols(r Reader) ([]string, error) {
 		return nil, err
 	}
 
+	max := 0
+	if l := limitsOf(r); l != nil {
+		max = l.MaxLocalSymbols
+	}
+
 	var syms []string
 	for r.Next() {
+		if max > 0 && len(syms) == max {
+			return nil, &LimitError{"local symbols", uint64(max), positionOf(r).Offset}
+		}
 		if r.Type() == StringType {
 			sym, err := r.StringValue()
 
@@ -215,3 +227,281 @@ func readSymbols(r Reader) ([]string, error) {
 	err := r.StepOut()
 	return syms, err
 }
+
+func isIonEncoding(as []SymbolToken) bool {
+	return len(as) > 0 && as[0].Text != nil && *as[0].Text == "$ion_encoding"
+}
+
+// ReadEncodingDirective reads an Ion 1.1 encoding directive, returning the symbol
+// and macro tables it establishes. The directive may refer to the symbols and
+// macros currently in effect, given by lst and mt, as the $ion_encoding module.
+func readEncodingDirective(r Reader, cat Catalog, lst SymbolTable, mt *MacroTable) (SymbolTable, *MacroTable, error) {
+	v, err := ReadValue(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lst, mt, err = evalEncodingDirective(v, cat, lst, mt)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkLocalSymbols(r, lst); err != nil {
+		return nil, nil, err
+	}
+	return lst, mt, nil
+}
+
+// EvalEncodingDirective evaluates an encoding directive that has already been read.
+func evalEncodingDirective(v Value, cat Catalog, lst SymbolTable, mt *MacroTable) (SymbolTable, *MacroTable, error) {
+	e := directiveEvaluator{
+		cat: cat,
+		modules: map[string]*Module{
+			"$ion":          NewModule("$ion", 2, V11SystemSymbolTable.Symbols(), V11SystemMacroTable),
+			"$ion_encoding": NewModule("$ion_encoding", 0, localSymbols(lst), mt),
+		},
+	}
+
+	var syms []string
+	macros, _ := NewMacroTable()
+
+	clauses, err := e.clauses(v)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	foundSymbols := false
+	foundMacros := false
+
+	for _, c := range clauses {
+		var err error
+		switch c.name {
+		case "import":
+			err = e.evalImport(c.args)
+		case "module":
+			err = e.evalModule(c.args)
+		case "symbol_table":
+			if foundSymbols {
+				return nil, nil, fmt.Errorf("ion: multiple symbol_table clauses found within a single encoding directive")
+			}
+			foundSymbols = true
+			syms, err = e.evalSymbolTable(c.args)
+		case "macro_table":
+			if foundMacros {
+				return nil, nil, fmt.Errorf("ion: multiple macro_table clauses found within a single encoding directive")
+			}
+			foundMacros = true
+			macros, err = e.evalMacroTable(c.args)
+		default:
+			err = fmt.Errorf("ion: unknown encoding directive clause %v", c.name)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return NewLocalSymbolTable([]SharedSymbolTable{V11SystemSymbolTable}, syms), macros, nil
+}
+
+// localSymbols returns the text of the symbols a symbol table defines beyond the
+// system symbols, including those it imports from shared tables.
+func localSymbols(st SymbolTable) []string {
+	if st == nil || st == V1SystemSymbolTable || st == V11SystemSymbolTable {
+		return nil
+	}
+
+	start := uint64(0)
+	if imps := st.Imports(); len(imps) > 0 && imps[0].Name() == "$ion" {
+		start = imps[0].MaxID()
+	}
+
+	var syms []string
+	for id := start + 1; id <= st.MaxID(); id++ {
+		text, _ := st.FindByID(id)
+		syms = append(syms, text)
+	}
+	return syms
+}
+
+// A directiveEvaluator evaluates the clauses of an encoding directive, keeping
+// track of the modules they import or define.
+type directiveEvaluator struct {
+	cat     Catalog
+	modules map[string]*Module
+}
+
+// A directiveClause is a single (name args...) clause of an encoding directive.
+type directiveClause struct {
+	name string
+	args []Value
+}
+
+// Clauses splits the given s-expression into clauses.
+func (e *directiveEvaluator) clauses(v Value) ([]directiveClause, error) {
+	s, ok := v.(*SexpValue)
+	if !ok {
+		return nil, fmt.Errorf("ion: expected an s-expression, found %v", v.Type())
+	}
+
+	var cs []directiveClause
+	for _, c := range s.Values() {
+		cv, ok := c.(*SexpValue)
+		if !ok || cv.IsNull() || cv.Len() == 0 {
+			return nil, fmt.Errorf("ion: expected an encoding directive clause, found %v", c.Type())
+		}
+		name, ok := symbolValueText(cv.Get(0))
+		if !ok {
+			return nil, fmt.Errorf("ion: encoding directive clause must start with a symbol")
+		}
+		cs = append(cs, directiveClause{name, cv.Values()[1:]})
+	}
+	return cs, nil
+}
+
+// EvalImport evaluates an (import name catalog_name version?) clause.
+func (e *directiveEvaluator) evalImport(args []Value) error {
+	if len(args) < 2 || len(args) > 3 {
+		return fmt.Errorf("ion: import expects 2 or 3 arguments, found %v", len(args))
+	}
+
+	name, ok := symbolValueText(args[0])
+	if !ok {
+		return fmt.Errorf("ion: import name must be a symbol")
+	}
+	key, ok := args[1].(*StringValue)
+	if !ok || key.IsNull() {
+		return fmt.Errorf("ion: import of %v must give a catalog name", name)
+	}
+
+	version := 0
+	if len(args) == 3 {
+		v, ok := args[2].(*IntValue)
+		if !ok || v.IsNull() || !v.BigInt().IsInt64() {
+			return fmt.Errorf("ion: import of %v has an invalid version", name)
+		}
+		version = int(v.BigInt().Int64())
+	}
+
+	m := findModule(e.cat, key.Text(), version)
+	if m == nil {
+		if version < 1 {
+			return fmt.Errorf("ion: module %v not found in catalog", key.Text())
+		}
+		return fmt.Errorf("ion: module %v/%v not found in catalog", key.Text(), version)
+	}
+	return e.bind(name, m)
+}
+
+// EvalModule evaluates a (module name clauses...) clause, which defines a module
+// for use by later clauses.
+func (e *directiveEvaluator) evalModule(args []Value) error {
+	if len(args) == 0 {
+		return fmt.Errorf("ion: module definition lacks a name")
+	}
+	name, ok := symbolValueText(args[0])
+	if !ok {
+		return fmt.Errorf("ion: module name must be a symbol")
+	}
+
+	var syms []string
+	macros, _ := NewMacroTable()
+
+	clauses, err := e.clauses(NewSexp(args[1:]...))
+	if err != nil {
+		return err
+	}
+	for _, c := range clauses {
+		var err error
+		switch c.name {
+		case "symbol_table":
+			syms, err = e.evalSymbolTable(c.args)
+		case "macro_table":
+			macros, err = e.evalMacroTable(c.args)
+		default:
+			err = fmt.Errorf("ion: unknown module clause %v", c.name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return e.bind(name, &Module{name: name, symbols: syms, macros: macros})
+}
+
+// Bind makes a module available to later clauses under the given name.
+func (e *directiveEvaluator) bind(name string, m *Module) error {
+	if _, ok := e.modules[name]; ok {
+		return fmt.Errorf("ion: module %v is already defined", name)
+	}
+	e.modules[name] = m
+	return nil
+}
+
+// Module looks up the module a symbol names.
+func (e *directiveEvaluator) module(v Value) (*Module, error) {
+	name, ok := symbolValueText(v)
+	if !ok {
+		return nil, fmt.Errorf("ion: module name must be a symbol with known text")
+	}
+	m, ok := e.modules[name]
+	if !ok {
+		return nil, fmt.Errorf("ion: unknown module %v", name)
+	}
+	return m, nil
+}
+
+// EvalSymbolTable evaluates the arguments of a symbol_table clause, each of which
+// is either the name of a module whose symbols are appended or a list of symbol
+// text.
+func (e *directiveEvaluator) evalSymbolTable(args []Value) ([]string, error) {
+	var syms []string
+	for _, a := range args {
+		switch a := a.(type) {
+		case *SymbolValue:
+			m, err := e.module(a)
+			if err != nil {
+				return nil, err
+			}
+			syms = append(syms, m.symbols...)
+
+		case *ListValue:
+			for _, v := range a.Values() {
+				text, err := textArg(v)
+				if err != nil {
+					// As in Ion 1.0, anything else defines a symbol with unknown text.
+					text = ""
+				}
+				syms = append(syms, text)
+			}
+
+		default:
+			return nil, fmt.Errorf("ion: expected a module name or a list of symbols, found %v", a.Type())
+		}
+	}
+	return syms, nil
+}
+
+// EvalMacroTable evaluates the arguments of a macro_table clause, each of which is
+// either the name of a module whose macros are appended or a macro definition.
+// Definitions may invoke any macro that precedes them in the table.
+func (e *directiveEvaluator) evalMacroTable(args []Value) (*MacroTable, error) {
+	mt, _ := NewMacroTable()
+	for _, a := range args {
+		if _, ok := a.(*SymbolValue); ok {
+			m, err := e.module(a)
+			if err != nil {
+				return nil, err
+			}
+			for _, mm := range m.macros.Macros() {
+				if err := mt.add(mm); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		if err := mt.compile([]Value{a}); err != nil {
+			return nil, err
+		}
+	}
+	return mt, nil
+}
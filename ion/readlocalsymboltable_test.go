@@ -1,9 +1,11 @@
 package ion
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLocalSymbolTableAppend(t *testing.T) {
@@ -155,6 +157,137 @@ func TestLocalSymbolTableAppendOutOfBounds(t *testing.T) {
 	checkUnknownSymbolID(t, systemMaxID+3, st)
 }
 
+func TestEncodingDirective(t *testing.T) {
+	text := `$ion_1_1
+			$ion_encoding::(
+			  (symbol_table ["s1", "s2"])
+			  (macro_table (macro pair (x y) [(%x), (%y)]))
+			)
+			(:pair 1 2)
+			$ion_encoding::(
+			  (symbol_table $ion_encoding ["s3"])
+			  (macro_table $ion_encoding (macro one () 1))
+			)
+			(:0 $66 $67)
+			(:one)`
+
+	r := NewReaderString(text)
+	vals, err := ReadValues(r)
+	require.NoError(t, err)
+	assertValues(t, "[1, 2] [s1, s2] 1", vals)
+
+	st := r.SymbolTable()
+	systemMaxID := getSystemMaxID(st)
+	assert.Equal(t, V11SystemSymbolTable.MaxID(), systemMaxID)
+
+	checkSymbol(t, "s1", systemMaxID+1, st)
+	checkSymbol(t, "s2", systemMaxID+2, st)
+	checkSymbol(t, "s3", systemMaxID+3, st)
+	checkUnknownSymbolID(t, systemMaxID+4, st)
+}
+
+func TestEncodingDirectiveModules(t *testing.T) {
+	mt, err := ParseMacroTable("(macro point (x y) {lat: (%x), lon: (%y)})")
+	require.NoError(t, err)
+
+	cat := NewModuleCatalog(
+		NewModule("com.example.geo", 1, []string{"old"}, nil),
+		NewModule("com.example.geo", 2, []string{"lat", "lon"}, mt),
+	)
+
+	text := `$ion_1_1
+			$ion_encoding::(
+			  (import geo "com.example.geo" 2)
+			  (module local (symbol_table ["s1"]) (macro_table (macro twice (v) [(%v), (%v)])))
+			  (symbol_table geo local)
+			  (macro_table geo local)
+			)
+			(:point 1 2)
+			(:1 a)
+			(:$ion::use "com.example.geo" 1)
+			(:twice b)`
+
+	r := NewReaderCat(strings.NewReader(text), cat)
+	vals, err := ReadValues(r)
+	require.NoError(t, err)
+	assertValues(t, "{lat: 1, lon: 2} [a, a] [b, b]", vals)
+
+	st := r.SymbolTable()
+	systemMaxID := getSystemMaxID(st)
+
+	checkSymbol(t, "lat", systemMaxID+1, st)
+	checkSymbol(t, "lon", systemMaxID+2, st)
+	checkSymbol(t, "s1", systemMaxID+3, st)
+	checkSymbol(t, "old", systemMaxID+4, st)
+	checkUnknownSymbolID(t, systemMaxID+5, st)
+}
+
+func TestEncodingDirectiveSystemMacros(t *testing.T) {
+	text := `$ion_1_1
+			(:$ion::set_symbols s1 "s2")
+			(:$ion::add_symbols s3)
+			(:$ion::set_macros (macro one () 1))
+			(:$ion::add_macros (macro two () 2))
+			(:$ion::values (:one) (:two))
+			(:$ion::values $66 $68)`
+
+	r := NewReaderString(text)
+	vals, err := ReadValues(r)
+	require.NoError(t, err)
+	assertValues(t, "1 2 s1 s3", vals)
+
+	st := r.SymbolTable()
+	systemMaxID := getSystemMaxID(st)
+
+	checkSymbol(t, "s1", systemMaxID+1, st)
+	checkSymbol(t, "s2", systemMaxID+2, st)
+	checkSymbol(t, "s3", systemMaxID+3, st)
+}
+
+func TestEncodingDirectiveVersions(t *testing.T) {
+	// Without an Ion 1.1 version marker, $ion_encoding sexps are ordinary values.
+	text := `$ion_encoding::((symbol_table ["s1"]))
+			$ion_1_1
+			$ion_encoding::((symbol_table ["s1"]) (macro_table (macro one () 1)))
+			(:one)
+			$ion_1_0
+			$ion_encoding::((symbol_table ["s1"]))`
+
+	r := NewReaderString(text)
+	vals, err := ReadValues(r)
+	require.NoError(t, err)
+
+	// Version markers reset the symbol and macro tables.
+	assertValues(t, `$ion_encoding::((symbol_table ["s1"])) 1 $ion_encoding::((symbol_table ["s1"]))`, vals)
+	assert.Equal(t, V1SystemSymbolTable, r.SymbolTable())
+}
+
+func TestEncodingDirectiveErrors(t *testing.T) {
+	cat := NewCatalog(NewSharedSymbolTable("com.example", 1, []string{"s1"}))
+
+	test := func(text, expected string) {
+		t.Run(text, func(t *testing.T) {
+			_, err := ReadValues(NewReaderCat(strings.NewReader("$ion_1_1 "+text), cat))
+			require.Error(t, err)
+			assert.Equal(t, expected, err.Error())
+		})
+	}
+
+	test("$ion_encoding::(foo)", "ion: expected an encoding directive clause, found symbol")
+	test("$ion_encoding::((foo))", "ion: unknown encoding directive clause foo")
+	test("$ion_encoding::((symbol_table) (symbol_table))", "ion: multiple symbol_table clauses found within a single encoding directive")
+	test("$ion_encoding::((macro_table) (macro_table))", "ion: multiple macro_table clauses found within a single encoding directive")
+	test("$ion_encoding::((symbol_table m))", "ion: unknown module m")
+	test("$ion_encoding::((symbol_table 1))", "ion: expected a module name or a list of symbols, found int")
+	test("$ion_encoding::((macro_table (macro a () 1) (macro a () 2)))", "ion: duplicate macro name a")
+	test("$ion_encoding::((import m))", "ion: import expects 2 or 3 arguments, found 1")
+	test(`$ion_encoding::((import m "com.example" 2))`, "ion: module com.example/2 not found in catalog")
+	test(`$ion_encoding::((import m "missing"))`, "ion: module missing not found in catalog")
+	test(`$ion_encoding::((import $ion "com.example"))`, "ion: module $ion is already defined")
+	test("$ion_encoding::((module m (import x)))", "ion: unknown module clause import")
+	test(`(:$ion::use "missing")`, "ion: module missing not found in catalog")
+}
+
 func getSystemMaxID(st SymbolTable) uint64 {
 	imports := st.Imports()
 	systemTable := imports[0]
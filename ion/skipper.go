@@ -103,7 +103,7 @@ func (t *tokenizer) skipValue() (int, error) {
 		c, err = t.skipBlob()
 	case tokenOpenBrace:
 		c, err = t.skipStruct()
-	case tokenOpenParen:
+	case tokenOpenParen, tokenOpenEExpression, tokenOpenExpressionGroup:
 		c, err = t.skipSexp()
 	case tokenOpenBracket:
 		c, err = t.skipList()
@@ -654,11 +654,22 @@ func (t *tokenizer) skipContainer(term int) (int, error) {
 
 // SkipContainerHelper skips over a container terminated by the given
 // char.
-func (t *tokenizer) skipContainerHelper(term int) error {
+func (t *tokenizer) skipContainerHelper(term int) (err error) {
 	if term != ']' && term != ')' && term != '}' {
 		panic(fmt.Sprintf("unexpected character: %q. Expected one of the closing container characters: ] } )", term))
 	}
 
+	t.depth++
+	defer func() {
+		if err != nil && t.depth > t.errDepth {
+			t.errDepth = t.depth
+		}
+		t.depth--
+	}()
+	if t.maxDepth > 0 && t.depth > t.maxDepth {
+		return &LimitError{"depth", uint64(t.maxDepth), t.pos - 1}
+	}
+
 	for {
 		c, _, err := t.skipWhitespace()
 		if err != nil {
@@ -741,6 +752,9 @@ func (t *tokenizer) skipDigits(c int) (int, error) {
 // SkipWhitespace skips whitespace (and comments) when we're out
 // in normal parsing territory.
 func (t *tokenizer) skipWhitespace() (int, bool, error) {
+	if t.json {
+		return t.skipWhitespaceWith(t.noJSONCommentsHandler)
+	}
 	return t.skipWhitespaceWith(t.skipCommentsHandler)
 }
 
@@ -811,7 +825,7 @@ func stopForCommentsHandler() (bool, error) {
 // ensureNoCommentsHandler is a commentHandler that returns an
 // error if any comments are found, else no error is returned.
 func (t *tokenizer) ensureNoCommentsHandler() (bool, error) {
-	return false, &UnexpectedTokenError{"comments are not allowed within a clob", t.Pos() - 1}
+	return false, &UnexpectedTokenError{Token: "comments are not allowed within a clob", Offset: t.Pos() - 1}
 }
 
 // SkipCommentsHandler is a commentHandler that skips over any
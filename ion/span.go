@@ -0,0 +1,218 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"bufio"
+	"io"
+	"math"
+)
+
+// A Span identifies the position of a value in a stream, along with the symbol
+// table in effect there, so that a SeekableReader can later seek back to it.
+type Span struct {
+	offset uint64
+	lst    SymbolTable
+
+	// For text, the 1-based line and column of the value.
+	line, column uint64
+}
+
+// Offset returns the byte offset of the value (including its annotations, but
+// not its field name) from the point at which its reader started reading.
+func (s Span) Offset() uint64 {
+	return s.offset
+}
+
+// A SeekableReader is a Reader that can return the Span of the value it's
+// positioned on, and seek back to a Span later.
+//
+// Binary and text Readers are SeekableReaders. Any of them can return Spans, but
+// only Readers created from an io.ReadSeeker, an io.ReaderAt (see NewReaderAt),
+// or a []byte can seek to them. Spans are not available for Ion 1.1 streams.
+type SeekableReader interface {
+	Reader
+
+	// Span returns the Span of the current value.
+	Span() (Span, error)
+
+	// Seek moves the Reader to the given Span, which must have been returned by
+	// a Reader reading the same input, so that the following call to Next moves
+	// to the value it identifies, with the symbol table that was in effect for it.
+	// The Reader then treats that value as the whole of its input: once the
+	// Reader moves past it, Next returns false until the Reader seeks again.
+	Seek(s Span) error
+}
+
+// NewReaderAt creates a new seekable reader that reads the given number of
+// bytes from the given io.ReaderAt.
+func NewReaderAt(in io.ReaderAt, size int64) Reader {
+	return NewReader(io.NewSectionReader(in, 0, size))
+}
+
+// A seekSource is the input of a reader that can seek to spans.
+type seekSource struct {
+	rs   io.ReadSeeker
+	ra   io.ReaderAt
+	base int64
+}
+
+// newSeekSource returns a seekSource for the given input, or nil if it can't
+// seek.
+func newSeekSource(in io.Reader) *seekSource {
+	s := &seekSource{}
+	s.rs, _ = in.(io.ReadSeeker)
+	s.ra, _ = in.(io.ReaderAt)
+	if s.rs == nil && s.ra == nil {
+		return nil
+	}
+
+	// Offsets are counted from the input's current position.
+	if s.rs != nil {
+		base, err := s.rs.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil
+		}
+		s.base = base
+	}
+	return s
+}
+
+// Open returns a bufio.Reader reading from the given offset.
+func (s *seekSource) open(offset uint64) (*bufio.Reader, error) {
+	off := s.base + int64(offset)
+	if s.ra != nil {
+		return bufio.NewReader(io.NewSectionReader(s.ra, off, math.MaxInt64-off)), nil
+	}
+	if _, err := s.rs.Seek(off, io.SeekStart); err != nil {
+		return nil, &IOError{err}
+	}
+	return bufio.NewReader(s.rs), nil
+}
+
+// seekTo resets the reader to read the value identified by the given span.
+func (r *reader) seekTo(s Span) {
+	r.ctx = ctxstack{}
+	r.eof = false
+	r.err = nil
+	r.clear()
+	r.lst = s.lst
+	r.exp = nil
+	r.macros = r.defaultMacros
+	r.hoisted = true
+	r.hoistRead = false
+}
+
+// atSpanEnd returns true if the reader has sought to a span and already moved
+// past the value it identifies. It's called by Next.
+func (r *reader) atSpanEnd() bool {
+	if !r.hoisted || r.ctx.peek() != ctxAtTopLevel {
+		return false
+	}
+	if r.hoistRead {
+		r.clear()
+		r.eof = true
+		return true
+	}
+	r.hoistRead = true
+	return false
+}
+
+// Span returns the Span of the current value.
+func (r *binaryReader) Span() (Span, error) {
+	if r.err != nil {
+		return Span{}, r.err
+	}
+	if r.valueType == NoType {
+		return Span{}, &UsageError{"Reader.Span", "no current value"}
+	}
+	if r.v11 || r.exp != nil {
+		return Span{}, &UsageError{"Reader.Span", "spans are not available for Ion 1.1"}
+	}
+	return Span{offset: r.rawStart, lst: r.lst}, nil
+}
+
+// Seek moves the reader to the given Span.
+func (r *binaryReader) Seek(s Span) error {
+	switch {
+	case r.bits.inMem:
+		buf, noCopy := r.bits.buf, r.bits.noCopy
+		r.bits = bitstream{}
+		r.bits.InitBytes(buf)
+		r.bits.noCopy = noCopy
+
+	case r.src != nil:
+		in, err := r.src.open(s.offset)
+		if err != nil {
+			return err
+		}
+		r.bits = bitstream{}
+		r.bits.Init(in)
+
+	default:
+		return &UsageError{"Reader.Seek", "reader's input is not seekable"}
+	}
+
+	r.bits.pos = s.offset
+	r.bits11 = r.newBitstream11()
+	r.applyLimits()
+	r.v11 = false
+	r.seekTo(s)
+	return nil
+}
+
+// Span returns the Span of the current value.
+func (t *textReader) Span() (Span, error) {
+	if t.err != nil {
+		return Span{}, t.err
+	}
+	if t.valueType == NoType {
+		return Span{}, &UsageError{"Reader.Span", "no current value"}
+	}
+	if t.v11 || t.exp != nil {
+		return Span{}, &UsageError{"Reader.Span", "spans are not available for Ion 1.1"}
+	}
+	return Span{t.spanStart, t.lst, t.spanLine, t.spanColumn}, nil
+}
+
+// Seek moves the reader to the given Span.
+func (t *textReader) Seek(s Span) error {
+	if t.src == nil {
+		return &UsageError{"Reader.Seek", "reader's input is not seekable"}
+	}
+	in, err := t.src.open(s.offset)
+	if err != nil {
+		return err
+	}
+
+	t.tok = tokenizer{
+		in:   in,
+		pos:  s.offset,
+		json: t.tok.json,
+	}
+	if s.line > 0 {
+		t.tok.startLine(s.line-1, s.offset-s.column+1)
+	}
+	t.path = []pathStep{{index: -1}}
+	t.recovering = false
+	t.applyLimits()
+	t.state = trsBeforeTypeAnnotations
+	t.v11 = false
+	t.jsonPrev = tokenError
+	t.jsonValue = false
+	t.seekTo(s)
+	return nil
+}
@@ -0,0 +1,187 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// encodeBinary encodes the top-level values in the given text as binary.
+func encodeBinary(t *testing.T, text string) []byte {
+	vals, err := ReadValues(NewReaderString(text))
+	require.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	w := NewBinaryWriter(&buf)
+	for _, v := range vals {
+		require.NoError(t, v.MarshalIon(w))
+	}
+	require.NoError(t, w.Finish())
+	return buf.Bytes()
+}
+
+// readSpans returns the spans and text of the top-level values read by r.
+func readSpans(t *testing.T, r Reader) ([]Span, []string) {
+	var spans []Span
+	var texts []string
+	for r.Next() {
+		s, err := r.(SeekableReader).Span()
+		require.NoError(t, err)
+		spans = append(spans, s)
+
+		v, err := ReadValue(r)
+		require.NoError(t, err)
+		text, err := MarshalText(v)
+		require.NoError(t, err)
+		texts = append(texts, string(text))
+	}
+	require.NoError(t, r.Err())
+	return spans, texts
+}
+
+// seekSpans seeks to each of the given spans in reverse order, checking that
+// the reader reads only the value each identifies.
+func seekSpans(t *testing.T, r Reader, spans []Span, texts []string) {
+	sr := r.(SeekableReader)
+	for i := len(spans) - 1; i >= 0; i-- {
+		require.NoError(t, sr.Seek(spans[i]))
+		require.True(t, sr.Next())
+
+		v, err := ReadValue(sr)
+		require.NoError(t, err)
+		text, err := MarshalText(v)
+		require.NoError(t, err)
+		assert.Equal(t, texts[i], string(text))
+
+		assert.False(t, sr.Next())
+		require.NoError(t, sr.Err())
+	}
+}
+
+const spanTestData = `a::b {c:[d, "e"], f:g::h} 1.5 (i j)`
+
+func TestSpansBinary(t *testing.T) {
+	// Each stream has its own local symbol table.
+	bs := append(encodeBinary(t, spanTestData), encodeBinary(t, `x y::z {w:v}`)...)
+
+	spans, texts := readSpans(t, NewReaderBytes(bs))
+	require.Len(t, spans, 7)
+	assert.Equal(t, []string{"a::b", "{c:[d,\"e\"],f:g::h}", "1.5", "(i j)", "x", "y::z", "{w:v}"}, texts)
+
+	test := func(name string, r Reader) {
+		t.Run(name, func(t *testing.T) {
+			seekSpans(t, r, spans, texts)
+		})
+	}
+	test("bytes", NewReaderBytes(bs))
+	test("no copy", NewReaderBytesNoCopy(bs))
+	test("read seeker", NewReader(bytes.NewReader(bs)))
+	test("reader at", NewReaderAt(bytes.NewReader(bs), int64(len(bs))))
+}
+
+func TestSpansText(t *testing.T) {
+	text := "$ion_symbol_table::{symbols:[\"s\"]}\n" + spanTestData + "\r\n// comment\r\n $10 'k'::l"
+
+	spans, texts := readSpans(t, NewReaderString(text))
+	require.Len(t, spans, 6)
+	assert.Equal(t, uint64(35), spans[0].Offset())
+
+	seekSpans(t, NewReader(bytes.NewReader([]byte(text))), spans, texts)
+	assert.Equal(t, "s", texts[4])
+}
+
+func TestSpanNested(t *testing.T) {
+	bs := encodeBinary(t, `{a:[b, c::d], e:f} g`)
+
+	test := func(name string, r Reader) {
+		t.Run(name, func(t *testing.T) {
+			sr := r.(SeekableReader)
+			require.True(t, sr.Next())
+			require.NoError(t, sr.StepIn())
+			require.True(t, sr.Next())
+			require.NoError(t, sr.StepIn())
+			require.True(t, sr.Next())
+			require.True(t, sr.Next())
+			span, err := sr.Span()
+			require.NoError(t, err)
+
+			// The nested value is read as if it were the only top-level value.
+			require.NoError(t, sr.Seek(span))
+			_symbolAF(t, sr, nil, []SymbolToken{NewSymbolTokenFromString("c")}, newSymbolTokenPtrFromString("d"), false, false)
+			assert.False(t, sr.Next())
+			assert.Error(t, sr.StepOut())
+		})
+	}
+	test("binary", NewReaderBytes(bs))
+	test("text", NewReader(bytes.NewReader([]byte(`{a:[b, c::d], e:f} g`))))
+}
+
+func TestSpanSeekReadSeekerOffset(t *testing.T) {
+	prefix := []byte("garbage")
+	bs := append(prefix, encodeBinary(t, `a b`)...)
+
+	in := bytes.NewReader(bs)
+	_, err := in.Seek(int64(len(prefix)), io.SeekStart)
+	require.NoError(t, err)
+
+	r := NewReader(in).(SeekableReader)
+	require.True(t, r.Next())
+	require.True(t, r.Next())
+	span, err := r.Span()
+	require.NoError(t, err)
+
+	require.NoError(t, r.Seek(span))
+	_symbol(t, r, NewSymbolTokenFromString("b"))
+	_eof(t, r)
+}
+
+func TestSpanErrors(t *testing.T) {
+	r := NewReader(bytes.NewBufferString("a")).(SeekableReader)
+	_, err := r.Span()
+	assert.IsType(t, &UsageError{}, err)
+
+	require.True(t, r.Next())
+	span, err := r.Span()
+	require.NoError(t, err)
+
+	// A reader over a plain io.Reader can return spans, but can't seek to them.
+	err = r.Seek(span)
+	assert.IsType(t, &UsageError{}, err)
+
+	r = NewReader(bytes.NewBuffer(encodeBinary(t, "a"))).(SeekableReader)
+	err = r.Seek(span)
+	assert.IsType(t, &UsageError{}, err)
+}
+
+func TestSpanPosition(t *testing.T) {
+	r := NewReaderString("a\n  b c\n d").(SeekableReader)
+	require.True(t, r.Next())
+	require.True(t, r.Next())
+	span, err := r.Span()
+	require.NoError(t, err)
+	assert.Equal(t, Position{4, 2, 3}, r.(PositionReader).Position())
+
+	// Lines and columns carry on from the span.
+	require.NoError(t, r.Seek(span))
+	require.True(t, r.Next())
+	assert.Equal(t, Position{4, 2, 3}, r.(PositionReader).Position())
+	assert.False(t, r.Next())
+}
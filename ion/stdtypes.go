@@ -0,0 +1,512 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	bigFloatType        = reflect.TypeOf(big.Float{})
+	bigRatType          = reflect.TypeOf(big.Rat{})
+	ipType              = reflect.TypeOf(net.IP{})
+	ipNetType           = reflect.TypeOf(net.IPNet{})
+	urlType             = reflect.TypeOf(url.URL{})
+	jsonNumberType      = reflect.TypeOf(json.Number(""))
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// isNativeType returns true if the given type is one that maps directly onto an
+// Ion type, and is encoded as such even though it may be a TextMarshaler.
+func isNativeType(t reflect.Type) bool {
+	switch t {
+	case timestampType, nativeTimeType, decimalType, bigIntType, symbolType:
+		return true
+	}
+	return false
+}
+
+// encodeStdType encodes values of the standard library types that have a
+// natural Ion representation, and of types that implement
+// encoding.TextMarshaler (as strings). It returns false if v is not one of them.
+//
+// Durations are encoded as ints (of nanoseconds); big.Floats as decimals, which
+// can represent them exactly, or as floats if they're infinite; big.Rats as
+// decimals if they have an exact decimal representation, and as strings
+// ("1/3") if not; IP addresses, networks, and URLs as strings; and json.Numbers
+// as ints or decimals.
+func (m *Encoder) encodeStdType(v reflect.Value) (bool, error) {
+	t := v.Type()
+	switch t {
+	case durationType:
+		return true, m.w.WriteInt(v.Int())
+
+	case bigFloatType:
+		return true, m.encodeBigFloat(addr(v).(*big.Float))
+
+	case bigRatType:
+		r := addr(v).(*big.Rat)
+		if d, ok := ratToDecimal(r); ok {
+			return true, m.w.WriteDecimal(d)
+		}
+		return true, m.w.WriteString(r.String())
+
+	case ipType:
+		if v.IsNil() {
+			return true, m.w.WriteNull()
+		}
+		return true, m.w.WriteString(v.Interface().(net.IP).String())
+
+	case ipNetType:
+		return true, m.w.WriteString(addr(v).(*net.IPNet).String())
+
+	case urlType:
+		return true, m.w.WriteString(addr(v).(*url.URL).String())
+
+	case jsonNumberType:
+		return true, m.encodeJSONNumber(json.Number(v.String()))
+	}
+
+	if t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface || isNativeType(t) {
+		return false, nil
+	}
+
+	var tm encoding.TextMarshaler
+	if t.Implements(textMarshalerType) {
+		tm = v.Interface().(encoding.TextMarshaler)
+	} else if v.CanAddr() && reflect.PtrTo(t).Implements(textMarshalerType) {
+		tm = v.Addr().Interface().(encoding.TextMarshaler)
+	} else {
+		return false, nil
+	}
+
+	text, err := tm.MarshalText()
+	if err != nil {
+		return true, err
+	}
+	return true, m.w.WriteString(string(text))
+}
+
+// addr returns a pointer to the given value, or to a copy of it if it isn't
+// addressable.
+func addr(v reflect.Value) interface{} {
+	if v.CanAddr() {
+		return v.Addr().Interface()
+	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return p.Interface()
+}
+
+// encodeBigFloat encodes a big.Float as an Ion decimal, or float if it's infinite.
+func (m *Encoder) encodeBigFloat(f *big.Float) error {
+	if f.IsInf() {
+		return m.w.WriteFloat(math.Inf(f.Sign()))
+	}
+
+	r, _ := f.Rat(nil)
+	d, ok := ratToDecimal(r)
+	if !ok {
+		return fmt.Errorf("ion: cannot encode %v as a decimal", f)
+	}
+	if f.Sign() == 0 && f.Signbit() {
+		d.isNegZero = true
+	}
+	return m.w.WriteDecimal(d)
+}
+
+// encodeJSONNumber encodes a json.Number as an Ion int if it's an integer, and
+// as a decimal otherwise.
+func (m *Encoder) encodeJSONNumber(n json.Number) error {
+	if n == "" {
+		return m.w.WriteInt(0)
+	}
+	if i, ok := new(big.Int).SetString(string(n), 10); ok {
+		return m.w.WriteBigInt(i)
+	}
+
+	d := &Decimal{}
+	if err := d.UnmarshalJSON([]byte(n)); err != nil {
+		return fmt.Errorf("ion: invalid json.Number %q", string(n))
+	}
+	return m.w.WriteDecimal(d)
+}
+
+// ratToDecimal returns the decimal exactly equal to r, if there is one (which is
+// to say, if r's denominator has no prime factors other than 2 and 5).
+func ratToDecimal(r *big.Rat) (*Decimal, bool) {
+	den := new(big.Int).Set(r.Denom())
+	twos := den.TrailingZeroBits()
+	den.Rsh(den, twos)
+
+	fives := uint(0)
+	five := big.NewInt(5)
+	q, rem := new(big.Int), new(big.Int)
+	for {
+		q.QuoRem(den, five, rem)
+		if rem.Sign() != 0 {
+			break
+		}
+		den, q = q, den
+		fives++
+	}
+	if !den.IsInt64() || den.Int64() != 1 {
+		return nil, false
+	}
+
+	scale := twos
+	if fives > scale {
+		scale = fives
+	}
+	if scale > math.MaxInt32 {
+		return nil, false
+	}
+
+	n := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+	n.Mul(n, r.Num())
+	n.Quo(n, r.Denom())
+	return NewDecimal(n, -int32(scale), false), true
+}
+
+// maxRatExponent is the largest decimal exponent (positive or negative) that
+// will be decoded into a big.Rat, whose size grows with the exponent. It's that
+// of the IEEE 754 decimal128 format.
+const maxRatExponent = 6144
+
+// decimalToRat returns the rational number equal to d, or an error if its
+// exponent is out of range.
+func decimalToRat(d *Decimal) (*big.Rat, error) {
+	n, exp := d.CoEx()
+	r := new(big.Rat).SetInt(n)
+	if exp == 0 || n.Sign() == 0 {
+		return r, nil
+	}
+
+	abs := int64(exp)
+	if abs < 0 {
+		abs = -abs
+	}
+	if abs > maxRatExponent {
+		return nil, fmt.Errorf("ion: cannot decode %v to big.Rat: exponent out of range", d)
+	}
+
+	p := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(abs), nil))
+	if exp > 0 {
+		return r.Mul(r, p), nil
+	}
+	return r.Quo(r, p), nil
+}
+
+// decimalToFloat sets f to d, rounded to f's precision (or, if f has none, to 64
+// bits or the size of d's coefficient, whichever is more). Powers of ten are
+// computed at that precision too, so large exponents are cheap.
+func decimalToFloat(f *big.Float, d *Decimal) {
+	n, exp := d.CoEx()
+	prec := f.Prec()
+	if prec == 0 {
+		prec = 64
+		if bits := uint(n.BitLen()); bits > prec {
+			prec = bits
+		}
+		f.SetPrec(prec)
+	}
+
+	f.SetInt(n)
+	if exp != 0 && n.Sign() != 0 {
+		// 10^exp = 5^exp * 2^exp, and multiplying by 2^exp is exact.
+		abs := uint64(exp)
+		if exp < 0 {
+			abs = uint64(-int64(exp))
+		}
+		p := pow5(abs, prec+32)
+		if exp > 0 {
+			f.Mul(f, p)
+		} else {
+			f.Quo(f, p)
+		}
+		f.SetMantExp(f, int(exp))
+	}
+
+	if d.isNegZero {
+		f.Neg(f)
+	}
+}
+
+// pow5 returns 5^n, computed at the given precision.
+func pow5(n uint64, prec uint) *big.Float {
+	z := new(big.Float).SetPrec(prec).SetInt64(1)
+	x := new(big.Float).SetPrec(prec).SetInt64(5)
+	for ; n > 0; n >>= 1 {
+		if n&1 != 0 {
+			z.Mul(z, x)
+		}
+		if n > 1 {
+			x.Mul(x, x)
+		}
+	}
+	return z
+}
+
+// decodeStdTypeTo decodes the current value into values of the standard library
+// types encodeStdType handles, and of types that implement
+// encoding.TextUnmarshaler (from strings and symbols). It returns false if v is
+// not one of them, or if the current value should be decoded into it as usual
+// (ints into Durations, say).
+//
+// As well as the representations encodeStdType uses, Durations can be decoded
+// from strings (as parsed by time.ParseDuration); big.Floats and big.Rats from
+// ints, floats, and decimals; and json.Numbers from ints, floats, decimals, and
+// strings.
+func (d *Decoder) decodeStdTypeTo(v reflect.Value) (bool, error) {
+	t := v.Type()
+	typ := d.r.Type()
+
+	switch t {
+	case durationType:
+		if typ != StringType {
+			return false, nil
+		}
+		s, err := d.r.StringValue()
+		if err != nil {
+			return true, err
+		}
+		dur, err := time.ParseDuration(*s)
+		if err != nil {
+			return true, fmt.Errorf("ion: cannot decode %q to time.Duration: %v", *s, err)
+		}
+		v.SetInt(int64(dur))
+		return true, nil
+
+	case bigFloatType:
+		return true, d.decodeBigFloatTo(v.Addr().Interface().(*big.Float))
+
+	case bigRatType:
+		return true, d.decodeBigRatTo(v.Addr().Interface().(*big.Rat))
+
+	case ipType:
+		if typ != StringType && typ != SymbolType {
+			return false, nil
+		}
+		s, err := d.stringValue()
+		if err != nil {
+			return true, err
+		}
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return true, fmt.Errorf("ion: cannot decode %q to net.IP", s)
+		}
+		v.Set(reflect.ValueOf(ip))
+		return true, nil
+
+	case ipNetType:
+		if typ != StringType && typ != SymbolType {
+			return false, nil
+		}
+		s, err := d.stringValue()
+		if err != nil {
+			return true, err
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return true, fmt.Errorf("ion: cannot decode %q to net.IPNet: %v", s, err)
+		}
+		v.Set(reflect.ValueOf(*n))
+		return true, nil
+
+	case urlType:
+		if typ != StringType && typ != SymbolType {
+			return false, nil
+		}
+		s, err := d.stringValue()
+		if err != nil {
+			return true, err
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			return true, fmt.Errorf("ion: cannot decode %q to url.URL: %v", s, err)
+		}
+		v.Set(reflect.ValueOf(*u))
+		return true, nil
+
+	case jsonNumberType:
+		n, err := d.jsonNumber()
+		if err != nil {
+			return true, err
+		}
+		v.SetString(n)
+		return true, nil
+	}
+
+	if (typ != StringType && typ != SymbolType) || !reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return false, nil
+	}
+	s, err := d.stringValue()
+	if err != nil {
+		return true, err
+	}
+	return true, v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+}
+
+// stringValue returns the text of the current string or symbol value.
+func (d *Decoder) stringValue() (string, error) {
+	if d.r.Type() == SymbolType {
+		sym, err := d.r.SymbolValue()
+		if err != nil {
+			return "", err
+		}
+		if sym.Text == nil {
+			return "", fmt.Errorf("ion: symbol $%v has unknown text", sym.LocalSID)
+		}
+		return *sym.Text, nil
+	}
+
+	s, err := d.r.StringValue()
+	if err != nil {
+		return "", err
+	}
+	return *s, nil
+}
+
+// decodeBigFloatTo decodes the current int, float, or decimal value into f.
+func (d *Decoder) decodeBigFloatTo(f *big.Float) error {
+	switch d.r.Type() {
+	case IntType:
+		i, err := d.r.BigIntValue()
+		if err != nil {
+			return err
+		}
+		f.SetInt(i)
+		return nil
+
+	case FloatType:
+		val, err := d.r.FloatValue()
+		if err != nil {
+			return err
+		}
+		if math.IsNaN(*val) {
+			return fmt.Errorf("ion: cannot decode nan to big.Float")
+		}
+		f.SetFloat64(*val)
+		return nil
+
+	case DecimalType:
+		val, err := d.r.DecimalValue()
+		if err != nil {
+			return err
+		}
+		decimalToFloat(f, val)
+		return nil
+	}
+	return fmt.Errorf("ion: cannot decode %v to big.Float", d.r.Type())
+}
+
+// decodeBigRatTo decodes the current int, float, decimal, or string value into r.
+func (d *Decoder) decodeBigRatTo(r *big.Rat) error {
+	switch d.r.Type() {
+	case IntType:
+		i, err := d.r.BigIntValue()
+		if err != nil {
+			return err
+		}
+		r.SetInt(i)
+		return nil
+
+	case FloatType:
+		val, err := d.r.FloatValue()
+		if err != nil {
+			return err
+		}
+		if r.SetFloat64(*val) == nil {
+			return fmt.Errorf("ion: cannot decode %v to big.Rat", *val)
+		}
+		return nil
+
+	case DecimalType:
+		val, err := d.r.DecimalValue()
+		if err != nil {
+			return err
+		}
+		rat, err := decimalToRat(val)
+		if err != nil {
+			return err
+		}
+		r.Set(rat)
+		return nil
+
+	case StringType:
+		s, err := d.r.StringValue()
+		if err != nil {
+			return err
+		}
+		if _, ok := r.SetString(*s); !ok {
+			return fmt.Errorf("ion: cannot decode %q to big.Rat", *s)
+		}
+		return nil
+	}
+	return fmt.Errorf("ion: cannot decode %v to big.Rat", d.r.Type())
+}
+
+// jsonNumber returns the current int, float, decimal, or string value as the
+// text of a json.Number.
+func (d *Decoder) jsonNumber() (string, error) {
+	switch d.r.Type() {
+	case IntType:
+		i, err := d.r.BigIntValue()
+		if err != nil {
+			return "", err
+		}
+		return i.String(), nil
+
+	case FloatType:
+		val, err := d.r.FloatValue()
+		if err != nil {
+			return "", err
+		}
+		if math.IsNaN(*val) || math.IsInf(*val, 0) {
+			return "", fmt.Errorf("ion: cannot decode %v to json.Number", *val)
+		}
+		return strconv.FormatFloat(*val, 'g', -1, 64), nil
+
+	case DecimalType:
+		val, err := d.r.DecimalValue()
+		if err != nil {
+			return "", err
+		}
+		return jsonDecimal(val), nil
+
+	case StringType:
+		s, err := d.r.StringValue()
+		if err != nil {
+			return "", err
+		}
+		if _, err := strconv.ParseFloat(*s, 64); err != nil {
+			return "", fmt.Errorf("ion: cannot decode %q to json.Number", *s)
+		}
+		return *s, nil
+	}
+	return "", fmt.Errorf("ion: cannot decode %v to json.Number", d.r.Type())
+}
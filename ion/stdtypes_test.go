@@ -0,0 +1,255 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"encoding/json"
+	"math/big"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A textLevel is a TextMarshaler with an underlying int.
+type textLevel int
+
+func (l textLevel) MarshalText() ([]byte, error) {
+	return []byte(strings.Repeat("!", int(l))), nil
+}
+
+func (l *textLevel) UnmarshalText(text []byte) error {
+	*l = textLevel(len(text))
+	return nil
+}
+
+// A textPoint is a TextMarshaler (via its pointer) struct.
+type textPoint struct {
+	X, Y int
+}
+
+func (p *textPoint) MarshalText() ([]byte, error) {
+	return []byte(string(rune('a'+p.X)) + string(rune('a'+p.Y))), nil
+}
+
+func (p *textPoint) UnmarshalText(text []byte) error {
+	p.X, p.Y = int(text[0]-'a'), int(text[1]-'a')
+	return nil
+}
+
+// roundTripStd marshals v (a pointer) to text and binary, checking the text and
+// that it unmarshals back to an equal value.
+func roundTripStd(t *testing.T, v interface{}, expected string) {
+	text, err := MarshalText(v)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(text))
+
+	bin, err := MarshalBinary(v)
+	require.NoError(t, err)
+
+	for _, data := range [][]byte{text, bin} {
+		out := reflect.New(reflect.TypeOf(v).Elem())
+		require.NoError(t, Unmarshal(data, out.Interface()))
+		assert.Equal(t, v, out.Interface())
+	}
+}
+
+func TestStdTypesRoundTrip(t *testing.T) {
+	dur := 90 * time.Second
+	roundTripStd(t, &dur, "90000000000")
+
+	rat := big.NewRat(-3, 8)
+	roundTripStd(t, rat, "-3.75d-1")
+	roundTripStd(t, big.NewRat(1, 3), `"1/3"`)
+	roundTripStd(t, big.NewRat(12, 1), "12.")
+
+	ip := net.ParseIP("192.168.0.1")
+	roundTripStd(t, &ip, `"192.168.0.1"`)
+	ip6 := net.ParseIP("2001:db8::1")
+	roundTripStd(t, &ip6, `"2001:db8::1"`)
+
+	_, ipNet, err := net.ParseCIDR("10.1.0.0/16")
+	require.NoError(t, err)
+	roundTripStd(t, ipNet, `"10.1.0.0/16"`)
+
+	u, err := url.Parse("https://example.com/a/b?c=d#e")
+	require.NoError(t, err)
+	roundTripStd(t, u, `"https://example.com/a/b?c=d#e"`)
+
+	for text, ion := range map[string]string{
+		"42":                    "42",
+		"-12345678901234567890": "-12345678901234567890",
+		"1.5":                   "1.5",
+	} {
+		n := json.Number(text)
+		roundTripStd(t, &n, ion)
+	}
+
+	// Numbers with exponents round-trip to an equal value, but not the same text.
+	n := json.Number("1.5e3")
+	text, err := MarshalText(&n)
+	require.NoError(t, err)
+	assert.Equal(t, "15d2", string(text))
+	require.NoError(t, Unmarshal(text, &n))
+	assert.Equal(t, json.Number("15e2"), n)
+
+	level := textLevel(3)
+	roundTripStd(t, &level, `"!!!"`)
+	roundTripStd(t, &textPoint{1, 2}, `"bc"`)
+}
+
+func TestStdTypesBigFloat(t *testing.T) {
+	f := new(big.Float).SetFloat64(-1.25)
+	text, err := MarshalText(f)
+	require.NoError(t, err)
+	assert.Equal(t, "-1.25", string(text))
+
+	test := func(ion string, expected string) {
+		out := new(big.Float)
+		require.NoError(t, UnmarshalString(ion, out))
+		assert.Equal(t, expected, out.Text('g', 20))
+	}
+	test("-1.25", "-1.25")
+	test("0.1", "0.1")
+	test("12345678901234567890123", "1.234567890123456789e+22")
+	test("2.5e0", "2.5")
+	test("-0.", "-0")
+
+	// Values that aren't exactly representable as decimals don't exist, but
+	// infinite ones do.
+	text, err = MarshalText(new(big.Float).SetInf(true))
+	require.NoError(t, err)
+	assert.Equal(t, "-inf", string(text))
+
+	// Huge exponents are cheap.
+	huge := func(ion string, mant float64, exp int) {
+		out := new(big.Float)
+		require.NoError(t, UnmarshalString(ion, out))
+		m := new(big.Float)
+		assert.Equal(t, exp, out.MantExp(m))
+		f, _ := m.Float64()
+		assert.InDelta(t, mant, f, 1e-15)
+	}
+	huge("1d100000000", 0.701607577702825695, 332192810)
+	huge("-3d-100000000", -0.534486815589719510, -332192807)
+	test("1d2000000000", "+Inf")
+	test("1d-2000000000", "0")
+	test("0d2000000000", "0")
+
+	assert.Error(t, UnmarshalString("nan", new(big.Float)))
+	assert.Error(t, UnmarshalString(`"1.5"`, new(big.Float)))
+}
+
+func TestStdTypesInStruct(t *testing.T) {
+	type holder struct {
+		Timeout time.Duration     `ion:"timeout"`
+		Price   *big.Rat          `ion:"price"`
+		Host    net.IP            `ion:"host"`
+		Link    url.URL           `ion:"link"`
+		Count   json.Number       `ion:"count"`
+		Level   textLevel         `ion:"level"`
+		Created time.Time         `ion:"created"`
+		Big     big.Int           `ion:"big"`
+		Missing *big.Float        `ion:"missing"`
+		Values  []json.Number     `ion:"values"`
+		ByName  map[string]net.IP `ion:"by_name"`
+	}
+
+	in := holder{
+		Timeout: time.Millisecond,
+		Price:   big.NewRat(199, 100),
+		Host:    net.ParseIP("127.0.0.1"),
+		Link:    url.URL{Scheme: "http", Host: "localhost"},
+		Count:   "7",
+		Level:   2,
+		Created: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Big:     *big.NewInt(5),
+		Values:  []json.Number{"1", "2.5"},
+		ByName:  map[string]net.IP{"a": net.ParseIP("::1")},
+	}
+
+	text, err := MarshalText(&in)
+	require.NoError(t, err)
+	assert.Equal(t, `{timeout:1000000,price:1.99,host:"127.0.0.1",link:"http://localhost",count:7,`+
+		`level:"!!",created:2020-01-02T03:04:05.000000000Z,big:5,missing:null,values:[1,2.5],`+
+		`by_name:{a:"::1"}}`, string(text))
+
+	var out holder
+	require.NoError(t, Unmarshal(text, &out))
+	assert.Equal(t, in.Timeout, out.Timeout)
+	assert.Equal(t, in.Price, out.Price)
+	assert.Equal(t, in.Host, out.Host)
+	assert.Equal(t, in.Link, out.Link)
+	assert.Equal(t, in.Count, out.Count)
+	assert.Equal(t, in.Level, out.Level)
+	assert.True(t, in.Created.Equal(out.Created))
+	assert.Equal(t, 0, in.Big.Cmp(&out.Big))
+	assert.Nil(t, out.Missing)
+	assert.Equal(t, in.Values, out.Values)
+	assert.Equal(t, in.ByName, out.ByName)
+}
+
+func TestStdTypesOtherRepresentations(t *testing.T) {
+	var dur time.Duration
+	require.NoError(t, UnmarshalString(`"1h30m"`, &dur))
+	assert.Equal(t, 90*time.Minute, dur)
+	assert.Error(t, UnmarshalString(`"soon"`, &dur))
+
+	var rat big.Rat
+	require.NoError(t, UnmarshalString("2.5e-1", &rat))
+	assert.Equal(t, "1/4", rat.String())
+	require.NoError(t, UnmarshalString("12", &rat))
+	assert.Equal(t, "12/1", rat.String())
+	require.NoError(t, UnmarshalString("1.5d2", &rat))
+	assert.Equal(t, "150/1", rat.String())
+	assert.Error(t, UnmarshalString(`"one third"`, &rat))
+
+	// Exponents that would make for huge rationals are refused.
+	require.NoError(t, UnmarshalString("1d6144", &rat))
+	require.NoError(t, UnmarshalString("0d100000000", &rat))
+	assert.Equal(t, "0/1", rat.String())
+	assert.Error(t, UnmarshalString("1d100000000", &rat))
+	assert.Error(t, UnmarshalString("1d-100000000", &rat))
+
+	var ip net.IP
+	require.NoError(t, UnmarshalString("'10.0.0.1'", &ip))
+	assert.Equal(t, "10.0.0.1", ip.String())
+	require.NoError(t, UnmarshalString("{{CgAAAg==}}", &ip))
+	assert.Equal(t, "10.0.0.2", ip.String())
+	assert.Error(t, UnmarshalString(`"not an ip"`, &ip))
+
+	var n json.Number
+	require.NoError(t, UnmarshalString("2.5e0", &n))
+	assert.Equal(t, json.Number("2.5"), n)
+	require.NoError(t, UnmarshalString("0d5", &n))
+	assert.Equal(t, json.Number("0e5"), n)
+	require.NoError(t, UnmarshalString("1d100000000", &n))
+	assert.Equal(t, json.Number("1e100000000"), n)
+	require.NoError(t, UnmarshalString(`"17"`, &n))
+	assert.Equal(t, json.Number("17"), n)
+	assert.Error(t, UnmarshalString(`"seventeen"`, &n))
+	assert.Error(t, UnmarshalString("+inf", &n))
+
+	// TextUnmarshalers with underlying kinds decode from those as usual.
+	var level textLevel
+	require.NoError(t, UnmarshalString("5", &level))
+	assert.Equal(t, textLevel(5), level)
+}
@@ -0,0 +1,603 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// V11SystemMacroTable is the Ion 1.1 system macro table. System macros may be
+// invoked from any Ion 1.1 stream, either by address or, in text, by a name
+// qualified with $ion (for example, (:$ion::make_string a b)).
+var V11SystemMacroTable *MacroTable
+
+func init() {
+	// The table is built here rather than in its declaration since parse_ion
+	// reads Ion, which may itself refer back to the system macros.
+	mt, err := NewMacroTable(
+		systemMacro("none", "", sysNone),
+		systemMacro("values", "values*", sysValues),
+		systemMacro("default", "expr* default_expr*", sysDefault),
+		systemMacro("meta", "anything*", sysNone),
+		systemMacro("repeat", "n value+", sysRepeat),
+		systemMacro("flatten", "sequences*", sysFlatten),
+		systemMacro("delta", "deltas*", sysDelta),
+		systemMacro("sum", "a b", sysSum),
+		systemMacro("annotate", "annotations* value", sysAnnotate),
+		systemMacro("make_string", "content*", sysMakeString),
+		systemMacro("make_symbol", "content*", sysMakeSymbol),
+		systemMacro("make_decimal", "coefficient exponent", sysMakeDecimal),
+		systemMacro("make_timestamp", "year month? day? hour? minute? second? offset_minutes?", sysMakeTimestamp),
+		systemMacro("make_blob", "lobs*", sysMakeBlob),
+		systemMacro("make_list", "sequences*", sysMakeList),
+		systemMacro("make_sexp", "sequences*", sysMakeSexp),
+		systemMacro("make_field", "name value", sysMakeField),
+		systemMacro("make_struct", "structs*", sysMakeStruct),
+		systemMacro("parse_ion", "data", sysParseIon),
+		systemMacro("set_symbols", "symbols*", sysSetSymbols),
+		systemMacro("add_symbols", "symbols*", sysAddSymbols),
+		systemMacro("set_macros", "macros*", sysSetMacros),
+		systemMacro("add_macros", "macros*", sysAddMacros),
+		systemMacro("use", "catalog_key version?", sysUse),
+	)
+	if err != nil {
+		panic(err)
+	}
+	V11SystemMacroTable = mt
+}
+
+// systemMacro creates a natively-implemented macro. The signature lists the
+// parameter names, each optionally followed by a cardinality modifier.
+func systemMacro(name, sig string, fn func(args [][]Value) ([]Value, error)) *Macro {
+	var params []MacroParameter
+	for _, p := range strings.Fields(sig) {
+		c := ExactlyOne
+		if mod := p[len(p)-1:]; isTDLModifier(mod) {
+			c = parseCardinality(mod)
+			p = p[:len(p)-1]
+		}
+		params = append(params, MacroParameter{p, c})
+	}
+
+	return &Macro{
+		name:   name,
+		params: params,
+		fn: func(args [][]Value) ([]Value, error) {
+			vals, err := fn(args)
+			if err != nil {
+				return nil, &MacroError{name, err.Error()}
+			}
+			return vals, nil
+		},
+	}
+}
+
+func sysNone(args [][]Value) ([]Value, error) {
+	return nil, nil
+}
+
+func sysValues(args [][]Value) ([]Value, error) {
+	return args[0], nil
+}
+
+func sysDefault(args [][]Value) ([]Value, error) {
+	if len(args[0]) > 0 {
+		return args[0], nil
+	}
+	return args[1], nil
+}
+
+func sysRepeat(args [][]Value) ([]Value, error) {
+	n, err := intArg(args[0][0])
+	if err != nil {
+		return nil, err
+	}
+	if n.Sign() < 0 || !n.IsInt64() || n.Int64() > math.MaxInt32 {
+		return nil, fmt.Errorf("invalid repeat count %v", n)
+	}
+
+	count := int(n.Int64())
+	vals := make([]Value, 0, count*len(args[1]))
+	for i := 0; i < count; i++ {
+		vals = append(vals, args[1]...)
+	}
+	return vals, nil
+}
+
+func sysFlatten(args [][]Value) ([]Value, error) {
+	var vals []Value
+	for _, v := range args[0] {
+		vs, err := sequenceArg(v)
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, vs...)
+	}
+	return vals, nil
+}
+
+func sysDelta(args [][]Value) ([]Value, error) {
+	sum := new(big.Int)
+	vals := make([]Value, len(args[0]))
+	for i, v := range args[0] {
+		n, err := intArg(v)
+		if err != nil {
+			return nil, err
+		}
+		sum.Add(sum, n)
+		vals[i] = NewBigInt(new(big.Int).Set(sum))
+	}
+	return vals, nil
+}
+
+func sysSum(args [][]Value) ([]Value, error) {
+	a, err := intArg(args[0][0])
+	if err != nil {
+		return nil, err
+	}
+	b, err := intArg(args[1][0])
+	if err != nil {
+		return nil, err
+	}
+	return []Value{NewBigInt(new(big.Int).Add(a, b))}, nil
+}
+
+func sysAnnotate(args [][]Value) ([]Value, error) {
+	var as []SymbolToken
+	for _, v := range args[0] {
+		if s, ok := v.(*SymbolValue); ok && !s.IsNull() {
+			as = append(as, s.Symbol())
+			continue
+		}
+		text, err := textArg(v)
+		if err != nil {
+			return nil, err
+		}
+		as = append(as, NewSymbolTokenFromString(text))
+	}
+
+	v := args[1][0]
+	return []Value{withAnnotations(v, append(as, v.Annotations()...))}, nil
+}
+
+func sysMakeString(args [][]Value) ([]Value, error) {
+	text, err := concatText(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return []Value{NewString(text)}, nil
+}
+
+func sysMakeSymbol(args [][]Value) ([]Value, error) {
+	text, err := concatText(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return []Value{NewSymbol(text)}, nil
+}
+
+func sysMakeDecimal(args [][]Value) ([]Value, error) {
+	coef, err := intArg(args[0][0])
+	if err != nil {
+		return nil, err
+	}
+	exp, err := intArg(args[1][0])
+	if err != nil {
+		return nil, err
+	}
+	if !exp.IsInt64() || exp.Int64() > math.MaxInt32 || exp.Int64() < math.MinInt32 {
+		return nil, fmt.Errorf("exponent %v out of range", exp)
+	}
+	return []Value{NewDecimalValue(NewDecimal(coef, int32(exp.Int64()), false))}, nil
+}
+
+func sysMakeTimestamp(args [][]Value) ([]Value, error) {
+	// Each component requires the one before it, except that the offset may
+	// follow any time with minute precision or finer.
+	var ts [5]int
+	n := 0
+	for ; n < 5 && len(args[n]) > 0; n++ {
+		v, err := intArg(args[n][0])
+		if err != nil {
+			return nil, err
+		}
+		if !v.IsInt64() || v.Int64() < 0 || v.Int64() > 9999 {
+			return nil, fmt.Errorf("%v %v out of range", timestampArgNames[n], v)
+		}
+		ts[n] = int(v.Int64())
+	}
+	for i := n; i < 7; i++ {
+		if len(args[i]) > 0 && n < 5 {
+			return nil, fmt.Errorf("%v requires %v", timestampArgNames[i], timestampArgNames[n])
+		}
+	}
+	if n == 4 {
+		return nil, fmt.Errorf("hour requires minute")
+	}
+	if n < 2 {
+		ts[1] = 1
+	}
+	if n < 3 {
+		ts[2] = 1
+	}
+
+	precision := []TimestampPrecision{
+		TimestampNoPrecision,
+		TimestampPrecisionYear,
+		TimestampPrecisionMonth,
+		TimestampPrecisionDay,
+		TimestampNoPrecision,
+		TimestampPrecisionMinute,
+	}[n]
+
+	if precision <= TimestampPrecisionDay {
+		date := time.Date(ts[0], time.Month(ts[1]), ts[2], 0, 0, 0, 0, time.UTC)
+		if !sameDate(date, ts) {
+			return nil, fmt.Errorf("invalid timestamp")
+		}
+		return []Value{NewTimestampValue(NewDateTimestamp(date, precision))}, nil
+	}
+
+	secs, nsecs, digits := 0, 0, uint8(0)
+	if len(args[5]) > 0 {
+		var err error
+		if secs, nsecs, digits, err = secondsArg(args[5][0]); err != nil {
+			return nil, err
+		}
+		precision = TimestampPrecisionSecond
+		if digits > 0 {
+			precision = TimestampPrecisionNanosecond
+		}
+	}
+
+	loc, kind := time.UTC, TimezoneUnspecified
+	if len(args[6]) > 0 {
+		offset, err := intArg(args[6][0])
+		if err != nil {
+			return nil, err
+		}
+		if !offset.IsInt64() || offset.Int64() <= -24*60 || offset.Int64() >= 24*60 {
+			return nil, fmt.Errorf("offset %v out of range", offset)
+		}
+		if offset.Sign() == 0 {
+			kind = TimezoneUTC
+		} else {
+			loc, kind = time.FixedZone("fixed", int(offset.Int64())*60), TimezoneLocal
+		}
+	}
+
+	if ts[3] > 23 || ts[4] > 59 {
+		return nil, fmt.Errorf("invalid timestamp")
+	}
+	date := time.Date(ts[0], time.Month(ts[1]), ts[2], ts[3], ts[4], secs, nsecs, loc)
+	if !sameDate(date, ts) {
+		return nil, fmt.Errorf("invalid timestamp")
+	}
+	return []Value{NewTimestampValue(NewTimestampWithFractionalSeconds(date, precision, kind, digits))}, nil
+}
+
+var timestampArgNames = []string{"year", "month", "day", "hour", "minute", "second", "offset_minutes"}
+
+// sameDate returns true if time.Date didn't normalize an out-of-range date.
+func sameDate(date time.Time, ts [5]int) bool {
+	return date.Year() == ts[0] && date.Month() == time.Month(ts[1]) && date.Day() == ts[2]
+}
+
+// secondsArg splits an int or decimal number of seconds into whole seconds and
+// nanoseconds, along with the number of fractional digits.
+func secondsArg(v Value) (int, int, uint8, error) {
+	var d *Decimal
+	switch v := v.(type) {
+	case *IntValue:
+		if !v.IsNull() {
+			d = NewDecimal(v.BigInt(), 0, false)
+		}
+	case *DecimalValue:
+		if !v.IsNull() {
+			d = v.Decimal()
+		}
+	}
+	if d == nil {
+		return 0, 0, 0, fmt.Errorf("expected an int or decimal second, found %v", describeArg(v))
+	}
+
+	coef, exp := d.CoEx()
+	if coef.Sign() < 0 || exp < -9 {
+		return 0, 0, 0, fmt.Errorf("invalid second %v", d)
+	}
+
+	whole := new(big.Int).Set(coef)
+	frac := new(big.Int)
+	digits := uint8(0)
+	if exp < 0 {
+		digits = uint8(-exp)
+		whole.QuoRem(coef, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil), frac)
+		frac.Mul(frac, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(9-digits)), nil))
+	} else {
+		whole.Mul(whole, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil))
+	}
+	if !whole.IsInt64() || whole.Int64() > 59 {
+		return 0, 0, 0, fmt.Errorf("invalid second %v", d)
+	}
+	return int(whole.Int64()), int(frac.Int64()), digits, nil
+}
+
+func sysMakeBlob(args [][]Value) ([]Value, error) {
+	var buf bytes.Buffer
+	for _, v := range args[0] {
+		bs, err := lobArg(v)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(bs)
+	}
+	return []Value{NewBlob(buf.Bytes())}, nil
+}
+
+func sysMakeList(args [][]Value) ([]Value, error) {
+	vals, err := sysFlatten(args)
+	if err != nil {
+		return nil, err
+	}
+	return []Value{NewList(vals...)}, nil
+}
+
+func sysMakeSexp(args [][]Value) ([]Value, error) {
+	vals, err := sysFlatten(args)
+	if err != nil {
+		return nil, err
+	}
+	return []Value{NewSexp(vals...)}, nil
+}
+
+func sysMakeField(args [][]Value) ([]Value, error) {
+	s := NewStruct()
+	if sym, ok := args[0][0].(*SymbolValue); ok && !sym.IsNull() {
+		s.AddField(sym.Symbol(), args[1][0])
+		return []Value{s}, nil
+	}
+
+	name, err := textArg(args[0][0])
+	if err != nil {
+		return nil, err
+	}
+	s.Add(name, args[1][0])
+	return []Value{s}, nil
+}
+
+func sysMakeStruct(args [][]Value) ([]Value, error) {
+	s := NewStruct()
+	for _, v := range args[0] {
+		sv, ok := v.(*StructValue)
+		if !ok {
+			return nil, fmt.Errorf("expected a struct, found %v", describeArg(v))
+		}
+		for _, f := range sv.Fields() {
+			s.AddField(f.Name, f.Value)
+		}
+	}
+	return []Value{s}, nil
+}
+
+func sysParseIon(args [][]Value) ([]Value, error) {
+	var data []byte
+	if s, ok := args[0][0].(*StringValue); ok && !s.IsNull() {
+		data = []byte(s.Text())
+	} else {
+		bs, err := lobArg(args[0][0])
+		if err != nil {
+			return nil, err
+		}
+		data = bs
+	}
+	return ReadValues(NewReaderBytes(data))
+}
+
+// The remaining system macros expand to encoding directives, which readers apply
+// when they are produced at the top level.
+
+func sysSetSymbols(args [][]Value) ([]Value, error) {
+	return encodingDirective(
+		directiveClauseOf("symbol_table", NewList(args[0]...)),
+		directiveClauseOf("macro_table", NewSymbol("$ion_encoding")),
+	), nil
+}
+
+func sysAddSymbols(args [][]Value) ([]Value, error) {
+	return encodingDirective(
+		directiveClauseOf("symbol_table", NewSymbol("$ion_encoding"), NewList(args[0]...)),
+		directiveClauseOf("macro_table", NewSymbol("$ion_encoding")),
+	), nil
+}
+
+func sysSetMacros(args [][]Value) ([]Value, error) {
+	return encodingDirective(
+		directiveClauseOf("symbol_table", NewSymbol("$ion_encoding")),
+		directiveClauseOf("macro_table", args[0]...),
+	), nil
+}
+
+func sysAddMacros(args [][]Value) ([]Value, error) {
+	return encodingDirective(
+		directiveClauseOf("symbol_table", NewSymbol("$ion_encoding")),
+		directiveClauseOf("macro_table", append([]Value{NewSymbol("$ion_encoding")}, args[0]...)...),
+	), nil
+}
+
+func sysUse(args [][]Value) ([]Value, error) {
+	key, err := textArg(args[0][0])
+	if err != nil {
+		return nil, err
+	}
+
+	imp := []Value{NewSymbol("the_module"), NewString(key)}
+	if len(args[1]) > 0 {
+		if _, err := intArg(args[1][0]); err != nil {
+			return nil, err
+		}
+		imp = append(imp, args[1][0])
+	}
+
+	return encodingDirective(
+		directiveClauseOf("import", imp...),
+		directiveClauseOf("symbol_table", NewSymbol("$ion_encoding"), NewSymbol("the_module")),
+		directiveClauseOf("macro_table", NewSymbol("$ion_encoding"), NewSymbol("the_module")),
+	), nil
+}
+
+// encodingDirective builds an encoding directive from the given clauses.
+func encodingDirective(clauses ...Value) []Value {
+	v := NewSexp(clauses...)
+	v.SetAnnotations(NewSymbolTokenFromString("$ion_encoding"))
+	return []Value{v}
+}
+
+// directiveClauseOf builds a single (name args...) encoding directive clause.
+func directiveClauseOf(name string, args ...Value) Value {
+	return NewSexp(append([]Value{NewSymbol(name)}, args...)...)
+}
+
+// intArg returns the value of an int argument.
+func intArg(v Value) (*big.Int, error) {
+	i, ok := v.(*IntValue)
+	if !ok || i.IsNull() {
+		return nil, fmt.Errorf("expected an int, found %v", describeArg(v))
+	}
+	return i.BigInt(), nil
+}
+
+// textArg returns the text of a string or symbol argument.
+func textArg(v Value) (string, error) {
+	switch v := v.(type) {
+	case *StringValue:
+		if !v.IsNull() {
+			return v.Text(), nil
+		}
+	case *SymbolValue:
+		if text, ok := symbolValueText(v); ok {
+			return text, nil
+		}
+	}
+	return "", fmt.Errorf("expected text, found %v", describeArg(v))
+}
+
+// concatText concatenates the text of string or symbol arguments.
+func concatText(vs []Value) (string, error) {
+	var sb strings.Builder
+	for _, v := range vs {
+		text, err := textArg(v)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(text)
+	}
+	return sb.String(), nil
+}
+
+// lobArg returns the bytes of a blob or clob argument.
+func lobArg(v Value) ([]byte, error) {
+	switch v := v.(type) {
+	case *BlobValue:
+		if !v.IsNull() {
+			return v.Bytes(), nil
+		}
+	case *ClobValue:
+		if !v.IsNull() {
+			return v.Bytes(), nil
+		}
+	}
+	return nil, fmt.Errorf("expected a lob, found %v", describeArg(v))
+}
+
+// sequenceArg returns the elements of a list or sexp argument. A null list or
+// sexp has no elements.
+func sequenceArg(v Value) ([]Value, error) {
+	switch v := v.(type) {
+	case *ListValue:
+		return v.Values(), nil
+	case *SexpValue:
+		return v.Values(), nil
+	}
+	return nil, fmt.Errorf("expected a list or sexp, found %v", describeArg(v))
+}
+
+// describeArg describes an unexpected argument in an error message.
+func describeArg(v Value) string {
+	if v.IsNull() {
+		return fmt.Sprintf("null.%v", v.Type())
+	}
+	return v.Type().String()
+}
+
+// withAnnotations returns a shallow copy of v with the given annotations, so
+// values shared with a macro's template aren't modified.
+func withAnnotations(v Value, as []SymbolToken) Value {
+	var c Value
+	switch v := v.(type) {
+	case *NullValue:
+		x := *v
+		c = &x
+	case *BoolValue:
+		x := *v
+		c = &x
+	case *IntValue:
+		x := *v
+		c = &x
+	case *FloatValue:
+		x := *v
+		c = &x
+	case *DecimalValue:
+		x := *v
+		c = &x
+	case *TimestampValue:
+		x := *v
+		c = &x
+	case *SymbolValue:
+		x := *v
+		c = &x
+	case *StringValue:
+		x := *v
+		c = &x
+	case *ClobValue:
+		x := *v
+		c = &x
+	case *BlobValue:
+		x := *v
+		c = &x
+	case *ListValue:
+		x := *v
+		x.vals = x.vals[:len(x.vals):len(x.vals)]
+		c = &x
+	case *SexpValue:
+		x := *v
+		x.vals = x.vals[:len(x.vals):len(x.vals)]
+		c = &x
+	case *StructValue:
+		x := *v
+		x.fields = x.fields[:len(x.fields):len(x.fields)]
+		c = &x
+	default:
+		panic(fmt.Sprintf("unexpected value type %T", v))
+	}
+
+	c.SetAnnotations(as...)
+	return c
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemMacros(t *testing.T) {
+	test := func(text, expected string) {
+		t.Run(text, func(t *testing.T) {
+			assertValues(t, expected, readMacros(t, nil, text))
+		})
+	}
+
+	test("(:none)", "")
+	test("(:values)", "")
+	test("(:values 1 a::b [c])", "1 a::b [c]")
+	test("(:default (::) 1 2)", "1 2")
+	test("(:default 0 1)", "0")
+	test("(:meta 1 {a: 2})", "")
+	test("(:repeat 3 x)", "x x x")
+	test("(:repeat 2 (:: a b))", "a b a b")
+	test("(:repeat 0 x)", "")
+	test("(:flatten [1, 2] (3) [])", "1 2 3")
+	test("(:delta 10 1 -2 5)", "10 11 9 14")
+	test("(:sum 1 2)", "3")
+	test("(:sum 9223372036854775807 1)", "9223372036854775808")
+	test("(:annotate (:: a \"b\") c::1)", "a::b::c::1")
+	test("(:make_string)", `""`)
+	test("(:make_string \"a\" b (:: 'c' \"d\"))", `"abcd"`)
+	test("(:make_symbol a \"b\")", "ab")
+	test("(:make_decimal 15 -1)", "1.5")
+	test("(:make_decimal 0 2)", "0d2")
+	test("(:make_timestamp 2020)", "2020T")
+	test("(:make_timestamp 2020 3)", "2020-03T")
+	test("(:make_timestamp 2020 3 4)", "2020-03-04")
+	test("(:make_timestamp 2020 3 4 5 6)", "2020-03-04T05:06-00:00")
+	test("(:make_timestamp 2020 3 4 5 6 7)", "2020-03-04T05:06:07-00:00")
+	test("(:make_timestamp 2020 3 4 5 6 7.25 0)", "2020-03-04T05:06:07.25Z")
+	test("(:make_timestamp 2020 3 4 5 6 (::) -90)", "2020-03-04T05:06-01:30")
+	test("(:make_blob {{AQI=}} {{\"c\"}})", "{{AQJj}}")
+	test("(:make_list)", "[]")
+	test("(:make_list [1] (2 3))", "[1, 2, 3]")
+	test("(:make_sexp [1] (2))", "(1 2)")
+	test("(:make_field foo 1)", "{foo: 1}")
+	test("(:make_struct {a: 1} {b: 2, c: 3})", "{a: 1, b: 2, c: 3}")
+	test("(:parse_ion \"1 a::b\")", "1 a::b")
+	test("(:parse_ion {{ \"(:values 1 2)\" }})", "1 2")
+}
+
+func TestSystemMacroErrors(t *testing.T) {
+	test := func(text string) {
+		t.Run(text, func(t *testing.T) {
+			_, err := ReadValues(NewReaderString(text))
+			assert.Error(t, err)
+		})
+	}
+
+	test("(:none 1)")
+	test("(:repeat -1 x)")
+	test("(:repeat 1)")
+	test("(:repeat a x)")
+	test("(:flatten 1)")
+	test("(:delta 1.5)")
+	test("(:sum 1)")
+	test("(:sum 1 2 3)")
+	test("(:annotate 1 x)")
+	test("(:make_string 1)")
+	test("(:make_decimal 1 1.5)")
+	test("(:make_timestamp 2020 13)")
+	test("(:make_timestamp 2020 2 30)")
+	test("(:make_timestamp 2020 1 1 1)")
+	test("(:make_timestamp 2020 (::) 1)")
+	test("(:make_timestamp 2020 1 1 1 1 60)")
+	test("(:make_timestamp 2020 1 1 1 1 1 1440)")
+	test("(:make_blob \"a\")")
+	test("(:make_list 1)")
+	test("(:make_field 1 2)")
+	test("(:make_struct [])")
+	test("(:parse_ion 1)")
+	test("(:parse_ion \"[\")")
+}
+
+func TestSystemMacroTable(t *testing.T) {
+	names := []string{
+		"none", "values", "default", "meta", "repeat", "flatten", "delta", "sum", "annotate",
+		"make_string", "make_symbol", "make_decimal", "make_timestamp", "make_blob",
+		"make_list", "make_sexp", "make_field", "make_struct", "parse_ion",
+		"set_symbols", "add_symbols", "set_macros", "add_macros", "use",
+	}
+	require.Equal(t, len(names), V11SystemMacroTable.Len())
+
+	for i, name := range names {
+		m, ok := V11SystemMacroTable.Get(uint64(i))
+		require.True(t, ok)
+		assert.Equal(t, name, m.Name())
+	}
+
+	m, _ := V11SystemMacroTable.Find("make_timestamp")
+	assert.Equal(t, "(macro make_timestamp (year month? day? hour? minute? second? offset_minutes?) ...)", m.String())
+
+	_, err := m.Expand([]Value{NewString("x")})
+	require.Error(t, err)
+	assert.Equal(t, "ion: macro make_timestamp: expected an int, found string", err.Error())
+}
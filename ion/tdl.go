@@ -0,0 +1,541 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"fmt"
+)
+
+// A tdlExpr is a compiled expression from a macro template, written in the
+// template definition language (TDL). Evaluating it with the macro's arguments
+// (one group of values per parameter) produces a stream of values.
+type tdlExpr interface {
+	eval(args [][]Value) ([]Value, error)
+}
+
+// A tdlLiteral evaluates to a fixed set of values.
+type tdlLiteral struct {
+	vals []Value
+}
+
+func (e *tdlLiteral) eval(args [][]Value) ([]Value, error) {
+	return e.vals[:len(e.vals):len(e.vals)], nil
+}
+
+// A tdlVariable evaluates to the values passed for a parameter.
+type tdlVariable struct {
+	index int
+}
+
+func (e *tdlVariable) eval(args [][]Value) ([]Value, error) {
+	return args[e.index], nil
+}
+
+// A tdlGroup evaluates each of its expressions and concatenates the results.
+type tdlGroup []tdlExpr
+
+func (e tdlGroup) eval(args [][]Value) ([]Value, error) {
+	var vals []Value
+	for _, x := range e {
+		vs, err := x.eval(args)
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, vs...)
+	}
+	return vals, nil
+}
+
+// A tdlInvocation invokes another macro. Omitted optional arguments are nil.
+type tdlInvocation struct {
+	macro *Macro
+	args  []tdlExpr
+}
+
+func (e *tdlInvocation) eval(args [][]Value) ([]Value, error) {
+	vals, err := evalArgs(e.args, args)
+	if err != nil {
+		return nil, err
+	}
+	return e.macro.Expand(vals...)
+}
+
+// A tdlCond is one of the if_none, if_some, if_single or if_multi special forms.
+// It evaluates its first argument and, depending on how many values that
+// produces, evaluates to its second or third argument.
+type tdlCond struct {
+	test func(n int) bool
+	args []tdlExpr
+}
+
+func (e *tdlCond) eval(args [][]Value) ([]Value, error) {
+	vals, err := evalOptional(e.args[0], args)
+	if err != nil {
+		return nil, err
+	}
+	if e.test(len(vals)) {
+		return evalOptional(e.args[1], args)
+	}
+	return evalOptional(e.args[2], args)
+}
+
+// evalOptional evaluates an expression for an optional argument, which may have
+// been omitted.
+func evalOptional(e tdlExpr, args [][]Value) ([]Value, error) {
+	if e == nil {
+		return nil, nil
+	}
+	return e.eval(args)
+}
+
+// evalArgs evaluates the arguments to a macro invocation.
+func evalArgs(es []tdlExpr, args [][]Value) ([][]Value, error) {
+	vals := make([][]Value, len(es))
+	for i, e := range es {
+		vs, err := evalOptional(e, args)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = vs
+	}
+	return vals, nil
+}
+
+// A tdlSequence is a list or sexp template whose elements are expressions.
+type tdlSequence struct {
+	typ         Type
+	annotations []SymbolToken
+	elems       tdlGroup
+}
+
+func (e *tdlSequence) eval(args [][]Value) ([]Value, error) {
+	vals, err := e.elems.eval(args)
+	if err != nil {
+		return nil, err
+	}
+
+	var v Value
+	if e.typ == ListType {
+		v = NewList(vals...)
+	} else {
+		v = NewSexp(vals...)
+	}
+	v.SetAnnotations(e.annotations...)
+	return []Value{v}, nil
+}
+
+// A tdlStruct is a struct template whose field values are expressions. A field
+// is repeated for each value its expression produces, and omitted if it
+// produces none.
+type tdlStruct struct {
+	annotations []SymbolToken
+	fields      []tdlField
+}
+
+type tdlField struct {
+	name SymbolToken
+	expr tdlExpr
+}
+
+func (e *tdlStruct) eval(args [][]Value) ([]Value, error) {
+	s := NewStruct()
+	for _, f := range e.fields {
+		vals, err := f.expr.eval(args)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range vals {
+			s.AddField(f.name, v)
+		}
+	}
+	s.SetAnnotations(e.annotations...)
+	return []Value{s}, nil
+}
+
+// conditionals are the special forms that choose between two expressions.
+var conditionals = map[string]func(n int) bool{
+	"if_none":   func(n int) bool { return n == 0 },
+	"if_some":   func(n int) bool { return n > 0 },
+	"if_single": func(n int) bool { return n == 1 },
+	"if_multi":  func(n int) bool { return n > 1 },
+}
+
+var conditionalParams = []MacroParameter{
+	{"expr", ZeroOrMore},
+	{"true_branch", ZeroOrMore},
+	{"false_branch", ZeroOrMore},
+}
+
+// A tdlCompiler compiles a macro definition's template.
+type tdlCompiler struct {
+	name   string
+	params []MacroParameter
+	mt     *MacroTable
+}
+
+// compileMacro compiles a (macro name (parameters) template) definition. The
+// template may invoke the macros in mt, which may be nil.
+func compileMacro(def Value, mt *MacroTable) (*Macro, error) {
+	s, ok := def.(*SexpValue)
+	if !ok || s.IsNull() || s.Len() < 3 || s.Len() > 4 || !isSymbolText(s.Get(0), "macro") {
+		return nil, &MacroError{"<unknown>", "expected (macro name (parameters) template)"}
+	}
+
+	c := tdlCompiler{mt: mt}
+	switch name := s.Get(1).(type) {
+	case *SymbolValue:
+		text, ok := symbolValueText(name)
+		if !ok {
+			return nil, &MacroError{"<unknown>", "macro name must be a symbol with known text"}
+		}
+		c.name = text
+	case *NullValue:
+	default:
+		return nil, &MacroError{"<unknown>", fmt.Sprintf("macro name must be a symbol or null, found %v", name.Type())}
+	}
+
+	if err := c.compileParams(s.Get(2)); err != nil {
+		return nil, err
+	}
+
+	m := &Macro{name: c.name, params: c.params, def: def}
+	if s.Len() == 4 {
+		body, err := c.compile(s.Get(3))
+		if err != nil {
+			return nil, err
+		}
+		m.body = body
+	}
+	return m, nil
+}
+
+// compileParams compiles the parameter list of a macro definition.
+func (c *tdlCompiler) compileParams(v Value) error {
+	s, ok := v.(*SexpValue)
+	if !ok || s.IsNull() {
+		return c.errorf("parameters must be an s-expression")
+	}
+
+	vals := s.Values()
+	for i := 0; i < len(vals); i++ {
+		name, ok := symbolValueText(vals[i])
+		if !ok || isTDLModifier(name) {
+			return c.errorf("invalid parameter %v", i)
+		}
+		if len(vals[i].Annotations()) > 0 {
+			return c.errorf("parameter %v cannot have annotations", name)
+		}
+		for _, p := range c.params {
+			if p.Name == name {
+				return c.errorf("duplicate parameter %v", name)
+			}
+		}
+
+		p := MacroParameter{Name: name, Cardinality: ExactlyOne}
+		if i+1 < len(vals) {
+			if mod, ok := symbolValueText(vals[i+1]); ok && isTDLModifier(mod) {
+				p.Cardinality = parseCardinality(mod)
+				i++
+			}
+		}
+		c.params = append(c.params, p)
+	}
+	return nil
+}
+
+func isTDLModifier(s string) bool {
+	return s == "!" || s == "?" || s == "*" || s == "+"
+}
+
+func parseCardinality(mod string) ParameterCardinality {
+	switch mod {
+	case "?":
+		return ZeroOrOne
+	case "*":
+		return ZeroOrMore
+	case "+":
+		return OneOrMore
+	default:
+		return ExactlyOne
+	}
+}
+
+// compile compiles a template expression.
+func (c *tdlCompiler) compile(v Value) (tdlExpr, error) {
+	if v.IsNull() {
+		return &tdlLiteral{[]Value{v}}, nil
+	}
+
+	switch v := v.(type) {
+	case *SexpValue:
+		if op, ok := tdlOperator(v); ok {
+			if len(v.Annotations()) > 0 {
+				return nil, c.errorf("(%v ...) cannot have annotations", op)
+			}
+			switch op {
+			case "%":
+				return c.compileVariable(v)
+			case ".":
+				return c.compileInvocation(v)
+			default:
+				return nil, c.errorf("expression group outside of macro arguments")
+			}
+		}
+		return c.compileSequence(v, SexpType, v.Values())
+
+	case *ListValue:
+		return c.compileSequence(v, ListType, v.Values())
+
+	case *StructValue:
+		e := &tdlStruct{annotations: v.Annotations()}
+		literal := true
+		for _, f := range v.Fields() {
+			x, err := c.compile(f.Value)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := x.(*tdlLiteral); !ok {
+				literal = false
+			}
+			e.fields = append(e.fields, tdlField{f.Name, x})
+		}
+		if literal {
+			return &tdlLiteral{[]Value{v}}, nil
+		}
+		return e, nil
+
+	default:
+		return &tdlLiteral{[]Value{v}}, nil
+	}
+}
+
+// compileSequence compiles a list or sexp template.
+func (c *tdlCompiler) compileSequence(v Value, t Type, vals []Value) (tdlExpr, error) {
+	e := &tdlSequence{typ: t, annotations: v.Annotations()}
+	literal := true
+	for _, val := range vals {
+		x, err := c.compile(val)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := x.(*tdlLiteral); !ok {
+			literal = false
+		}
+		e.elems = append(e.elems, x)
+	}
+	if literal {
+		return &tdlLiteral{[]Value{v}}, nil
+	}
+	return e, nil
+}
+
+// compileVariable compiles a (%name) variable reference.
+func (c *tdlCompiler) compileVariable(s *SexpValue) (tdlExpr, error) {
+	if s.Len() != 2 {
+		return nil, c.errorf("expected (%%name)")
+	}
+	name, ok := symbolValueText(s.Get(1))
+	if !ok {
+		return nil, c.errorf("expected (%%name)")
+	}
+	for i, p := range c.params {
+		if p.Name == name {
+			return &tdlVariable{i}, nil
+		}
+	}
+	return nil, c.errorf("unknown variable %v", name)
+}
+
+// compileInvocation compiles a (.name args...) macro invocation or special form.
+func (c *tdlCompiler) compileInvocation(s *SexpValue) (tdlExpr, error) {
+	if s.Len() < 2 {
+		return nil, c.errorf("expected (.name arguments...)")
+	}
+	ref, args := s.Get(1), s.Values()[2:]
+
+	if name, ok := symbolValueText(ref); ok && len(ref.Annotations()) == 0 {
+		if name == "literal" {
+			return &tdlLiteral{args}, nil
+		}
+		if test, ok := conditionals[name]; ok {
+			if len(args) != len(conditionalParams) {
+				return nil, c.errorf("%v expects %v arguments, found %v", name, len(conditionalParams), len(args))
+			}
+			xs, err := c.compileArgs(name, conditionalParams, args)
+			if err != nil {
+				return nil, err
+			}
+			return &tdlCond{test, xs}, nil
+		}
+	}
+
+	m, err := c.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	xs, err := c.compileArgs(m.displayName(), m.params, args)
+	if err != nil {
+		return nil, err
+	}
+	return &tdlInvocation{m, xs}, nil
+}
+
+// resolve finds the macro referred to by name or address in an invocation.
+// Unqualified names are looked up in the macro table first, falling back to
+// the system macros; names annotated with $ion refer only to system macros.
+func (c *tdlCompiler) resolve(ref Value) (*Macro, error) {
+	switch ref := ref.(type) {
+	case *SymbolValue:
+		name, ok := symbolValueText(ref)
+		if !ok {
+			break
+		}
+
+		as := ref.Annotations()
+		switch {
+		case len(as) == 0:
+			if m, ok := c.mt.Find(name); ok {
+				return m, nil
+			}
+			if m, ok := V11SystemMacroTable.Find(name); ok {
+				return m, nil
+			}
+		case len(as) == 1 && as[0].Text != nil && *as[0].Text == "$ion":
+			if m, ok := V11SystemMacroTable.Find(name); ok {
+				return m, nil
+			}
+		default:
+			return nil, c.errorf("invalid macro reference")
+		}
+		return nil, c.errorf("unknown macro %v", name)
+
+	case *IntValue:
+		if addr, ok := ref.Int64(); ok && addr >= 0 && !ref.IsNull() {
+			if m, ok := c.mt.Get(uint64(addr)); ok {
+				return m, nil
+			}
+			return nil, c.errorf("unknown macro address %v", addr)
+		}
+	}
+	return nil, c.errorf("invalid macro reference")
+}
+
+// compileArgs compiles the arguments of an invocation, one per parameter. Extra
+// arguments are passed to the last parameter if it is variadic.
+func (c *tdlCompiler) compileArgs(name string, params []MacroParameter, args []Value) ([]tdlExpr, error) {
+	n := len(params)
+	if len(args) > n && (n == 0 || !params[n-1].Cardinality.variadic()) {
+		return nil, c.errorf("%v expects at most %v arguments, found %v", name, n, len(args))
+	}
+
+	xs := make([]tdlExpr, n)
+	for i, p := range params {
+		switch {
+		case i >= len(args):
+			if !p.Cardinality.optional() {
+				return nil, c.errorf("%v requires an argument for %v", name, p.Name)
+			}
+
+		case i == n-1 && len(args) > n:
+			var g tdlGroup
+			for _, a := range args[i:] {
+				x, err := c.compileArg(name, p, a)
+				if err != nil {
+					return nil, err
+				}
+				g = append(g, x)
+			}
+			xs[i] = g
+
+		default:
+			x, err := c.compileArg(name, p, args[i])
+			if err != nil {
+				return nil, err
+			}
+			xs[i] = x
+		}
+	}
+	return xs, nil
+}
+
+// compileArg compiles a single argument, which may be an expression group.
+func (c *tdlCompiler) compileArg(name string, p MacroParameter, arg Value) (tdlExpr, error) {
+	s, ok := arg.(*SexpValue)
+	if !ok {
+		return c.compile(arg)
+	}
+	if op, ok := tdlOperator(s); !ok || op != ".." {
+		return c.compile(arg)
+	}
+
+	if p.Cardinality == ExactlyOne {
+		return nil, c.errorf("%v parameter %v does not accept an expression group", name, p.Name)
+	}
+	if len(s.Annotations()) > 0 {
+		return nil, c.errorf("(.. ...) cannot have annotations")
+	}
+
+	g := tdlGroup{}
+	for _, v := range s.Values()[1:] {
+		x, err := c.compile(v)
+		if err != nil {
+			return nil, err
+		}
+		g = append(g, x)
+	}
+	return g, nil
+}
+
+func (c *tdlCompiler) errorf(format string, args ...interface{}) error {
+	name := c.name
+	if name == "" {
+		name = "<anonymous>"
+	}
+	return &MacroError{name, fmt.Sprintf(format, args...)}
+}
+
+// tdlOperator returns the operator (".", "%" or "..") that begins a TDL
+// s-expression, if there is one.
+func tdlOperator(s *SexpValue) (string, bool) {
+	if s.IsNull() || s.Len() == 0 || len(s.Get(0).Annotations()) > 0 {
+		return "", false
+	}
+	op, ok := symbolValueText(s.Get(0))
+	if !ok || (op != "." && op != "%" && op != "..") {
+		return "", false
+	}
+	return op, true
+}
+
+// symbolValueText returns the text of a non-null symbol value, if it has any.
+func symbolValueText(v Value) (string, bool) {
+	sv, ok := v.(*SymbolValue)
+	if !ok || sv.IsNull() {
+		return "", false
+	}
+	st := sv.Symbol()
+	if st.Text == nil {
+		return "", false
+	}
+	return *st.Text, true
+}
+
+// isSymbolText returns true if v is a symbol with the given text.
+func isSymbolText(v Value, text string) bool {
+	s, ok := symbolValueText(v)
+	return ok && s == text
+}
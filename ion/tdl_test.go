@@ -0,0 +1,98 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTDLTemplates(t *testing.T) {
+	mt, err := ParseMacroTable(`
+		(macro point (x y) {x: (%x), y: (%y)})
+		(macro tagged (v*) tag::[(%v), end])
+		(macro twice (v*) (.values (%v) (%v)))
+		(macro pair (a b) (.point (%a) (.sum (%b) 1)))
+		(macro either (a? b) (.if_none (%a) (%b) (%a)))
+		(macro count (v*) (.if_none (%v) none (.if_single (%v) single multi)))
+		(macro some (v*) (.if_some (%v) yes no))
+		(macro multi (v*) (.if_multi (%v) yes no))
+		(macro quoted () (.literal (%x) (.point 1 2)))
+		(macro grouped (v*) (.make_list (.. [1] (2)) (%v)))
+		(macro constant () {a: [1, (b c)]})`)
+	require.NoError(t, err)
+
+	test := func(text, expected string) {
+		t.Run(text, func(t *testing.T) {
+			assertValues(t, expected, readMacros(t, mt, text))
+		})
+	}
+
+	test("(:point 1 2)", "{x: 1, y: 2}")
+	test("(:point (:values 1) (:values a::b))", "{x: 1, y: a::b}")
+	test("(:tagged 1 2)", "tag::[1, 2, end]")
+	test("(:tagged)", "tag::[end]")
+	test("(:twice 1 2)", "1 2 1 2")
+	test("(:pair 1 2)", "{x: 1, y: 3}")
+	test("(:either 1 2)", "1")
+	test("(:either (::) 2)", "2")
+	test("(:count)", "none")
+	test("(:count 1)", "single")
+	test("(:count 1 2)", "multi")
+	test("(:some)", "no")
+	test("(:some 1)", "yes")
+	test("(:multi 1)", "no")
+	test("(:multi 1 2)", "yes")
+	test("(:quoted)", "(%x) (.point 1 2)")
+	test("(:grouped [3])", "[1, 2, 3]")
+	test("(:constant)", "{a: [1, (b c)]}")
+}
+
+func TestTDLErrors(t *testing.T) {
+	test := func(tdl string) {
+		t.Run(tdl, func(t *testing.T) {
+			_, err := ParseMacro(tdl, nil)
+			assert.Error(t, err)
+		})
+	}
+
+	test("(macro m () (%x))")
+	test("(macro m (x) (%))")
+	test("(macro m (x) (%x y))")
+	test("(macro m () (.unknown))")
+	test("(macro m () (.99))")
+	test("(macro m () (.sum 1))")
+	test("(macro m () (.sum 1 2 3))")
+	test("(macro m () (.sum (.. 1) 2))")
+	test("(macro m () (.. 1 2))")
+	test("(macro m () a::(.values 1))")
+	test("(macro m () (.if_none 1 2 3 4))")
+}
+
+func TestTDLMacroReferences(t *testing.T) {
+	mt, err := ParseMacroTable(`
+		(macro values (v) (.annotate (.. custom) (%v)))
+		(macro local (v) (.values (%v)))
+		(macro system (v) (.$ion::values (%v)))
+		(macro address (v) (.0 (%v)))`)
+	require.NoError(t, err)
+
+	assertValues(t, "custom::1", readMacros(t, mt, "(:local 1)"))
+	assertValues(t, "1", readMacros(t, mt, "(:system 1)"))
+	assertValues(t, "custom::1", readMacros(t, mt, "(:address 1)"))
+}
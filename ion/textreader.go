@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"math"
 	"strconv"
+	"strings"
 )
 
 // trs is the state of the text reader.
@@ -57,36 +58,103 @@ type textReader struct {
 
 	tok   tokenizer
 	state trs
-	cat   Catalog
+
+	// Once an $ion_1_1 version marker is seen, top-level $ion_encoding sexps
+	// are read as encoding directives.
+	v11 bool
+
+	// Readers created by NewJSONReader only accept JSON. jsonPrev is the last
+	// token read, and jsonValue records whether a top-level value has been seen.
+	jsonOpts  JSONReaderOpts
+	jsonPrev  token
+	jsonValue bool
+
+	// The position of the current value, including its annotations, and the
+	// 1-based line and column at which it starts.
+	spanStart  uint64
+	spanLine   uint64
+	spanColumn uint64
+
+	// The index and field name of the current value at each level of nesting,
+	// starting with the top level, for reporting the paths of errors.
+	path []pathStep
+
+	// Lenient readers record syntax errors in errs rather than failing, and
+	// are recovering until they've skipped ahead to the next top-level value.
+	// unclosed is the number of containers that were open at the error, and
+	// resyncedAt is where the reader last picked up again after one.
+	lenient    bool
+	recovering bool
+	errs       []error
+	unclosed   int
+	resyncedAt uint64
+}
+
+// A pathStep is the position of a value within its container.
+type pathStep struct {
+	index int
+	name  *SymbolToken
 }
 
-func newTextReaderBuf(in *bufio.Reader, cat Catalog) Reader {
+func newTextReaderBuf(in *bufio.Reader, cat Catalog, mt *MacroTable) Reader {
 	tr := textReader{
-		cat: cat,
 		tok: tokenizer{
 			in: in,
 		},
 		state: trsBeforeTypeAnnotations,
+		path:  []pathStep{{index: -1}},
 	}
+	tr.cat = cat
 	tr.lst = V1SystemSymbolTable
+	tr.macros = mt
+	tr.defaultMacros = mt
 
 	return &tr
 }
 
 // Next moves the reader to the next value.
 func (t *textReader) Next() bool {
-	if t.state == trsDone || t.eof {
+	for {
+		ok := t.next()
+		if ok {
+			if err := t.checkValue(t.spanStart); err != nil {
+				t.explode(err)
+				return false
+			}
+			return true
+		}
+		if !t.recovering || t.ctx.peek() != ctxAtTopLevel {
+			return false
+		}
+		if !t.resync() {
+			return false
+		}
+	}
+}
+
+// next moves the reader to the next value, returning false if it encounters
+// an error.
+func (t *textReader) next() bool {
+	if t.state == trsDone || t.eof || t.recovering || t.atSpanEnd() {
+		return false
+	}
+	ok, err := t.nextExpanded()
+	if err != nil {
+		t.explode(err)
 		return false
 	}
+	if ok {
+		return !t.eof
+	}
 
 	// If we haven't fully read the current value, skip over it.
-	err := t.finishValue()
-	if err != nil {
+	if err := t.finishValue(); err != nil {
 		t.explode(err)
 		return false
 	}
 
 	t.clear()
+	t.path[len(t.path)-1].index++
 
 	// Loop until we've consumed enough tokens to know what the next value is.
 	for {
@@ -94,6 +162,16 @@ func (t *textReader) Next() bool {
 			t.explode(err)
 			return false
 		}
+		if t.tok.json {
+			if err := t.verifyJSON(); err != nil {
+				t.explode(err)
+				return false
+			}
+		}
+		if t.state == trsBeforeTypeAnnotations && len(t.annotations) == 0 {
+			t.spanStart = t.tok.start
+			t.spanLine, t.spanColumn = t.tok.position(t.spanStart)
+		}
 
 		var done bool
 		var err error
@@ -145,7 +223,7 @@ func (t *textReader) nextAfterValue() (bool, error) {
 			t.eof = true
 			return true, nil
 		}
-		return false, &UnexpectedTokenError{"}", t.tok.Pos() - 1}
+		return false, &UnexpectedTokenError{Token: "}", Offset: t.tok.Pos() - 1}
 
 	case tokenCloseBracket:
 		// No more values in this list.
@@ -153,10 +231,10 @@ func (t *textReader) nextAfterValue() (bool, error) {
 			t.eof = true
 			return true, nil
 		}
-		return false, &UnexpectedTokenError{"]", t.tok.Pos() - 1}
+		return false, &UnexpectedTokenError{Token: "]", Offset: t.tok.Pos() - 1}
 
 	default:
-		return false, &UnexpectedTokenError{tok.String(), t.tok.Pos() - 1}
+		return false, &UnexpectedTokenError{Token: tok.String(), Offset: t.tok.Pos() - 1}
 	}
 }
 
@@ -184,6 +262,13 @@ func (t *textReader) nextBeforeFieldName() (bool, error) {
 
 		if tok == tokenSymbolQuoted {
 			t.fieldName = &SymbolToken{Text: &val, LocalSID: SymbolIDUnknown}
+		} else if t.tok.json {
+			// JSON field names are always text, never symbol IDs.
+			st, err := NewSymbolToken(t.SymbolTable(), val)
+			if err != nil {
+				return false, err
+			}
+			t.fieldName = &st
 		} else {
 			st, err := newSymbolToken(t.SymbolTable(), val)
 			if err != nil {
@@ -197,7 +282,7 @@ func (t *textReader) nextBeforeFieldName() (bool, error) {
 			return false, err
 		}
 		if tok = t.tok.Token(); tok != tokenColon {
-			return false, &UnexpectedTokenError{tok.String(), t.tok.Pos() - 1}
+			return false, &UnexpectedTokenError{Token: tok.String(), Offset: t.tok.Pos() - 1}
 		}
 
 		t.state = trsBeforeTypeAnnotations
@@ -205,7 +290,7 @@ func (t *textReader) nextBeforeFieldName() (bool, error) {
 		return false, nil
 
 	default:
-		return false, &UnexpectedTokenError{tok.String(), t.tok.Pos() - 1}
+		return false, &UnexpectedTokenError{Token: tok.String(), Offset: t.tok.Pos() - 1}
 	}
 }
 
@@ -219,16 +304,17 @@ func (t *textReader) nextBeforeTypeAnnotations() (bool, error) {
 			t.eof = true
 			return true, nil
 		}
-		return false, &UnexpectedEOFError{t.tok.Pos() - 1}
+		return false, &UnexpectedEOFError{Offset: t.tok.Pos() - 1}
 
 	case tokenSymbolOperator, tokenDot:
 		if t.ctx.peek() != ctxInSexp {
 			// Operators can only appear inside an sexp.
-			return false, &UnexpectedTokenError{tok.String(), t.tok.Pos() - 1}
+			return false, &UnexpectedTokenError{Token: tok.String(), Offset: t.tok.Pos() - 1}
 		}
 		fallthrough
 
 	case tokenSymbolQuoted, tokenSymbol:
+		pos := t.tok.Pos()
 		val, err := t.tok.ReadValue(tok)
 		if err != nil {
 			return false, err
@@ -239,6 +325,12 @@ func (t *textReader) nextBeforeTypeAnnotations() (bool, error) {
 			return false, err
 		}
 
+		if t.tok.json {
+			if err := t.verifyJSONSymbol(val, ok, ws, pos); err != nil {
+				return false, err
+			}
+		}
+
 		if ok {
 			// val was an annotation; remember it and keep going.
 			if tok == tokenSymbol {
@@ -246,8 +338,8 @@ func (t *textReader) nextBeforeTypeAnnotations() (bool, error) {
 					return false, err
 				}
 			} else if tok == tokenSymbolOperator {
-				return false, &SyntaxError{
-					"annotations that include a '" + val + "' must be enclosed in quotes", t.tok.Pos() - 1}
+				return false, &SyntaxError{Msg: "annotations that include a '" + val + "' must be enclosed in quotes", Offset: t.tok.Pos() - 1}
+
 			}
 
 			var token SymbolToken
@@ -264,6 +356,11 @@ func (t *textReader) nextBeforeTypeAnnotations() (bool, error) {
 			return false, nil
 		}
 
+		if tok == tokenSymbol && t.ctx.peek() == ctxAtTopLevel && len(t.annotations) == 0 && t.onVersionMarker(val) {
+			t.state = t.stateAfterValue()
+			return false, nil
+		}
+
 		if tok == tokenSymbolQuoted {
 			t.value = &SymbolToken{Text: &val, LocalSID: SymbolIDUnknown}
 			t.valueType = SymbolType
@@ -314,12 +411,18 @@ func (t *textReader) nextBeforeTypeAnnotations() (bool, error) {
 			if t.IsNull() {
 				t.clear()
 				t.lst = V1SystemSymbolTable
+				if t.v11 {
+					t.lst = V11SystemSymbolTable
+				}
 				return false, nil
 			}
 
 			st, err := readLocalSymbolTable(t, t.cat)
 			if err == nil {
 				t.lst = st
+				if t.v11 {
+					t.lst = rebaseSymbolTable(st)
+				}
 				return false, nil
 			}
 			return false, err
@@ -337,15 +440,48 @@ func (t *textReader) nextBeforeTypeAnnotations() (bool, error) {
 		t.state = trsBeforeContainer
 		t.valueType = SexpType
 		t.value = SexpType
+
+		// If it's an encoding directive, install it and keep going.
+		if t.v11 && t.ctx.peek() == ctxAtTopLevel && isIonEncoding(t.annotations) {
+			lst, mt, err := readEncodingDirective(t, t.cat, t.lst, t.macroTable())
+			if err != nil {
+				return false, err
+			}
+			t.lst = lst
+			t.macros = mt
+			return false, nil
+		}
+
 		return true, nil
 
+	case tokenOpenEExpression:
+		if len(t.annotations) > 0 {
+			return false, &SyntaxError{Msg: "e-expressions cannot be annotated", Offset: t.tok.Pos() - 1}
+		}
+
+		fieldName := t.fieldName
+		vals, err := t.readEExpression()
+		if err != nil {
+			return false, err
+		}
+		t.state = t.stateAfterValue()
+
+		// Return the first value the e-expression expands to, if any.
+		t.expand(vals, fieldName)
+		ok, err := t.nextExpanded()
+		if err != nil || ok {
+			return ok, err
+		}
+		t.clear()
+		return false, nil
+
 	case tokenCloseBracket:
 		// No more values in this list.
 		if t.ctx.peek() == ctxInList {
 			t.eof = true
 			return true, nil
 		}
-		return false, &UnexpectedTokenError{"]", t.tok.Pos() - 1}
+		return false, &UnexpectedTokenError{Token: "]", Offset: t.tok.Pos() - 1}
 
 	case tokenCloseParen:
 		// No more values in this sexp.
@@ -353,10 +489,10 @@ func (t *textReader) nextBeforeTypeAnnotations() (bool, error) {
 			t.eof = true
 			return true, nil
 		}
-		return false, &UnexpectedTokenError{")", t.tok.Pos() - 1}
+		return false, &UnexpectedTokenError{Token: ")", Offset: t.tok.Pos() - 1}
 
 	default:
-		return false, &UnexpectedTokenError{tok.String(), t.tok.Pos() - 1}
+		return false, &UnexpectedTokenError{Token: tok.String(), Offset: t.tok.Pos() - 1}
 	}
 }
 
@@ -365,12 +501,23 @@ func (t *textReader) StepIn() error {
 	if t.err != nil {
 		return t.err
 	}
+	if ok, err := t.stepInExpanded(); ok {
+		return err
+	}
 	if t.state != trsBeforeContainer {
 		return &UsageError{"Reader.StepIn", fmt.Sprintf("cannot step in to a %v", t.valueType)}
 	}
 
+	if err := t.checkDepth(t.spanStart); err != nil {
+		t.explode(err)
+		return err
+	}
+
 	ctx := containerTypeToCtx(t.valueType)
 	t.ctx.push(ctx)
+	t.tok.depth = len(t.ctx.arr)
+	t.path[len(t.path)-1].name = t.fieldName
+	t.path = append(t.path, pathStep{index: -1})
 
 	if ctx == ctxInStruct {
 		t.state = trsBeforeFieldName
@@ -393,24 +540,45 @@ func (t *textReader) StepOut() error {
 	if ctx == ctxAtTopLevel {
 		return &UsageError{"Reader.StepOut", "cannot step out of top-level datagram"}
 	}
+	if t.recovering {
+		// The rest of the container is skipped once we're back at the top level.
+		t.ctx.pop()
+		t.tok.depth = len(t.ctx.arr)
+		t.path = t.path[:len(t.path)-1]
+		t.clear()
+		t.eof = false
+		return nil
+	}
+	if t.stepOutExpanded() {
+		return nil
+	}
 	ctype := ctxToContainerType(ctx)
 
+	// A JSON reader reads through the rest of the container to check it.
+	if t.tok.json {
+		for t.Next() {
+		}
+		if t.err != nil {
+			return t.err
+		}
+	}
+
 	// Finish off whatever value *inside* the container that we're currently reading.
 	_, err := t.tok.FinishValue()
 	if err != nil {
-		t.explode(err)
-		return err
+		return t.stepOutError(err)
 	}
 
 	// If we haven't seen the end of the container yet, skip values until we find it.
+	t.tok.depth = len(t.ctx.arr) - 1
 	if !t.eof {
 		if err := t.tok.SkipContainerContents(ctype); err != nil {
-			t.explode(err)
-			return err
+			return t.stepOutError(err)
 		}
 	}
 
 	t.ctx.pop()
+	t.path = t.path[:len(t.path)-1]
 	t.state = t.stateAfterValue()
 	t.clear()
 	t.eof = false
@@ -418,17 +586,201 @@ func (t *textReader) StepOut() error {
 	return nil
 }
 
+// ReadEExpression reads an e-expression whose opening "(:" has just been read,
+// returning the values it expands to.
+func (t *textReader) readEExpression() ([]Value, error) {
+	t.tok.SetFinished()
+
+	m, err := t.readMacroRef()
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := t.readArgs(m)
+	if err != nil {
+		return nil, err
+	}
+	if args, err = m.collectArgs(args); err != nil {
+		return nil, err
+	}
+	return m.Expand(args...)
+}
+
+// ReadMacroRef reads the name or address of the macro an e-expression invokes.
+// Names qualified with $ion refer to system macros.
+func (t *textReader) readMacroRef() (*Macro, error) {
+	if err := t.tok.Next(); err != nil {
+		return nil, err
+	}
+	pos := t.tok.Pos() - 1
+
+	switch tok := t.tok.Token(); tok {
+	case tokenSymbol, tokenSymbolQuoted:
+		name, err := t.tok.ReadValue(tok)
+		if err != nil {
+			return nil, err
+		}
+
+		mt := t.macroTable()
+		if tok == tokenSymbol && name == "$ion" {
+			ok, _, err := t.tok.SkipDoubleColon()
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				if err := t.tok.Next(); err != nil {
+					return nil, err
+				}
+				if tok = t.tok.Token(); tok != tokenSymbol && tok != tokenSymbolQuoted {
+					return nil, &UnexpectedTokenError{Token: tok.String(), Offset: t.tok.Pos() - 1}
+				}
+				if name, err = t.tok.ReadValue(tok); err != nil {
+					return nil, err
+				}
+				mt = V11SystemMacroTable
+			}
+		}
+
+		if m, ok := mt.Find(name); ok {
+			return m, nil
+		}
+		if m, ok := V11SystemMacroTable.Find(name); ok {
+			return m, nil
+		}
+		return nil, &SyntaxError{Msg: fmt.Sprintf("unknown macro %v", name), Offset: pos}
+
+	case tokenNumber:
+		if err := t.onNumber(tok); err != nil {
+			return nil, err
+		}
+		addr, ok := t.value.(int64)
+		t.clear()
+
+		if ok && addr >= 0 {
+			if m, ok := t.macroTable().Get(uint64(addr)); ok {
+				return m, nil
+			}
+		}
+		return nil, &SyntaxError{Msg: "invalid macro address", Offset: pos}
+
+	default:
+		return nil, &UnexpectedTokenError{Token: tok.String(), Offset: pos}
+	}
+}
+
+// ReadArgs reads e-expression arguments up to the closing paren, returning the
+// values passed as each argument. If m is nil, it instead reads the contents of
+// an expression group.
+func (t *textReader) readArgs(m *Macro) ([][]Value, error) {
+	t.ctx.push(ctxInSexp)
+
+	var args [][]Value
+	for {
+		if err := t.tok.Next(); err != nil {
+			return nil, err
+		}
+
+		switch tok := t.tok.Token(); tok {
+		case tokenCloseParen:
+			t.ctx.pop()
+			t.clear()
+			return args, nil
+
+		case tokenOpenEExpression:
+			vals, err := t.readEExpression()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, vals)
+
+		case tokenOpenExpressionGroup:
+			pos := t.tok.Pos() - 1
+			if m == nil {
+				return nil, &SyntaxError{Msg: "expression groups cannot be nested", Offset: pos}
+			}
+			if i := len(args); i < len(m.params) && m.params[i].Cardinality == ExactlyOne {
+				msg := fmt.Sprintf("parameter %v of %v does not accept an expression group", m.params[i].Name, m.displayName())
+				return nil, &SyntaxError{Msg: msg, Offset: pos}
+			}
+
+			t.tok.SetFinished()
+			group, err := t.readArgs(nil)
+			if err != nil {
+				return nil, err
+			}
+
+			var vals []Value
+			for _, g := range group {
+				vals = append(vals, g...)
+			}
+			args = append(args, vals)
+
+		default:
+			v, err := t.readArg()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, []Value{v})
+		}
+	}
+}
+
+// ReadArg reads an e-expression argument that's a plain value, starting with
+// the current token.
+func (t *textReader) readArg() (Value, error) {
+	t.clear()
+	t.state = trsBeforeTypeAnnotations
+
+	for {
+		done, err := t.nextBeforeTypeAnnotations()
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			break
+		}
+		if err := t.tok.Next(); err != nil {
+			return nil, err
+		}
+	}
+
+	if t.eof {
+		return nil, &SyntaxError{Msg: "annotations without a value", Offset: t.tok.Pos() - 1}
+	}
+	return ReadValue(t)
+}
+
 // VerifyUnquotedSymbol checks for certain 'special' values that are returned from
 // the tokenizer as symbols but cannot be used as field names or annotations.
 func (t *textReader) verifyUnquotedSymbol(val string, ctx string) error {
 	switch val {
 	case "null", "true", "false", "nan":
-		return &SyntaxError{fmt.Sprintf("unquoted keyword '%v' as %v", val, ctx), t.tok.Pos() - 1}
+		return &SyntaxError{Msg: fmt.Sprintf("unquoted keyword '%v' as %v", val, ctx), Offset: t.tok.Pos() - 1}
 	}
 	return nil
 }
 
 // OnSymbol handles finding a symbol-token value.
+// onVersionMarker switches between Ion 1.0 and Ion 1.1 if val is a version marker,
+// resetting the symbol and macro tables. It returns false if val is not a version
+// marker. So as not to change how existing Ion 1.0 data is read, $ion_1_0 is only
+// treated as a version marker once an $ion_1_1 marker has been seen.
+func (t *textReader) onVersionMarker(val string) bool {
+	switch {
+	case val == "$ion_1_1":
+		t.v11 = true
+		t.lst = V11SystemSymbolTable
+	case val == "$ion_1_0" && t.v11:
+		t.v11 = false
+		t.lst = V1SystemSymbolTable
+	default:
+		return false
+	}
+
+	t.macros = t.defaultMacros
+	return true
+}
+
 func (t *textReader) onSymbol(val string, tok token, ws bool) error {
 	valueType := SymbolType
 	var value interface{} = val
@@ -491,7 +843,7 @@ func (t *textReader) readNullType() (Type, error) {
 	}
 	if t.tok.Token() != tokenSymbol {
 		msg := fmt.Sprintf("invalid symbol null.%v", t.tok.Token())
-		return NoType, &SyntaxError{msg, t.tok.Pos() - 1}
+		return NoType, &SyntaxError{Msg: msg, Offset: t.tok.Pos() - 1}
 	}
 
 	val, err := t.tok.ReadValue(tokenSymbol)
@@ -528,7 +880,7 @@ func (t *textReader) readNullType() (Type, error) {
 		return SexpType, nil
 	default:
 		msg := fmt.Sprintf("invalid symbol null.%v", t.tok.Token())
-		return NoType, &SyntaxError{msg, t.tok.Pos() - 1}
+		return NoType, &SyntaxError{Msg: msg, Offset: t.tok.Pos() - 1}
 	}
 }
 
@@ -547,7 +899,7 @@ func (t *textReader) onNumber(tok token) error {
 		valueType = IntType
 		value, err = parseInt(val, 2)
 		if err != nil {
-			return err
+			return t.invalidValue(err)
 		}
 
 	case tokenHex:
@@ -559,7 +911,7 @@ func (t *textReader) onNumber(tok token) error {
 		valueType = IntType
 		value, err = parseInt(val, 16)
 		if err != nil {
-			return err
+			return t.invalidValue(err)
 		}
 
 	case tokenNumber:
@@ -567,6 +919,9 @@ func (t *textReader) onNumber(tok token) error {
 		if err != nil {
 			return err
 		}
+		if t.tok.json {
+			val, tt = t.jsonOpts.mapNumber(val, tt)
+		}
 
 		valueType = tt
 
@@ -582,7 +937,7 @@ func (t *textReader) onNumber(tok token) error {
 		}
 
 		if err != nil {
-			return err
+			return t.invalidValue(err)
 		}
 
 	case tokenFloatInf:
@@ -613,7 +968,7 @@ func (t *textReader) onTimestamp() error {
 
 	value, err := parseTimestamp(val)
 	if err != nil {
-		return err
+		return t.invalidValue(err)
 	}
 
 	t.state = t.stateAfterValue()
@@ -671,7 +1026,7 @@ func (t *textReader) onLob() error {
 
 		val, err = base64.StdEncoding.DecodeString(b64)
 		if err != nil {
-			return err
+			return t.invalidValue(fmt.Errorf("invalid base64 blob: %v", err))
 		}
 	}
 
@@ -682,8 +1037,19 @@ func (t *textReader) onLob() error {
 	return nil
 }
 
+// InvalidValue wraps an error found while parsing the current token's value
+// in a SyntaxError, so that it's reported with the token's location.
+func (t *textReader) invalidValue(err error) error {
+	msg := strings.TrimPrefix(err.Error(), "ion: ")
+	return &SyntaxError{Msg: msg, Offset: t.tok.start}
+}
+
 // FinishValue finishes reading the current value, if there is one.
 func (t *textReader) finishValue() error {
+	if t.tok.json {
+		return t.finishJSONValue()
+	}
+
 	ok, err := t.tok.FinishValue()
 	if err != nil {
 		return err
@@ -711,6 +1077,60 @@ func (t *textReader) stateAfterValue() trs {
 // Explode explodes the reader state when something unexpected
 // happens and further calls to Next are a bad idea.
 func (t *textReader) explode(err error) {
+	offset, loc := errorLocation(err)
+	if loc != nil && loc.Line == 0 {
+		loc.Line, loc.Column = t.tok.position(offset)
+		loc.Path = t.pathString()
+	}
+
+	// Lenient readers carry on past syntax errors.
+	if loc != nil && t.recoverFrom(err) {
+		return
+	}
+
 	t.state = trsDone
 	t.err = err
 }
+
+// stepOutError handles an error encountered while stepping out of a container.
+func (t *textReader) stepOutError(err error) error {
+	t.explode(err)
+	if t.recovering {
+		return t.StepOut()
+	}
+	return err
+}
+
+// Position returns the position of the current value, or of the reader if
+// there is no current value.
+func (t *textReader) Position() Position {
+	if t.valueType != NoType {
+		return Position{t.spanStart, t.spanLine, t.spanColumn}
+	}
+	line, column := t.tok.position(t.tok.Pos())
+	return Position{t.tok.Pos(), line, column}
+}
+
+// pathString returns the path to the current value, made of the field names
+// and indexes of the values at each level of nesting below the top level.
+func (t *textReader) pathString() string {
+	var buf strings.Builder
+	for i := 1; i < len(t.path) && i <= len(t.ctx.arr); i++ {
+		step := t.path[i]
+		if i == len(t.path)-1 {
+			step.name = t.fieldName
+		}
+
+		if t.ctx.arr[i-1] != ctxInStruct {
+			fmt.Fprintf(&buf, "[%v]", step.index)
+		} else if step.name != nil {
+			buf.WriteByte('.')
+			if step.name.Text != nil {
+				buf.WriteString(*step.name.Text)
+			} else {
+				fmt.Fprintf(&buf, "$%v", step.name.LocalSID)
+			}
+		}
+	}
+	return buf.String()
+}
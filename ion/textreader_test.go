@@ -19,6 +19,7 @@ import (
 	"bytes"
 	"math"
 	"math/big"
+	"strings"
 	"testing"
 	"time"
 
@@ -531,6 +532,148 @@ func TestInStruct(t *testing.T) {
 	require.False(t, r.IsInStruct(), "IsInStruct returned true before we were in a struct")
 }
 
+func TestReadEExpressions(t *testing.T) {
+	mt, err := ParseMacroTable(`
+		(macro point (x y) {x: (%x), y: (%y)})
+		(macro pair (a b) (.values (%a) (%b)))`)
+	require.NoError(t, err)
+
+	r := NewReaderMacros(strings.NewReader(`
+		(:point 1 2)
+		{a: (:pair 3 4), b: (:none), c: 5}
+		[(:values), (:$ion::values 6 7), 8]
+		(:1 (:0 9 10) a::11)`), nil, mt)
+
+	_struct(t, r, func(t *testing.T, r Reader) {
+		_intAF(t, r, newSymbolTokenPtrFromString("x"), nil, 1)
+		_intAF(t, r, newSymbolTokenPtrFromString("y"), nil, 2)
+	})
+	_struct(t, r, func(t *testing.T, r Reader) {
+		_intAF(t, r, newSymbolTokenPtrFromString("a"), nil, 3)
+		_intAF(t, r, newSymbolTokenPtrFromString("a"), nil, 4)
+		_intAF(t, r, newSymbolTokenPtrFromString("c"), nil, 5)
+	})
+	_list(t, r, func(t *testing.T, r Reader) {
+		_int(t, r, 6)
+		_int(t, r, 7)
+		_int(t, r, 8)
+	})
+	_struct(t, r, func(t *testing.T, r Reader) {
+		_intAF(t, r, newSymbolTokenPtrFromString("x"), nil, 9)
+		_intAF(t, r, newSymbolTokenPtrFromString("y"), nil, 10)
+	})
+	_intAF(t, r, nil, []SymbolToken{NewSymbolTokenFromString("a")}, 11)
+	_eof(t, r)
+}
+
+func TestSkipEExpressions(t *testing.T) {
+	r := NewReaderString("[(:values 1 2 3), 4] (:values [a, b] c) d")
+
+	require.True(t, r.Next())
+	require.NoError(t, r.StepIn())
+	_int(t, r, 1)
+	require.NoError(t, r.StepOut())
+
+	require.True(t, r.Next())
+	require.NoError(t, r.StepIn())
+	_symbol(t, r, NewSymbolTokenFromString("a"))
+	require.NoError(t, r.StepOut())
+
+	_symbol(t, r, NewSymbolTokenFromString("c"))
+	_symbol(t, r, NewSymbolTokenFromString("d"))
+	_eof(t, r)
+}
+
+func TestReadEExpressionErrors(t *testing.T) {
+	test := func(str string) {
+		t.Run(str, func(t *testing.T) {
+			_, err := ReadValues(NewReaderString(str))
+			assert.Error(t, err)
+		})
+	}
+
+	test("(:unknown)")
+	test("(:99)")
+	test("(:-1)")
+	test("(:1.5)")
+	test("(:\"values\")")
+	test("a::(:values)")
+	test("(:: 1)")
+	test("[(:: 1)]")
+	test("(:values (:: (:: 1)))")
+	test("(:sum (:: 1) 2)")
+	test("(:sum 1)")
+	test("(:values a::)")
+	test("(:values 1")
+}
+
+func TestTextReaderErrorLocation(t *testing.T) {
+	test := func(str string, eline, ecol uint64, epath string) {
+		t.Run(str, func(t *testing.T) {
+			_, err := ReadValues(NewReaderString(str))
+			require.Error(t, err)
+
+			_, loc := errorLocation(err)
+			require.NotNil(t, loc, "%v", err)
+			assert.Equal(t, eline, loc.Line, "%v", err)
+			assert.Equal(t, ecol, loc.Column, "%v", err)
+			assert.Equal(t, epath, loc.Path, "%v", err)
+		})
+	}
+
+	test("1 2 ]", 1, 5, "")
+	test("{orders:[1, 2,\n  {price: 1.2.3}]}", 2, 14, ".orders[2].price")
+	test("{a:{b:(c d\r\n  [e, f, \"g]}}", 2, 15, ".a.b[2][2]")
+	test("{a:b,\n c:d,\n 'e", 3, 4, "")
+	test("[a, b, c", 1, 9, "[3]")
+}
+
+func TestTextReaderErrorMessage(t *testing.T) {
+	_, err := ReadValues(NewReaderString("{orders:[1, 2,\n  {price: 1.2.3}]}"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "(line 2, column 14, offset 28, path .orders[2].price)")
+}
+
+func TestTextReaderValueErrorLocation(t *testing.T) {
+	test := func(str, msg string, loc Location) {
+		t.Run(str, func(t *testing.T) {
+			_, err := ReadValues(NewReaderString(str))
+			require.Error(t, err)
+			assert.IsType(t, &SyntaxError{}, err)
+			assert.Contains(t, err.Error(), msg)
+
+			_, l := errorLocation(err)
+			require.NotNil(t, l)
+			assert.Equal(t, loc, *l)
+		})
+	}
+
+	test("{a:[{{ bad }}]}", "invalid base64 blob", Location{Line: 1, Column: 5, Path: ".a[0]"})
+	test("{a:[2020-13-01T]}", "invalid timestamp", Location{Line: 1, Column: 5, Path: ".a[0]"})
+	test("[1,\n  1d99999999999999999999]", "ParseDecimal", Location{Line: 2, Column: 3, Path: "[1]"})
+}
+
+func TestTextReaderPosition(t *testing.T) {
+	r := NewReaderString("a\r\n  b::[c,\n d] // e\n{f:g}").(PositionReader)
+	assert.Equal(t, Position{0, 1, 1}, r.Position())
+
+	require.True(t, r.Next())
+	assert.Equal(t, Position{0, 1, 1}, r.Position())
+
+	require.True(t, r.Next())
+	assert.Equal(t, Position{5, 2, 3}, r.Position())
+
+	require.NoError(t, r.StepIn())
+	require.True(t, r.Next())
+	require.True(t, r.Next())
+	assert.Equal(t, Position{13, 3, 2}, r.Position())
+	require.NoError(t, r.StepOut())
+
+	require.True(t, r.Next())
+	assert.Equal(t, Position{21, 4, 1}, r.Position())
+	require.False(t, r.Next())
+}
+
 type containerhandler func(t *testing.T, r Reader)
 
 func _sexp(t *testing.T, r Reader, f containerhandler) {
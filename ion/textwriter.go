@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"io"
 	"math/big"
+	"strings"
 )
 
 // TextWriterOpts defines a set of bit flag options for text writers.
@@ -33,6 +34,10 @@ const (
 
 	// TextWriterPretty enables pretty-printing mode.
 	TextWriterPretty TextWriterOpts = 2
+
+	// TextWriterCanonical writes values in canonical form (see NewCanonicalWriter),
+	// without pretty-printing. Values are buffered until Finish is called.
+	TextWriterCanonical TextWriterOpts = 4
 )
 
 // textWriter is a writer that writes human-readable text
@@ -56,6 +61,11 @@ func NewTextWriter(out io.Writer, sts ...SharedSymbolTable) Writer {
 
 // NewTextWriterOpts returns a new text writer with the given options.
 func NewTextWriterOpts(out io.Writer, opts TextWriterOpts, sts ...SharedSymbolTable) Writer {
+	if opts&TextWriterCanonical != 0 {
+		opts &^= TextWriterCanonical | TextWriterPretty
+		return NewCanonicalWriter(NewTextWriterOpts(out, opts, sts...))
+	}
+
 	return &textWriter{
 		writer:      writer{out: out},
 		opts:        opts,
@@ -255,6 +265,39 @@ func (w *textWriter) EndStruct() error {
 	return w.err
 }
 
+// BeginEExpression begins writing an e-expression. System macros are referred to
+// by their qualified names, and other macros by their names.
+func (w *textWriter) BeginEExpression(m *Macro) error {
+	if w.err == nil {
+		w.err = w.beginEExpression("Writer.BeginEExpression", m)
+	}
+	return w.err
+}
+
+// EndEExpression finishes writing an e-expression.
+func (w *textWriter) EndEExpression() error {
+	if w.err == nil {
+		w.err = w.end("Writer.EndEExpression", ctxInEExpression, ')')
+	}
+	return w.err
+}
+
+// BeginExpressionGroup begins writing an expression group.
+func (w *textWriter) BeginExpressionGroup() error {
+	if w.err == nil {
+		w.err = w.beginExpressionGroup("Writer.BeginExpressionGroup")
+	}
+	return w.err
+}
+
+// EndExpressionGroup finishes writing an expression group.
+func (w *textWriter) EndExpressionGroup() error {
+	if w.err == nil {
+		w.err = w.end("Writer.EndExpressionGroup", ctxInExpressionGroup, ')')
+	}
+	return w.err
+}
+
 // Finish finishes writing the current datagram.
 func (w *textWriter) Finish() error {
 	if w.err != nil {
@@ -367,7 +410,7 @@ func (w *textWriter) writeSeparator() error {
 			sep = ","
 		}
 
-	case ctxInSexp:
+	case ctxInSexp, ctxInEExpression, ctxInExpressionGroup:
 		// In an sexp, values are separated by whitespace.
 		if w.pretty() {
 			sep = "\n"
@@ -441,6 +484,60 @@ func (w *textWriter) begin(api string, t ctx, c byte) error {
 	return writeRawChar(c, w.out)
 }
 
+// beginEExpression starts writing an e-expression.
+func (w *textWriter) beginEExpression(api string, m *Macro) error {
+	if len(w.annotations) > 0 {
+		return &UsageError{api, "e-expressions cannot be annotated"}
+	}
+
+	var ref string
+	switch _, system := V11SystemMacroTable.Address(m); {
+	case system:
+		ref = "$ion::" + m.name
+	case m.name != "":
+		var buf strings.Builder
+		if err := writeSymbolFromString(m.name, &buf); err != nil {
+			return err
+		}
+		ref = buf.String()
+	default:
+		return &UsageError{api, "cannot refer to an anonymous macro in text"}
+	}
+
+	if err := w.begin(api, ctxInEExpression, '('); err != nil {
+		return err
+	}
+	if err := writeRawString(":"+ref, w.out); err != nil {
+		return err
+	}
+
+	// The macro reference is followed by its arguments like the values of an sexp.
+	w.needsSeparator = true
+	w.emptyContainer = false
+	return nil
+}
+
+// beginExpressionGroup starts writing an expression group.
+func (w *textWriter) beginExpressionGroup(api string) error {
+	if w.ctx.peek() != ctxInEExpression {
+		return &UsageError{api, "expression groups can only be written as e-expression arguments"}
+	}
+	if len(w.annotations) > 0 {
+		return &UsageError{api, "expression groups cannot be annotated"}
+	}
+
+	if err := w.begin(api, ctxInExpressionGroup, '('); err != nil {
+		return err
+	}
+	if err := writeRawString("::", w.out); err != nil {
+		return err
+	}
+
+	w.needsSeparator = true
+	w.emptyContainer = false
+	return nil
+}
+
 // end finishes writing a container of the given type
 func (w *textWriter) end(api string, t ctx, c byte) error {
 	if w.ctx.peek() != t {
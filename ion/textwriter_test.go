@@ -403,6 +403,92 @@ func TestWriteTextPretty(t *testing.T) {
 	assert.Equal(t, expected, actual)
 }
 
+func TestWriteTextEExpressions(t *testing.T) {
+	mt, err := ParseMacroTable("(macro point (x y) {x: (%x), y: (%y)}) (macro 'two words' (v*) (%v))")
+	require.NoError(t, err)
+	point, _ := mt.Find("point")
+	twoWords, _ := mt.Find("two words")
+	values, _ := V11SystemMacroTable.Find("values")
+	none, _ := V11SystemMacroTable.Find("none")
+
+	expected := "(:point 1 (:$ion::values a::b))\n{a:(:'two words' (:: 2 [3]) (::))}\n(:$ion::none)"
+	testTextWriter(t, expected, func(w Writer) {
+		mw := w.(MacroWriter)
+
+		assert.NoError(t, mw.BeginEExpression(point))
+		{
+			assert.NoError(t, mw.WriteInt(1))
+			assert.NoError(t, mw.BeginEExpression(values))
+			assert.NoError(t, mw.Annotation(NewSymbolTokenFromString("a")))
+			assert.NoError(t, mw.WriteSymbolFromString("b"))
+			assert.NoError(t, mw.EndEExpression())
+		}
+		assert.NoError(t, mw.EndEExpression())
+
+		assert.NoError(t, mw.BeginStruct())
+		{
+			assert.NoError(t, mw.FieldName(NewSymbolTokenFromString("a")))
+			assert.NoError(t, mw.BeginEExpression(twoWords))
+			{
+				assert.NoError(t, mw.BeginExpressionGroup())
+				assert.NoError(t, mw.WriteInt(2))
+				assert.NoError(t, mw.BeginList())
+				assert.NoError(t, mw.WriteInt(3))
+				assert.NoError(t, mw.EndList())
+				assert.NoError(t, mw.EndExpressionGroup())
+				assert.NoError(t, mw.BeginExpressionGroup())
+				assert.NoError(t, mw.EndExpressionGroup())
+			}
+			assert.NoError(t, mw.EndEExpression())
+		}
+		assert.NoError(t, mw.EndStruct())
+
+		assert.NoError(t, mw.BeginEExpression(none))
+		assert.NoError(t, mw.EndEExpression())
+	})
+
+	vals := readMacros(t, mt, expected)
+	assertValues(t, "{x: 1, y: a::b} {a: 2, a: [3]}", vals)
+}
+
+func TestWriteTextEExpressionErrors(t *testing.T) {
+	values, _ := V11SystemMacroTable.Find("values")
+	anonymous, err := ParseMacro("(macro null () 1)", nil)
+	require.NoError(t, err)
+
+	test := func(name string, f func(w MacroWriter) error) {
+		t.Run(name, func(t *testing.T) {
+			writeText(func(w Writer) {
+				assert.Error(t, f(w.(MacroWriter)))
+			})
+		})
+	}
+
+	test("anonymous", func(w MacroWriter) error {
+		return w.BeginEExpression(anonymous)
+	})
+	test("annotated", func(w MacroWriter) error {
+		w.Annotation(NewSymbolTokenFromString("a"))
+		return w.BeginEExpression(values)
+	})
+	test("group outside e-expression", func(w MacroWriter) error {
+		return w.BeginExpressionGroup()
+	})
+	test("nested group", func(w MacroWriter) error {
+		w.BeginEExpression(values)
+		w.BeginExpressionGroup()
+		return w.BeginExpressionGroup()
+	})
+	test("mismatched end", func(w MacroWriter) error {
+		w.BeginEExpression(values)
+		return w.EndSexp()
+	})
+	test("unfinished", func(w MacroWriter) error {
+		w.BeginEExpression(values)
+		return w.Finish()
+	})
+}
+
 func testTextWriter(t *testing.T, expected string, f func(Writer)) {
 	actual := writeText(f)
 	assert.Equal(t, expected, actual)
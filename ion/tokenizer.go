@@ -57,6 +57,9 @@ const (
 	tokenCloseBracket     // ]
 	tokenOpenDoubleBrace  // {{
 	tokenCloseDoubleBrace // }}
+
+	tokenOpenEExpression     // (:
+	tokenOpenExpressionGroup // (::
 )
 
 const clobText = true
@@ -121,6 +124,11 @@ func (t token) String() string {
 	case tokenCloseDoubleBrace:
 		return "}}"
 
+	case tokenOpenEExpression:
+		return "(:"
+	case tokenOpenExpressionGroup:
+		return "(::"
+
 	default:
 		return "<???>"
 	}
@@ -133,6 +141,32 @@ type tokenizer struct {
 	token      token
 	unfinished bool
 	pos        uint64
+
+	// The position of the first byte of the current token.
+	start uint64
+
+	// The 0-based line of the next byte to read, and the positions at which
+	// the most recent lines start, indexed by line modulo lineHistory.
+	line  uint64
+	lines [lineHistory]uint64
+
+	// Limits on the input: the position past which reading fails, and the
+	// maximum size of a scalar and, while reading one, where it started and the
+	// position past which it's too big. The reader keeps depth up to date with
+	// its own, so that skipped containers count towards maxDepth.
+	maxPos      uint64
+	maxScalar   uint64
+	scalarStart uint64
+	scalarEnd   uint64
+	maxDepth    int
+	depth       int
+
+	// The depth at which skipping a container last failed, so that a lenient
+	// reader knows how many containers it has to close to resync.
+	errDepth int
+
+	// When json is set, the tokenizer only accepts the lexical grammar of JSON.
+	json bool
 }
 
 func tokenizeString(in string) *tokenizer {
@@ -158,6 +192,41 @@ func (t *tokenizer) Pos() uint64 {
 	return t.pos
 }
 
+// lineHistory is the number of recent lines whose positions the tokenizer
+// remembers.
+const lineHistory = 16
+
+// Position returns the 1-based line and column of the given position, which
+// must not be after the tokenizer's current position. It returns zeroes if the
+// position is too far back for the tokenizer to remember.
+func (t *tokenizer) position(pos uint64) (uint64, uint64) {
+	line := t.line
+	for line > 0 && t.lines[line%lineHistory] > pos {
+		if t.line-line == lineHistory-1 {
+			return 0, 0
+		}
+		line--
+	}
+	start := t.lines[line%lineHistory]
+	if start > pos {
+		return 0, 0
+	}
+	return line + 1, pos - start + 1
+}
+
+// startLine sets the line and the position at which it starts, for tokenizers
+// that start reading partway through their input.
+func (t *tokenizer) startLine(line, start uint64) {
+	t.line = line
+	t.lines[line%lineHistory] = start
+}
+
+// newline records that a newline was just read.
+func (t *tokenizer) newline() {
+	t.line++
+	t.lines[t.line%lineHistory] = t.pos
+}
+
 // Next advances to the next token in the input stream.
 func (t *tokenizer) Next() error {
 	var c int
@@ -172,6 +241,7 @@ func (t *tokenizer) Next() error {
 	if err != nil {
 		return err
 	}
+	t.start = t.pos - 1
 
 	switch {
 	case c == -1:
@@ -215,7 +285,29 @@ func (t *tokenizer) Next() error {
 		return t.ok(tokenCloseBracket, false)
 
 	case c == '(':
-		return t.ok(tokenOpenParen, true)
+		// Ion 1.1 e-expressions open with "(:" and expression groups with "(::".
+		c2, err := t.peek()
+		if err != nil {
+			return err
+		}
+		if c2 != ':' {
+			return t.ok(tokenOpenParen, true)
+		}
+		if _, err = t.read(); err != nil {
+			return err
+		}
+
+		c3, err := t.peek()
+		if err != nil {
+			return err
+		}
+		if c3 == ':' {
+			if _, err = t.read(); err != nil {
+				return err
+			}
+			return t.ok(tokenOpenExpressionGroup, true)
+		}
+		return t.ok(tokenOpenEExpression, true)
 
 	case c == ')':
 		return t.ok(tokenCloseParen, false)
@@ -352,6 +444,9 @@ func (t *tokenizer) FinishValue() (bool, error) {
 
 // ReadValue reads the value of a token of the given type.
 func (t *tokenizer) ReadValue(tok token) (string, error) {
+	t.beginScalar()
+	defer t.endScalar()
+
 	var str string
 	var err error
 
@@ -363,7 +458,11 @@ func (t *tokenizer) ReadValue(tok token) (string, error) {
 	case tokenSymbolOperator, tokenDot:
 		str, err = t.readOperator()
 	case tokenString:
-		str, err = t.readString()
+		if t.json {
+			str, err = t.readJSONString()
+		} else {
+			str, err = t.readString()
+		}
 	case tokenLongString:
 		str, err = t.readLongString()
 	case tokenBinary:
@@ -386,6 +485,13 @@ func (t *tokenizer) ReadValue(tok token) (string, error) {
 
 // ReadNumber reads a number and determines the type.
 func (t *tokenizer) ReadNumber() (string, Type, error) {
+	t.beginScalar()
+	defer t.endScalar()
+
+	if t.json {
+		return t.readJSONNumber()
+	}
+
 	w := strings.Builder{}
 
 	c, err := t.read()
@@ -411,7 +517,7 @@ func (t *tokenizer) ReadNumber() (string, Type, error) {
 
 	if first == '0' {
 		if w.Len()-oldlen > 1 {
-			return "", NoType, &SyntaxError{"invalid leading zeroes", t.pos - 1}
+			return "", NoType, &SyntaxError{Msg: "invalid leading zeroes", Offset: t.pos - 1}
 		}
 	}
 
@@ -775,7 +881,7 @@ func (t *tokenizer) readEscapedChar(isClob bool) (rune, error) {
 		return t.readHexEscapeSeq(2)
 	}
 
-	return 0, &SyntaxError{fmt.Sprintf("bad escape sequence '\\%c'", c), t.pos - 2}
+	return 0, &SyntaxError{Msg: fmt.Sprintf("bad escape sequence '\\%c'", c), Offset: t.pos - 2}
 }
 
 func (t *tokenizer) readHexEscapeSeq(length int) (rune, error) {
@@ -1080,6 +1186,9 @@ func (t *tokenizer) readTimestampFinish(c int, w fmt.Stringer) (string, error) {
 }
 
 func (t *tokenizer) ReadBlob() (string, error) {
+	t.beginScalar()
+	defer t.endScalar()
+
 	w := strings.Builder{}
 
 	var (
@@ -1112,6 +1221,9 @@ func (t *tokenizer) ReadBlob() (string, error) {
 }
 
 func (t *tokenizer) ReadShortClob() ([]byte, error) {
+	t.beginScalar()
+	defer t.endScalar()
+
 	val, err := t.readClob()
 	if err != nil {
 		return nil, err
@@ -1137,6 +1249,9 @@ func (t *tokenizer) ReadShortClob() ([]byte, error) {
 }
 
 func (t *tokenizer) ReadLongClob() ([]byte, error) {
+	t.beginScalar()
+	defer t.endScalar()
+
 	val, err := t.readLongClob()
 	if err != nil {
 		return nil, err
@@ -1301,9 +1416,9 @@ func (t *tokenizer) expect(f matcher) error {
 // unexpected.
 func (t *tokenizer) invalidChar(c int) error {
 	if c == -1 {
-		return &UnexpectedEOFError{t.pos - 1}
+		return &UnexpectedEOFError{Offset: t.pos - 1}
 	}
-	return &UnexpectedRuneError{rune(c), t.pos - 1}
+	return &UnexpectedRuneError{Rune: rune(c), Offset: t.pos - 1}
 }
 
 // SkipN skips over the next n bytes of input. Presumably you've
@@ -1375,11 +1490,41 @@ func (t *tokenizer) peek() (int, error) {
 // returned as (-1, nil) rather than (0, io.EOF), because I find it
 // easier to reason about that way. Newlines are normalized to '\n'.
 func (t *tokenizer) read() (int, error) {
+	c, err := t.readInput()
+	if err == nil && c != -1 {
+		if t.maxPos != 0 && t.pos > t.maxPos {
+			return 0, &LimitError{"total size", t.maxPos, t.pos - 1}
+		}
+		if t.scalarEnd != 0 && t.pos > t.scalarEnd {
+			return 0, &LimitError{"scalar size", t.maxScalar, t.scalarStart}
+		}
+	}
+	return c, err
+}
+
+// beginScalar starts enforcing maxScalar on the scalar about to be read.
+func (t *tokenizer) beginScalar() {
+	if t.maxScalar > 0 {
+		t.scalarStart = t.pos
+		t.scalarEnd = t.pos + t.maxScalar
+	}
+}
+
+// endScalar stops enforcing maxScalar.
+func (t *tokenizer) endScalar() {
+	t.scalarEnd = 0
+}
+
+// readInput reads a byte of input for read.
+func (t *tokenizer) readInput() (int, error) {
 	t.pos++
 	if len(t.buffer) > 0 {
 		// We've already peeked ahead; read from our buffer.
 		c := t.buffer[len(t.buffer)-1]
 		t.buffer = t.buffer[:len(t.buffer)-1]
+		if c == '\n' {
+			t.newline()
+		}
 		return c, nil
 	}
 
@@ -1404,9 +1549,14 @@ func (t *tokenizer) read() (int, error) {
 			if err != nil {
 				return 0, err
 			}
+			t.pos++
 		}
+		t.newline()
 		return '\n', nil
 	}
+	if c == '\n' {
+		t.newline()
+	}
 
 	return int(c), nil
 }
@@ -1414,6 +1564,9 @@ func (t *tokenizer) read() (int, error) {
 // Unread pushes a character (or -1) back into the input stream to
 // be read again later.
 func (t *tokenizer) unread(c int) {
+	if c == '\n' {
+		t.line--
+	}
 	t.pos--
 	t.buffer = append(t.buffer, c)
 }
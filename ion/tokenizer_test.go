@@ -17,6 +17,7 @@ package ion
 
 import (
 	"io"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -41,6 +42,35 @@ func TestNext(t *testing.T) {
 	next(tokenOpenBrace)
 }
 
+func TestNextEExpression(t *testing.T) {
+	tok := tokenizeString("(:foo (:: x) ( :bar) (::))")
+
+	next := func(tt token) {
+		require.NoError(t, tok.Next())
+		require.Equal(t, tt, tok.Token())
+		if tt == tokenSymbol {
+			_, err := tok.ReadValue(tt)
+			require.NoError(t, err)
+		} else {
+			tok.SetFinished()
+		}
+	}
+
+	next(tokenOpenEExpression)
+	next(tokenSymbol)
+	next(tokenOpenExpressionGroup)
+	next(tokenSymbol)
+	next(tokenCloseParen)
+	next(tokenOpenParen)
+	next(tokenColon)
+	next(tokenSymbol)
+	next(tokenCloseParen)
+	next(tokenOpenExpressionGroup)
+	next(tokenCloseParen)
+	next(tokenCloseParen)
+	next(tokenEOF)
+}
+
 func TestReadSymbol(t *testing.T) {
 	test := func(str string, expected string, next token) {
 		t.Run(str, func(t *testing.T) {
@@ -387,6 +417,52 @@ func TestPeekN(t *testing.T) {
 	peekN(t, tok, 10, io.EOF)
 }
 
+func TestTokenizerPosition(t *testing.T) {
+	tok := tokenizeString("ab\r\ncd\nef")
+	read(t, tok, 'a')
+	read(t, tok, 'b')
+	read(t, tok, '\n')
+	read(t, tok, 'c')
+
+	test := func(pos, eline, ecol uint64) {
+		line, col := tok.position(pos)
+		assert.Equal(t, eline, line, "line of %v", pos)
+		assert.Equal(t, ecol, col, "column of %v", pos)
+	}
+	test(1, 1, 2)
+	test(2, 1, 3)
+	test(4, 2, 1)
+
+	tok.unread('c')
+	tok.unread('\n')
+	test(1, 1, 2)
+
+	read(t, tok, '\n')
+	read(t, tok, 'c')
+	read(t, tok, 'd')
+	read(t, tok, '\n')
+	read(t, tok, 'e')
+	test(4, 2, 1)
+	test(7, 3, 1)
+}
+
+func TestTokenizerPositionHistory(t *testing.T) {
+	tok := tokenizeString(strings.Repeat("a\n", 2*lineHistory))
+	for i := 0; i < 2*lineHistory; i++ {
+		read(t, tok, 'a')
+		read(t, tok, '\n')
+	}
+
+	line, col := tok.position(2 * (lineHistory + 1))
+	assert.Equal(t, uint64(lineHistory+2), line)
+	assert.Equal(t, uint64(1), col)
+
+	// The first line is long forgotten.
+	line, col = tok.position(0)
+	assert.Equal(t, uint64(0), line)
+	assert.Equal(t, uint64(0), col)
+}
+
 func peekN(t *testing.T, tok *tokenizer, n int, ee error, ecs ...int) {
 	cs, err := tok.peekN(n)
 	require.Equal(t, ee, err)
@@ -469,7 +545,7 @@ func TestReadUnread(t *testing.T) {
 }
 
 func TestTokenToString(t *testing.T) {
-	for i := tokenError; i <= tokenCloseDoubleBrace+1; i++ {
+	for i := tokenError; i <= tokenOpenExpressionGroup+1; i++ {
 		assert.NotEmpty(t, i.String(), "expected non-empty string for token %v", int(i))
 	}
 }
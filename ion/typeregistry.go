@@ -0,0 +1,168 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// An EncodeFunc writes the given value, of the type it was registered for, to
+// the given Writer.
+type EncodeFunc func(w Writer, v interface{}) error
+
+// A DecodeFunc reads the Reader's current (non-null) value into v, a pointer to
+// a value of the type it was registered for.
+type DecodeFunc func(r Reader, v interface{}) error
+
+// A TypeRegistry maps Go types to functions that encode and decode them, so
+// that types you can't add MarshalIon and UnmarshalIon methods to (UUIDs,
+// third-party decimals, and so on) can be marshaled without wrapping them.
+//
+// Encoders and Decoders consult their own registry (see Encoder.SetTypeRegistry
+// and Decoder.SetTypeRegistry), if any, and then DefaultTypeRegistry, before
+// falling back to their usual handling of a value. Registered types take
+// precedence over Marshaler and Unmarshaler implementations. Pointers to
+// registered types are handled as pointers usually are: nil encodes as null,
+// and null decodes as nil (or the zero value of the registered type).
+//
+// A TypeRegistry is safe for concurrent use.
+type TypeRegistry struct {
+	// funcs holds a *typeFuncs, which is replaced rather than modified, so that
+	// looking up a type (for every value marshaled) doesn't need a lock.
+	mu    sync.Mutex
+	funcs atomic.Value
+}
+
+// typeFuncs are the functions registered in a TypeRegistry.
+type typeFuncs struct {
+	encoders map[reflect.Type]EncodeFunc
+	decoders map[reflect.Type]DecodeFunc
+}
+
+// noTypeFuncs are the functions registered in a new TypeRegistry.
+var noTypeFuncs typeFuncs
+
+// DefaultTypeRegistry is the registry consulted by every Encoder and Decoder.
+var DefaultTypeRegistry = NewTypeRegistry()
+
+// NewTypeRegistry creates a new, empty, TypeRegistry.
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{}
+}
+
+// RegisterType registers functions to encode and decode values of the given
+// type in DefaultTypeRegistry.
+func RegisterType(t reflect.Type, enc EncodeFunc, dec DecodeFunc) {
+	DefaultTypeRegistry.Register(t, enc, dec)
+}
+
+// Register registers functions to encode and decode values of the given type,
+// replacing any previously registered for it. Either function may be nil, in
+// which case values of the type are encoded or decoded as usual.
+func (r *TypeRegistry) Register(t reflect.Type, enc EncodeFunc, dec DecodeFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old := r.load()
+	fs := &typeFuncs{
+		encoders: make(map[reflect.Type]EncodeFunc, len(old.encoders)+1),
+		decoders: make(map[reflect.Type]DecodeFunc, len(old.decoders)+1),
+	}
+	for k, v := range old.encoders {
+		fs.encoders[k] = v
+	}
+	for k, v := range old.decoders {
+		fs.decoders[k] = v
+	}
+
+	if enc != nil {
+		fs.encoders[t] = enc
+	} else {
+		delete(fs.encoders, t)
+	}
+	if dec != nil {
+		fs.decoders[t] = dec
+	} else {
+		delete(fs.decoders, t)
+	}
+	r.funcs.Store(fs)
+}
+
+// load returns the functions currently registered.
+func (r *TypeRegistry) load() *typeFuncs {
+	if fs, ok := r.funcs.Load().(*typeFuncs); ok {
+		return fs
+	}
+	return &noTypeFuncs
+}
+
+// encoder returns the function registered to encode values of the given type,
+// if any.
+func (r *TypeRegistry) encoder(t reflect.Type) EncodeFunc {
+	if r == nil {
+		return nil
+	}
+	fs := r.load()
+	if len(fs.encoders) == 0 {
+		return nil
+	}
+	return fs.encoders[t]
+}
+
+// decoder returns the function registered to decode values of the given type,
+// if any.
+func (r *TypeRegistry) decoder(t reflect.Type) DecodeFunc {
+	if r == nil {
+		return nil
+	}
+	fs := r.load()
+	if len(fs.decoders) == 0 {
+		return nil
+	}
+	return fs.decoders[t]
+}
+
+// SetTypeRegistry sets a registry for the encoder to consult ahead of
+// DefaultTypeRegistry.
+func (m *Encoder) SetTypeRegistry(types *TypeRegistry) {
+	m.types = types
+}
+
+// typeEncoder returns the function registered to encode values of the given
+// type, if any.
+func (m *Encoder) typeEncoder(t reflect.Type) EncodeFunc {
+	if enc := m.types.encoder(t); enc != nil {
+		return enc
+	}
+	return DefaultTypeRegistry.encoder(t)
+}
+
+// SetTypeRegistry sets a registry for the decoder to consult ahead of
+// DefaultTypeRegistry.
+func (d *Decoder) SetTypeRegistry(types *TypeRegistry) {
+	d.types = types
+}
+
+// typeDecoder returns the function registered to decode values of the given
+// type, if any.
+func (d *Decoder) typeDecoder(t reflect.Type) DecodeFunc {
+	if dec := d.types.decoder(t); dec != nil {
+		return dec
+	}
+	return DefaultTypeRegistry.decoder(t)
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A foreignID stands in for a type from another package.
+type foreignID [4]byte
+
+var foreignIDType = reflect.TypeOf(foreignID{})
+
+func encodeForeignID(w Writer, v interface{}) error {
+	id := v.(foreignID)
+	return w.WriteString(hex.EncodeToString(id[:]))
+}
+
+func decodeForeignID(r Reader, v interface{}) error {
+	s, err := r.StringValue()
+	if err != nil {
+		return err
+	}
+	bs, err := hex.DecodeString(*s)
+	if err != nil {
+		return err
+	}
+	if len(bs) != 4 {
+		return errors.New("bad id")
+	}
+	copy(v.(*foreignID)[:], bs)
+	return nil
+}
+
+func newForeignIDRegistry() *TypeRegistry {
+	types := NewTypeRegistry()
+	types.Register(foreignIDType, encodeForeignID, decodeForeignID)
+	return types
+}
+
+type foreignHolder struct {
+	ID    foreignID   `ion:"id"`
+	Ptr   *foreignID  `ion:"ptr"`
+	Many  []foreignID `ion:"many"`
+	Other int         `ion:"other"`
+}
+
+func encodeWithTypes(t *testing.T, types *TypeRegistry, v interface{}) string {
+	buf := strings.Builder{}
+	enc := NewTextEncoder(&buf)
+	enc.SetTypeRegistry(types)
+	require.NoError(t, enc.Encode(v))
+	require.NoError(t, enc.Finish())
+	return buf.String()
+}
+
+func decodeWithTypes(t *testing.T, types *TypeRegistry, text string, v interface{}) error {
+	dec := NewDecoder(NewReaderString(text))
+	dec.SetTypeRegistry(types)
+	return dec.DecodeTo(v)
+}
+
+func TestTypeRegistryEncode(t *testing.T) {
+	types := newForeignIDRegistry()
+	ptr := foreignID{5, 6, 7, 8}
+	v := foreignHolder{
+		ID:    foreignID{1, 2, 3, 4},
+		Ptr:   &ptr,
+		Many:  []foreignID{{0xFF, 0, 0, 0}},
+		Other: 9,
+	}
+
+	assert.Equal(t, `{id:"01020304",ptr:"05060708",many:["ff000000"],other:9}`+"\n", encodeWithTypes(t, types, v))
+	assert.Equal(t, `"01020304"`+"\n", encodeWithTypes(t, types, v.ID))
+	assert.Equal(t, "[1,2,3,4]\n", encodeWithTypes(t, nil, v.ID))
+
+	v.Ptr = nil
+	assert.Equal(t, `{id:"01020304",ptr:null,many:["ff000000"],other:9}`+"\n", encodeWithTypes(t, types, v))
+}
+
+func TestTypeRegistryDecode(t *testing.T) {
+	types := newForeignIDRegistry()
+
+	var v foreignHolder
+	require.NoError(t, decodeWithTypes(t, types, `{id:"01020304", ptr:"05060708", many:["ff000000"], other:9}`, &v))
+	assert.Equal(t, foreignID{1, 2, 3, 4}, v.ID)
+	require.NotNil(t, v.Ptr)
+	assert.Equal(t, foreignID{5, 6, 7, 8}, *v.Ptr)
+	assert.Equal(t, []foreignID{{0xFF, 0, 0, 0}}, v.Many)
+	assert.Equal(t, 9, v.Other)
+
+	require.NoError(t, decodeWithTypes(t, types, `{id:null, ptr:null}`, &v))
+	assert.Equal(t, foreignID{}, v.ID)
+	assert.Nil(t, v.Ptr)
+
+	var id foreignID
+	require.NoError(t, decodeWithTypes(t, types, `"0a0b0c0d"`, &id))
+	assert.Equal(t, foreignID{10, 11, 12, 13}, id)
+
+	assert.Error(t, decodeWithTypes(t, types, `"0a"`, &id))
+}
+
+func TestTypeRegistryDefault(t *testing.T) {
+	RegisterType(foreignIDType, encodeForeignID, decodeForeignID)
+	defer RegisterType(foreignIDType, nil, nil)
+
+	data, err := MarshalText(foreignID{1, 2, 3, 4})
+	require.NoError(t, err)
+	assert.Equal(t, `"01020304"`, string(data))
+
+	var id foreignID
+	require.NoError(t, UnmarshalString(`"0a0b0c0d"`, &id))
+	assert.Equal(t, foreignID{10, 11, 12, 13}, id)
+
+	// An encoder's own registry takes precedence.
+	types := NewTypeRegistry()
+	types.Register(foreignIDType, func(w Writer, v interface{}) error {
+		return w.WriteSymbolFromString("mine")
+	}, nil)
+	assert.Equal(t, "mine\n", encodeWithTypes(t, types, id))
+
+	// Types registered for encoding only are decoded as usual.
+	require.NoError(t, decodeWithTypes(t, types, `"01020304"`, &id))
+	assert.Equal(t, foreignID{1, 2, 3, 4}, id)
+}
+
+// A registeredMarshaler implements Marshaler and Unmarshaler.
+type registeredMarshaler struct {
+	s string
+}
+
+func (m *registeredMarshaler) MarshalIon(w Writer) error {
+	return w.WriteString("marshaler")
+}
+
+func (m *registeredMarshaler) UnmarshalIon(r Reader) error {
+	m.s = "unmarshaler"
+	return nil
+}
+
+func TestTypeRegistryOverridesMarshaler(t *testing.T) {
+	types := NewTypeRegistry()
+	types.Register(reflect.TypeOf(registeredMarshaler{}),
+		func(w Writer, v interface{}) error {
+			return w.WriteString("registry " + v.(registeredMarshaler).s)
+		},
+		func(r Reader, v interface{}) error {
+			v.(*registeredMarshaler).s = "registry"
+			return nil
+		})
+
+	assert.Equal(t, "\"registry x\"\n", encodeWithTypes(t, types, &registeredMarshaler{"x"}))
+	assert.Equal(t, "\"marshaler\"\n", encodeWithTypes(t, nil, &registeredMarshaler{"x"}))
+
+	var m registeredMarshaler
+	require.NoError(t, decodeWithTypes(t, types, `"x"`, &m))
+	assert.Equal(t, "registry", m.s)
+	require.NoError(t, decodeWithTypes(t, nil, `"x"`, &m))
+	assert.Equal(t, "unmarshaler", m.s)
+}
@@ -95,6 +95,17 @@ type Unmarshaler interface {
 //	  []interface{}{}                                 list
 //	  []interface{}{}                                 sexp
 //	  map[string]interface{}{}/struct/interface{}     struct
+//
+// Some standard library types have mappings of their own:
+//
+//	  Go type                                         Ion Type
+//	--------------------------                     ---------------
+//	  time.Duration                                   int (nanoseconds), string
+//	  big.Float/big.Rat                               decimal, int, float
+//	  big.Rat                                         string ("1/3")
+//	  net.IP/net.IPNet/url.URL                        string, symbol
+//	  json.Number                                     int, decimal, float, string
+//	  encoding.TextUnmarshaler                        string, symbol
 func Unmarshal(data []byte, v interface{}, ssts ...SharedSymbolTable) error {
 	catalog := NewCatalog(ssts...)
 	return NewDecoder(NewReaderCat(bytes.NewReader(data), catalog)).DecodeTo(v)
@@ -115,7 +126,8 @@ func UnmarshalFrom(r Reader, v interface{}) error {
 
 // A Decoder decodes go values from an Ion reader.
 type Decoder struct {
-	r Reader
+	r     Reader
+	types *TypeRegistry
 }
 
 // NewDecoder creates a new decoder.
@@ -132,6 +144,12 @@ func NewTextDecoder(in io.Reader) *Decoder {
 	return NewDecoder(NewReader(in))
 }
 
+// NewJSONDecoder creates a new decoder that only accepts JSON, as read by
+// NewJSONReader.
+func NewJSONDecoder(in io.Reader) *Decoder {
+	return NewDecoder(NewJSONReader(in))
+}
+
 // Decode decodes a value from the underlying Ion reader without any expectations
 // about what it's going to get. Structs become map[string]interface{}s, Lists and
 // Sexps become []interface{}s.
@@ -311,9 +329,17 @@ func (d *Decoder) decodeTo(v reflect.Value) error {
 	}
 
 	t := v.Type()
+	if dec := d.typeDecoder(t); dec != nil && v.CanAddr() {
+		return dec(d.r, v.Addr().Interface())
+	}
 	if t.Kind() != reflect.Ptr && v.CanAddr() && reflect.PtrTo(t).Implements(unmarshalerType) {
 		return v.Addr().Interface().(Unmarshaler).UnmarshalIon(d.r)
 	}
+	if v.CanAddr() {
+		if ok, err := d.decodeStdTypeTo(v); ok {
+			return err
+		}
+	}
 
 	switch d.r.Type() {
 	case BoolType:
@@ -0,0 +1,948 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// A Value is an in-memory Ion value. Unlike the generic maps and slices produced
+// by Decoder.Decode, a tree of Values preserves everything in the Ion data model:
+// annotations, the distinction between lists and sexps, symbols and strings, clobs
+// and blobs, and the order (and duplication) of struct fields.
+//
+// There is one concrete Value type per Ion type. Any of them may represent a typed
+// null, in which case IsNull returns true; an untyped null is represented by a
+// *NullValue.
+//
+//	v, err := ReadValue(r)
+//	if err != nil {
+//		return err
+//	}
+//	if s, ok := v.(*StructValue); ok {
+//		s.Add("checked", NewBool(true))
+//	}
+//	return v.MarshalIon(w)
+//
+// Values implement Marshaler, so they can also be passed directly to MarshalText,
+// MarshalBinary, or an Encoder.
+type Value interface {
+	Marshaler
+
+	// Type returns the Ion type of this value.
+	Type() Type
+
+	// IsNull returns true if this value is a (possibly typed) Ion null.
+	IsNull() bool
+
+	// Annotations returns the annotations attached to this value.
+	Annotations() []SymbolToken
+
+	// SetAnnotations replaces the annotations attached to this value.
+	SetAnnotations(as ...SymbolToken)
+}
+
+// A StructField is a single name/value pair inside a StructValue.
+type StructField struct {
+	Name  SymbolToken
+	Value Value
+}
+
+// A valueNode holds the state shared by all concrete Value types.
+type valueNode struct {
+	annotations []SymbolToken
+	null        bool
+}
+
+// IsNull returns true if this value is null.
+func (n *valueNode) IsNull() bool {
+	return n.null
+}
+
+// Annotations returns the value's annotations.
+func (n *valueNode) Annotations() []SymbolToken {
+	return n.annotations
+}
+
+// SetAnnotations replaces the value's annotations.
+func (n *valueNode) SetAnnotations(as ...SymbolToken) {
+	if len(as) == 0 {
+		n.annotations = nil
+		return
+	}
+	n.annotations = append([]SymbolToken{}, as...)
+}
+
+// writeAnnotations writes the value's annotations (if any) to the given writer.
+func (n *valueNode) writeAnnotations(w Writer) error {
+	if len(n.annotations) == 0 {
+		return nil
+	}
+	return w.Annotations(n.annotations...)
+}
+
+// marshalNull writes the value's annotations followed by a typed null.
+func (n *valueNode) marshalNull(w Writer, t Type) error {
+	if err := n.writeAnnotations(w); err != nil {
+		return err
+	}
+	return w.WriteNullType(t)
+}
+
+// NewNullValue creates a null value of the given type. NullType (or NoType)
+// creates an untyped null.
+func NewNullValue(t Type) Value {
+	null := valueNode{null: true}
+
+	switch t {
+	case NoType, NullType:
+		return &NullValue{null}
+	case BoolType:
+		return &BoolValue{valueNode: null}
+	case IntType:
+		return &IntValue{valueNode: null}
+	case FloatType:
+		return &FloatValue{valueNode: null}
+	case DecimalType:
+		return &DecimalValue{valueNode: null}
+	case TimestampType:
+		return &TimestampValue{valueNode: null}
+	case SymbolType:
+		return &SymbolValue{valueNode: null}
+	case StringType:
+		return &StringValue{valueNode: null}
+	case ClobType:
+		return &ClobValue{valueNode: null}
+	case BlobType:
+		return &BlobValue{valueNode: null}
+	case ListType:
+		return &ListValue{sequence{valueNode: null}}
+	case SexpType:
+		return &SexpValue{sequence{valueNode: null}}
+	case StructType:
+		return &StructValue{valueNode: null}
+	default:
+		panic(fmt.Sprintf("invalid type %v", t))
+	}
+}
+
+// A NullValue is an untyped Ion null.
+type NullValue struct {
+	valueNode
+}
+
+// NewNull creates an untyped null value.
+func NewNull() *NullValue {
+	return &NullValue{valueNode{null: true}}
+}
+
+// Type returns NullType.
+func (v *NullValue) Type() Type {
+	return NullType
+}
+
+// MarshalIon writes the value to the given writer.
+func (v *NullValue) MarshalIon(w Writer) error {
+	if err := v.writeAnnotations(w); err != nil {
+		return err
+	}
+	return w.WriteNull()
+}
+
+// A BoolValue is an Ion bool.
+type BoolValue struct {
+	valueNode
+	val bool
+}
+
+// NewBool creates a bool value.
+func NewBool(val bool) *BoolValue {
+	return &BoolValue{val: val}
+}
+
+// Type returns BoolType.
+func (v *BoolValue) Type() Type {
+	return BoolType
+}
+
+// Bool returns the value, or false if the value is null.
+func (v *BoolValue) Bool() bool {
+	return v.val
+}
+
+// SetBool sets the value, clearing any null.
+func (v *BoolValue) SetBool(val bool) {
+	v.val = val
+	v.null = false
+}
+
+// MarshalIon writes the value to the given writer.
+func (v *BoolValue) MarshalIon(w Writer) error {
+	if v.null {
+		return v.marshalNull(w, BoolType)
+	}
+	if err := v.writeAnnotations(w); err != nil {
+		return err
+	}
+	return w.WriteBool(v.val)
+}
+
+// An IntValue is an arbitrary-size Ion int.
+type IntValue struct {
+	valueNode
+	val *big.Int
+}
+
+// NewInt creates an int value.
+func NewInt(val int64) *IntValue {
+	return &IntValue{val: big.NewInt(val)}
+}
+
+// NewBigInt creates an int value from a big.Int.
+func NewBigInt(val *big.Int) *IntValue {
+	return &IntValue{val: new(big.Int).Set(val)}
+}
+
+// Type returns IntType.
+func (v *IntValue) Type() Type {
+	return IntType
+}
+
+// IntSize returns the size of integer needed to losslessly represent the value.
+func (v *IntValue) IntSize() IntSize {
+	switch {
+	case v.null:
+		return NullInt
+	case !v.val.IsInt64():
+		return BigInt
+	case v.val.Int64() > math.MaxInt32 || v.val.Int64() < math.MinInt32:
+		return Int64
+	default:
+		return Int32
+	}
+}
+
+// Int64 returns the value as an int64. It returns false if the value is null
+// or does not fit in an int64.
+func (v *IntValue) Int64() (int64, bool) {
+	if v.null || !v.val.IsInt64() {
+		return 0, false
+	}
+	return v.val.Int64(), true
+}
+
+// BigInt returns a copy of the value as a big.Int, or nil if the value is null.
+func (v *IntValue) BigInt() *big.Int {
+	if v.null {
+		return nil
+	}
+	return new(big.Int).Set(v.val)
+}
+
+// SetInt64 sets the value, clearing any null.
+func (v *IntValue) SetInt64(val int64) {
+	v.val = big.NewInt(val)
+	v.null = false
+}
+
+// SetBigInt sets the value, clearing any null.
+func (v *IntValue) SetBigInt(val *big.Int) {
+	v.val = new(big.Int).Set(val)
+	v.null = false
+}
+
+// MarshalIon writes the value to the given writer.
+func (v *IntValue) MarshalIon(w Writer) error {
+	if v.null {
+		return v.marshalNull(w, IntType)
+	}
+	if err := v.writeAnnotations(w); err != nil {
+		return err
+	}
+	if v.val.IsInt64() {
+		return w.WriteInt(v.val.Int64())
+	}
+	return w.WriteBigInt(v.val)
+}
+
+// A FloatValue is an Ion float.
+type FloatValue struct {
+	valueNode
+	val float64
+}
+
+// NewFloat creates a float value.
+func NewFloat(val float64) *FloatValue {
+	return &FloatValue{val: val}
+}
+
+// Type returns FloatType.
+func (v *FloatValue) Type() Type {
+	return FloatType
+}
+
+// Float returns the value, or zero if the value is null.
+func (v *FloatValue) Float() float64 {
+	return v.val
+}
+
+// SetFloat sets the value, clearing any null.
+func (v *FloatValue) SetFloat(val float64) {
+	v.val = val
+	v.null = false
+}
+
+// MarshalIon writes the value to the given writer.
+func (v *FloatValue) MarshalIon(w Writer) error {
+	if v.null {
+		return v.marshalNull(w, FloatType)
+	}
+	if err := v.writeAnnotations(w); err != nil {
+		return err
+	}
+	return w.WriteFloat(v.val)
+}
+
+// A DecimalValue is an arbitrary-precision Ion decimal.
+type DecimalValue struct {
+	valueNode
+	val *Decimal
+}
+
+// NewDecimalValue creates a decimal value.
+func NewDecimalValue(val *Decimal) *DecimalValue {
+	return &DecimalValue{val: val}
+}
+
+// Type returns DecimalType.
+func (v *DecimalValue) Type() Type {
+	return DecimalType
+}
+
+// Decimal returns the value, or nil if the value is null.
+func (v *DecimalValue) Decimal() *Decimal {
+	if v.null {
+		return nil
+	}
+	return v.val
+}
+
+// SetDecimal sets the value, clearing any null.
+func (v *DecimalValue) SetDecimal(val *Decimal) {
+	v.val = val
+	v.null = false
+}
+
+// MarshalIon writes the value to the given writer.
+func (v *DecimalValue) MarshalIon(w Writer) error {
+	if v.null {
+		return v.marshalNull(w, DecimalType)
+	}
+	if err := v.writeAnnotations(w); err != nil {
+		return err
+	}
+	return w.WriteDecimal(v.val)
+}
+
+// A TimestampValue is an Ion timestamp.
+type TimestampValue struct {
+	valueNode
+	val Timestamp
+}
+
+// NewTimestampValue creates a timestamp value.
+func NewTimestampValue(val Timestamp) *TimestampValue {
+	return &TimestampValue{val: val}
+}
+
+// Type returns TimestampType.
+func (v *TimestampValue) Type() Type {
+	return TimestampType
+}
+
+// Timestamp returns the value, or the zero Timestamp if the value is null.
+func (v *TimestampValue) Timestamp() Timestamp {
+	return v.val
+}
+
+// SetTimestamp sets the value, clearing any null.
+func (v *TimestampValue) SetTimestamp(val Timestamp) {
+	v.val = val
+	v.null = false
+}
+
+// MarshalIon writes the value to the given writer.
+func (v *TimestampValue) MarshalIon(w Writer) error {
+	if v.null {
+		return v.marshalNull(w, TimestampType)
+	}
+	if err := v.writeAnnotations(w); err != nil {
+		return err
+	}
+	return w.WriteTimestamp(v.val)
+}
+
+// A SymbolValue is an Ion symbol.
+type SymbolValue struct {
+	valueNode
+	val SymbolToken
+}
+
+// NewSymbol creates a symbol value with the given text.
+func NewSymbol(text string) *SymbolValue {
+	return &SymbolValue{val: NewSymbolTokenFromString(text)}
+}
+
+// NewSymbolValue creates a symbol value from a SymbolToken.
+func NewSymbolValue(val SymbolToken) *SymbolValue {
+	return &SymbolValue{val: val}
+}
+
+// Type returns SymbolType.
+func (v *SymbolValue) Type() Type {
+	return SymbolType
+}
+
+// Symbol returns the value's SymbolToken.
+func (v *SymbolValue) Symbol() SymbolToken {
+	return v.val
+}
+
+// SetSymbol sets the value, clearing any null.
+func (v *SymbolValue) SetSymbol(val SymbolToken) {
+	v.val = val
+	v.null = false
+}
+
+// MarshalIon writes the value to the given writer.
+func (v *SymbolValue) MarshalIon(w Writer) error {
+	if v.null {
+		return v.marshalNull(w, SymbolType)
+	}
+	if err := v.writeAnnotations(w); err != nil {
+		return err
+	}
+	return w.WriteSymbol(v.val)
+}
+
+// A StringValue is an Ion string.
+type StringValue struct {
+	valueNode
+	val string
+}
+
+// NewString creates a string value.
+func NewString(val string) *StringValue {
+	return &StringValue{val: val}
+}
+
+// Type returns StringType.
+func (v *StringValue) Type() Type {
+	return StringType
+}
+
+// Text returns the value, or "" if the value is null.
+func (v *StringValue) Text() string {
+	return v.val
+}
+
+// SetText sets the value, clearing any null.
+func (v *StringValue) SetText(val string) {
+	v.val = val
+	v.null = false
+}
+
+// MarshalIon writes the value to the given writer.
+func (v *StringValue) MarshalIon(w Writer) error {
+	if v.null {
+		return v.marshalNull(w, StringType)
+	}
+	if err := v.writeAnnotations(w); err != nil {
+		return err
+	}
+	return w.WriteString(v.val)
+}
+
+// A ClobValue is an Ion clob.
+type ClobValue struct {
+	valueNode
+	val []byte
+}
+
+// NewClob creates a clob value.
+func NewClob(val []byte) *ClobValue {
+	return &ClobValue{val: val}
+}
+
+// Type returns ClobType.
+func (v *ClobValue) Type() Type {
+	return ClobType
+}
+
+// Bytes returns the value, or nil if the value is null.
+func (v *ClobValue) Bytes() []byte {
+	return v.val
+}
+
+// SetBytes sets the value, clearing any null.
+func (v *ClobValue) SetBytes(val []byte) {
+	v.val = val
+	v.null = false
+}
+
+// MarshalIon writes the value to the given writer.
+func (v *ClobValue) MarshalIon(w Writer) error {
+	if v.null {
+		return v.marshalNull(w, ClobType)
+	}
+	if err := v.writeAnnotations(w); err != nil {
+		return err
+	}
+	return w.WriteClob(v.val)
+}
+
+// A BlobValue is an Ion blob.
+type BlobValue struct {
+	valueNode
+	val []byte
+}
+
+// NewBlob creates a blob value.
+func NewBlob(val []byte) *BlobValue {
+	return &BlobValue{val: val}
+}
+
+// Type returns BlobType.
+func (v *BlobValue) Type() Type {
+	return BlobType
+}
+
+// Bytes returns the value, or nil if the value is null.
+func (v *BlobValue) Bytes() []byte {
+	return v.val
+}
+
+// SetBytes sets the value, clearing any null.
+func (v *BlobValue) SetBytes(val []byte) {
+	v.val = val
+	v.null = false
+}
+
+// MarshalIon writes the value to the given writer.
+func (v *BlobValue) MarshalIon(w Writer) error {
+	if v.null {
+		return v.marshalNull(w, BlobType)
+	}
+	if err := v.writeAnnotations(w); err != nil {
+		return err
+	}
+	return w.WriteBlob(v.val)
+}
+
+// A sequence holds the children of a list or sexp.
+type sequence struct {
+	valueNode
+	vals []Value
+}
+
+// Len returns the number of child values.
+func (s *sequence) Len() int {
+	return len(s.vals)
+}
+
+// Values returns the child values.
+func (s *sequence) Values() []Value {
+	return s.vals
+}
+
+// Get returns the i'th child value.
+func (s *sequence) Get(i int) Value {
+	return s.vals[i]
+}
+
+// Set replaces the i'th child value.
+func (s *sequence) Set(i int, v Value) {
+	s.vals[i] = v
+}
+
+// Append adds values to the end of the sequence, clearing any null.
+func (s *sequence) Append(vs ...Value) {
+	s.vals = append(s.vals, vs...)
+	s.null = false
+}
+
+// Remove removes the i'th child value.
+func (s *sequence) Remove(i int) {
+	s.vals = append(s.vals[:i], s.vals[i+1:]...)
+}
+
+// marshalSequence writes out the children of a sequence between the given begin
+// and end calls.
+func (s *sequence) marshalSequence(w Writer, t Type, begin, end func() error) error {
+	if s.null {
+		return s.marshalNull(w, t)
+	}
+	if err := s.writeAnnotations(w); err != nil {
+		return err
+	}
+	if err := begin(); err != nil {
+		return err
+	}
+	for _, v := range s.vals {
+		if err := v.MarshalIon(w); err != nil {
+			return err
+		}
+	}
+	return end()
+}
+
+// A ListValue is an Ion list.
+type ListValue struct {
+	sequence
+}
+
+// NewList creates a list value containing the given values.
+func NewList(vs ...Value) *ListValue {
+	l := &ListValue{}
+	l.Append(vs...)
+	return l
+}
+
+// Type returns ListType.
+func (v *ListValue) Type() Type {
+	return ListType
+}
+
+// MarshalIon writes the value to the given writer.
+func (v *ListValue) MarshalIon(w Writer) error {
+	return v.marshalSequence(w, ListType, w.BeginList, w.EndList)
+}
+
+// A SexpValue is an Ion s-expression.
+type SexpValue struct {
+	sequence
+}
+
+// NewSexp creates an s-expression value containing the given values.
+func NewSexp(vs ...Value) *SexpValue {
+	s := &SexpValue{}
+	s.Append(vs...)
+	return s
+}
+
+// Type returns SexpType.
+func (v *SexpValue) Type() Type {
+	return SexpType
+}
+
+// MarshalIon writes the value to the given writer.
+func (v *SexpValue) MarshalIon(w Writer) error {
+	return v.marshalSequence(w, SexpType, w.BeginSexp, w.EndSexp)
+}
+
+// A StructValue is an Ion struct. Fields are kept in the order they were added,
+// and a field name may occur more than once.
+type StructValue struct {
+	valueNode
+	fields []StructField
+}
+
+// NewStruct creates an empty struct value.
+func NewStruct() *StructValue {
+	return &StructValue{}
+}
+
+// Type returns StructType.
+func (v *StructValue) Type() Type {
+	return StructType
+}
+
+// Len returns the number of fields in the struct.
+func (v *StructValue) Len() int {
+	return len(v.fields)
+}
+
+// Fields returns the struct's fields in order.
+func (v *StructValue) Fields() []StructField {
+	return v.fields
+}
+
+// Get returns the value of the first field with the given name, or nil if
+// there is no such field.
+func (v *StructValue) Get(name string) Value {
+	for _, f := range v.fields {
+		if f.Name.Text != nil && *f.Name.Text == name {
+			return f.Value
+		}
+	}
+	return nil
+}
+
+// GetAll returns the values of all fields with the given name.
+func (v *StructValue) GetAll(name string) []Value {
+	var vs []Value
+	for _, f := range v.fields {
+		if f.Name.Text != nil && *f.Name.Text == name {
+			vs = append(vs, f.Value)
+		}
+	}
+	return vs
+}
+
+// Add appends a field with the given name, clearing any null.
+func (v *StructValue) Add(name string, val Value) {
+	v.AddField(NewSymbolTokenFromString(name), val)
+}
+
+// AddField appends a field with the given name token, clearing any null.
+func (v *StructValue) AddField(name SymbolToken, val Value) {
+	v.fields = append(v.fields, StructField{Name: name, Value: val})
+	v.null = false
+}
+
+// Set replaces all fields with the given name by a single field holding val,
+// positioned where the first such field was (or at the end if there was none).
+func (v *StructValue) Set(name string, val Value) {
+	for i, f := range v.fields {
+		if f.Name.Text != nil && *f.Name.Text == name {
+			v.fields[i].Value = val
+			v.removeFrom(name, i+1)
+			return
+		}
+	}
+	v.Add(name, val)
+}
+
+// Remove removes all fields with the given name, returning how many were removed.
+func (v *StructValue) Remove(name string) int {
+	return v.removeFrom(name, 0)
+}
+
+// removeFrom removes all fields with the given name at or after index start.
+func (v *StructValue) removeFrom(name string, start int) int {
+	kept := v.fields[:start]
+	for _, f := range v.fields[start:] {
+		if f.Name.Text == nil || *f.Name.Text != name {
+			kept = append(kept, f)
+		}
+	}
+	removed := len(v.fields) - len(kept)
+	v.fields = kept
+	return removed
+}
+
+// MarshalIon writes the value to the given writer.
+func (v *StructValue) MarshalIon(w Writer) error {
+	if v.null {
+		return v.marshalNull(w, StructType)
+	}
+	if err := v.writeAnnotations(w); err != nil {
+		return err
+	}
+	if err := w.BeginStruct(); err != nil {
+		return err
+	}
+	for _, f := range v.fields {
+		if err := w.FieldName(f.Name); err != nil {
+			return err
+		}
+		if err := f.Value.MarshalIon(w); err != nil {
+			return err
+		}
+	}
+	return w.EndStruct()
+}
+
+// ReadValue reads the value the given Reader is currently positioned on (that is,
+// after a successful call to Next) into memory. Containers are read recursively,
+// leaving the Reader positioned after the end of the container.
+func ReadValue(r Reader) (Value, error) {
+	as, err := r.Annotations()
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := readValue(r)
+	if err != nil {
+		return nil, err
+	}
+
+	v.SetAnnotations(as...)
+	return v, nil
+}
+
+// ReadValues reads all remaining values in the Reader's current value stream
+// into memory.
+func ReadValues(r Reader) ([]Value, error) {
+	var vs []Value
+	for r.Next() {
+		errs := errorCount(r)
+		v, err := ReadValue(r)
+		if err != nil {
+			return nil, err
+		}
+
+		// Drop values that a LenientReader abandoned partway through.
+		if errorCount(r) == errs {
+			vs = append(vs, v)
+		}
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return vs, nil
+}
+
+// readValue reads the current value, minus its annotations.
+func readValue(r Reader) (Value, error) {
+	t := r.Type()
+	if r.IsNull() {
+		return NewNullValue(t), nil
+	}
+
+	switch t {
+	case NullType:
+		return NewNull(), nil
+
+	case BoolType:
+		val, err := r.BoolValue()
+		if err != nil {
+			return nil, err
+		}
+		return NewBool(*val), nil
+
+	case IntType:
+		val, err := r.BigIntValue()
+		if err != nil {
+			return nil, err
+		}
+		return NewBigInt(val), nil
+
+	case FloatType:
+		val, err := r.FloatValue()
+		if err != nil {
+			return nil, err
+		}
+		return NewFloat(*val), nil
+
+	case DecimalType:
+		val, err := r.DecimalValue()
+		if err != nil {
+			return nil, err
+		}
+		return NewDecimalValue(val), nil
+
+	case TimestampType:
+		val, err := r.TimestampValue()
+		if err != nil {
+			return nil, err
+		}
+		return NewTimestampValue(*val), nil
+
+	case SymbolType:
+		val, err := r.SymbolValue()
+		if err != nil {
+			return nil, err
+		}
+		return NewSymbolValue(*val), nil
+
+	case StringType:
+		val, err := r.StringValue()
+		if err != nil {
+			return nil, err
+		}
+		return NewString(*val), nil
+
+	case ClobType:
+		val, err := r.ByteValue()
+		if err != nil {
+			return nil, err
+		}
+		return NewClob(val), nil
+
+	case BlobType:
+		val, err := r.ByteValue()
+		if err != nil {
+			return nil, err
+		}
+		return NewBlob(val), nil
+
+	case ListType:
+		vs, err := readChildren(r)
+		if err != nil {
+			return nil, err
+		}
+		return NewList(vs...), nil
+
+	case SexpType:
+		vs, err := readChildren(r)
+		if err != nil {
+			return nil, err
+		}
+		return NewSexp(vs...), nil
+
+	case StructType:
+		return readStruct(r)
+
+	default:
+		return nil, &UsageError{"ReadValue", fmt.Sprintf("cannot read a value of type %v", t)}
+	}
+}
+
+// readChildren reads the contents of the current list or sexp.
+func readChildren(r Reader) ([]Value, error) {
+	if err := r.StepIn(); err != nil {
+		return nil, err
+	}
+
+	vs, err := ReadValues(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return vs, r.StepOut()
+}
+
+// readStruct reads the contents of the current struct.
+func readStruct(r Reader) (*StructValue, error) {
+	if err := r.StepIn(); err != nil {
+		return nil, err
+	}
+
+	s := NewStruct()
+	for r.Next() {
+		name, err := r.FieldName()
+		if err != nil {
+			return nil, err
+		}
+		if name == nil {
+			return nil, &UsageError{"ReadValue", "struct field has no name"}
+		}
+
+		v, err := ReadValue(r)
+		if err != nil {
+			return nil, err
+		}
+		s.AddField(*name, v)
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	return s, r.StepOut()
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadValues(t *testing.T) {
+	ionText := `a::b::{x:1,y:[1.5,2e0],x:"two",z:(c 'd' null.sexp)}
+				123456789012345678901234567890
+				2020-01-02T03:04:05Z
+				{{aGVsbG8=}}
+				{{"clob"}}
+				null
+				null.int`
+
+	vs, err := ReadValues(NewReaderString(ionText))
+	require.NoError(t, err)
+	require.Len(t, vs, 7)
+
+	s, ok := vs[0].(*StructValue)
+	require.True(t, ok)
+	assert.Equal(t, []SymbolToken{NewSymbolTokenFromString("a"), NewSymbolTokenFromString("b")}, s.Annotations())
+	assert.Equal(t, 4, s.Len())
+	assert.Equal(t, int64(1), mustInt64(t, s.Get("x")))
+	require.Len(t, s.GetAll("x"), 2)
+	assert.Equal(t, "two", s.GetAll("x")[1].(*StringValue).Text())
+
+	l := s.Get("y").(*ListValue)
+	require.Equal(t, 2, l.Len())
+	assert.Equal(t, DecimalType, l.Get(0).Type())
+	assert.Equal(t, 2.0, l.Get(1).(*FloatValue).Float())
+
+	sexp := s.Get("z").(*SexpValue)
+	require.Equal(t, 3, sexp.Len())
+	assert.Equal(t, "c", *sexp.Get(0).(*SymbolValue).Symbol().Text)
+	assert.True(t, sexp.Get(2).IsNull())
+	assert.Equal(t, SexpType, sexp.Get(2).Type())
+
+	bi, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	assert.Equal(t, BigInt, vs[1].(*IntValue).IntSize())
+	assert.Equal(t, 0, bi.Cmp(vs[1].(*IntValue).BigInt()))
+
+	assert.Equal(t, TimestampPrecisionSecond, vs[2].(*TimestampValue).Timestamp().GetPrecision())
+	assert.Equal(t, []byte("hello"), vs[3].(*BlobValue).Bytes())
+	assert.Equal(t, []byte("clob"), vs[4].(*ClobValue).Bytes())
+
+	assert.Equal(t, NullType, vs[5].Type())
+	assert.True(t, vs[5].IsNull())
+	assert.Equal(t, IntType, vs[6].Type())
+	assert.True(t, vs[6].IsNull())
+	assert.Equal(t, NullInt, vs[6].(*IntValue).IntSize())
+}
+
+func TestValueRoundTrip(t *testing.T) {
+	ionText := `a::{x:1,y:[1.5,2e+0,null.float],x:"two",z:(c d::'e f' null.sexp),'':{{"clob"}}} ` +
+		`-12345678901234567890 2020-01-02T03:04:05.600Z {{aGVsbG8=}} null null.struct true`
+
+	vs, err := ReadValues(NewReaderString(ionText))
+	require.NoError(t, err)
+
+	buf := strings.Builder{}
+	w := NewTextWriterOpts(&buf, TextWriterQuietFinish)
+	for _, v := range vs {
+		require.NoError(t, v.MarshalIon(w))
+	}
+	require.NoError(t, w.Finish())
+
+	assert.Equal(t, ionText, strings.ReplaceAll(buf.String(), "\n", " "))
+
+	bin, err := MarshalBinary(vs[0])
+	require.NoError(t, err)
+
+	r := NewReaderBytes(bin)
+	require.True(t, r.Next())
+	v, err := ReadValue(r)
+	require.NoError(t, err)
+
+	text, err := MarshalText(v)
+	require.NoError(t, err)
+	assert.Equal(t, `a::{x:1,y:[1.5,2e+0,null.float],x:"two",z:(c d::'e f' null.sexp),'':{{"clob"}}}`, string(text))
+}
+
+func TestStructValue(t *testing.T) {
+	s := NewStruct()
+	s.Add("a", NewInt(1))
+	s.Add("b", NewInt(2))
+	s.Add("a", NewInt(3))
+	s.Add("c", NewInt(4))
+	s.Add("a", NewInt(5))
+
+	assert.Equal(t, 5, s.Len())
+	assert.Equal(t, int64(1), mustInt64(t, s.Get("a")))
+	assert.Nil(t, s.Get("d"))
+
+	s.Set("a", NewInt(6))
+	require.Equal(t, 3, s.Len())
+	assert.Equal(t, "a", *s.Fields()[0].Name.Text)
+	assert.Equal(t, int64(6), mustInt64(t, s.Fields()[0].Value))
+
+	s.Set("d", NewInt(7))
+	assert.Equal(t, "d", *s.Fields()[3].Name.Text)
+
+	assert.Equal(t, 1, s.Remove("b"))
+	assert.Equal(t, 0, s.Remove("b"))
+
+	text, err := MarshalText(s)
+	require.NoError(t, err)
+	assert.Equal(t, "{a:6,c:4,d:7}", string(text))
+}
+
+func TestNullValues(t *testing.T) {
+	for _, typ := range []Type{NullType, BoolType, IntType, FloatType, DecimalType, TimestampType,
+		SymbolType, StringType, ClobType, BlobType, ListType, SexpType, StructType} {
+		v := NewNullValue(typ)
+		assert.Equal(t, typ, v.Type())
+		assert.True(t, v.IsNull())
+
+		text, err := MarshalText(v)
+		require.NoError(t, err)
+		if typ == NullType {
+			assert.Equal(t, "null", string(text))
+		} else {
+			assert.Equal(t, textNulls[typ], string(text))
+		}
+	}
+
+	l := NewNullValue(ListType).(*ListValue)
+	l.Append(NewBool(true))
+	assert.False(t, l.IsNull())
+
+	i := NewNullValue(IntType).(*IntValue)
+	_, ok := i.Int64()
+	assert.False(t, ok)
+	assert.Nil(t, i.BigInt())
+	i.SetInt64(42)
+	assert.False(t, i.IsNull())
+}
+
+func mustInt64(t *testing.T, v Value) int64 {
+	i, ok := v.(*IntValue).Int64()
+	require.True(t, ok)
+	return i
+}
@@ -0,0 +1,196 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"fmt"
+)
+
+// A valueFrame tracks our position within one level of a Value tree.
+type valueFrame struct {
+	vals   []Value
+	fields []StructField
+	next   int
+}
+
+// len returns the number of values at this level.
+func (f *valueFrame) len() int {
+	if f.fields != nil {
+		return len(f.fields)
+	}
+	return len(f.vals)
+}
+
+// valueReader is a reader that reads from an in-memory tree of Values.
+type valueReader struct {
+	reader
+
+	frames []valueFrame
+	cur    Value
+}
+
+// NewValueReader creates a new Reader that reads the given in-memory values as
+// a stream of top-level values.
+func NewValueReader(vals ...Value) Reader {
+	return &valueReader{
+		reader: reader{
+			lst: V1SystemSymbolTable,
+		},
+		frames: []valueFrame{{vals: vals}},
+	}
+}
+
+// Next moves the reader to the next value.
+func (r *valueReader) Next() bool {
+	if r.eof || r.err != nil {
+		return false
+	}
+
+	r.clear()
+	r.cur = nil
+
+	f := &r.frames[len(r.frames)-1]
+	if f.next >= f.len() {
+		r.eof = true
+		return false
+	}
+
+	var v Value
+	if f.fields != nil {
+		field := f.fields[f.next]
+		name := field.Name
+		r.fieldName = &name
+		v = field.Value
+	} else {
+		v = f.vals[f.next]
+	}
+	f.next++
+
+	if v == nil {
+		r.err = &UsageError{"Reader.Next", "nil Value in tree"}
+		return false
+	}
+
+	r.cur = v
+	r.valueType = v.Type()
+	r.annotations = v.Annotations()
+	r.value = readerValueOf(v)
+
+	return true
+}
+
+// readerValueOf converts a Value to the representation the shared reader
+// accessors expect.
+func readerValueOf(v Value) interface{} {
+	if v.IsNull() {
+		return nil
+	}
+
+	switch v := v.(type) {
+	case *BoolValue:
+		return v.Bool()
+	case *IntValue:
+		if i, ok := v.Int64(); ok {
+			return i
+		}
+		return v.BigInt()
+	case *FloatValue:
+		return v.Float()
+	case *DecimalValue:
+		return v.Decimal()
+	case *TimestampValue:
+		return v.Timestamp()
+	case *SymbolValue:
+		sym := v.Symbol()
+		return &sym
+	case *StringValue:
+		return v.Text()
+	case *ClobValue:
+		return nonNilBytes(v.Bytes())
+	case *BlobValue:
+		return nonNilBytes(v.Bytes())
+	default:
+		// Containers are represented by their type, same as the binary reader.
+		return v.Type()
+	}
+}
+
+// nonNilBytes ensures a non-null empty lob isn't mistaken for a null one.
+func nonNilBytes(bs []byte) []byte {
+	if bs == nil {
+		return []byte{}
+	}
+	return bs
+}
+
+// StepIn steps in to the current container value.
+func (r *valueReader) StepIn() error {
+	if r.err != nil {
+		return r.err
+	}
+
+	f, err := valueFrameOf(r.cur, r.valueType)
+	if err != nil {
+		return err
+	}
+
+	r.ctx.push(containerTypeToCtx(r.valueType))
+	r.frames = append(r.frames, f)
+	r.clear()
+	r.cur = nil
+
+	return nil
+}
+
+// valueFrameOf creates a frame for stepping in to the given container value.
+func valueFrameOf(v Value, t Type) (valueFrame, error) {
+	var f valueFrame
+	switch v := v.(type) {
+	case *ListValue:
+		f.vals = v.Values()
+	case *SexpValue:
+		f.vals = v.Values()
+	case *StructValue:
+		f.fields = v.Fields()
+		if f.fields == nil {
+			f.fields = []StructField{}
+		}
+	default:
+		return f, &UsageError{"Reader.StepIn", fmt.Sprintf("cannot step in to a %v", t)}
+	}
+	if v.IsNull() {
+		return f, &UsageError{"Reader.StepIn", "cannot step in to a null container"}
+	}
+	return f, nil
+}
+
+// StepOut steps out of the current container value.
+func (r *valueReader) StepOut() error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.ctx.peek() == ctxAtTopLevel {
+		return &UsageError{"Reader.StepOut", "cannot step out of top-level datagram"}
+	}
+
+	r.frames = r.frames[:len(r.frames)-1]
+	r.clear()
+	r.cur = nil
+	r.ctx.pop()
+	r.eof = false
+
+	return nil
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValueReader(t *testing.T) {
+	s := NewStruct()
+	s.SetAnnotations(NewSymbolTokenFromString("a"))
+	s.Add("x", NewInt(1))
+	s.Add("y", NewList(NewString("foo"), NewNullValue(StringType)))
+	s.Add("x", NewSexp())
+
+	r := NewValueReader(s, NewBlob(nil), NewNull(), NewSymbol("bar"))
+
+	x := NewSymbolTokenFromString("x")
+	_structAF(t, r, nil, []SymbolToken{NewSymbolTokenFromString("a")}, func(t *testing.T, r Reader) {
+		assert.True(t, r.IsInStruct())
+		_intAF(t, r, &x, nil, 1)
+		_list(t, r, func(t *testing.T, r Reader) {
+			_string(t, r, newString("foo"))
+			_null(t, r, StringType)
+			_eof(t, r)
+		})
+		_sexp(t, r, func(t *testing.T, r Reader) {
+			_eof(t, r)
+		})
+		_eof(t, r)
+	})
+	_blob(t, r, []byte{})
+	_null(t, r, NullType)
+	_symbol(t, r, NewSymbolTokenFromString("bar"))
+	_eof(t, r)
+	_eof(t, r)
+}
+
+func TestValueReaderStepOutEarly(t *testing.T) {
+	r := NewValueReader(NewList(NewInt(1), NewInt(2)), NewBool(true))
+
+	_next(t, r, ListType)
+	require.NoError(t, r.StepIn())
+	_int(t, r, 1)
+	require.NoError(t, r.StepOut())
+	_bool(t, r, true)
+	_eof(t, r)
+
+	assert.Error(t, r.StepOut())
+}
+
+func TestValueReaderStepInErrors(t *testing.T) {
+	r := NewValueReader(NewInt(1), NewNullValue(ListType))
+
+	_next(t, r, IntType)
+	assert.Error(t, r.StepIn())
+
+	_null(t, r, ListType)
+	assert.Error(t, r.StepIn())
+}
+
+func TestValueReaderRoundTrip(t *testing.T) {
+	ionText := `a::{x:1,y:[1.5,2e0,null.float],z:(c d::'e f' null.sexp)} 123456789012345678901234567890 {{"clob"}}`
+
+	vs, err := ReadValues(NewReaderString(ionText))
+	require.NoError(t, err)
+
+	copied, err := ReadValues(NewValueReader(vs...))
+	require.NoError(t, err)
+
+	assert.Equal(t, vs, copied)
+}
@@ -0,0 +1,231 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"math/big"
+)
+
+// A ValueWriter is a Writer that builds an in-memory tree of Values rather than
+// serializing them.
+type ValueWriter interface {
+	Writer
+
+	// Values returns the top-level values written so far.
+	Values() []Value
+}
+
+// valueWriter is a writer that builds a tree of Values.
+type valueWriter struct {
+	writer
+
+	vals       []Value
+	containers []Value
+}
+
+// NewValueWriter creates a new ValueWriter.
+func NewValueWriter() ValueWriter {
+	return &valueWriter{}
+}
+
+// Values returns the top-level values written so far.
+func (w *valueWriter) Values() []Value {
+	return w.vals
+}
+
+// WriteNull writes an untyped null.
+func (w *valueWriter) WriteNull() error {
+	return w.writeValue("Writer.WriteNull", NewNull())
+}
+
+// WriteNullType writes a typed null.
+func (w *valueWriter) WriteNullType(t Type) error {
+	return w.writeValue("Writer.WriteNullType", NewNullValue(t))
+}
+
+// WriteBool writes a bool.
+func (w *valueWriter) WriteBool(val bool) error {
+	return w.writeValue("Writer.WriteBool", NewBool(val))
+}
+
+// WriteInt writes an integer.
+func (w *valueWriter) WriteInt(val int64) error {
+	return w.writeValue("Writer.WriteInt", NewInt(val))
+}
+
+// WriteUint writes an unsigned integer.
+func (w *valueWriter) WriteUint(val uint64) error {
+	return w.writeValue("Writer.WriteUint", NewBigInt(new(big.Int).SetUint64(val)))
+}
+
+// WriteBigInt writes a big integer.
+func (w *valueWriter) WriteBigInt(val *big.Int) error {
+	return w.writeValue("Writer.WriteBigInt", NewBigInt(val))
+}
+
+// WriteFloat writes a floating-point value.
+func (w *valueWriter) WriteFloat(val float64) error {
+	return w.writeValue("Writer.WriteFloat", NewFloat(val))
+}
+
+// WriteDecimal writes an arbitrary-precision decimal value.
+func (w *valueWriter) WriteDecimal(val *Decimal) error {
+	if val == nil {
+		return w.WriteNullType(DecimalType)
+	}
+	return w.writeValue("Writer.WriteDecimal", NewDecimalValue(val))
+}
+
+// WriteTimestamp writes a timestamp value.
+func (w *valueWriter) WriteTimestamp(val Timestamp) error {
+	return w.writeValue("Writer.WriteTimestamp", NewTimestampValue(val))
+}
+
+// WriteSymbol writes a symbol given a SymbolToken.
+func (w *valueWriter) WriteSymbol(val SymbolToken) error {
+	return w.writeValue("Writer.WriteSymbol", NewSymbolValue(val))
+}
+
+// WriteSymbolFromString writes a symbol value given a string.
+func (w *valueWriter) WriteSymbolFromString(val string) error {
+	return w.writeValue("Writer.WriteSymbolFromString", NewSymbol(val))
+}
+
+// WriteString writes a string.
+func (w *valueWriter) WriteString(val string) error {
+	return w.writeValue("Writer.WriteString", NewString(val))
+}
+
+// WriteClob writes a clob.
+func (w *valueWriter) WriteClob(val []byte) error {
+	if val == nil {
+		return w.WriteNullType(ClobType)
+	}
+	return w.writeValue("Writer.WriteClob", NewClob(append([]byte{}, val...)))
+}
+
+// WriteBlob writes a blob.
+func (w *valueWriter) WriteBlob(val []byte) error {
+	if val == nil {
+		return w.WriteNullType(BlobType)
+	}
+	return w.writeValue("Writer.WriteBlob", NewBlob(append([]byte{}, val...)))
+}
+
+// BeginList begins writing a list.
+func (w *valueWriter) BeginList() error {
+	return w.begin("Writer.BeginList", NewList())
+}
+
+// EndList finishes writing a list.
+func (w *valueWriter) EndList() error {
+	return w.end("Writer.EndList", ctxInList)
+}
+
+// BeginSexp begins writing an s-expression.
+func (w *valueWriter) BeginSexp() error {
+	return w.begin("Writer.BeginSexp", NewSexp())
+}
+
+// EndSexp finishes writing an s-expression.
+func (w *valueWriter) EndSexp() error {
+	return w.end("Writer.EndSexp", ctxInSexp)
+}
+
+// BeginStruct begins writing a struct.
+func (w *valueWriter) BeginStruct() error {
+	return w.begin("Writer.BeginStruct", NewStruct())
+}
+
+// EndStruct finishes writing a struct.
+func (w *valueWriter) EndStruct() error {
+	return w.end("Writer.EndStruct", ctxInStruct)
+}
+
+// Finish checks that all containers have been closed. The values written so far
+// remain available via Values.
+func (w *valueWriter) Finish() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.ctx.peek() != ctxAtTopLevel {
+		return &UsageError{"Writer.Finish", "not at top level"}
+	}
+
+	w.clear()
+	return nil
+}
+
+// writeValue attaches the pending field name and annotations to the given value
+// and adds it to the current container.
+func (w *valueWriter) writeValue(api string, v Value) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	name := w.fieldName
+	v.SetAnnotations(w.annotations...)
+	w.clear()
+
+	if len(w.containers) == 0 {
+		w.vals = append(w.vals, v)
+		return nil
+	}
+
+	switch c := w.containers[len(w.containers)-1].(type) {
+	case *StructValue:
+		if name == nil {
+			w.err = &UsageError{api, "field name not set"}
+			return w.err
+		}
+		c.AddField(*name, v)
+	case *ListValue:
+		c.Append(v)
+	case *SexpValue:
+		c.Append(v)
+	}
+
+	return nil
+}
+
+// begin starts building the given container value.
+func (w *valueWriter) begin(api string, v Value) error {
+	if err := w.writeValue(api, v); err != nil {
+		return err
+	}
+
+	w.ctx.push(containerTypeToCtx(v.Type()))
+	w.containers = append(w.containers, v)
+
+	return nil
+}
+
+// end finishes building a container of the given type.
+func (w *valueWriter) end(api string, t ctx) error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.ctx.peek() != t {
+		w.err = &UsageError{api, "not in that kind of container"}
+		return w.err
+	}
+
+	w.clear()
+	w.ctx.pop()
+	w.containers = w.containers[:len(w.containers)-1]
+
+	return nil
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValueWriter(t *testing.T) {
+	w := NewValueWriter()
+
+	require.NoError(t, w.Annotation(NewSymbolTokenFromString("a")))
+	require.NoError(t, w.BeginStruct())
+	require.NoError(t, w.FieldName(NewSymbolTokenFromString("x")))
+	require.NoError(t, w.WriteInt(1))
+	require.NoError(t, w.FieldName(NewSymbolTokenFromString("y")))
+	require.NoError(t, w.BeginList())
+	require.NoError(t, w.WriteString("foo"))
+	require.NoError(t, w.WriteNullType(StringType))
+	require.NoError(t, w.EndList())
+	require.NoError(t, w.FieldName(NewSymbolTokenFromString("x")))
+	require.NoError(t, w.BeginSexp())
+	require.NoError(t, w.EndSexp())
+	require.NoError(t, w.EndStruct())
+	require.NoError(t, w.WriteUint(1<<63))
+	require.NoError(t, w.WriteBlob([]byte{}))
+	require.NoError(t, w.Finish())
+
+	vs := w.Values()
+	require.Len(t, vs, 3)
+
+	s := vs[0].(*StructValue)
+	assert.Equal(t, []SymbolToken{NewSymbolTokenFromString("a")}, s.Annotations())
+	assert.Len(t, s.GetAll("x"), 2)
+	assert.Equal(t, 2, s.Get("y").(*ListValue).Len())
+	assert.Equal(t, "9223372036854775808", vs[1].(*IntValue).BigInt().String())
+	assert.Equal(t, []byte{}, vs[2].(*BlobValue).Bytes())
+	assert.False(t, vs[2].IsNull())
+}
+
+func TestValueWriterErrors(t *testing.T) {
+	w := NewValueWriter()
+	require.NoError(t, w.BeginStruct())
+	assert.Error(t, w.WriteInt(1))
+
+	w = NewValueWriter()
+	require.NoError(t, w.BeginList())
+	assert.Error(t, w.EndStruct())
+
+	w = NewValueWriter()
+	require.NoError(t, w.BeginList())
+	assert.Error(t, w.Finish())
+}
+
+func TestValueWriterRoundTrip(t *testing.T) {
+	ionText := `a::{x:1,y:[1.5,2e0,null.float],z:(c d::'e f' null.sexp)} 2020T {{"clob"}}`
+
+	r := NewReaderString(ionText)
+	w := NewValueWriter()
+	for r.Next() {
+		v, err := ReadValue(r)
+		require.NoError(t, err)
+		require.NoError(t, v.MarshalIon(w))
+	}
+	require.NoError(t, r.Err())
+	require.NoError(t, w.Finish())
+
+	expected, err := ReadValues(NewReaderString(ionText))
+	require.NoError(t, err)
+	assert.Equal(t, expected, w.Values())
+}
@@ -139,6 +139,43 @@ type Writer interface {
 	IsInStruct() bool
 }
 
+// A MacroWriter is a Writer that can also write Ion 1.1 e-expressions, which
+// invoke a macro with the values written between BeginEExpression and
+// EndEExpression as its arguments.
+//
+//	var w MacroWriter
+//	w.BeginEExpression(m)
+//	{
+//		w.WriteString("foo")
+//		w.BeginExpressionGroup()
+//		{
+//			w.WriteInt(1)
+//			w.WriteInt(2)
+//		}
+//		w.EndExpressionGroup()
+//	}
+//	w.EndEExpression()
+//
+// Each value (or nested e-expression) written inside an e-expression is passed
+// as the argument for the next parameter of the macro. An expression group
+// passes several values as a single argument to a parameter that accepts more
+// than one value.
+type MacroWriter interface {
+	Writer
+
+	// BeginEExpression begins writing an e-expression invoking the given macro.
+	BeginEExpression(m *Macro) error
+
+	// EndEExpression finishes writing an e-expression.
+	EndEExpression() error
+
+	// BeginExpressionGroup begins writing an expression group.
+	BeginExpressionGroup() error
+
+	// EndExpressionGroup finishes writing an expression group.
+	EndExpressionGroup() error
+}
+
 // A writer holds shared stuff for all writers.
 type writer struct {
 	out io.Writer
@@ -0,0 +1,110 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionschema
+
+import (
+	"fmt"
+
+	"github.com/amazon-ion/ion-go/ion"
+)
+
+// builtinTypes holds the types that are available to every schema.
+var builtinTypes = func() map[string]*Type {
+	types := map[string]*Type{}
+
+	add := func(name string, ts ...ion.Type) {
+		types[name] = &Type{name: name, constraints: []constraint{&builtinConstraint{name: name, types: ts}}}
+
+		dollar := "$" + name
+		types[dollar] = &Type{name: dollar, constraints: []constraint{&builtinConstraint{name: dollar, types: ts, nulls: true}}}
+	}
+
+	add("blob", ion.BlobType)
+	add("bool", ion.BoolType)
+	add("clob", ion.ClobType)
+	add("decimal", ion.DecimalType)
+	add("float", ion.FloatType)
+	add("int", ion.IntType)
+	add("list", ion.ListType)
+	add("sexp", ion.SexpType)
+	add("string", ion.StringType)
+	add("struct", ion.StructType)
+	add("symbol", ion.SymbolType)
+	add("timestamp", ion.TimestampType)
+	add("text", ion.StringType, ion.SymbolType)
+	add("lob", ion.ClobType, ion.BlobType)
+	add("number", ion.IntType, ion.FloatType, ion.DecimalType)
+	add("any")
+
+	types["$null"] = &Type{name: "$null", constraints: []constraint{&builtinConstraint{name: "$null", types: []ion.Type{ion.NullType}, nulls: true}}}
+	types["nothing"] = &Type{name: "nothing", constraints: []constraint{&builtinConstraint{name: "nothing", types: []ion.Type{}}}}
+
+	// $any accepts untyped nulls too.
+	types["$any"].constraints[0].(*builtinConstraint).types = nil
+
+	return types
+}()
+
+// A builtinConstraint checks the Ion type of a value for one of the built-in types.
+type builtinConstraint struct {
+	name string
+
+	// The Ion types accepted; nil means all types.
+	types []ion.Type
+
+	// Whether typed nulls of the accepted types are accepted.
+	nulls bool
+}
+
+func (c *builtinConstraint) validate(v ion.Value, path string) []Violation {
+	if v.IsNull() && !c.nulls {
+		return []Violation{{path, "type", fmt.Sprintf("expected type %v, found %v", c.name, typeName(v)), nil}}
+	}
+	if c.types == nil {
+		return nil
+	}
+	for _, t := range c.types {
+		if v.Type() == t {
+			return nil
+		}
+	}
+	return []Violation{{path, "type", fmt.Sprintf("expected type %v, found %v", c.name, typeName(v)), nil}}
+}
+
+// acceptsNullOf returns true if this type's base Ion types include the given one;
+// used by the ISL 1.0 nullable:: annotation.
+func (c *builtinConstraint) acceptsNullOf(t ion.Type) bool {
+	if c.types == nil || t == ion.NullType {
+		return true
+	}
+	for _, bt := range c.types {
+		if bt == t {
+			return true
+		}
+	}
+	return false
+}
+
+// typeName describes the type of a value for use in messages.
+func typeName(v ion.Value) string {
+	if v.IsNull() {
+		if v.Type() == ion.NullType {
+			return "null"
+		}
+		return "null." + v.Type().String()
+	}
+	return v.Type().String()
+}
@@ -0,0 +1,1080 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionschema
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/amazon-ion/ion-go/ion"
+)
+
+// A constraint is a single rule within a type definition.
+type constraint interface {
+	validate(v ion.Value, path string) []Violation
+}
+
+// A constraintParser parses the value of a constraint field in a type definition.
+type constraintParser func(p *parser, v ion.Value) (constraint, error)
+
+// constraintParsers maps constraint names to their parsers. It's populated in
+// init, since parsing constraints recursively parses type definitions.
+var constraintParsers map[string]constraintParser
+
+// v1Constraints and v2Constraints hold the constraints that only exist in one
+// version of ISL; in the other version they're treated as open content.
+var (
+	v1Constraints = map[string]bool{"scale": true}
+	v2Constraints = map[string]bool{"exponent": true, "utf8_byte_length": true, "ieee754_float": true, "field_names": true}
+)
+
+func init() {
+	constraintParsers = map[string]constraintParser{
+		"type":                parseTypeConstraint,
+		"all_of":              parseAllOf,
+		"any_of":              parseAnyOf,
+		"one_of":              parseOneOf,
+		"not":                 parseNot,
+		"fields":              parseFields,
+		"element":             parseElement,
+		"contains":            parseContains,
+		"valid_values":        parseValidValues,
+		"regex":               parseRegex,
+		"codepoint_length":    lengthParser("codepoint_length", codepointLength),
+		"container_length":    lengthParser("container_length", containerLength),
+		"byte_length":         lengthParser("byte_length", byteLength),
+		"utf8_byte_length":    lengthParser("utf8_byte_length", utf8ByteLength),
+		"precision":           parsePrecision,
+		"scale":               parseScale,
+		"exponent":            parseExponent,
+		"timestamp_precision": parseTimestampPrecision,
+		"timestamp_offset":    parseTimestampOffset,
+		"annotations":         parseAnnotations,
+		"ieee754_float":       parseIEEE754Float,
+		"ordered_elements":    parseOrderedElements,
+		"field_names":         parseFieldNames,
+	}
+}
+
+// violation is a shorthand for creating a single violation.
+func violation(path, constraint, format string, args ...interface{}) []Violation {
+	return []Violation{{Path: path, Constraint: constraint, Message: fmt.Sprintf(format, args...)}}
+}
+
+// mismatch returns a violation for a value of the wrong type for a constraint.
+func mismatch(v ion.Value, path, constraint, expected string) []Violation {
+	return violation(path, constraint, "expected %v, found %v", expected, typeName(v))
+}
+
+// typeConstraint implements the type constraint.
+type typeConstraint struct {
+	ref *typeRef
+}
+
+func parseTypeConstraint(p *parser, v ion.Value) (constraint, error) {
+	ref, err := p.parseTypeRef(v, false)
+	if err != nil {
+		return nil, err
+	}
+	return &typeConstraint{ref}, nil
+}
+
+func (c *typeConstraint) validate(v ion.Value, path string) []Violation {
+	return refViolation(c.ref, path, c.ref.validate(v, path))
+}
+
+// refViolation wraps the violations of a value validated against a referenced type.
+// Built-in types report their own type violations, so those aren't wrapped.
+func refViolation(ref *typeRef, path string, vs []Violation) []Violation {
+	if len(vs) == 0 || ref.resolved.builtin() != nil {
+		return vs
+	}
+	return []Violation{{path, "type", fmt.Sprintf("value does not match type %v", ref), vs}}
+}
+
+// refViolations validates a value against each of the given types, returning a
+// violation for each type it doesn't match.
+func refViolations(refs []*typeRef, v ion.Value, path string) (int, []Violation) {
+	var children []Violation
+	matched := 0
+	for _, ref := range refs {
+		if vs := ref.validate(v, path); len(vs) > 0 {
+			children = append(children, refViolation(ref, path, vs)...)
+		} else {
+			matched++
+		}
+	}
+	return matched, children
+}
+
+// allOfConstraint implements the all_of constraint.
+type allOfConstraint struct {
+	refs []*typeRef
+}
+
+func parseAllOf(p *parser, v ion.Value) (constraint, error) {
+	refs, err := p.parseTypeRefs(v, "all_of")
+	if err != nil {
+		return nil, err
+	}
+	return &allOfConstraint{refs}, nil
+}
+
+func (c *allOfConstraint) validate(v ion.Value, path string) []Violation {
+	matched, children := refViolations(c.refs, v, path)
+	if matched == len(c.refs) {
+		return nil
+	}
+	return []Violation{{path, "all_of", fmt.Sprintf("value matches %v of %v types", matched, len(c.refs)), children}}
+}
+
+// anyOfConstraint implements the any_of constraint.
+type anyOfConstraint struct {
+	refs []*typeRef
+}
+
+func parseAnyOf(p *parser, v ion.Value) (constraint, error) {
+	refs, err := p.parseTypeRefs(v, "any_of")
+	if err != nil {
+		return nil, err
+	}
+	return &anyOfConstraint{refs}, nil
+}
+
+func (c *anyOfConstraint) validate(v ion.Value, path string) []Violation {
+	for _, ref := range c.refs {
+		if ref.matches(v) {
+			return nil
+		}
+	}
+	_, children := refViolations(c.refs, v, path)
+	return []Violation{{path, "any_of", "value matches none of the types", children}}
+}
+
+// oneOfConstraint implements the one_of constraint.
+type oneOfConstraint struct {
+	refs []*typeRef
+}
+
+func parseOneOf(p *parser, v ion.Value) (constraint, error) {
+	refs, err := p.parseTypeRefs(v, "one_of")
+	if err != nil {
+		return nil, err
+	}
+	return &oneOfConstraint{refs}, nil
+}
+
+func (c *oneOfConstraint) validate(v ion.Value, path string) []Violation {
+	matched, children := refViolations(c.refs, v, path)
+	switch matched {
+	case 1:
+		return nil
+	case 0:
+		return []Violation{{path, "one_of", "value matches none of the types", children}}
+	default:
+		return violation(path, "one_of", "value matches %v types, expected exactly one", matched)
+	}
+}
+
+// notConstraint implements the not constraint.
+type notConstraint struct {
+	ref *typeRef
+}
+
+func parseNot(p *parser, v ion.Value) (constraint, error) {
+	ref, err := p.parseTypeRef(v, false)
+	if err != nil {
+		return nil, err
+	}
+	return &notConstraint{ref}, nil
+}
+
+func (c *notConstraint) validate(v ion.Value, path string) []Violation {
+	if c.ref.matches(v) {
+		return violation(path, "not", "value unexpectedly matches type %v", c.ref)
+	}
+	return nil
+}
+
+// A fieldDef is a single field declared by a fields constraint.
+type fieldDef struct {
+	name string
+	ref  *typeRef
+}
+
+// fieldsConstraint implements the fields constraint (and ISL 1.0's content: closed).
+type fieldsConstraint struct {
+	fields []fieldDef
+	closed bool
+}
+
+func parseFields(p *parser, v ion.Value) (constraint, error) {
+	s, ok := v.(*ion.StructValue)
+	if !ok || s.IsNull() {
+		return nil, p.schema.errorf("fields must be a struct")
+	}
+
+	c := &fieldsConstraint{}
+	for _, a := range v.Annotations() {
+		if p.schema.v2() && a.Text != nil && *a.Text == "closed" {
+			c.closed = true
+		} else {
+			return nil, p.schema.errorf("unexpected annotation on fields")
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, f := range s.Fields() {
+		if f.Name.Text == nil {
+			return nil, p.schema.errorf("field name has unknown text")
+		}
+		name := *f.Name.Text
+		if seen[name] && p.schema.v2() {
+			return nil, p.schema.errorf("duplicate field %v", name)
+		}
+		seen[name] = true
+
+		ref, err := p.parseTypeRef(f.Value, true)
+		if err != nil {
+			return nil, err
+		}
+		c.fields = append(c.fields, fieldDef{name, ref})
+	}
+
+	if len(c.fields) == 0 && p.schema.v2() {
+		return nil, p.schema.errorf("fields must not be empty")
+	}
+	return c, nil
+}
+
+func (c *fieldsConstraint) validate(v ion.Value, path string) []Violation {
+	s, ok := v.(*ion.StructValue)
+	if !ok || s.IsNull() {
+		return mismatch(v, path, "fields", "struct")
+	}
+
+	var children []Violation
+	declared := map[string]bool{}
+
+	for _, fd := range c.fields {
+		declared[fd.name] = true
+
+		occurs := exactRange(0, 1)
+		if fd.ref.inline != nil && fd.ref.inline.occurs != nil {
+			occurs = fd.ref.inline.occurs
+		}
+
+		count := 0
+		for _, f := range s.Fields() {
+			if f.Name.Text == nil || *f.Name.Text != fd.name {
+				continue
+			}
+			count++
+			fp := fieldPath(path, f.Name)
+			children = append(children, refViolation(fd.ref, fp, fd.ref.validate(f.Value, fp))...)
+		}
+
+		if !occurs.containsInt(count) {
+			name := ion.NewSymbolTokenFromString(fd.name)
+			children = append(children, Violation{Path: fieldPath(path, name), Constraint: "occurs",
+				Message: fmt.Sprintf("expected %v occurrences, found %v", occurs, count)})
+		}
+	}
+
+	if c.closed {
+		for _, f := range s.Fields() {
+			if f.Name.Text == nil || !declared[*f.Name.Text] {
+				children = append(children, Violation{Path: fieldPath(path, f.Name), Constraint: "fields",
+					Message: "unexpected field in closed struct"})
+			}
+		}
+	}
+
+	if len(children) == 0 {
+		return nil
+	}
+	return []Violation{{path, "fields", "one or more fields don't match expectations", children}}
+}
+
+// A child is a value within a container, along with its path.
+type child struct {
+	path  string
+	value ion.Value
+}
+
+// children returns the children of a non-null container, or false if the value is
+// not a container.
+func children(v ion.Value, path string) ([]child, bool) {
+	if v.IsNull() {
+		return nil, false
+	}
+
+	switch v := v.(type) {
+	case *ion.ListValue:
+		return indexedChildren(v.Values(), path), true
+	case *ion.SexpValue:
+		return indexedChildren(v.Values(), path), true
+	case *ion.StructValue:
+		cs := make([]child, 0, v.Len())
+		for _, f := range v.Fields() {
+			cs = append(cs, child{fieldPath(path, f.Name), f.Value})
+		}
+		return cs, true
+	}
+	return nil, false
+}
+
+// indexedChildren returns the children of a list or sexp.
+func indexedChildren(vs []ion.Value, path string) []child {
+	cs := make([]child, len(vs))
+	for i, v := range vs {
+		cs[i] = child{indexPath(path, i), v}
+	}
+	return cs
+}
+
+// elementConstraint implements the element constraint.
+type elementConstraint struct {
+	ref      *typeRef
+	distinct bool
+}
+
+func parseElement(p *parser, v ion.Value) (constraint, error) {
+	ref, err := p.parseTypeRef(v, false)
+	if err != nil {
+		return nil, err
+	}
+	return &elementConstraint{ref: ref, distinct: p.schema.v2() && hasAnnotation(v, "distinct")}, nil
+}
+
+func (c *elementConstraint) validate(v ion.Value, path string) []Violation {
+	cs, ok := children(v, path)
+	if !ok {
+		return mismatch(v, path, "element", "list, sexp, or struct")
+	}
+
+	var violations []Violation
+	for i, ch := range cs {
+		violations = append(violations, refViolation(c.ref, ch.path, c.ref.validate(ch.value, ch.path))...)
+		if c.distinct {
+			for _, prev := range cs[:i] {
+				if ion.EquivalentValues(prev.value, ch.value) {
+					violations = append(violations, Violation{Path: ch.path, Constraint: "element",
+						Message: fmt.Sprintf("value is a duplicate of %v", prev.path)})
+					break
+				}
+			}
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+	return []Violation{{path, "element", "one or more elements don't match expectations", violations}}
+}
+
+// containsConstraint implements the contains constraint.
+type containsConstraint struct {
+	values []ion.Value
+}
+
+func parseContains(p *parser, v ion.Value) (constraint, error) {
+	l, ok := v.(*ion.ListValue)
+	if !ok || l.IsNull() || len(v.Annotations()) > 0 {
+		return nil, p.schema.errorf("contains must be a list")
+	}
+	return &containsConstraint{l.Values()}, nil
+}
+
+func (c *containsConstraint) validate(v ion.Value, path string) []Violation {
+	cs, ok := children(v, path)
+	if !ok {
+		return mismatch(v, path, "contains", "list, sexp, or struct")
+	}
+
+	var missing []string
+	for _, expected := range c.values {
+		found := false
+		for _, ch := range cs {
+			if ion.EquivalentValues(expected, ch.value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, valueString(expected))
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+	return violation(path, "contains", "missing value(s): %v", strings.Join(missing, ", "))
+}
+
+// validValuesConstraint implements the valid_values constraint.
+type validValuesConstraint struct {
+	values []ion.Value
+	ranges []*valueRange
+}
+
+func parseValidValues(p *parser, v ion.Value) (constraint, error) {
+	c := &validValuesConstraint{}
+
+	if hasAnnotation(v, "range") {
+		r, err := parseValueRange(v)
+		if err != nil {
+			return nil, p.schema.errorf("invalid valid_values: %v", err)
+		}
+		c.ranges = append(c.ranges, r)
+		return c, nil
+	}
+
+	l, ok := v.(*ion.ListValue)
+	if !ok || l.IsNull() || len(v.Annotations()) > 0 {
+		return nil, p.schema.errorf("valid_values must be a list")
+	}
+
+	for _, vv := range l.Values() {
+		if hasAnnotation(vv, "range") {
+			r, err := parseValueRange(vv)
+			if err != nil {
+				return nil, p.schema.errorf("invalid valid_values: %v", err)
+			}
+			c.ranges = append(c.ranges, r)
+			continue
+		}
+		if len(vv.Annotations()) > 0 {
+			return nil, p.schema.errorf("valid_values must not be annotated: %v", valueString(vv))
+		}
+		c.values = append(c.values, vv)
+	}
+
+	return c, nil
+}
+
+// parseValueRange parses a range of numbers or timestamps.
+func parseValueRange(v ion.Value) (*valueRange, error) {
+	if l, ok := v.(*ion.ListValue); ok && !l.IsNull() {
+		for _, b := range l.Values() {
+			if b.Type() == ion.TimestampType {
+				return parseRange(v, timestampRange)
+			}
+		}
+	}
+	return parseRange(v, numberRange)
+}
+
+func (c *validValuesConstraint) validate(v ion.Value, path string) []Violation {
+	for _, r := range c.ranges {
+		if rangeContainsValue(r, v) {
+			return nil
+		}
+	}
+
+	bare := withoutAnnotations(v)
+	for _, vv := range c.values {
+		if ion.EquivalentValues(vv, bare) {
+			return nil
+		}
+	}
+
+	return violation(path, "valid_values", "%v is not a valid value", valueString(bare))
+}
+
+// rangeContainsValue returns true if the given number or timestamp range contains
+// the given value.
+func rangeContainsValue(r *valueRange, v ion.Value) bool {
+	if v.IsNull() {
+		return false
+	}
+
+	if r.kind == timestampRange {
+		ts, ok := v.(*ion.TimestampValue)
+		return ok && r.contains(timestampRat(ts.Timestamp()))
+	}
+
+	x, inf := numberRat(v)
+	switch {
+	case inf > 0:
+		return r.max == nil
+	case inf < 0:
+		return r.min == nil
+	case x == nil:
+		return false
+	}
+	return r.contains(x)
+}
+
+// regexConstraint implements the regex constraint.
+type regexConstraint struct {
+	re *regexp.Regexp
+}
+
+func parseRegex(p *parser, v ion.Value) (constraint, error) {
+	s, ok := v.(*ion.StringValue)
+	if !ok || s.IsNull() {
+		return nil, p.schema.errorf("regex must be a string")
+	}
+
+	flags := ""
+	for _, a := range v.Annotations() {
+		switch {
+		case a.Text != nil && *a.Text == "i":
+			flags += "i"
+		case a.Text != nil && *a.Text == "m":
+			flags += "m"
+		default:
+			return nil, p.schema.errorf("unexpected regex flag %v", a.String())
+		}
+	}
+
+	pattern := s.Text()
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, p.schema.errorf("invalid regex %q: %v", s.Text(), err)
+	}
+	return &regexConstraint{re}, nil
+}
+
+func (c *regexConstraint) validate(v ion.Value, path string) []Violation {
+	text, ok := textOf(v)
+	if !ok {
+		return mismatch(v, path, "regex", "string or symbol")
+	}
+	if !c.re.MatchString(text) {
+		return violation(path, "regex", "%q does not match regex %v", text, c.re)
+	}
+	return nil
+}
+
+// lengthConstraint implements the various *_length constraints.
+type lengthConstraint struct {
+	name     string
+	r        *valueRange
+	measure  func(v ion.Value) (int, bool)
+	expected string
+}
+
+// lengthParser returns a parser for a length constraint using the given measure.
+func lengthParser(name string, measure lengthMeasure) constraintParser {
+	return func(p *parser, v ion.Value) (constraint, error) {
+		r, err := parseRange(v, intRange)
+		if err != nil {
+			return nil, p.schema.errorf("invalid %v: %v", name, err)
+		}
+		if r.min != nil && r.min.Sign() < 0 {
+			return nil, p.schema.errorf("%v must not be negative", name)
+		}
+		return &lengthConstraint{name, r, measure.fn, measure.expected}, nil
+	}
+}
+
+// A lengthMeasure measures the length of a value for a length constraint.
+type lengthMeasure struct {
+	fn       func(v ion.Value) (int, bool)
+	expected string
+}
+
+var (
+	codepointLength = lengthMeasure{func(v ion.Value) (int, bool) {
+		text, ok := textOf(v)
+		return utf8.RuneCountInString(text), ok
+	}, "string or symbol"}
+
+	utf8ByteLength = lengthMeasure{func(v ion.Value) (int, bool) {
+		text, ok := textOf(v)
+		return len(text), ok
+	}, "string or symbol"}
+
+	containerLength = lengthMeasure{func(v ion.Value) (int, bool) {
+		cs, ok := children(v, "")
+		return len(cs), ok
+	}, "list, sexp, or struct"}
+
+	byteLength = lengthMeasure{func(v ion.Value) (int, bool) {
+		if v.IsNull() {
+			return 0, false
+		}
+		switch v := v.(type) {
+		case *ion.BlobValue:
+			return len(v.Bytes()), true
+		case *ion.ClobValue:
+			return len(v.Bytes()), true
+		}
+		return 0, false
+	}, "blob or clob"}
+)
+
+func (c *lengthConstraint) validate(v ion.Value, path string) []Violation {
+	n, ok := c.measure(v)
+	if !ok {
+		return mismatch(v, path, c.name, c.expected)
+	}
+	if !c.r.containsInt(n) {
+		return violation(path, c.name, "expected %v, found %v", c.r, n)
+	}
+	return nil
+}
+
+// decimalConstraint implements the precision, scale, and exponent constraints.
+type decimalConstraint struct {
+	name    string
+	r       *valueRange
+	measure func(d *ion.Decimal) int
+}
+
+func parsePrecision(p *parser, v ion.Value) (constraint, error) {
+	c, err := parseDecimalConstraint(p, v, "precision", decimalPrecision)
+	if err == nil && c.r.min != nil && c.r.min.Sign() <= 0 {
+		return nil, p.schema.errorf("precision must be positive")
+	}
+	return c, err
+}
+
+func parseScale(p *parser, v ion.Value) (constraint, error) {
+	return parseDecimalConstraint(p, v, "scale", func(d *ion.Decimal) int {
+		_, exp := d.CoEx()
+		return -int(exp)
+	})
+}
+
+func parseExponent(p *parser, v ion.Value) (constraint, error) {
+	return parseDecimalConstraint(p, v, "exponent", func(d *ion.Decimal) int {
+		_, exp := d.CoEx()
+		return int(exp)
+	})
+}
+
+func parseDecimalConstraint(p *parser, v ion.Value, name string, measure func(d *ion.Decimal) int) (*decimalConstraint, error) {
+	r, err := parseRange(v, intRange)
+	if err != nil {
+		return nil, p.schema.errorf("invalid %v: %v", name, err)
+	}
+	return &decimalConstraint{name, r, measure}, nil
+}
+
+// decimalPrecision returns the number of digits in a decimal's coefficient.
+func decimalPrecision(d *ion.Decimal) int {
+	coef, _ := d.CoEx()
+	if coef.Sign() == 0 {
+		return 1
+	}
+	return len(new(big.Int).Abs(coef).String())
+}
+
+func (c *decimalConstraint) validate(v ion.Value, path string) []Violation {
+	d, ok := v.(*ion.DecimalValue)
+	if !ok || v.IsNull() {
+		return mismatch(v, path, c.name, "decimal")
+	}
+	if n := c.measure(d.Decimal()); !c.r.containsInt(n) {
+		return violation(path, c.name, "expected %v, found %v", c.r, n)
+	}
+	return nil
+}
+
+// timestampPrecisionConstraint implements the timestamp_precision constraint.
+type timestampPrecisionConstraint struct {
+	r *valueRange
+}
+
+func parseTimestampPrecision(p *parser, v ion.Value) (constraint, error) {
+	r, err := parseRange(v, precisionRange)
+	if err != nil {
+		return nil, p.schema.errorf("invalid timestamp_precision: %v", err)
+	}
+	return &timestampPrecisionConstraint{r}, nil
+}
+
+func (c *timestampPrecisionConstraint) validate(v ion.Value, path string) []Violation {
+	ts, ok := v.(*ion.TimestampValue)
+	if !ok || v.IsNull() {
+		return mismatch(v, path, "timestamp_precision", "timestamp")
+	}
+	if p := timestampPrecision(ts.Timestamp()); !c.r.containsInt(p) {
+		return violation(path, "timestamp_precision", "expected %v, found %v", c.r, precisionName(p))
+	}
+	return nil
+}
+
+// precisionName describes a timestamp precision.
+func precisionName(p int) string {
+	for name, np := range timestampPrecisions {
+		if np == p {
+			return name
+		}
+	}
+	return fmt.Sprintf("%v fractional digits", p)
+}
+
+// timestampOffsetConstraint implements the timestamp_offset constraint.
+type timestampOffsetConstraint struct {
+	offsets []string
+}
+
+var offsetPattern = regexp.MustCompile(`^[+-]([01][0-9]|2[0-3]):[0-5][0-9]$`)
+
+func parseTimestampOffset(p *parser, v ion.Value) (constraint, error) {
+	l, ok := v.(*ion.ListValue)
+	if !ok || l.IsNull() || l.Len() == 0 || len(v.Annotations()) > 0 {
+		return nil, p.schema.errorf("timestamp_offset must be a non-empty list")
+	}
+
+	c := &timestampOffsetConstraint{}
+	for _, ov := range l.Values() {
+		s, ok := ov.(*ion.StringValue)
+		if !ok || ov.IsNull() || !offsetPattern.MatchString(s.Text()) {
+			return nil, p.schema.errorf("invalid timestamp offset %v", valueString(ov))
+		}
+		c.offsets = append(c.offsets, s.Text())
+	}
+	return c, nil
+}
+
+func (c *timestampOffsetConstraint) validate(v ion.Value, path string) []Violation {
+	ts, ok := v.(*ion.TimestampValue)
+	if !ok || v.IsNull() {
+		return mismatch(v, path, "timestamp_offset", "timestamp")
+	}
+
+	offset := timestampOffset(ts.Timestamp())
+	for _, o := range c.offsets {
+		if o == offset {
+			return nil
+		}
+	}
+	return violation(path, "timestamp_offset", "unexpected offset %v", offset)
+}
+
+// timestampOffset formats the offset of a timestamp.
+func timestampOffset(ts ion.Timestamp) string {
+	if ts.GetTimezoneKind() == ion.TimezoneUnspecified {
+		return "-00:00"
+	}
+
+	_, secs := ts.GetDateTime().Zone()
+	sign := '+'
+	if secs < 0 {
+		sign = '-'
+		secs = -secs
+	}
+	return fmt.Sprintf("%c%02d:%02d", sign, secs/3600, secs/60%60)
+}
+
+// An annotationDef is a single annotation listed in an annotations constraint.
+type annotationDef struct {
+	text     string
+	required bool
+}
+
+// annotationsConstraint implements the annotations constraint.
+type annotationsConstraint struct {
+	defs    []annotationDef
+	closed  bool
+	ordered bool
+
+	// ISL 2.0 allows the annotations to be validated against a type instead.
+	ref *typeRef
+}
+
+func parseAnnotations(p *parser, v ion.Value) (constraint, error) {
+	l, ok := v.(*ion.ListValue)
+	if p.schema.v2() && (!ok || len(v.Annotations()) == 0) {
+		ref, err := p.parseTypeRef(v, false)
+		if err != nil {
+			return nil, err
+		}
+		return &annotationsConstraint{ref: ref}, nil
+	}
+	if !ok || l.IsNull() {
+		return nil, p.schema.errorf("annotations must be a list")
+	}
+
+	c := &annotationsConstraint{}
+	allRequired := false
+	for _, a := range v.Annotations() {
+		switch {
+		case a.Text != nil && *a.Text == "closed":
+			c.closed = true
+		case a.Text != nil && *a.Text == "required":
+			allRequired = true
+		case a.Text != nil && *a.Text == "ordered" && !p.schema.v2():
+			c.ordered = true
+		default:
+			return nil, p.schema.errorf("unexpected annotation %v on annotations", a.String())
+		}
+	}
+
+	for _, av := range l.Values() {
+		text, ok := symbolOf(av)
+		if !ok {
+			return nil, p.schema.errorf("annotations must be symbols")
+		}
+
+		def := annotationDef{text, allRequired}
+		if !p.schema.v2() {
+			switch {
+			case hasAnnotation(av, "required"):
+				def.required = true
+			case hasAnnotation(av, "optional"):
+				def.required = false
+			}
+		}
+		c.defs = append(c.defs, def)
+	}
+
+	return c, nil
+}
+
+func (c *annotationsConstraint) validate(v ion.Value, path string) []Violation {
+	as := v.Annotations()
+
+	if c.ref != nil {
+		l := ion.NewList()
+		for _, a := range as {
+			l.Append(ion.NewSymbolValue(a))
+		}
+		if vs := c.ref.validate(l, path); len(vs) > 0 {
+			return []Violation{{path, "annotations", fmt.Sprintf("annotations don't match type %v", c.ref), vs}}
+		}
+		return nil
+	}
+
+	var problems []string
+	index := map[string]int{}
+	for i, d := range c.defs {
+		index[d.text] = i
+		if d.required && !hasAnnotation(v, d.text) {
+			problems = append(problems, fmt.Sprintf("missing required annotation %v", d.text))
+		}
+	}
+
+	last := -1
+	for _, a := range as {
+		i, ok := -1, false
+		if a.Text != nil {
+			i, ok = index[*a.Text]
+		}
+		if !ok {
+			if c.closed {
+				problems = append(problems, fmt.Sprintf("unexpected annotation %v", annotationText(a)))
+			}
+			continue
+		}
+		if c.ordered && i < last {
+			problems = append(problems, fmt.Sprintf("annotation %v is out of order", annotationText(a)))
+		}
+		last = i
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return violation(path, "annotations", "%v", strings.Join(problems, "; "))
+}
+
+// annotationText describes an annotation in a message.
+func annotationText(a ion.SymbolToken) string {
+	if a.Text == nil {
+		return fmt.Sprintf("$%v", a.LocalSID)
+	}
+	return *a.Text
+}
+
+// ieee754FloatConstraint implements the ieee754_float constraint.
+type ieee754FloatConstraint struct {
+	format string
+}
+
+func parseIEEE754Float(p *parser, v ion.Value) (constraint, error) {
+	format, ok := symbolOf(v)
+	if !ok || (format != "binary16" && format != "binary32" && format != "binary64") {
+		return nil, p.schema.errorf("ieee754_float must be one of binary16, binary32, or binary64")
+	}
+	return &ieee754FloatConstraint{format}, nil
+}
+
+func (c *ieee754FloatConstraint) validate(v ion.Value, path string) []Violation {
+	fv, ok := v.(*ion.FloatValue)
+	if !ok || v.IsNull() {
+		return mismatch(v, path, "ieee754_float", "float")
+	}
+
+	f := fv.Float()
+	if math.IsNaN(f) || math.IsInf(f, 0) || f == 0 {
+		return nil
+	}
+
+	var exact bool
+	switch c.format {
+	case "binary16":
+		exact = isBinary16(f)
+	case "binary32":
+		exact = float64(float32(f)) == f
+	default:
+		exact = true
+	}
+
+	if !exact {
+		return violation(path, "ieee754_float", "%v cannot be represented exactly as %v", f, c.format)
+	}
+	return nil
+}
+
+// isBinary16 returns true if the given finite, non-zero float can be represented
+// exactly as an IEEE-754 half-precision float.
+func isBinary16(f float64) bool {
+	f = math.Abs(f)
+	if f > 65504 {
+		return false
+	}
+
+	_, exp := math.Frexp(f)
+	if exp-1 < -14 {
+		// Subnormal: a multiple of 2^-24.
+		scaled := math.Ldexp(f, 24)
+		return scaled == math.Trunc(scaled)
+	}
+
+	// Normal: 11 significant bits.
+	scaled := math.Ldexp(f, 11-exp)
+	return scaled == math.Trunc(scaled)
+}
+
+// orderedElementsConstraint implements the ordered_elements constraint.
+type orderedElementsConstraint struct {
+	refs []*typeRef
+}
+
+func parseOrderedElements(p *parser, v ion.Value) (constraint, error) {
+	l, ok := v.(*ion.ListValue)
+	if !ok || l.IsNull() || len(v.Annotations()) > 0 {
+		return nil, p.schema.errorf("ordered_elements must be a list")
+	}
+
+	c := &orderedElementsConstraint{}
+	for _, tv := range l.Values() {
+		ref, err := p.parseTypeRef(tv, true)
+		if err != nil {
+			return nil, err
+		}
+		c.refs = append(c.refs, ref)
+	}
+	return c, nil
+}
+
+func (c *orderedElementsConstraint) validate(v ion.Value, path string) []Violation {
+	var vs []ion.Value
+	switch sv := v.(type) {
+	case *ion.ListValue:
+		vs = sv.Values()
+	case *ion.SexpValue:
+		vs = sv.Values()
+	}
+	if vs == nil && (v.IsNull() || (v.Type() != ion.ListType && v.Type() != ion.SexpType)) {
+		return mismatch(v, path, "ordered_elements", "list or sexp")
+	}
+
+	// memo[i][j] records whether elements i.. can be matched by refs j..; it's
+	// filled in lazily since matching backtracks over the occurs ranges.
+	memo := map[[2]int]bool{}
+
+	var match func(i, j int) bool
+	match = func(i, j int) bool {
+		if j == len(c.refs) {
+			return i == len(vs)
+		}
+
+		key := [2]int{i, j}
+		if res, ok := memo[key]; ok {
+			return res
+		}
+
+		ref := c.refs[j]
+		occurs := exactRange(1, 1)
+		if ref.inline != nil && ref.inline.occurs != nil {
+			occurs = ref.inline.occurs
+		}
+
+		res := false
+		max := occurs.maxInt()
+		for n := 0; n <= max && i+n <= len(vs); n++ {
+			if n > 0 && !ref.matches(vs[i+n-1]) {
+				break
+			}
+			if n >= occurs.minInt() && match(i+n, j+1) {
+				res = true
+				break
+			}
+		}
+
+		memo[key] = res
+		return res
+	}
+
+	if !match(0, 0) {
+		return violation(path, "ordered_elements", "elements don't match the expected sequence of types")
+	}
+	return nil
+}
+
+// fieldNamesConstraint implements the field_names constraint.
+type fieldNamesConstraint struct {
+	ref      *typeRef
+	distinct bool
+}
+
+func parseFieldNames(p *parser, v ion.Value) (constraint, error) {
+	ref, err := p.parseTypeRef(v, false)
+	if err != nil {
+		return nil, err
+	}
+	return &fieldNamesConstraint{ref: ref, distinct: hasAnnotation(v, "distinct")}, nil
+}
+
+func (c *fieldNamesConstraint) validate(v ion.Value, path string) []Violation {
+	s, ok := v.(*ion.StructValue)
+	if !ok || v.IsNull() {
+		return mismatch(v, path, "field_names", "struct")
+	}
+
+	var violations []Violation
+	seen := map[string]bool{}
+	for _, f := range s.Fields() {
+		fp := fieldPath(path, f.Name)
+		violations = append(violations, refViolation(c.ref, fp, c.ref.validate(ion.NewSymbolValue(f.Name), fp))...)
+		if c.distinct && f.Name.Text != nil {
+			if seen[*f.Name.Text] {
+				violations = append(violations, Violation{Path: fp, Constraint: "field_names", Message: "duplicate field name"})
+			}
+			seen[*f.Name.Text] = true
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+	return []Violation{{path, "field_names", "one or more field names don't match expectations", violations}}
+}
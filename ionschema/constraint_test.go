@@ -0,0 +1,182 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionschema
+
+import (
+	"testing"
+)
+
+func TestLogicConstraints(t *testing.T) {
+	doc := `
+		$ion_schema_2_0
+		type::{name: all, all_of: [int, {valid_values: range::[0, 10]}]}
+		type::{name: some, any_of: [int, string]}
+		type::{name: one, one_of: [int, {valid_values: [1, 2]}]}
+		type::{name: not, not: int}
+	`
+	testValid(t, loadType(t, doc, "all"), "0", "10")
+	testInvalid(t, loadType(t, doc, "all"), "11", "1.0")
+	testValid(t, loadType(t, doc, "some"), "1", `"a"`)
+	testInvalid(t, loadType(t, doc, "some"), "a")
+	testValid(t, loadType(t, doc, "one"), "3")
+	testInvalid(t, loadType(t, doc, "one"), "1", "a", "1.0")
+	testValid(t, loadType(t, doc, "not"), "a", "null")
+	testInvalid(t, loadType(t, doc, "not"), "1")
+}
+
+func TestFieldsConstraint(t *testing.T) {
+	typ := loadType(t, `
+		type::{name: t, fields: {a: {type: int, occurs: required}, b: {type: string, occurs: range::[0, 2]}, c: int}}
+	`, "t")
+	testValid(t, typ, "{a: 1}", `{a: 1, b: "x", b: "y"}`, "{a: 1, c: 2, d: 3}")
+	testInvalid(t, typ, "{}", `{a: 1, b: "x", b: "y", b: "z"}`, "{a: 1, c: 1, c: 2}", "{a: x}", "[]", "null.struct")
+
+	typ = loadType(t, `type::{name: t, content: closed, fields: {a: int}}`, "t")
+	testValid(t, typ, "{a: 1}", "{}")
+	testInvalid(t, typ, "{a: 1, b: 2}")
+
+	typ = loadType(t, `$ion_schema_2_0 type::{name: t, fields: closed::{a: int}}`, "t")
+	testValid(t, typ, "{a: 1}")
+	testInvalid(t, typ, "{b: 2}")
+}
+
+func TestElementConstraint(t *testing.T) {
+	typ := loadType(t, `type::{name: t, element: int}`, "t")
+	testValid(t, typ, "[]", "[1, 2]", "(1 2)", "{a: 1}")
+	testInvalid(t, typ, "[1, a]", "{a: b}", "1", "null.list")
+
+	typ = loadType(t, `$ion_schema_2_0 type::{name: t, element: distinct::int}`, "t")
+	testValid(t, typ, "[1, 2]")
+	testInvalid(t, typ, "[1, 1]")
+}
+
+func TestContainsConstraint(t *testing.T) {
+	typ := loadType(t, `type::{name: t, contains: [1, a, [b]]}`, "t")
+	testValid(t, typ, "[a, 2, [b], 1]", "(1 a [b])")
+	testInvalid(t, typ, "[1, a]", "[1, a, [c]]", "1")
+}
+
+func TestValidValuesConstraint(t *testing.T) {
+	typ := loadType(t, `type::{name: t, valid_values: [1, a, "b", range::[10, exclusive::20], range::[2000T, 2001T]]}`, "t")
+	testValid(t, typ, "1", "ann::1", "a", `"b"`, "10", "19.5", "1.5e1", "2000-06-01T")
+	testInvalid(t, typ, "2", "b", "20", "2002T", "1.0e0")
+
+	typ = loadType(t, `$ion_schema_2_0 type::{name: t, valid_values: range::[min, 0]}`, "t")
+	testValid(t, typ, "0", "-inf", "-1.5")
+	testInvalid(t, typ, "1", "+inf", "nan", "a")
+}
+
+func TestRegexConstraint(t *testing.T) {
+	typ := loadType(t, `type::{name: t, regex: "^a+$"}`, "t")
+	testValid(t, typ, `"aa"`, "aa")
+	testInvalid(t, typ, `"AA"`, `"ab"`, "1")
+
+	typ = loadType(t, `type::{name: t, regex: i::"^a+$"}`, "t")
+	testValid(t, typ, `"AA"`)
+}
+
+func TestLengthConstraints(t *testing.T) {
+	doc := `
+		$ion_schema_2_0
+		type::{name: codepoints, codepoint_length: 2}
+		type::{name: utf8, utf8_byte_length: range::[min, 2]}
+		type::{name: container, container_length: range::[1, max]}
+		type::{name: bytes, byte_length: 3}
+	`
+	testValid(t, loadType(t, doc, "codepoints"), `"ab"`, `"é€"`)
+	testInvalid(t, loadType(t, doc, "codepoints"), `"a"`, "[a, b]")
+	testValid(t, loadType(t, doc, "utf8"), `"é"`, `""`)
+	testInvalid(t, loadType(t, doc, "utf8"), `"€"`)
+	testValid(t, loadType(t, doc, "container"), "[1]", "{a: 1}", "(a b)")
+	testInvalid(t, loadType(t, doc, "container"), "[]", `"a"`)
+	testValid(t, loadType(t, doc, "bytes"), "{{YWJj}}", `{{"abc"}}`)
+	testInvalid(t, loadType(t, doc, "bytes"), "{{}}", `"abc"`)
+}
+
+func TestDecimalConstraints(t *testing.T) {
+	doc := `
+		type::{name: precision, precision: range::[1, 3]}
+		type::{name: scale, scale: 2}
+	`
+	testValid(t, loadType(t, doc, "precision"), "1.23", "0.0", "123d5")
+	testInvalid(t, loadType(t, doc, "precision"), "12.34", "1")
+	testValid(t, loadType(t, doc, "scale"), "1.23", "0.00")
+	testInvalid(t, loadType(t, doc, "scale"), "1.2", "1")
+
+	typ := loadType(t, `$ion_schema_2_0 type::{name: t, exponent: range::[-2, 0]}`, "t")
+	testValid(t, typ, "1.23", "1.")
+	testInvalid(t, typ, "1.234", "1d1")
+}
+
+func TestTimestampConstraints(t *testing.T) {
+	doc := `
+		type::{name: precision, timestamp_precision: range::[day, second]}
+		type::{name: offset, timestamp_offset: ["+00:00", "-00:00", "-08:00"]}
+	`
+	testValid(t, loadType(t, doc, "precision"), "2000-01-01", "2000-01-01T00:00Z", "2000-01-01T00:00:00Z")
+	testInvalid(t, loadType(t, doc, "precision"), "2000-01T", "2000-01-01T00:00:00.0Z", "1")
+	testValid(t, loadType(t, doc, "offset"), "2000-01-01T00:00Z", "2000-01-01T00:00-00:00", "2000-01-01T00:00-08:00")
+	testInvalid(t, loadType(t, doc, "offset"), "2000-01-01T00:00+08:00", "1")
+}
+
+func TestAnnotationsConstraint(t *testing.T) {
+	typ := loadType(t, `type::{name: t, annotations: ordered::closed::[a, required::b]}`, "t")
+	testValid(t, typ, "b::1", "a::b::1")
+	testInvalid(t, typ, "1", "a::1", "b::a::1", "c::b::1")
+
+	typ = loadType(t, `$ion_schema_2_0 type::{name: t, annotations: required::[a, b]}`, "t")
+	testValid(t, typ, "a::b::1", "b::a::c::1")
+	testInvalid(t, typ, "a::1")
+
+	typ = loadType(t, `$ion_schema_2_0 type::{name: t, annotations: {container_length: 1, element: {regex: "^x"}}}`, "t")
+	testValid(t, typ, "xy::1")
+	testInvalid(t, typ, "1", "y::1", "x::x::1")
+}
+
+func TestIEEE754FloatConstraint(t *testing.T) {
+	doc := `
+		$ion_schema_2_0
+		type::{name: half, ieee754_float: binary16}
+		type::{name: single, ieee754_float: binary32}
+	`
+	testValid(t, loadType(t, doc, "half"), "1e0", "65504e0", "0.5e0", "nan", "-inf", "5.960464477539063e-8")
+	testInvalid(t, loadType(t, doc, "half"), "65505e0", "0.1e0", "1", "2.9802322387695312e-8")
+	testValid(t, loadType(t, doc, "single"), "0.5e0", "16777216e0")
+	testInvalid(t, loadType(t, doc, "single"), "0.1e0", "16777217e0")
+}
+
+func TestOrderedElementsConstraint(t *testing.T) {
+	typ := loadType(t, `
+		type::{name: t, ordered_elements: [symbol, {type: int, occurs: range::[0, max]}, {type: int, occurs: required}, {type: string, occurs: optional}]}
+	`, "t")
+	testValid(t, typ, "[a, 1]", "(a 1 2 3)", `[a, 1, "x"]`)
+	testInvalid(t, typ, "[a]", "[1]", `[a, "x"]`, `[a, 1, "x", "y"]`, "{}")
+}
+
+func TestFieldNamesConstraint(t *testing.T) {
+	typ := loadType(t, `$ion_schema_2_0 type::{name: t, field_names: distinct::{regex: "^[a-z]+$"}}`, "t")
+	testValid(t, typ, "{a: 1, b: 2}", "{}")
+	testInvalid(t, typ, "{a: 1, a: 2}", "{A: 1}", "[]")
+}
+
+func TestVersionSpecificConstraints(t *testing.T) {
+	// Constraints from the other ISL version are open content.
+	typ := loadType(t, `type::{name: t, exponent: 1}`, "t")
+	testValid(t, typ, "1.234")
+
+	typ = loadType(t, `$ion_schema_2_0 type::{name: t, scale: 1}`, "t")
+	testValid(t, typ, "1.234")
+}
@@ -0,0 +1,350 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionschema
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/amazon-ion/ion-go/ion"
+)
+
+// rangeKind identifies the kind of values a valueRange holds.
+type rangeKind uint8
+
+const (
+	intRange rangeKind = iota
+	numberRange
+	timestampRange
+	precisionRange
+)
+
+// A valueRange is an (optionally open-ended, optionally exclusive) interval. All
+// kinds of ranges are represented using rationals: ints and numbers directly,
+// timestamps as seconds since the epoch, and timestamp precisions as the number
+// of digits after the seconds (see timestampPrecisions).
+type valueRange struct {
+	kind             rangeKind
+	min, max         *big.Rat
+	minExcl, maxExcl bool
+}
+
+// contains returns true if the given value falls within the range.
+func (r *valueRange) contains(x *big.Rat) bool {
+	if r.min != nil {
+		c := x.Cmp(r.min)
+		if c < 0 || (c == 0 && r.minExcl) {
+			return false
+		}
+	}
+	if r.max != nil {
+		c := x.Cmp(r.max)
+		if c > 0 || (c == 0 && r.maxExcl) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsInt returns true if the given int falls within the range.
+func (r *valueRange) containsInt(i int) bool {
+	return r.contains(new(big.Rat).SetInt64(int64(i)))
+}
+
+// minInt returns the smallest int in the range (or 0 if unbounded).
+func (r *valueRange) minInt() int {
+	if r.min == nil {
+		return 0
+	}
+	i := ratCeil(r.min)
+	if r.minExcl && new(big.Rat).SetInt64(int64(i)).Cmp(r.min) == 0 {
+		i++
+	}
+	return i
+}
+
+// maxInt returns the largest int in the range (or MaxInt32 if unbounded).
+func (r *valueRange) maxInt() int {
+	if r.max == nil {
+		return math.MaxInt32
+	}
+	i := ratFloor(r.max)
+	if r.maxExcl && new(big.Rat).SetInt64(int64(i)).Cmp(r.max) == 0 {
+		i--
+	}
+	return i
+}
+
+// String returns the range in ISL syntax.
+func (r *valueRange) String() string {
+	if r.min != nil && r.max != nil && !r.minExcl && !r.maxExcl && r.min.Cmp(r.max) == 0 {
+		return r.boundString(r.min)
+	}
+
+	min, max := "min", "max"
+	if r.min != nil {
+		min = r.boundString(r.min)
+		if r.minExcl {
+			min = "exclusive::" + min
+		}
+	}
+	if r.max != nil {
+		max = r.boundString(r.max)
+		if r.maxExcl {
+			max = "exclusive::" + max
+		}
+	}
+	return fmt.Sprintf("range::[%v, %v]", min, max)
+}
+
+// boundString returns a single bound in ISL syntax.
+func (r *valueRange) boundString(b *big.Rat) string {
+	switch r.kind {
+	case precisionRange:
+		for name, p := range timestampPrecisions {
+			if b.Cmp(big.NewRat(int64(p), 1)) == 0 {
+				return name
+			}
+		}
+	case timestampRange:
+		f, _ := b.Float64()
+		sec := math.Floor(f)
+		return time.Unix(int64(sec), int64((f-sec)*1e9)).UTC().Format(time.RFC3339Nano)
+	}
+	if b.IsInt() {
+		return b.Num().String()
+	}
+	return b.FloatString(10)
+}
+
+// timestampPrecisions maps the precision names used by the timestamp_precision
+// constraint to the number of digits after the seconds; coarser precisions are
+// negative.
+var timestampPrecisions = map[string]int{
+	"year":        -4,
+	"month":       -3,
+	"day":         -2,
+	"minute":      -1,
+	"second":      0,
+	"millisecond": 3,
+	"microsecond": 6,
+	"nanosecond":  9,
+}
+
+// parseRange parses either a single value (an exact range) or a range::[min, max].
+func parseRange(v ion.Value, kind rangeKind) (*valueRange, error) {
+	if !hasAnnotation(v, "range") {
+		if len(v.Annotations()) > 0 {
+			return nil, fmt.Errorf("unexpected annotations on %v", valueString(v))
+		}
+		x, err := rangeBound(v, kind)
+		if err != nil {
+			return nil, err
+		}
+		if x == nil {
+			return nil, fmt.Errorf("invalid range %v", valueString(v))
+		}
+		return &valueRange{kind: kind, min: x, max: x}, nil
+	}
+
+	l, ok := v.(*ion.ListValue)
+	if !ok || l.IsNull() || l.Len() != 2 || len(v.Annotations()) != 1 {
+		return nil, fmt.Errorf("invalid range %v", valueString(v))
+	}
+
+	r := &valueRange{kind: kind}
+	var err error
+
+	min, max := l.Get(0), l.Get(1)
+	if r.minExcl, err = boundExclusive(min); err != nil {
+		return nil, err
+	}
+	if r.maxExcl, err = boundExclusive(max); err != nil {
+		return nil, err
+	}
+
+	if !isSymbol(min, "min") {
+		if r.min, err = rangeBound(min, kind); err != nil {
+			return nil, err
+		}
+		if r.min == nil {
+			return nil, fmt.Errorf("invalid range minimum %v", valueString(min))
+		}
+	} else if r.minExcl {
+		return nil, fmt.Errorf("min cannot be exclusive")
+	}
+
+	if !isSymbol(max, "max") {
+		if r.max, err = rangeBound(max, kind); err != nil {
+			return nil, err
+		}
+		if r.max == nil {
+			return nil, fmt.Errorf("invalid range maximum %v", valueString(max))
+		}
+	} else if r.maxExcl {
+		return nil, fmt.Errorf("max cannot be exclusive")
+	}
+
+	if r.min == nil && r.max == nil {
+		return nil, fmt.Errorf("range must have at least one bound")
+	}
+	if r.min != nil && r.max != nil {
+		c := r.min.Cmp(r.max)
+		if c > 0 || (c == 0 && (r.minExcl || r.maxExcl)) {
+			return nil, fmt.Errorf("range %v is empty", valueString(v))
+		}
+	}
+
+	return r, nil
+}
+
+// boundExclusive returns true if a range bound is annotated exclusive.
+func boundExclusive(v ion.Value) (bool, error) {
+	as := v.Annotations()
+	if len(as) == 0 {
+		return false, nil
+	}
+	if len(as) == 1 && as[0].Text != nil && *as[0].Text == "exclusive" {
+		return true, nil
+	}
+	return false, fmt.Errorf("unexpected annotations on range bound %v", valueString(v))
+}
+
+// rangeBound converts a range bound to a rational, returning nil if the value is
+// not of the right kind.
+func rangeBound(v ion.Value, kind rangeKind) (*big.Rat, error) {
+	if v.IsNull() {
+		return nil, nil
+	}
+
+	switch kind {
+	case intRange:
+		if i, ok := v.(*ion.IntValue); ok {
+			return new(big.Rat).SetInt(i.BigInt()), nil
+		}
+	case numberRange:
+		x, inf := numberRat(v)
+		if inf != 0 {
+			return nil, fmt.Errorf("range bounds must be finite")
+		}
+		return x, nil
+	case timestampRange:
+		if ts, ok := v.(*ion.TimestampValue); ok {
+			if ts.Timestamp().GetTimezoneKind() == ion.TimezoneUnspecified && ts.Timestamp().GetPrecision() > ion.TimestampPrecisionDay {
+				return nil, fmt.Errorf("timestamp range bounds must have a known offset")
+			}
+			return timestampRat(ts.Timestamp()), nil
+		}
+	case precisionRange:
+		if s, ok := v.(*ion.SymbolValue); ok && s.Symbol().Text != nil {
+			if p, ok := timestampPrecisions[*s.Symbol().Text]; ok {
+				return big.NewRat(int64(p), 1), nil
+			}
+			return nil, fmt.Errorf("invalid timestamp precision %v", *s.Symbol().Text)
+		}
+	}
+
+	return nil, nil
+}
+
+// numberRat converts a non-null number to a rational. It returns nil for non-numbers
+// and NaN, and a non-zero inf for infinite floats.
+func numberRat(v ion.Value) (*big.Rat, int) {
+	if v.IsNull() {
+		return nil, 0
+	}
+
+	switch v := v.(type) {
+	case *ion.IntValue:
+		return new(big.Rat).SetInt(v.BigInt()), 0
+	case *ion.DecimalValue:
+		return decimalRat(v.Decimal()), 0
+	case *ion.FloatValue:
+		f := v.Float()
+		switch {
+		case math.IsNaN(f):
+			return nil, 0
+		case math.IsInf(f, 1):
+			return nil, 1
+		case math.IsInf(f, -1):
+			return nil, -1
+		}
+		return new(big.Rat).SetFloat64(f), 0
+	}
+	return nil, 0
+}
+
+// decimalRat converts a decimal to a rational.
+func decimalRat(d *ion.Decimal) *big.Rat {
+	coef, exp := d.CoEx()
+	r := new(big.Rat).SetInt(coef)
+
+	pow := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(int(exp)))), nil)
+	if exp >= 0 {
+		return r.Mul(r, new(big.Rat).SetInt(pow))
+	}
+	return r.Quo(r, new(big.Rat).SetInt(pow))
+}
+
+// timestampRat converts a timestamp to seconds since the epoch.
+func timestampRat(ts ion.Timestamp) *big.Rat {
+	t := ts.GetDateTime()
+	r := new(big.Rat).SetInt64(t.Unix())
+	return r.Add(r, big.NewRat(int64(t.Nanosecond()), 1e9))
+}
+
+// timestampPrecision returns the precision of a timestamp in the same units as
+// timestampPrecisions.
+func timestampPrecision(ts ion.Timestamp) int {
+	switch ts.GetPrecision() {
+	case ion.TimestampPrecisionYear:
+		return -4
+	case ion.TimestampPrecisionMonth:
+		return -3
+	case ion.TimestampPrecisionDay:
+		return -2
+	case ion.TimestampPrecisionMinute:
+		return -1
+	case ion.TimestampPrecisionSecond:
+		return 0
+	default:
+		return int(ts.GetNumberOfFractionalSeconds())
+	}
+}
+
+// ratFloor returns the largest int less than or equal to r.
+func ratFloor(r *big.Rat) int {
+	q := new(big.Int).Div(r.Num(), r.Denom())
+	return int(q.Int64())
+}
+
+// ratCeil returns the smallest int greater than or equal to r.
+func ratCeil(r *big.Rat) int {
+	i := ratFloor(r)
+	if !r.IsInt() {
+		i++
+	}
+	return i
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
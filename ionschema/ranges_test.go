@@ -0,0 +1,74 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionschema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRange(t *testing.T) {
+	test := func(text string, kind rangeKind, expected string) {
+		t.Run(text, func(t *testing.T) {
+			r, err := parseRange(value(t, text), kind)
+			require.NoError(t, err)
+			assert.Equal(t, expected, r.String())
+		})
+	}
+
+	test("3", intRange, "3")
+	test("range::[1, 3]", intRange, "range::[1, 3]")
+	test("range::[min, exclusive::3]", intRange, "range::[min, exclusive::3]")
+	test("range::[exclusive::1.5, max]", numberRange, "range::[exclusive::1.5000000000, max]")
+	test("range::[1e0, 2d0]", numberRange, "range::[1, 2]")
+	test("range::[day, second]", precisionRange, "range::[day, second]")
+	test("range::[2000-01-01T00:00Z, max]", timestampRange, "range::[2000-01-01T00:00:00Z, max]")
+}
+
+func TestParseRangeErrors(t *testing.T) {
+	test := func(text string, kind rangeKind) {
+		t.Run(text, func(t *testing.T) {
+			_, err := parseRange(value(t, text), kind)
+			assert.Error(t, err)
+		})
+	}
+
+	test("a", intRange)
+	test("1.5", intRange)
+	test("x::1", intRange)
+	test("range::[min, max]", intRange)
+	test("range::[3, 1]", intRange)
+	test("range::[exclusive::1, 1]", intRange)
+	test("range::[exclusive::min, 1]", intRange)
+	test("range::[1]", intRange)
+	test("range::[0, +inf]", numberRange)
+	test("range::[2000-01-01T00:00-00:00, max]", timestampRange)
+	test("range::[day, week]", precisionRange)
+}
+
+func TestRangeInts(t *testing.T) {
+	r, err := parseRange(value(t, "range::[exclusive::1, exclusive::5]"), intRange)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, r.minInt())
+	assert.Equal(t, 4, r.maxInt())
+	assert.False(t, r.containsInt(1))
+	assert.True(t, r.containsInt(2))
+	assert.True(t, r.containsInt(4))
+	assert.False(t, r.containsInt(5))
+}
@@ -0,0 +1,615 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+// Package ionschema implements the Ion Schema Language (ISL), versions 1.0 and 2.0.
+//
+// Schemas are loaded through a System, which finds schema documents (and the
+// schemas they import) using one or more Authorities:
+//
+//	sys := ionschema.NewSystem(ionschema.NewFileAuthority("schemas"))
+//	schema, err := sys.LoadSchema("orders.isl")
+//	...
+//	violations := schema.Type("order").Validate(value)
+//
+// Values are validated in memory, as ion.Values; see Type.ValidateReader to
+// validate the value an ion.Reader is positioned on.
+package ionschema
+
+import (
+	"fmt"
+
+	"github.com/amazon-ion/ion-go/ion"
+)
+
+// Version markers for schema documents.
+const (
+	islVersion1 = "$ion_schema_1_0"
+	islVersion2 = "$ion_schema_2_0"
+)
+
+// An InvalidSchemaError is returned when a schema document is not valid ISL.
+type InvalidSchemaError struct {
+	ID  string
+	Msg string
+}
+
+func (e *InvalidSchemaError) Error() string {
+	if e.ID == "" {
+		return fmt.Sprintf("ionschema: invalid schema: %v", e.Msg)
+	}
+	return fmt.Sprintf("ionschema: invalid schema %v: %v", e.ID, e.Msg)
+}
+
+// A Schema is a collection of named types, loaded from a schema document.
+type Schema struct {
+	id      string
+	version string
+	system  *System
+
+	types   map[string]*Type
+	ordered []*Type
+	imports []schemaImport
+
+	// The type references made by this schema, resolved once loading completes.
+	refs []*typeRef
+
+	// The types made visible by this schema's imports, keyed by (alias) name.
+	imported map[string]*Type
+}
+
+// A schemaImport is an entry in a schema header's imports list.
+type schemaImport struct {
+	id    string
+	typ   string
+	alias string
+}
+
+// ID returns the ID the schema was loaded with, or "" for a schema created
+// directly from a document.
+func (s *Schema) ID() string {
+	return s.id
+}
+
+// Version returns the ISL version of the schema, either "1.0" or "2.0".
+func (s *Schema) Version() string {
+	if s.version == islVersion2 {
+		return "2.0"
+	}
+	return "1.0"
+}
+
+// Types returns the types declared by the schema, in declaration order.
+func (s *Schema) Types() []*Type {
+	return s.ordered
+}
+
+// Type returns the type with the given name, which may be declared by the
+// schema, imported by it, or built in. It returns nil if there is no such type.
+func (s *Schema) Type(name string) *Type {
+	if t, ok := s.types[name]; ok {
+		return t
+	}
+	if t, ok := s.imported[name]; ok {
+		return t
+	}
+	return builtinTypes[name]
+}
+
+// v2 returns true if this is an ISL 2.0 schema.
+func (s *Schema) v2() bool {
+	return s.version == islVersion2
+}
+
+// errorf returns an InvalidSchemaError for this schema.
+func (s *Schema) errorf(format string, args ...interface{}) error {
+	return &InvalidSchemaError{s.id, fmt.Sprintf(format, args...)}
+}
+
+// parse parses the given schema document.
+func (s *Schema) parse(vs []ion.Value, loaded *[]*Schema) error {
+	s.version = islVersion1
+	s.types = map[string]*Type{}
+
+	for i, v := range vs {
+		if sym, ok := v.(*ion.SymbolValue); ok && !v.IsNull() && len(v.Annotations()) == 0 {
+			text := sym.Symbol().Text
+			if text != nil && (*text == islVersion1 || *text == islVersion2) {
+				if i != 0 {
+					return s.errorf("version marker %v must be the first value", *text)
+				}
+				s.version = *text
+				continue
+			}
+		}
+
+		switch {
+		case hasAnnotation(v, "schema_header"):
+			if err := s.parseHeader(v, loaded); err != nil {
+				return err
+			}
+		case hasAnnotation(v, "type"):
+			p := parser{s}
+			t, err := p.parseTypeDef(v, true, false)
+			if err != nil {
+				return err
+			}
+			if _, ok := s.types[t.name]; ok {
+				return s.errorf("duplicate type %v", t.name)
+			}
+			if _, ok := builtinTypes[t.name]; ok {
+				return s.errorf("type %v redefines a built-in type", t.name)
+			}
+			s.types[t.name] = t
+			s.ordered = append(s.ordered, t)
+		}
+		// Anything else (including schema_footer) is open content.
+	}
+
+	return nil
+}
+
+// parseHeader parses a schema header, loading the schemas it imports.
+func (s *Schema) parseHeader(v ion.Value, loaded *[]*Schema) error {
+	header, ok := v.(*ion.StructValue)
+	if !ok || header.IsNull() {
+		return s.errorf("schema_header must be a struct")
+	}
+
+	imports := header.Get("imports")
+	if imports == nil {
+		return nil
+	}
+
+	l, ok := imports.(*ion.ListValue)
+	if !ok || l.IsNull() {
+		return s.errorf("imports must be a list")
+	}
+
+	for _, iv := range l.Values() {
+		imp, err := s.parseImport(iv)
+		if err != nil {
+			return err
+		}
+		s.imports = append(s.imports, imp)
+
+		if s.system != nil {
+			if _, err := s.system.load(imp.id, loaded); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseImport parses a single import, either in a schema header or inline.
+func (s *Schema) parseImport(v ion.Value) (schemaImport, error) {
+	var imp schemaImport
+
+	st, ok := v.(*ion.StructValue)
+	if !ok || st.IsNull() {
+		return imp, s.errorf("import must be a struct")
+	}
+
+	id, ok := textOf(st.Get("id"))
+	if !ok || st.Get("id").Type() != ion.StringType {
+		return imp, s.errorf("import id must be a string")
+	}
+	imp.id = id
+
+	if tv := st.Get("type"); tv != nil {
+		if imp.typ, ok = symbolOf(tv); !ok {
+			return imp, s.errorf("import type must be a symbol")
+		}
+	}
+	if av := st.Get("as"); av != nil {
+		if imp.alias, ok = symbolOf(av); !ok {
+			return imp, s.errorf("import alias must be a symbol")
+		}
+		if imp.typ == "" {
+			return imp, s.errorf("import alias requires a type")
+		}
+	}
+
+	return imp, nil
+}
+
+// resolve resolves the schema's imports and type references. It's called once
+// all schemas being loaded have been parsed, and may load more schemas (for
+// inline imports), which are appended to loaded.
+func (s *Schema) resolve(loaded *[]*Schema) error {
+	s.imported = map[string]*Type{}
+
+	for _, imp := range s.imports {
+		other, err := s.system.load(imp.id, loaded)
+		if err != nil {
+			return err
+		}
+
+		if imp.typ == "" {
+			for _, t := range other.ordered {
+				s.imported[t.name] = t
+			}
+			continue
+		}
+
+		t, ok := other.types[imp.typ]
+		if !ok {
+			return s.errorf("schema %v has no type %v", imp.id, imp.typ)
+		}
+		name := imp.typ
+		if imp.alias != "" {
+			name = imp.alias
+		}
+		s.imported[name] = t
+	}
+
+	for _, ref := range s.refs {
+		if err := ref.resolve(loaded); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// A Type is a named or anonymous ISL type definition.
+type Type struct {
+	name   string
+	schema *Schema
+
+	constraints []constraint
+
+	// For types used within fields or ordered_elements, how many times a
+	// matching value may occur.
+	occurs *valueRange
+}
+
+// Name returns the name of the type, or "" for an anonymous type.
+func (t *Type) Name() string {
+	return t.name
+}
+
+// String returns the name of the type, or a placeholder for an anonymous type.
+func (t *Type) String() string {
+	if t.name == "" {
+		return "<anonymous type>"
+	}
+	return t.name
+}
+
+// Validate validates the given value against the type, returning any
+// violations. A value is valid if there are no violations.
+func (t *Type) Validate(v ion.Value) []Violation {
+	return t.validate(v, "")
+}
+
+// IsValid returns true if the given value is valid for the type.
+func (t *Type) IsValid(v ion.Value) bool {
+	return len(t.Validate(v)) == 0
+}
+
+// ValidateReader reads the value the given reader is positioned on and validates
+// it against the type. If the value is invalid, a *ValidationError describing
+// the violations is returned.
+func (t *Type) ValidateReader(r ion.Reader) error {
+	v, err := ion.ReadValue(r)
+	if err != nil {
+		return err
+	}
+
+	if vs := t.Validate(v); len(vs) > 0 {
+		return &ValidationError{t.String(), vs}
+	}
+	return nil
+}
+
+// validate validates a value at the given path against the type.
+func (t *Type) validate(v ion.Value, path string) []Violation {
+	var vs []Violation
+	for _, c := range t.constraints {
+		vs = append(vs, c.validate(v, path)...)
+	}
+	return vs
+}
+
+// builtin returns the type's built-in constraint, if it is a built-in type.
+func (t *Type) builtin() *builtinConstraint {
+	if len(t.constraints) == 1 {
+		if b, ok := t.constraints[0].(*builtinConstraint); ok {
+			return b
+		}
+	}
+	return nil
+}
+
+// acceptsNullOf returns true if the type's base type accepts values of the given
+// Ion type; used by the ISL 1.0 nullable:: annotation.
+func (t *Type) acceptsNullOf(it ion.Type) bool {
+	if b := t.builtin(); b != nil {
+		return b.acceptsNullOf(it)
+	}
+	for _, c := range t.constraints {
+		if tc, ok := c.(*typeConstraint); ok {
+			return tc.ref.resolved.acceptsNullOf(it)
+		}
+	}
+	return true
+}
+
+// A typeRef is a reference to a type: by name, by inline definition, or by
+// inline import.
+type typeRef struct {
+	schema *Schema
+
+	name     string
+	importID string
+	inline   *Type
+
+	// ISL 1.0 nullable:: and ISL 2.0 $null_or:: annotations.
+	nullable bool
+	nullOr   bool
+
+	resolved *Type
+}
+
+// resolve looks up the type the reference refers to.
+func (r *typeRef) resolve(loaded *[]*Schema) error {
+	switch {
+	case r.inline != nil:
+		r.resolved = r.inline
+
+	case r.importID != "":
+		other, err := r.schema.system.load(r.importID, loaded)
+		if err != nil {
+			return err
+		}
+		t, ok := other.types[r.name]
+		if !ok {
+			return r.schema.errorf("schema %v has no type %v", r.importID, r.name)
+		}
+		r.resolved = t
+
+	default:
+		t := r.schema.Type(r.name)
+		if t == nil {
+			return r.schema.errorf("unknown type %v", r.name)
+		}
+		r.resolved = t
+	}
+
+	return nil
+}
+
+// validate validates a value against the referenced type.
+func (r *typeRef) validate(v ion.Value, path string) []Violation {
+	if v.IsNull() {
+		if r.nullOr && v.Type() == ion.NullType {
+			return nil
+		}
+		if r.nullable && r.resolved.acceptsNullOf(v.Type()) {
+			return nil
+		}
+	}
+	return r.resolved.validate(v, path)
+}
+
+// matches returns true if the value is valid for the referenced type.
+func (r *typeRef) matches(v ion.Value) bool {
+	return len(r.validate(v, "")) == 0
+}
+
+// String returns a description of the referenced type.
+func (r *typeRef) String() string {
+	if r.inline != nil {
+		return r.inline.String()
+	}
+	return r.name
+}
+
+// A parser parses type definitions and references within a schema.
+type parser struct {
+	schema *Schema
+}
+
+// parseTypeDef parses a type definition. Named definitions are top-level type::
+// declarations; allowOccurs is set for inline types in fields and ordered_elements.
+func (p *parser) parseTypeDef(v ion.Value, named, allowOccurs bool) (*Type, error) {
+	s, ok := v.(*ion.StructValue)
+	if !ok || s.IsNull() {
+		return nil, p.schema.errorf("type definition must be a struct, found %v", valueString(v))
+	}
+
+	t := &Type{schema: p.schema}
+	hasType := false
+	closed := false
+	seen := map[string]bool{}
+
+	for _, f := range s.Fields() {
+		if f.Name.Text == nil {
+			return nil, p.schema.errorf("type definition field name has unknown text")
+		}
+		name := *f.Name.Text
+
+		if seen[name] && (constraintParsers[name] != nil || name == "name" || name == "occurs") {
+			return nil, p.schema.errorf("duplicate %v in type definition", name)
+		}
+		seen[name] = true
+
+		switch name {
+		case "name":
+			text, ok := symbolOf(f.Value)
+			if !ok || !named {
+				return nil, p.schema.errorf("invalid type name %v", valueString(f.Value))
+			}
+			t.name = text
+
+		case "occurs":
+			if !allowOccurs {
+				return nil, p.schema.errorf("occurs is only valid in fields or ordered_elements")
+			}
+			occurs, err := parseOccurs(f.Value)
+			if err != nil {
+				return nil, p.schema.errorf("invalid occurs: %v", err)
+			}
+			t.occurs = occurs
+
+		case "content":
+			if p.schema.v2() {
+				// content is not a constraint in ISL 2.0; treat it as open content.
+				continue
+			}
+			if !isSymbol(f.Value, "closed") {
+				return nil, p.schema.errorf("content must be the symbol closed")
+			}
+			closed = true
+
+		default:
+			parse := constraintParsers[name]
+			if parse == nil || (p.schema.v2() && v1Constraints[name]) || (!p.schema.v2() && v2Constraints[name]) {
+				// Open content.
+				continue
+			}
+			c, err := parse(p, f.Value)
+			if err != nil {
+				return nil, err
+			}
+			t.constraints = append(t.constraints, c)
+			if name == "type" {
+				hasType = true
+			}
+		}
+	}
+
+	if named && t.name == "" {
+		return nil, p.schema.errorf("type definition is missing a name")
+	}
+
+	if closed {
+		t.closeFields()
+	}
+
+	// In ISL 1.0, a type without a type constraint only accepts non-null values.
+	if !hasType && !p.schema.v2() {
+		ref := &typeRef{schema: p.schema, name: "any", resolved: builtinTypes["any"]}
+		t.constraints = append([]constraint{&typeConstraint{ref}}, t.constraints...)
+	}
+
+	return t, nil
+}
+
+// closeFields implements the ISL 1.0 content: closed constraint.
+func (t *Type) closeFields() {
+	for _, c := range t.constraints {
+		if fc, ok := c.(*fieldsConstraint); ok {
+			fc.closed = true
+			return
+		}
+	}
+	t.constraints = append(t.constraints, &fieldsConstraint{closed: true})
+}
+
+// parseTypeRef parses a type reference. Annotations other than nullable:: and
+// $null_or:: are left for the caller to interpret.
+func (p *parser) parseTypeRef(v ion.Value, allowOccurs bool) (*typeRef, error) {
+	ref := &typeRef{schema: p.schema}
+
+	for _, a := range v.Annotations() {
+		switch {
+		case a.Text != nil && *a.Text == "nullable" && !p.schema.v2():
+			ref.nullable = true
+		case a.Text != nil && *a.Text == "$null_or" && p.schema.v2():
+			ref.nullOr = true
+		}
+	}
+
+	if v.IsNull() {
+		return nil, p.schema.errorf("invalid type reference %v", valueString(v))
+	}
+
+	switch tv := v.(type) {
+	case *ion.SymbolValue:
+		name, _ := symbolOf(tv)
+		if name == "" {
+			return nil, p.schema.errorf("invalid type reference %v", valueString(v))
+		}
+		ref.name = name
+
+	case *ion.StructValue:
+		if tv.Get("id") != nil {
+			imp, err := p.schema.parseImport(tv)
+			if err != nil {
+				return nil, err
+			}
+			if imp.typ == "" || imp.alias != "" {
+				return nil, p.schema.errorf("inline import must have a type and no alias")
+			}
+			ref.importID, ref.name = imp.id, imp.typ
+			break
+		}
+
+		t, err := p.parseTypeDef(tv, false, allowOccurs)
+		if err != nil {
+			return nil, err
+		}
+		ref.inline = t
+
+	default:
+		return nil, p.schema.errorf("invalid type reference %v", valueString(v))
+	}
+
+	p.schema.refs = append(p.schema.refs, ref)
+	return ref, nil
+}
+
+// parseTypeRefs parses a non-empty list of type references.
+func (p *parser) parseTypeRefs(v ion.Value, constraint string) ([]*typeRef, error) {
+	l, ok := v.(*ion.ListValue)
+	if !ok || l.IsNull() || len(v.Annotations()) > 0 {
+		return nil, p.schema.errorf("%v must be a list of types", constraint)
+	}
+
+	refs := make([]*typeRef, 0, l.Len())
+	for _, tv := range l.Values() {
+		ref, err := p.parseTypeRef(tv, false)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// parseOccurs parses an occurs constraint.
+func parseOccurs(v ion.Value) (*valueRange, error) {
+	switch {
+	case isSymbol(v, "optional"):
+		return exactRange(0, 1), nil
+	case isSymbol(v, "required"):
+		return exactRange(1, 1), nil
+	}
+
+	r, err := parseRange(v, intRange)
+	if err != nil {
+		return nil, err
+	}
+	if r.min != nil && r.min.Sign() < 0 {
+		return nil, fmt.Errorf("occurs must not be negative")
+	}
+	if r.max != nil && r.max.Sign() == 0 && r.min != nil && r.min.Sign() == 0 {
+		return nil, fmt.Errorf("occurs must allow at least one occurrence")
+	}
+	return r, nil
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionschema
+
+import (
+	"testing"
+
+	"github.com/amazon-ion/ion-go/ion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadType creates a schema from the given document and returns the named type.
+func loadType(t *testing.T, doc, name string) *Type {
+	s, err := NewSystem().NewSchemaString(doc)
+	require.NoError(t, err)
+	typ := s.Type(name)
+	require.NotNil(t, typ, name)
+	return typ
+}
+
+// value parses a single Ion value.
+func value(t *testing.T, text string) ion.Value {
+	vs, err := ion.ReadValues(ion.NewReaderString(text))
+	require.NoError(t, err)
+	require.Len(t, vs, 1)
+	return vs[0]
+}
+
+// testValid checks that each of the given values is valid for the type.
+func testValid(t *testing.T, typ *Type, texts ...string) {
+	for _, text := range texts {
+		assert.Empty(t, typ.Validate(value(t, text)), text)
+	}
+}
+
+// testInvalid checks that each of the given values is invalid for the type.
+func testInvalid(t *testing.T, typ *Type, texts ...string) {
+	for _, text := range texts {
+		assert.NotEmpty(t, typ.Validate(value(t, text)), text)
+	}
+}
+
+func TestSchemaTypes(t *testing.T) {
+	s, err := NewSystem().NewSchemaString(`
+		$ion_schema_2_0
+		schema_header::{}
+		type::{name: a, type: int}
+		type::{name: b, type: a}
+		schema_footer::{}
+	`)
+	require.NoError(t, err)
+
+	assert.Equal(t, "2.0", s.Version())
+	require.Len(t, s.Types(), 2)
+	assert.Equal(t, "a", s.Types()[0].Name())
+	assert.Equal(t, "b", s.Types()[1].Name())
+	assert.NotNil(t, s.Type("string"))
+	assert.Nil(t, s.Type("c"))
+
+	testValid(t, s.Type("b"), "1", "ann::2")
+	testInvalid(t, s.Type("b"), "1.0", "null.int", "a")
+}
+
+func TestBuiltinTypes(t *testing.T) {
+	s, err := NewSystem().NewSchemaString(`$ion_schema_2_0`)
+	require.NoError(t, err)
+
+	testValid(t, s.Type("text"), `"a"`, "a")
+	testInvalid(t, s.Type("text"), "1", "null.string")
+	testValid(t, s.Type("$text"), "null.string", "null.symbol")
+	testInvalid(t, s.Type("$text"), "null", "null.int")
+	testValid(t, s.Type("number"), "1", "1.0", "1e0")
+	testValid(t, s.Type("any"), "1", "[]")
+	testInvalid(t, s.Type("any"), "null")
+	testValid(t, s.Type("$any"), "null", "null.list")
+	testValid(t, s.Type("$null"), "null")
+	testInvalid(t, s.Type("nothing"), "1", "null")
+}
+
+func TestNullability(t *testing.T) {
+	typ := loadType(t, `
+		$ion_schema_2_0
+		type::{name: t, type: $null_or::int}
+	`, "t")
+	testValid(t, typ, "1", "null")
+	testInvalid(t, typ, "null.int", "a")
+
+	typ = loadType(t, `type::{name: t, type: nullable::int}`, "t")
+	testValid(t, typ, "1", "null", "null.int")
+	testInvalid(t, typ, "null.string")
+}
+
+func TestISL1DefaultType(t *testing.T) {
+	// In ISL 1.0, a type without a type constraint rejects nulls.
+	typ := loadType(t, `type::{name: t, codepoint_length: 1}`, "t")
+	testValid(t, typ, "a")
+	testInvalid(t, typ, "null", "null.string", "ab")
+
+	// In ISL 2.0, it accepts anything.
+	typ = loadType(t, `$ion_schema_2_0 type::{name: t}`, "t")
+	testValid(t, typ, "null", "1")
+}
+
+func TestInvalidSchemas(t *testing.T) {
+	test := func(doc string) {
+		t.Run(doc, func(t *testing.T) {
+			_, err := NewSystem().NewSchemaString(doc)
+			require.Error(t, err)
+			_, ok := err.(*InvalidSchemaError)
+			assert.True(t, ok, err.Error())
+		})
+	}
+
+	test(`type::{name: a, type: b}`)
+	test(`type::{type: int}`)
+	test(`type::{name: a, type: int} type::{name: a, type: int}`)
+	test(`type::{name: int, type: int}`)
+	test(`type::{name: a, occurs: 1}`)
+	test(`type::{name: a, fields: {b: {occurs: range::[2, 1]}}}`)
+	test(`type::{name: a, regex: "("}`)
+	test(`type::{name: a, regex: x::"a"}`)
+	test(`type::{name: a, codepoint_length: range::[-1, 2]}`)
+	test(`type::{name: a, timestamp_offset: ["+25:00"]}`)
+	test(`type::{name: a, all_of: int}`)
+	test(`$ion_schema_2_0 type::{name: a, ieee754_float: binary8}`)
+	test(`$ion_schema_2_0 type::{name: a, fields: {}}`)
+	test(`type::{name: a} $ion_schema_1_0`)
+}
+
+func TestValidateReader(t *testing.T) {
+	typ := loadType(t, `type::{name: t, type: int}`, "t")
+
+	r := ion.NewReaderString("1 a")
+	require.True(t, r.Next())
+	assert.NoError(t, typ.ValidateReader(r))
+
+	require.True(t, r.Next())
+	err := typ.ValidateReader(r)
+	require.Error(t, err)
+
+	verr, ok := err.(*ValidationError)
+	require.True(t, ok)
+	assert.Equal(t, "t", verr.Type)
+	assert.Equal(t, "ionschema: value does not match type t\n  type: expected type int, found symbol", err.Error())
+}
+
+func TestViolationPaths(t *testing.T) {
+	typ := loadType(t, `
+		$ion_schema_2_0
+		type::{name: t, fields: {a: {element: int}, 'b c': string}}
+	`, "t")
+
+	vs := typ.Validate(value(t, `{a: [1, x], 'b c': 2}`))
+	require.Len(t, vs, 1)
+	assert.Equal(t, `fields: one or more fields don't match expectations
+  .a: type: value does not match type <anonymous type>
+    .a: element: one or more elements don't match expectations
+      .a[1]: type: expected type int, found symbol
+  ."b c": type: expected type string, found int`, vs[0].String())
+}
@@ -0,0 +1,201 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionschema
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/amazon-ion/ion-go/ion"
+)
+
+// An Authority finds schema documents by ID.
+type Authority interface {
+	// Find returns the schema document with the given ID, or nil if this
+	// authority doesn't have it.
+	Find(id string) ([]byte, error)
+}
+
+// fileAuthority finds schema documents in a directory.
+type fileAuthority struct {
+	dir string
+}
+
+// NewFileAuthority creates an Authority that treats schema IDs as paths relative
+// to the given directory.
+func NewFileAuthority(dir string) Authority {
+	return &fileAuthority{dir}
+}
+
+func (a *fileAuthority) Find(id string) ([]byte, error) {
+	clean := filepath.Clean(filepath.FromSlash(id))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("ionschema: invalid schema id %v", id)
+	}
+
+	bs, err := ioutil.ReadFile(filepath.Join(a.dir, clean))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return bs, err
+}
+
+// mapAuthority finds schema documents in a map.
+type mapAuthority struct {
+	docs map[string]string
+}
+
+// NewMapAuthority creates an Authority that serves the schema documents in the
+// given map, keyed by ID.
+func NewMapAuthority(docs map[string]string) Authority {
+	return &mapAuthority{docs}
+}
+
+func (a *mapAuthority) Find(id string) ([]byte, error) {
+	doc, ok := a.docs[id]
+	if !ok {
+		return nil, nil
+	}
+	return []byte(doc), nil
+}
+
+// A System loads schemas from its Authorities, caching them by ID.
+type System struct {
+	// Authorities are consulted in order to find schema documents.
+	Authorities []Authority
+
+	// Catalog, if set, is used to resolve shared symbol tables when reading
+	// binary schema documents.
+	Catalog ion.Catalog
+
+	schemas map[string]*Schema
+}
+
+// NewSystem creates a new System that loads schemas from the given authorities.
+func NewSystem(authorities ...Authority) *System {
+	return &System{
+		Authorities: authorities,
+		schemas:     map[string]*Schema{},
+	}
+}
+
+// LoadSchema loads the schema with the given ID, along with any schemas it
+// imports.
+func (sys *System) LoadSchema(id string) (*Schema, error) {
+	var loaded []*Schema
+	s, err := sys.load(id, &loaded)
+	if err != nil {
+		return nil, err
+	}
+	if err := sys.finish(loaded); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewSchema creates a schema from the document read from the given reader. Any
+// schemas it imports are loaded from the system's authorities.
+func (sys *System) NewSchema(r ion.Reader) (*Schema, error) {
+	vs, err := ion.ReadValues(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var loaded []*Schema
+	s := &Schema{system: sys}
+	if err := s.parse(vs, &loaded); err != nil {
+		return nil, err
+	}
+	if err := sys.finish(append([]*Schema{s}, loaded...)); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewSchemaString creates a schema from the given schema document.
+func (sys *System) NewSchemaString(doc string) (*Schema, error) {
+	return sys.NewSchema(ion.NewReaderString(doc))
+}
+
+// load finds and parses the schema with the given ID, appending it (and any
+// schemas it imports) to loaded. Schemas are added to loaded before they're
+// parsed so that import cycles terminate.
+func (sys *System) load(id string, loaded *[]*Schema) (*Schema, error) {
+	if s, ok := sys.schemas[id]; ok {
+		return s, nil
+	}
+	for _, s := range *loaded {
+		if s.id == id {
+			return s, nil
+		}
+	}
+
+	doc, err := sys.find(id)
+	if err != nil {
+		return nil, err
+	}
+
+	vs, err := ion.ReadValues(ion.NewReaderCat(bytes.NewReader(doc), sys.Catalog))
+	if err != nil {
+		return nil, &InvalidSchemaError{id, err.Error()}
+	}
+
+	s := &Schema{id: id, system: sys}
+	*loaded = append(*loaded, s)
+	if err := s.parse(vs, loaded); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// find asks each authority in turn for the schema document with the given ID.
+func (sys *System) find(id string) ([]byte, error) {
+	for _, a := range sys.Authorities {
+		doc, err := a.Find(id)
+		if err != nil {
+			return nil, err
+		}
+		if doc != nil {
+			return doc, nil
+		}
+	}
+	return nil, fmt.Errorf("ionschema: schema %v not found", id)
+}
+
+// finish resolves the references of newly-loaded schemas and, if they're all
+// valid, caches them. Resolving may load further schemas (via inline imports),
+// which are resolved in turn.
+func (sys *System) finish(loaded []*Schema) error {
+	for i := 0; i < len(loaded); i++ {
+		if err := loaded[i].resolve(&loaded); err != nil {
+			return err
+		}
+	}
+
+	if sys.schemas == nil {
+		sys.schemas = map[string]*Schema{}
+	}
+	for _, s := range loaded {
+		if s.id != "" {
+			sys.schemas[s.id] = s
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionschema
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSchemas = map[string]string{
+	"base.isl": `
+		$ion_schema_2_0
+		type::{name: positive, type: int, valid_values: range::[1, max]}
+		type::{name: short, type: string, codepoint_length: range::[0, 3]}
+	`,
+	"order.isl": `
+		$ion_schema_2_0
+		schema_header::{imports: [{id: "base.isl", type: positive, as: qty}]}
+		type::{name: order, fields: closed::{
+			qty: qty,
+			code: {id: "base.isl", type: short},
+			items: {type: list, element: item}
+		}}
+		type::{name: item, type: symbol}
+	`,
+	"all.isl": `
+		$ion_schema_2_0
+		schema_header::{imports: [{id: "base.isl"}]}
+		type::{name: t, one_of: [positive, short]}
+	`,
+	"a.isl": `
+		schema_header::{imports: [{id: "b.isl"}]}
+		type::{name: a, type: list, element: b}
+	`,
+	"b.isl": `
+		schema_header::{imports: [{id: "a.isl"}]}
+		type::{name: b, any_of: [int, a]}
+	`,
+	"broken.isl": `
+		$ion_schema_2_0
+		schema_header::{imports: [{id: "base.isl", type: missing}]}
+	`,
+}
+
+func TestLoadSchema(t *testing.T) {
+	sys := NewSystem(NewMapAuthority(testSchemas))
+
+	s, err := sys.LoadSchema("order.isl")
+	require.NoError(t, err)
+	assert.Equal(t, "order.isl", s.ID())
+	assert.NotNil(t, s.Type("qty"))
+	assert.Nil(t, s.Type("positive"))
+
+	typ := s.Type("order")
+	testValid(t, typ, `{qty: 2, code: "abc", items: [a, b]}`, "{}")
+	testInvalid(t, typ, "{qty: 0}", `{code: "abcd"}`, "{items: [1]}", "{other: 1}")
+
+	// Schemas are cached.
+	again, err := sys.LoadSchema("order.isl")
+	require.NoError(t, err)
+	assert.True(t, s == again)
+
+	base, err := sys.LoadSchema("base.isl")
+	require.NoError(t, err)
+	assert.True(t, s.Type("qty") == base.Type("positive"))
+}
+
+func TestLoadSchemaImportAll(t *testing.T) {
+	sys := NewSystem(NewMapAuthority(testSchemas))
+
+	s, err := sys.LoadSchema("all.isl")
+	require.NoError(t, err)
+	testValid(t, s.Type("t"), "1", `"ab"`)
+	testInvalid(t, s.Type("t"), "0", `"abcd"`)
+}
+
+func TestLoadSchemaCycle(t *testing.T) {
+	sys := NewSystem(NewMapAuthority(testSchemas))
+
+	s, err := sys.LoadSchema("a.isl")
+	require.NoError(t, err)
+	testValid(t, s.Type("a"), "[]", "[1, [2, []]]")
+	testInvalid(t, s.Type("a"), "[x]", "[1, [y]]")
+}
+
+func TestLoadSchemaErrors(t *testing.T) {
+	sys := NewSystem(NewMapAuthority(testSchemas))
+
+	_, err := sys.LoadSchema("missing.isl")
+	assert.EqualError(t, err, "ionschema: schema missing.isl not found")
+
+	_, err = sys.LoadSchema("broken.isl")
+	assert.EqualError(t, err, "ionschema: invalid schema broken.isl: schema base.isl has no type missing")
+
+	// A failed load doesn't cache anything.
+	assert.Empty(t, sys.schemas)
+}
+
+func TestNewSchemaImports(t *testing.T) {
+	sys := NewSystem(NewMapAuthority(testSchemas))
+
+	s, err := sys.NewSchemaString(`
+		$ion_schema_2_0
+		schema_header::{imports: [{id: "base.isl", type: short}]}
+		type::{name: t, element: short}
+	`)
+	require.NoError(t, err)
+	assert.Equal(t, "", s.ID())
+	testValid(t, s.Type("t"), `["a"]`)
+	testInvalid(t, s.Type("t"), `["abcd"]`)
+}
+
+func TestFileAuthority(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ionschema")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sub", "s.isl"), []byte(testSchemas["base.isl"]), 0644))
+
+	a := NewFileAuthority(dir)
+
+	doc, err := a.Find("sub/s.isl")
+	require.NoError(t, err)
+	assert.Equal(t, testSchemas["base.isl"], string(doc))
+
+	doc, err = a.Find("missing.isl")
+	assert.NoError(t, err)
+	assert.Nil(t, doc)
+
+	_, err = a.Find("../s.isl")
+	assert.Error(t, err)
+
+	s, err := NewSystem(a).LoadSchema("sub/s.isl")
+	require.NoError(t, err)
+	assert.Len(t, s.Types(), 2)
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionschema
+
+import (
+	"math/big"
+
+	"github.com/amazon-ion/ion-go/ion"
+)
+
+// hasAnnotation returns true if the value is annotated with the given text.
+func hasAnnotation(v ion.Value, text string) bool {
+	for _, a := range v.Annotations() {
+		if a.Text != nil && *a.Text == text {
+			return true
+		}
+	}
+	return false
+}
+
+// isSymbol returns true if the value is the given unannotated symbol.
+func isSymbol(v ion.Value, text string) bool {
+	s, ok := symbolOf(v)
+	return ok && s == text && len(v.Annotations()) == 0
+}
+
+// symbolOf returns the text of a non-null symbol value.
+func symbolOf(v ion.Value) (string, bool) {
+	s, ok := v.(*ion.SymbolValue)
+	if !ok || s.IsNull() || s.Symbol().Text == nil {
+		return "", false
+	}
+	return *s.Symbol().Text, true
+}
+
+// textOf returns the text of a non-null string or symbol value.
+func textOf(v ion.Value) (string, bool) {
+	if s, ok := v.(*ion.StringValue); ok && !s.IsNull() {
+		return s.Text(), true
+	}
+	return symbolOf(v)
+}
+
+// valueString formats a value as Ion text for use in messages.
+func valueString(v ion.Value) string {
+	if v == nil {
+		return "<missing>"
+	}
+	text, err := ion.MarshalText(v)
+	if err != nil {
+		return "<" + v.Type().String() + ">"
+	}
+	return string(text)
+}
+
+// exactRange returns an int range from min to max inclusive.
+func exactRange(min, max int) *valueRange {
+	return &valueRange{
+		kind: intRange,
+		min:  big.NewRat(int64(min), 1),
+		max:  big.NewRat(int64(max), 1),
+	}
+}
+
+// withoutAnnotations returns a copy of v without its (top-level) annotations.
+func withoutAnnotations(v ion.Value) ion.Value {
+	if len(v.Annotations()) == 0 {
+		return v
+	}
+
+	vw := ion.NewValueWriter()
+	if err := v.MarshalIon(vw); err != nil {
+		return v
+	}
+	c := vw.Values()[0]
+	c.SetAnnotations()
+	return c
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionschema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/amazon-ion/ion-go/ion"
+)
+
+// A Violation describes one way in which a value fails to conform to a type.
+type Violation struct {
+	// Path locates the offending value relative to the value being validated,
+	// e.g. ".orders[3].price". The root value's path is empty.
+	Path string
+
+	// Constraint is the name of the constraint that was violated, e.g. "fields".
+	Constraint string
+
+	// Message describes the violation.
+	Message string
+
+	// Children holds the violations that caused this one, e.g. the reasons a
+	// field's value did not match the field's type.
+	Children []Violation
+}
+
+// String returns the violation and its children as an indented, multi-line string.
+func (v Violation) String() string {
+	sb := strings.Builder{}
+	v.writeTo(&sb, 0)
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// writeTo writes the violation to the given builder at the given indentation.
+func (v Violation) writeTo(sb *strings.Builder, indent int) {
+	sb.WriteString(strings.Repeat("  ", indent))
+	if v.Path != "" {
+		sb.WriteString(v.Path)
+		sb.WriteString(": ")
+	}
+	fmt.Fprintf(sb, "%v: %v\n", v.Constraint, v.Message)
+
+	for _, c := range v.Children {
+		c.writeTo(sb, indent+1)
+	}
+}
+
+// A ValidationError is returned when a value read through an ion.Reader does
+// not conform to a type.
+type ValidationError struct {
+	Type       string
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	sb := strings.Builder{}
+	fmt.Fprintf(&sb, "ionschema: value does not match type %v", e.Type)
+	for _, v := range e.Violations {
+		sb.WriteString("\n")
+		v.writeTo(&sb, 1)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// fieldPath returns the path of the given field within the value at path.
+func fieldPath(path string, name ion.SymbolToken) string {
+	text := "$0"
+	if name.Text != nil {
+		text = *name.Text
+	}
+	if isIdentifier(text) {
+		return path + "." + text
+	}
+	return fmt.Sprintf("%v.%q", path, text)
+}
+
+// indexPath returns the path of the i'th element of the value at path.
+func indexPath(path string, i int) string {
+	return fmt.Sprintf("%v[%v]", path, i)
+}
+
+// isIdentifier returns true if the given text can be written in a path unquoted.
+func isIdentifier(text string) bool {
+	if text == "" {
+		return false
+	}
+	for i, c := range text {
+		if !(c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9')) {
+			return false
+		}
+	}
+	return true
+}